@@ -21,6 +21,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/labring/image-cri-shim/pkg/shim"
 	"github.com/labring/image-cri-shim/pkg/types"
@@ -33,6 +34,7 @@ import (
 var cfg *types.Config
 var shimAuth *types.ShimAuthConfig
 var cfgFile string
+var reloadInterval time.Duration
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -69,6 +71,7 @@ func Execute() {
 
 func init() {
 	rootCmd.Flags().StringVarP(&cfgFile, "file", "f", "", "image shim root config")
+	rootCmd.Flags().DurationVar(&reloadInterval, "reload-interval", shim.DefaultReloadInterval, "interval to check the image shim root config for changes, it is also reloaded on SIGHUP")
 }
 
 func run(cfg *types.Config, auth *types.ShimAuthConfig) {
@@ -92,6 +95,7 @@ func run(cfg *types.Config, auth *types.ShimAuthConfig) {
 	signal.Notify(signalCh, syscall.SIGINT, syscall.SIGTERM)
 
 	stopCh := make(chan struct{}, 1)
+	go shim.Watch(imgShim, cfgFile, reloadInterval, stopCh)
 	select {
 	case <-signalCh:
 		close(stopCh)
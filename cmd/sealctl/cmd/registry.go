@@ -26,6 +26,7 @@ func newRegistryCmd() *cobra.Command {
 		Short: "registry related",
 	}
 	cmd.AddCommand(commands.NewServeRegistryCommand())
+	cmd.AddCommand(commands.NewSyncRegistryCommand("sealctl registry"))
 	return cmd
 }
 
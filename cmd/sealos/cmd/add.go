@@ -34,6 +34,10 @@ add to default cluster:
 add with different ssh setting:
 	sealos add --masters x.x.x.x --nodes x.x.x.x --passwd your_diff_passwd
 Please note that the masters and nodes added in one command should have the save password.
+
+keep the joined hosts if adding failed, then continue with the saved Clusterfile:
+	sealos add --nodes x.x.x.x --no-rollback
+	sealos apply -f /root/.sealos/default/Clusterfile --resume
 `
 
 // addCmd represents the add command
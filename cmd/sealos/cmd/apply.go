@@ -15,35 +15,64 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/spf13/cobra"
 
 	"github.com/labring/sealos/pkg/apply"
+	"github.com/labring/sealos/pkg/apply/applydrivers"
 	"github.com/labring/sealos/pkg/utils/logger"
 )
 
 var clusterFile string
 
+var exampleApply = `
+apply a Clusterfile:
+	sealos apply -f Clusterfile
+
+show what would be changed without applying it:
+	sealos apply -f Clusterfile --dry-run
+	sealos apply -f Clusterfile --dry-run -o json
+`
+
 func newApplyCmd() *cobra.Command {
 	applyArgs := &apply.Args{}
+	var (
+		dryRun bool
+		output string
+	)
 	// applyCmd represents the apply command
 	var applyCmd = &cobra.Command{
 		Use:     "apply",
 		Short:   "Run cloud images within a kubernetes cluster with Clusterfile",
-		Example: `sealos apply -f Clusterfile`,
+		Example: exampleApply,
 		Args:    cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			applier, err := apply.NewApplierFromFile(cmd, clusterFile, applyArgs)
 			if err != nil {
 				return err
 			}
+			if dryRun {
+				plan, err := applier.Plan()
+				if err != nil {
+					return err
+				}
+				return plan.Print(os.Stdout, output)
+			}
 			return applier.Apply()
 		},
 		PostRun: func(cmd *cobra.Command, args []string) {
-			logger.Info(getContact())
+			if !dryRun {
+				logger.Info(getContact())
+			}
 		},
 	}
 	setRequireBuildahAnnotation(applyCmd)
 	applyCmd.Flags().StringVarP(&clusterFile, "Clusterfile", "f", "Clusterfile", "apply a kubernetes cluster")
+	applyCmd.Flags().BoolVar(&dryRun, "dry-run", false, "only print the plan of changes, without applying them")
+	applyCmd.Flags().StringVarP(&output, "output", "o", applydrivers.PlanOutputText,
+		fmt.Sprintf("output format of the dry-run plan, one of %s|%s", applydrivers.PlanOutputText, applydrivers.PlanOutputJSON))
 	applyArgs.RegisterFlags(applyCmd.Flags())
 	return applyCmd
 }
@@ -15,21 +15,47 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"path"
+	"text/tabwriter"
+	"time"
 
 	"github.com/labring/sealos/pkg/runtime"
 
 	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
 
 	"github.com/labring/sealos/pkg/apply/processor"
+	"github.com/labring/sealos/pkg/cert"
+	"github.com/labring/sealos/pkg/checker"
 	"github.com/labring/sealos/pkg/clusterfile"
 	"github.com/labring/sealos/pkg/constants"
 	"github.com/labring/sealos/pkg/runtime/factory"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
 	fileutils "github.com/labring/sealos/pkg/utils/file"
+	"github.com/labring/sealos/pkg/utils/flags"
 	"github.com/labring/sealos/pkg/utils/logger"
 )
 
+var exampleCertCheck = `
+show the expiry of all certs in the local workdir and on all masters:
+	sealos cert check
+
+print the expiry in json:
+	sealos cert check -o json
+`
+
+var exampleCertRenew = `
+renew the certs which expire within 30 days:
+	sealos cert renew --before 30d
+
+renew all the renewable certs:
+	sealos cert renew --before 36500d
+`
+
 func newCertCmd() *cobra.Command {
 	var altNames []string
 
@@ -48,49 +74,177 @@ func newCertCmd() *cobra.Command {
     3. kubectl get pod, to check if it works or not
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			processor.SyncNewVersionConfig(clusterName)
-
-			clusterPath := constants.Clusterfile(clusterName)
-			pathResolver := constants.NewPathResolver(clusterName)
-
-			var runtimeConfigPath string
-
-			for _, f := range []string{
-				path.Join(pathResolver.ConfigsPath(), "kubeadm-init.yaml"),
-				path.Join(pathResolver.EtcPath(), "kubeadm-init.yaml"),
-				path.Join(pathResolver.ConfigsPath(), "k3s-init.yaml"),
-			} {
-				if fileutils.IsExist(f) {
-					runtimeConfigPath = f
-					break
-				}
+			cm, err := getCertManager()
+			if errors.Is(err, errCertNotSupported) {
+				// updating the cert SANs used to be a no-op on the runtimes without cert management, e.g. k3s
+				logger.Warn(err)
+				return nil
 			}
-			if runtimeConfigPath == "" {
-				logger.Warn("cannot locate the default runtime config file")
+			if err != nil {
+				return err
 			}
-			var opts []clusterfile.OptionFunc
-			if runtimeConfigPath != "" {
-				opts = append(opts, clusterfile.WithCustomRuntimeConfigFiles([]string{runtimeConfigPath}))
+			return cm.UpdateCertSANs(altNames)
+		},
+	}
+	cmd.PersistentFlags().StringVarP(&clusterName, "cluster", "c", "default", "name of cluster to applied exec action")
+	cmd.Flags().StringSliceVar(&altNames, "alt-names", []string{}, "add extra Subject Alternative Names for certs, domain or ip, eg. sealos.io or 10.103.97.2")
+	_ = cmd.MarkFlagRequired("alt-names")
+	cmd.AddCommand(newCertCheckCmd(), newCertRenewCmd())
+
+	return cmd
+}
+
+var errCertNotSupported = errors.New("sealos cert is not supported")
+
+func getCertManager() (runtime.CertManager, error) {
+	rt, cluster, err := newRuntimeFromClusterName(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	cm, ok := rt.(runtime.CertManager)
+	if !ok {
+		return nil, fmt.Errorf("%w on %s clusters", errCertNotSupported, cluster.GetDistribution())
+	}
+	logger.Info("using %s cert implement", cluster.GetDistribution())
+	return cm, nil
+}
+
+func newCertCheckCmd() *cobra.Command {
+	var (
+		output string
+		before = flags.Duration(30 * 24 * time.Hour)
+	)
+	cmd := &cobra.Command{
+		Use:     "check",
+		Short:   "show the expiry of certs in the local workdir and on all masters",
+		Example: exampleCertCheck,
+		Args:    cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			switch output {
+			case "", checker.OutputJSON, checker.OutputYAML:
+				return nil
+			}
+			return fmt.Errorf("unsupported output format %q, must be one of %s|%s", output, checker.OutputJSON, checker.OutputYAML)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != "" {
+				// keep the logs out of the machine-readable output
+				logger.SetConsoleOutput(os.Stderr)
 			}
-			cf := clusterfile.NewClusterFile(clusterPath, opts...)
-			if err := cf.Process(); err != nil {
+			cm, err := getCertManager()
+			if err != nil {
 				return err
 			}
-
-			rt, err := factory.New(cf.GetCluster(), cf.GetRuntimeConfig())
+			expirations, err := cm.CheckExpiration()
 			if err != nil {
-				return fmt.Errorf("create runtime failed: %v", err)
+				return err
 			}
-			if cm, ok := rt.(runtime.CertManager); ok {
-				logger.Info("using %s cert update implement", cf.GetCluster().GetDistribution())
-				return cm.UpdateCertSANs(altNames)
+			var data []byte
+			switch output {
+			case checker.OutputJSON:
+				data, err = json.MarshalIndent(expirations, "", "  ")
+				data = append(data, '\n')
+			case checker.OutputYAML:
+				data, err = yaml.Marshal(expirations)
+			default:
+				return printExpirations(expirations, time.Duration(before))
+			}
+			if err != nil {
+				return err
 			}
-			return nil
+			_, err = os.Stdout.Write(data)
+			return err
 		},
 	}
-	cmd.Flags().StringVarP(&clusterName, "cluster", "c", "default", "name of cluster to applied exec action")
-	cmd.Flags().StringSliceVar(&altNames, "alt-names", []string{}, "add extra Subject Alternative Names for certs, domain or ip, eg. sealos.io or 10.103.97.2")
-	_ = cmd.MarkFlagRequired("alt-names")
+	cmd.Flags().StringVarP(&output, "output", "o", "",
+		fmt.Sprintf("output format, one of %s|%s, default is a table", checker.OutputJSON, checker.OutputYAML))
+	cmd.Flags().Var(&before, "before", "mark the certs which expire within this duration in the table, eg. 30d or 72h")
+	return cmd
+}
 
+func printExpirations(expirations []cert.Expiration, before time.Duration) error {
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "HOST\tNAME\tEXPIRES\tRESIDUAL TIME\tCA\tRENEWABLE\tSTATUS")
+	for _, e := range expirations {
+		residual := e.ResidualTime(now)
+		status := "OK"
+		switch {
+		case residual <= 0:
+			status = "EXPIRED"
+		case e.ExpiresWithin(now, before):
+			status = "EXPIRING"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%t\t%s\n", e.Host, e.Name, e.NotAfter.Format(time.RFC3339),
+			formatResidualTime(residual), e.CA, e.Renewable, status)
+	}
+	return w.Flush()
+}
+
+func formatResidualTime(d time.Duration) string {
+	if d <= 0 {
+		return "<invalid>"
+	}
+	if days := d / (24 * time.Hour); days > 0 {
+		return fmt.Sprintf("%dd", days)
+	}
+	return d.Truncate(time.Minute).String()
+}
+
+func newCertRenewCmd() *cobra.Command {
+	before := flags.Duration(30 * 24 * time.Hour)
+	cmd := &cobra.Command{
+		Use:     "renew",
+		Short:   "renew the certs which expire soon, the affected static pods are restarted one master at a time",
+		Example: exampleCertRenew,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cm, err := getCertManager()
+			if err != nil {
+				return err
+			}
+			return cm.Renew(time.Duration(before))
+		},
+	}
+	cmd.Flags().Var(&before, "before", "renew the certs which expire within this duration, eg. 30d or 72h")
 	return cmd
 }
+
+// newRuntimeFromClusterName creates the runtime of an existing cluster with its stored runtime config.
+func newRuntimeFromClusterName(clusterName string) (runtime.Interface, *v2.Cluster, error) {
+	processor.SyncNewVersionConfig(clusterName)
+
+	clusterPath := constants.Clusterfile(clusterName)
+	pathResolver := constants.NewPathResolver(clusterName)
+
+	var runtimeConfigPath string
+
+	for _, f := range []string{
+		path.Join(pathResolver.ConfigsPath(), "kubeadm-init.yaml"),
+		path.Join(pathResolver.EtcPath(), "kubeadm-init.yaml"),
+		path.Join(pathResolver.EtcPath(), "k3s-init.yaml"),
+		path.Join(pathResolver.EtcPath(), "rke2-init.yaml"),
+	} {
+		if fileutils.IsExist(f) {
+			runtimeConfigPath = f
+			break
+		}
+	}
+	if runtimeConfigPath == "" {
+		logger.Warn("cannot locate the default runtime config file")
+	}
+	var opts []clusterfile.OptionFunc
+	if runtimeConfigPath != "" {
+		opts = append(opts, clusterfile.WithCustomRuntimeConfigFiles([]string{runtimeConfigPath}))
+	}
+	cf := clusterfile.NewClusterFile(clusterPath, opts...)
+	if err := cf.Process(); err != nil {
+		return nil, nil, err
+	}
+
+	rt, err := factory.New(cf.GetCluster(), cf.GetRuntimeConfig())
+	if err != nil {
+		return nil, nil, fmt.Errorf("create runtime failed: %v", err)
+	}
+	return rt, cf.GetCluster(), nil
+}
@@ -0,0 +1,170 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+
+	"github.com/labring/sealos/pkg/etcd"
+	"github.com/labring/sealos/pkg/runtime"
+	"github.com/labring/sealos/pkg/utils/confirm"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+var exampleEtcdSnapshot = `
+take a snapshot from master0 and keep the latest 5 snapshots:
+	sealos etcd snapshot save
+
+take a named snapshot without removing the old ones:
+	sealos etcd snapshot save --name before-upgrade --retain 0
+
+list the stored snapshots:
+	sealos etcd snapshot list
+
+restore all masters from a stored snapshot or a snapshot file:
+	sealos etcd snapshot restore before-upgrade
+	sealos etcd snapshot restore /path/to/snapshot.db
+`
+
+func newEtcdCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "etcd",
+		Short: "manage the etcd of cluster",
+	}
+	cmd.PersistentFlags().StringVarP(&clusterName, "cluster", "c", "default", "name of cluster to applied etcd action")
+	cmd.AddCommand(newEtcdSnapshotCmd())
+	return cmd
+}
+
+func newEtcdSnapshotCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "snapshot",
+		Short:   "save, restore and list etcd snapshots",
+		Example: exampleEtcdSnapshot,
+	}
+	cmd.AddCommand(newEtcdSnapshotSaveCmd(), newEtcdSnapshotRestoreCmd(), newEtcdSnapshotListCmd())
+	return cmd
+}
+
+func getEtcdManager() (runtime.EtcdManager, error) {
+	rt, cluster, err := newRuntimeFromClusterName(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	em, ok := rt.(runtime.EtcdManager)
+	if !ok {
+		return nil, fmt.Errorf("etcd snapshot is not supported by %s", cluster.GetDistribution())
+	}
+	return em, nil
+}
+
+func newEtcdSnapshotSaveCmd() *cobra.Command {
+	var (
+		name   string
+		retain int
+	)
+	cmd := &cobra.Command{
+		Use:   "save",
+		Short: "take a snapshot from master0 and store it in the cluster workdir",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			em, err := getEtcdManager()
+			if err != nil {
+				return err
+			}
+			store := etcd.NewStore(clusterName)
+			file := store.NewPath(name)
+			if _, err = os.Stat(file); err == nil {
+				return fmt.Errorf("snapshot %s already exists", file)
+			}
+			if err = em.SnapshotSave(file); err != nil {
+				return err
+			}
+			logger.Info("etcd snapshot is saved to %s", file)
+			return store.Prune(retain)
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "name of the snapshot, default is generated from the current time")
+	cmd.Flags().IntVar(&retain, "retain", etcd.DefaultRetainSize, "number of the latest snapshots to keep, 0 to keep all")
+	return cmd
+}
+
+func newEtcdSnapshotRestoreCmd() *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "restore NAME|FILE",
+		Short: "restore the etcd of all masters from a snapshot, the control plane is restarted",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			snapshot, err := etcd.NewStore(clusterName).Get(args[0])
+			if err != nil {
+				return err
+			}
+			status, err := etcd.SnapshotStatus(snapshot.Path)
+			if err != nil {
+				return fmt.Errorf("invalid snapshot %s: %v", snapshot.Path, err)
+			}
+			if !force {
+				prompt := fmt.Sprintf("The control plane of cluster %s will be stopped and all data written after revision %d will be lost. "+
+					"Are you sure to restore from snapshot %s?", clusterName, status.Revision, snapshot.Name)
+				yes, err := confirm.Confirm(prompt, "you have canceled to restore etcd snapshot")
+				if err != nil {
+					return err
+				}
+				if !yes {
+					return errors.New("cancelled")
+				}
+			}
+			em, err := getEtcdManager()
+			if err != nil {
+				return err
+			}
+			if err = em.SnapshotRestore(snapshot.Path); err != nil {
+				return err
+			}
+			logger.Info("etcd is restored from snapshot %s, the control plane is restarting", snapshot.Name)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "restore without confirmation")
+	return cmd
+}
+
+func newEtcdSnapshotListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "list the snapshots stored in the cluster workdir",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			snapshots, err := etcd.NewStore(clusterName).List()
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tSIZE\tCREATED")
+			for _, s := range snapshots {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", s.Name, units.HumanSize(float64(s.Size)), s.CreatedAt.Format(time.RFC3339))
+			}
+			return w.Flush()
+		},
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/labring/sealos/pkg/clusterfile"
+)
+
+var exampleHistory = `
+list the revisions saved after each successful apply:
+	sealos history
+
+print the Clusterfile of a revision:
+	sealos history --revision 3
+
+show the changes from revision 3 to revision 5, or to the latest one:
+	sealos history diff 3 5
+	sealos history diff 3
+`
+
+func newHistoryCmd() *cobra.Command {
+	var revision int
+	cmd := &cobra.Command{
+		Use:     "history",
+		Short:   "list and diff the revisions of cluster",
+		Example: exampleHistory,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			history := clusterfile.NewHistory(clusterName)
+			if revision > 0 {
+				r, err := history.Get(revision)
+				if err != nil {
+					return err
+				}
+				fmt.Print(r.Clusterfile)
+				return nil
+			}
+			list, err := history.List()
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "REVISION\tCREATED\tSEALOS VERSION\tMASTERS\tNODES\tIMAGES")
+			for _, r := range list {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%d\t%s\n", r.Revision, r.CreatedAt.Format(time.RFC3339), r.SealosVersion,
+					len(r.Masters), len(r.Nodes), strings.Join(r.Images, ","))
+			}
+			return w.Flush()
+		},
+	}
+	cmd.PersistentFlags().StringVarP(&clusterName, "cluster", "c", "default", "name of cluster to show history")
+	cmd.Flags().IntVar(&revision, "revision", 0, "print the Clusterfile of the revision")
+	cmd.AddCommand(newHistoryDiffCmd())
+	return cmd
+}
+
+func newHistoryDiffCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "diff FROM [TO]",
+		Short: "show the changes of Clusterfile between two revisions, TO is the latest revision if omitted",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			revisions := make([]int, 2)
+			for i := range args {
+				v, err := strconv.Atoi(args[i])
+				if err != nil || v <= 0 {
+					return fmt.Errorf("invalid revision %q", args[i])
+				}
+				revisions[i] = v
+			}
+			history := clusterfile.NewHistory(clusterName)
+			from, err := history.Get(revisions[0])
+			if err != nil {
+				return err
+			}
+			to, err := history.Get(revisions[1])
+			if err != nil {
+				return err
+			}
+			diff, err := clusterfile.DiffRevisions(from, to)
+			if err != nil {
+				return err
+			}
+			fmt.Print(diff)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/labring/sealos/pkg/apply"
+	"github.com/labring/sealos/pkg/clusterfile"
+	"github.com/labring/sealos/pkg/utils/confirm"
+)
+
+var exampleRollback = `
+re-apply the images and hosts of revision 3:
+	sealos rollback --to 3
+
+roll back without confirmation:
+	sealos rollback --to 3 --force
+`
+
+func newRollbackCmd() *cobra.Command {
+	var (
+		to    int
+		force bool
+	)
+	cmd := &cobra.Command{
+		Use:     "rollback",
+		Short:   "roll back cluster to the images and hosts of a revision",
+		Example: exampleRollback,
+		Args:    cobra.NoArgs,
+		Long: `Roll back cluster to the images and hosts of a revision saved by sealos history.
+
+The app images of the revision are run again, and the app images installed since then are
+dropped from the cluster together with their working containers, but the resources they
+created in the cluster are kept and should be cleaned up manually if needed.
+Only the images and the hosts are rolled back, the configs and the runtime config of the
+revision are not re-applied, compare them with "sealos history diff <revision>".`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if to <= 0 {
+				return errors.New("revision must be specified with --to, see sealos history")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := clusterfile.NewHistory(clusterName).Get(to)
+			if err != nil {
+				return err
+			}
+			if !force {
+				prompt := fmt.Sprintf("The images %s will be run again, the app images installed since then will be dropped from cluster %s without uninstalling the resources they created, "+
+					"and the hosts will be changed to masters %v, nodes %v. "+
+					"Are you sure to roll back to revision %d?", strings.Join(r.Images, ","), clusterName, r.Masters, r.Nodes, r.Revision)
+				yes, err := confirm.Confirm(prompt, "you have canceled to roll back cluster")
+				if err != nil {
+					return err
+				}
+				if !yes {
+					return errors.New("cancelled")
+				}
+			}
+			applier, err := apply.NewRollbackApplier(cmd, clusterName, to)
+			if err != nil {
+				return err
+			}
+			return applier.Apply()
+		},
+	}
+	cmd.Flags().StringVarP(&clusterName, "cluster", "c", "default", "name of cluster to roll back")
+	cmd.Flags().IntVar(&to, "to", 0, "revision to roll back to")
+	cmd.Flags().BoolVar(&force, "force", false, "roll back without confirmation")
+	return cmd
+}
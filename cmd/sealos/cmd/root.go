@@ -15,6 +15,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -25,6 +26,7 @@ import (
 
 	"github.com/labring/sealos/pkg/buildah"
 	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/ssh"
 	"github.com/labring/sealos/pkg/system"
 	"github.com/labring/sealos/pkg/utils/file"
 	"github.com/labring/sealos/pkg/utils/logger"
@@ -41,6 +43,12 @@ var rootCmd = &cobra.Command{
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	//	Run: func(cmd *cobra.Command, args []string) { },
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		if stats := ssh.GetPoolStats(); stats.Dials > 0 {
+			logger.Debug("ssh connection pool: %d dials, %d reuses, %d reconnects, %d evictions",
+				stats.Dials, stats.Reuses, stats.Reconnects, stats.Evictions)
+		}
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -50,6 +58,10 @@ func Execute() {
 		if rootCmd.SilenceErrors {
 			fmt.Println(err)
 		}
+		var exitErr interface{ ExitCode() int }
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
 		os.Exit(1)
 	}
 }
@@ -57,6 +69,8 @@ func Execute() {
 func init() {
 	cobra.OnInitialize(onBootOnDie)
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "enable debug logger")
+	rootCmd.PersistentFlags().BoolVar(&ssh.InsecureSkipHostKeyCheck, "insecure-skip-host-key-check", false,
+		"accept any ssh host key without verifying it against known_hosts and the pinned keys of cluster")
 	buildah.RegisterRootCommand(rootCmd)
 
 	groups := templates.CommandGroups{
@@ -65,8 +79,11 @@ func init() {
 			Commands: []*cobra.Command{
 				newApplyCmd(),
 				newCertCmd(),
+				newEtcdCmd(),
+				newHistoryCmd(),
 				newRunCmd(),
 				newResetCmd(),
+				newRollbackCmd(),
 				newStatusCmd(),
 			},
 		},
@@ -82,6 +99,7 @@ func init() {
 			Commands: []*cobra.Command{
 				newExecCmd(),
 				newScpCmd(),
+				newSSHKeysCmd(),
 			},
 		},
 		{
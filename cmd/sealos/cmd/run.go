@@ -48,6 +48,25 @@ create cluster to your baremetal server, appoint the iplist:
 	sealos run labring/kubernetes:v1.24.0 --single
   
 
+resume a failed run from the last successful pipeline:
+	sealos run labring/kubernetes:v1.24.0 --masters 192.168.0.2,192.168.0.3,192.168.0.4 \
+	--nodes 192.168.0.5,192.168.0.6,192.168.0.7 --passwd 'xxx' --resume
+
+create a cluster whose hosts are only reachable through jump hosts:
+	sealos run labring/kubernetes:v1.24.0 --masters 192.168.0.2 --passwd 'xxx' -J admin@bastion.example.com:2222
+
+authenticate with the keys of ssh-agent and forward the agent to the hosts:
+	sealos run labring/kubernetes:v1.24.0 --masters 192.168.0.2 --identity-agent $SSH_AUTH_SOCK --forward-agent
+
+limit the bandwidth used to sync images to the registry hosts:
+	sealos run labring/kubernetes:v1.24.0 --masters 192.168.0.2 --passwd 'xxx' --registry-sync-bandwidth 50MB
+
+let the synced registry hosts serve images to the others on large clusters:
+	sealos run labring/kubernetes:v1.24.0 --masters 192.168.0.2,192.168.0.3,192.168.0.4 --passwd 'xxx' --registry-sync-fan-out 3
+
+skip some of the pre-flight checks on the hosts:
+	sealos run labring/kubernetes:v1.24.0 --masters 192.168.0.2 --passwd 'xxx' --skip-preflight=swap,disk
+
 create a cluster with custom environment variables:
 	sealos run -e DashBoardPort=8443 mydashboard:latest  --masters 192.168.0.2,192.168.0.3,192.168.0.4 \
 	--nodes 192.168.0.5,192.168.0.6,192.168.0.7 --passwd 'xxx'
@@ -0,0 +1,85 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/labring/sealos/pkg/clusterfile"
+	"github.com/labring/sealos/pkg/ssh"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+var exampleSSHKeysRefresh = `
+pin the current host keys of all hosts in the default cluster again:
+	sealos ssh-keys refresh
+
+rotate the pinned host key of a reinstalled host:
+	sealos ssh-keys refresh --ips 192.168.0.3
+`
+
+func newSSHKeysCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ssh-keys",
+		Short: "manage the ssh host keys pinned for cluster",
+	}
+	cmd.PersistentFlags().StringVarP(&clusterName, "cluster", "c", "default", "name of cluster to manage ssh host keys")
+	cmd.AddCommand(newSSHKeysRefreshCmd())
+	return cmd
+}
+
+func newSSHKeysRefreshCmd() *cobra.Command {
+	var (
+		roles   []string
+		ips     []string
+		cluster *v2.Cluster
+	)
+	cmd := &cobra.Command{
+		Use:     "refresh",
+		Short:   "remove the pinned host keys and pin the keys presented by the hosts now",
+		Example: exampleSSHKeysRefresh,
+		Args:    cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) (err error) {
+			cluster, err = clusterfile.GetClusterFromName(clusterName)
+			return
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var targets []string
+			if len(ips) > 0 || len(roles) > 0 {
+				targets = getTargets(cluster, ips, roles)
+			}
+			if err := ssh.RemovePinnedHostKeys(clusterName, targets...); err != nil {
+				return fmt.Errorf("failed to remove pinned host keys: %v", err)
+			}
+			if len(targets) == 0 {
+				targets = cluster.GetAllIPS()
+			}
+			client := ssh.NewCacheClientFromCluster(cluster, false)
+			for _, target := range targets {
+				if err := client.Ping(target); err != nil {
+					return err
+				}
+			}
+			logger.Info("host keys of %v are pinned in %s", targets, ssh.PinnedHostKeysFile(clusterName))
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVarP(&roles, "roles", "r", []string{}, "refresh the host keys of nodes with role")
+	cmd.Flags().StringSliceVar(&ips, "ips", []string{}, "refresh the host keys of nodes with ip address")
+	return cmd
+}
@@ -16,28 +16,74 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/labring/sealos/pkg/checker"
 	"github.com/labring/sealos/pkg/clusterfile"
+	"github.com/labring/sealos/pkg/utils/logger"
 
 	"github.com/spf13/cobra"
 )
 
+var exampleStatus = `
+show the state of the default cluster:
+	sealos status
+
+run all checkers even if some of them failed:
+	sealos status --all
+
+print a machine-readable report, the exit code is a bitmask of the failed checkers,
+registry=1, cri-shim=2, crictl=4, initsystem=8, node=16, pod=32, service=64, cluster=128:
+	sealos status -o json
+	sealos status -o yaml
+`
+
 // newStatusCmd
 func newStatusCmd() *cobra.Command {
+	var (
+		output string
+		all    bool
+	)
 	checkCmd := &cobra.Command{
-		Use:   "status",
-		Short: "state of sealos",
-		Args:  cobra.NoArgs,
+		Use:     "status",
+		Short:   "state of sealos",
+		Example: exampleStatus,
+		Args:    cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			switch output {
+			case "", checker.OutputJSON, checker.OutputYAML:
+				return nil
+			}
+			return fmt.Errorf("unsupported output format %q, must be one of %s|%s", output, checker.OutputJSON, checker.OutputYAML)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cluster, err := clusterfile.GetClusterFromName(clusterName)
 			if err != nil {
 				return fmt.Errorf("get default cluster failed, %v", err)
 			}
-			list := []checker.Interface{checker.NewRegistryChecker(), checker.NewCRIShimChecker(), checker.NewCRICtlChecker(), checker.NewInitSystemChecker(), checker.NewNodeChecker(), checker.NewPodChecker(), checker.NewSvcChecker(), checker.NewClusterChecker()}
+			inspectors := []checker.Inspector{checker.NewRegistryChecker(), checker.NewCRIShimChecker(), checker.NewCRICtlChecker(), checker.NewInitSystemChecker(), checker.NewNodeChecker(), checker.NewPodChecker(), checker.NewSvcChecker(), checker.NewClusterChecker()}
+			if output != "" {
+				// keep the logs of the checkers out of the report
+				logger.SetConsoleOutput(os.Stderr)
+				report := checker.RunInspectList(inspectors, cluster, checker.PhasePost)
+				if err = report.Print(os.Stdout, output); err != nil {
+					return err
+				}
+				return report.Err()
+			}
+			list := make([]checker.Interface, 0, len(inspectors))
+			for i := range inspectors {
+				list = append(list, inspectors[i])
+			}
+			if all {
+				return checker.RunAllCheckList(list, cluster, checker.PhasePost)
+			}
 			return checker.RunCheckList(list, cluster, checker.PhasePost)
 		},
 	}
 	checkCmd.Flags().StringVarP(&clusterName, "cluster", "c", "default", "name of cluster to applied status action")
+	checkCmd.Flags().StringVarP(&output, "output", "o", "",
+		fmt.Sprintf("output format of the status report, one of %s|%s, all checkers are run if set", checker.OutputJSON, checker.OutputYAML))
+	checkCmd.Flags().BoolVar(&all, "all", false, "run all checkers instead of stopping at the first error")
 	return checkCmd
 }
@@ -27,6 +27,7 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	"github.com/labring/sealos/pkg/apply/processor"
+	"github.com/labring/sealos/pkg/buildah"
 	"github.com/labring/sealos/pkg/client-go/kubernetes"
 	"github.com/labring/sealos/pkg/clusterfile"
 	"github.com/labring/sealos/pkg/constants"
@@ -37,6 +38,7 @@ import (
 	"github.com/labring/sealos/pkg/utils/confirm"
 	"github.com/labring/sealos/pkg/utils/iputils"
 	"github.com/labring/sealos/pkg/utils/logger"
+	stringsutil "github.com/labring/sealos/pkg/utils/strings"
 	"github.com/labring/sealos/pkg/utils/yaml"
 )
 
@@ -82,6 +84,10 @@ type Applier struct {
 	Client             kubernetes.Client
 	CurrentClusterInfo *version.Info
 	RunNewImages       []string
+	// the app images to remove from the cluster, e.g. the ones installed after the revision to roll back to
+	RemoveImages []string
+	// the hosts joined by the failed scale-up have been removed
+	rolledBack bool
 }
 
 func (c *Applier) Apply() error {
@@ -97,10 +103,19 @@ func (c *Applier) Apply() error {
 			return
 		}
 		c.applyAfter()
+		if clusterErr == nil && appErr == nil {
+			c.saveRevision()
+		}
 	}()
+	checkpoint, err := c.loadCheckpoint()
+	if err != nil {
+		clusterErr = processor.NewPreProcessError(err)
+		return clusterErr
+	}
 	c.initStatus()
-	if c.ClusterCurrent == nil || c.ClusterCurrent.CreationTimestamp.IsZero() {
-		if !c.ClusterDesired.CreationTimestamp.IsZero() {
+	resumeCreate := checkpoint != nil && checkpoint.Kind == processor.CheckpointKindCreate
+	if c.ClusterCurrent == nil || c.ClusterCurrent.CreationTimestamp.IsZero() || resumeCreate {
+		if !c.ClusterDesired.CreationTimestamp.IsZero() && !resumeCreate {
 			if yes, _ := confirm.Confirm("Desired cluster CreationTimestamp is not zero, do you want to initialize it again?", "you have canceled to create cluster"); !yes {
 				clusterErr = processor.NewPreProcessError(fmt.Errorf("canceled to create cluster"))
 				return clusterErr
@@ -113,7 +128,7 @@ func (c *Applier) Apply() error {
 		}
 		c.ClusterDesired.CreationTimestamp = metav1.Now()
 	} else {
-		clusterErr, appErr = c.reconcileCluster()
+		clusterErr, appErr = c.reconcileCluster(checkpoint)
 		c.ClusterDesired.CreationTimestamp = c.ClusterCurrent.CreationTimestamp
 	}
 	c.updateStatus(clusterErr, appErr)
@@ -156,11 +171,17 @@ func (c *Applier) updateStatus(clusterErr error, appErr error) {
 	}
 	// update cluster condition using clusterErr
 	var condition v2.ClusterCondition
-	if clusterErr != nil {
+	switch {
+	case clusterErr != nil && c.rolledBack:
+		// the cluster is back to the previous hosts, keep it available for the next apply
+		condition = v2.NewFailedClusterCondition(fmt.Sprintf("%v, rolled back to the previous hosts", clusterErr))
+		c.ClusterDesired.Status.Phase = v2.ClusterSuccess
+		logger.Error("Applied to cluster error: %v", clusterErr)
+	case clusterErr != nil:
 		condition = v2.NewFailedClusterCondition(clusterErr.Error())
 		c.ClusterDesired.Status.Phase = v2.ClusterFailed
 		logger.Error("Applied to cluster error: %v", clusterErr)
-	} else {
+	default:
 		condition = v2.NewSuccessClusterCondition()
 		c.ClusterDesired.Status.Phase = v2.ClusterSuccess
 	}
@@ -181,15 +202,40 @@ func (c *Applier) updateStatus(clusterErr error, appErr error) {
 	c.ClusterDesired.Status.CommandConditions = v2.UpdateCommandCondition(c.ClusterDesired.Status.CommandConditions, cmdCondition)
 }
 
-func (c *Applier) reconcileCluster() (clusterErr error, appErr error) {
+// loadCheckpoint returns the checkpoint of the last failed run if resuming.
+func (c *Applier) loadCheckpoint() (*processor.Checkpoint, error) {
+	if !processor.IsResume(c.Context) {
+		return nil, nil
+	}
+	cp, err := processor.LoadCheckpoint(c.ClusterDesired.Name)
+	if err != nil {
+		return nil, err
+	}
+	if cp == nil {
+		return nil, processor.ErrNoCheckpoint
+	}
+	return cp, nil
+}
+
+func (c *Applier) reconcileCluster(checkpoint *processor.Checkpoint) (clusterErr error, appErr error) {
 	// sync newVersion pki and etc dir in `.sealos/default/pki` and `.sealos/default/etc`
 	processor.SyncNewVersionConfig(c.ClusterDesired.Name)
+	if len(c.RemoveImages) != 0 {
+		if appErr = c.removeApps(c.RemoveImages); appErr != nil {
+			return nil, appErr
+		}
+	}
 	if len(c.RunNewImages) != 0 {
 		logger.Debug("run new images: %+v", c.RunNewImages)
 		if appErr = c.installApp(c.RunNewImages); appErr != nil {
 			return nil, appErr
 		}
 	}
+	if checkpoint != nil && checkpoint.Kind == processor.CheckpointKindScale {
+		// hosts to join have been written into the Clusterfile by the failed run,
+		// so they cannot be computed from the diff anymore.
+		return c.scaleCluster(checkpoint.MastersToJoin, nil, checkpoint.NodesToJoin, nil), nil
+	}
 	mj, md := iputils.GetDiffHosts(c.ClusterCurrent.GetMasterIPAndPortList(), c.ClusterDesired.GetMasterIPAndPortList())
 	nj, nd := iputils.GetDiffHosts(c.ClusterCurrent.GetNodeIPAndPortList(), c.ClusterDesired.GetNodeIPAndPortList())
 	return c.scaleCluster(mj, md, nj, nd), nil
@@ -228,6 +274,30 @@ func (c *Applier) installApp(images []string) error {
 	return nil
 }
 
+// removeApps removes the app images from the cluster and deletes their working containers,
+// the resources created by the apps in the cluster are kept.
+func (c *Applier) removeApps(images []string) error {
+	logger.Info("start to remove apps %v from this cluster", images)
+	bder, err := buildah.New(c.ClusterDesired.Name)
+	if err != nil {
+		return err
+	}
+	return removeApps(bder, c.ClusterDesired, images)
+}
+
+func removeApps(bder buildah.Interface, cluster *v2.Cluster, images []string) error {
+	for _, img := range images {
+		if index, mount := cluster.FindImage(img); mount != nil {
+			if err := bder.Delete(mount.Name); err != nil {
+				return fmt.Errorf("failed to delete working container of %s: %v", img, err)
+			}
+			cluster.Status.Mounts = append(cluster.Status.Mounts[:index], cluster.Status.Mounts[index+1:]...)
+		}
+		cluster.Spec.Image = stringsutil.RemoveFromSlice(cluster.Spec.Image, img)
+	}
+	return nil
+}
+
 func (c *Applier) scaleCluster(mj, md, nj, nd []string) error {
 	if len(mj) == 0 && len(md) == 0 && len(nj) == 0 && len(nd) == 0 {
 		logger.Info("no nodes that need to be scaled")
@@ -236,13 +306,16 @@ func (c *Applier) scaleCluster(mj, md, nj, nd []string) error {
 	logger.Info("start to scale this cluster")
 	logger.Debug("current cluster: master %s, worker %s", c.ClusterCurrent.GetMasterIPAndPortList(), c.ClusterCurrent.GetNodeIPAndPortList())
 	logger.Debug("desired cluster: master %s, worker %s", c.ClusterDesired.GetMasterIPAndPortList(), c.ClusterDesired.GetNodeIPAndPortList())
-	scaleProcessor, err := processor.NewScaleProcessor(c.ClusterFile, c.ClusterDesired.Name, c.ClusterDesired.Spec.Image, mj, md, nj, nd)
+	scaleProcessor, err := processor.NewScaleProcessor(c.Context, c.ClusterFile, c.ClusterDesired.Name, c.ClusterDesired.Spec.Image, mj, md, nj, nd)
 	if err != nil {
 		return err
 	}
 	cluster := c.ClusterDesired
 	err = scaleProcessor.Execute(cluster)
 	if err != nil {
+		if (len(mj) > 0 || len(nj) > 0) && c.shouldRollback(err) {
+			return c.rollbackScale(mj, nj, err)
+		}
 		return err
 	}
 	logger.Info("succeeded in scaling this cluster")
@@ -320,6 +393,17 @@ func (c *Applier) saveClusterFile() {
 	}
 }
 
+// save a revision of the Clusterfile after a successful apply, so that it can be rolled back to
+func (c *Applier) saveRevision() {
+	objects := c.getWriteBackObjects()
+	r, err := clusterfile.NewHistory(c.ClusterDesired.Name).Record(c.ClusterDesired, objects[1:]...)
+	if err != nil {
+		logger.Error("failed to save revision of cluster: %v", err)
+		return
+	}
+	logger.Debug("cluster revision %d is saved", r.Revision)
+}
+
 func (c *Applier) applyAfter() {
 	c.saveClusterFile()
 	c.syncWorkdir()
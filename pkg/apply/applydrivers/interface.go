@@ -17,4 +17,6 @@ package applydrivers
 type Interface interface {
 	Apply() error
 	Delete() error
+	// Plan computes the changes Apply would make without mutating any host.
+	Plan() (*Plan, error)
 }
@@ -0,0 +1,299 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package applydrivers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/labring/sealos/pkg/apply/processor"
+	"github.com/labring/sealos/pkg/buildah"
+	"github.com/labring/sealos/pkg/clusterfile"
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/guest"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/iputils"
+	"github.com/labring/sealos/pkg/utils/maps"
+)
+
+const (
+	PlanActionCreate  = "create"
+	PlanActionInstall = "install"
+	PlanActionScale   = "scale"
+	PlanActionNone    = "none"
+
+	ConfigChangeCreate    = "create"
+	ConfigChangeUpdate    = "update"
+	ConfigChangeUnchanged = "unchanged"
+)
+
+// Plan is the computed diff between the current and the desired cluster,
+// it is produced without mutating any host.
+type Plan struct {
+	ClusterName     string          `json:"clusterName"`
+	Actions         []string        `json:"actions"`
+	MastersToJoin   []string        `json:"mastersToJoin,omitempty"`
+	MastersToDelete []string        `json:"mastersToDelete,omitempty"`
+	NodesToJoin     []string        `json:"nodesToJoin,omitempty"`
+	NodesToDelete   []string        `json:"nodesToDelete,omitempty"`
+	Upgrade         *PlanUpgrade    `json:"upgrade,omitempty"`
+	ImagesToMount   []PlanImage     `json:"imagesToMount,omitempty"`
+	ImagesToUnmount []PlanImage     `json:"imagesToUnmount,omitempty"`
+	GuestCommands   []guest.Command `json:"guestCommands,omitempty"`
+	Configs         []PlanConfig    `json:"configs,omitempty"`
+}
+
+type PlanUpgrade struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type PlanImage struct {
+	Image string       `json:"image"`
+	Type  v2.ImageType `json:"type"`
+	Hosts []string     `json:"hosts"`
+}
+
+type PlanConfig struct {
+	Name     string `json:"name"`
+	Match    string `json:"match,omitempty"`
+	Path     string `json:"path"`
+	Strategy string `json:"strategy,omitempty"`
+	Change   string `json:"change"`
+}
+
+// Plan computes what Apply would do, only the local image storage is inspected.
+func (c *Applier) Plan() (*Plan, error) {
+	plan := &Plan{ClusterName: c.ClusterDesired.Name}
+	if c.ClusterCurrent == nil || c.ClusterCurrent.CreationTimestamp.IsZero() {
+		if err := c.planCreate(plan); err != nil {
+			return nil, err
+		}
+	} else if err := c.planReconcile(plan); err != nil {
+		return nil, err
+	}
+	if err := c.planConfigs(plan); err != nil {
+		return nil, err
+	}
+	if len(plan.Actions) == 0 {
+		plan.Actions = []string{PlanActionNone}
+	}
+	return plan, nil
+}
+
+func (c *Applier) planCreate(plan *Plan) error {
+	desired := c.ClusterDesired
+	plan.Actions = append(plan.Actions, PlanActionCreate)
+	plan.MastersToJoin = desired.GetMasterIPAndPortList()
+	plan.NodesToJoin = desired.GetNodeIPAndPortList()
+	mounts, err := c.inspectMounts(desired.Spec.Image)
+	if err != nil {
+		return err
+	}
+	hosts := desired.GetAllIPS()
+	plan.ImagesToMount = planImages(desired, mounts, hosts)
+	plan.GuestCommands = guest.Commands(desired, mounts, hosts)
+	return nil
+}
+
+func (c *Applier) planReconcile(plan *Plan) error {
+	current, desired := c.ClusterCurrent, c.ClusterDesired
+	if len(c.RunNewImages) > 0 {
+		plan.Actions = append(plan.Actions, PlanActionInstall)
+		mounts, err := c.inspectMounts(c.RunNewImages)
+		if err != nil {
+			return err
+		}
+		hosts := current.GetAllIPS()
+		plan.ImagesToMount = append(plan.ImagesToMount, planImages(current, mounts, hosts)...)
+		plan.GuestCommands = append(plan.GuestCommands, guest.Commands(current, mounts, hosts)...)
+		if rootfs := current.GetRootfsImage(); rootfs != nil {
+			for i := range mounts {
+				if v := mounts[i].KubeVersion(); v != "" && v != rootfs.KubeVersion() {
+					plan.Upgrade = &PlanUpgrade{From: rootfs.KubeVersion(), To: v}
+					plan.ImagesToUnmount = append(plan.ImagesToUnmount,
+						PlanImage{Image: rootfs.ImageName, Type: rootfs.Type, Hosts: hosts})
+				}
+			}
+		}
+	}
+
+	plan.MastersToJoin, plan.MastersToDelete = iputils.GetDiffHosts(current.GetMasterIPAndPortList(), desired.GetMasterIPAndPortList())
+	plan.NodesToJoin, plan.NodesToDelete = iputils.GetDiffHosts(current.GetNodeIPAndPortList(), desired.GetNodeIPAndPortList())
+	joins := append(append([]string{}, plan.MastersToJoin...), plan.NodesToJoin...)
+	deletes := append(append([]string{}, plan.MastersToDelete...), plan.NodesToDelete...)
+	if len(joins) == 0 && len(deletes) == 0 {
+		return nil
+	}
+	plan.Actions = append(plan.Actions, PlanActionScale)
+	// the same as ScaleProcessor, application images are not sent to the new hosts.
+	mounts := make([]v2.MountImage, 0)
+	for _, m := range current.Status.Mounts {
+		if !m.IsApplication() {
+			mounts = append(mounts, m)
+		}
+	}
+	if len(joins) > 0 {
+		plan.ImagesToMount = append(plan.ImagesToMount, planImages(desired, mounts, joins)...)
+		plan.GuestCommands = append(plan.GuestCommands, guest.Commands(desired, mounts, joins)...)
+	}
+	if len(deletes) > 0 {
+		plan.ImagesToUnmount = append(plan.ImagesToUnmount, planImages(current, mounts, deletes)...)
+	}
+	return nil
+}
+
+func (c *Applier) planConfigs(plan *Plan) error {
+	currentConfigs := make(map[string]v2.Config)
+	cf := clusterfile.NewClusterFile(constants.Clusterfile(c.ClusterDesired.Name))
+	if err := cf.Process(); err == nil {
+		for _, cfg := range cf.GetConfigs() {
+			currentConfigs[cfg.Name] = cfg
+		}
+	} else if err != clusterfile.ErrClusterFileNotExists {
+		return err
+	}
+	for _, cfg := range c.ClusterFile.GetConfigs() {
+		change := ConfigChangeCreate
+		if old, ok := currentConfigs[cfg.Name]; ok {
+			change = ConfigChangeUpdate
+			if old.Spec == cfg.Spec {
+				change = ConfigChangeUnchanged
+			}
+		}
+		plan.Configs = append(plan.Configs, PlanConfig{
+			Name:     cfg.Name,
+			Match:    cfg.Spec.Match,
+			Path:     cfg.Spec.Path,
+			Strategy: string(cfg.Spec.Strategy),
+			Change:   change,
+		})
+	}
+	return nil
+}
+
+// localInspector inspects the images in local storage only, plan never reaches the registries.
+type localInspector struct {
+	buildah.Interface
+}
+
+func (i localInspector) InspectImage(name string, _ ...string) (*buildah.InspectOutput, error) {
+	return i.Interface.InspectImage(name)
+}
+
+// inspectMounts reads the image configs from local storage without creating containers or pulling images.
+func (c *Applier) inspectMounts(images []string) ([]v2.MountImage, error) {
+	if len(images) == 0 {
+		return nil, nil
+	}
+	bder, err := buildah.New(c.ClusterDesired.Name)
+	if err != nil {
+		return nil, err
+	}
+	env := maps.FromSlice(c.ClusterDesired.Spec.Env)
+	mounts := make([]v2.MountImage, 0, len(images))
+	for _, img := range images {
+		if _, m := c.ClusterDesired.FindImage(img); m != nil {
+			mounts = append(mounts, *m)
+			continue
+		}
+		mount := v2.MountImage{ImageName: img, Name: img}
+		if err = processor.OCIToImageMount(localInspector{bder}, &mount); err != nil {
+			return nil, fmt.Errorf("failed to inspect image %s, pull it before planning: %v", img, err)
+		}
+		mount.Env = maps.Merge(mount.Env, env, processor.GetEnvs(c.Context))
+		mounts = append(mounts, mount)
+	}
+	return mounts, nil
+}
+
+func planImages(cluster *v2.Cluster, mounts []v2.MountImage, hosts []string) []PlanImage {
+	ret := make([]PlanImage, 0, len(mounts))
+	for _, m := range mounts {
+		targets := hosts
+		if m.IsApplication() {
+			targets = []string{cluster.GetMaster0IPAndPort()}
+		}
+		ret = append(ret, PlanImage{Image: m.ImageName, Type: m.Type, Hosts: targets})
+	}
+	return ret
+}
+
+const (
+	PlanOutputText = "text"
+	PlanOutputJSON = "json"
+)
+
+// Print writes the plan in the given format, text or json.
+func (p *Plan) Print(w io.Writer, format string) error {
+	switch format {
+	case PlanOutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(p)
+	case PlanOutputText, "":
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Cluster:\t%s\n", p.ClusterName)
+	fmt.Fprintf(tw, "Actions:\t%s\n", strings.Join(p.Actions, ", "))
+	if p.Upgrade != nil {
+		fmt.Fprintf(tw, "Upgrade:\t%s -> %s\n", p.Upgrade.From, p.Upgrade.To)
+	}
+	for _, item := range []struct {
+		title string
+		hosts []string
+	}{
+		{"Masters to join", p.MastersToJoin},
+		{"Masters to delete", p.MastersToDelete},
+		{"Nodes to join", p.NodesToJoin},
+		{"Nodes to delete", p.NodesToDelete},
+	} {
+		if len(item.hosts) > 0 {
+			fmt.Fprintf(tw, "%s:\t%s\n", item.title, strings.Join(item.hosts, ", "))
+		}
+	}
+	printImages := func(title string, images []PlanImage) {
+		if len(images) == 0 {
+			return
+		}
+		fmt.Fprintf(tw, "\n%s:\n", title)
+		fmt.Fprintln(tw, "  IMAGE\tTYPE\tHOSTS")
+		for _, img := range images {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", img.Image, img.Type, strings.Join(img.Hosts, ","))
+		}
+	}
+	printImages("Images to mount", p.ImagesToMount)
+	printImages("Images to unmount", p.ImagesToUnmount)
+	if len(p.GuestCommands) > 0 {
+		fmt.Fprintln(tw, "\nGuest commands:")
+		for _, cmd := range p.GuestCommands {
+			fmt.Fprintf(tw, "  [%s] on %s\n", cmd.Image, strings.Join(cmd.Hosts, ","))
+			fmt.Fprintf(tw, "    %s\n", cmd.Command)
+		}
+	}
+	if len(p.Configs) > 0 {
+		fmt.Fprintln(tw, "\nConfigs:")
+		fmt.Fprintln(tw, "  NAME\tMATCH\tPATH\tSTRATEGY\tCHANGE")
+		for _, cfg := range p.Configs {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", cfg.Name, cfg.Match, cfg.Path, cfg.Strategy, cfg.Change)
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,66 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package applydrivers
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+func TestPlanReconcileScale(t *testing.T) {
+	newCluster := func(nodes ...string) *v2.Cluster {
+		cluster := &v2.Cluster{
+			Spec: v2.ClusterSpec{
+				Hosts: []v2.Host{
+					{IPS: []string{"192.168.0.2:22"}, Roles: []string{v2.MASTER}},
+					{IPS: nodes, Roles: []string{v2.NODE}},
+				},
+			},
+		}
+		cluster.Name = "default"
+		return cluster
+	}
+	current := newCluster("192.168.0.3:22")
+	current.CreationTimestamp = metav1.Now()
+	current.Status.Mounts = []v2.MountImage{
+		{Name: "rootfs", ImageName: "labring/kubernetes:v1.25.0", Type: v2.RootfsImage, Cmd: []string{"bash init.sh"}},
+		{Name: "helm", ImageName: "labring/helm:v3.8.2", Type: v2.AppImage, Cmd: []string{"cp helm /usr/bin"}},
+	}
+	applier := &Applier{ClusterCurrent: current, ClusterDesired: newCluster("192.168.0.4:22")}
+
+	plan := &Plan{}
+	if err := applier.planReconcile(plan); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(plan.Actions, []string{PlanActionScale}) {
+		t.Errorf("unexpected actions %v", plan.Actions)
+	}
+	if !reflect.DeepEqual(plan.NodesToJoin, []string{"192.168.0.4:22"}) ||
+		!reflect.DeepEqual(plan.NodesToDelete, []string{"192.168.0.3:22"}) {
+		t.Errorf("unexpected nodes to join %v and to delete %v", plan.NodesToJoin, plan.NodesToDelete)
+	}
+	// application images are not sent to the new hosts
+	wantMount := []PlanImage{{Image: "labring/kubernetes:v1.25.0", Type: v2.RootfsImage, Hosts: []string{"192.168.0.4:22"}}}
+	if !reflect.DeepEqual(plan.ImagesToMount, wantMount) {
+		t.Errorf("unexpected images to mount %+v", plan.ImagesToMount)
+	}
+	if len(plan.GuestCommands) != 1 || !reflect.DeepEqual(plan.GuestCommands[0].Hosts, []string{"192.168.0.4:22"}) {
+		t.Errorf("unexpected guest commands %+v", plan.GuestCommands)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package applydrivers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/labring/sealos/pkg/apply/processor"
+	"github.com/labring/sealos/pkg/clusterfile"
+	"github.com/labring/sealos/pkg/constants"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/logger"
+	stringsutil "github.com/labring/sealos/pkg/utils/strings"
+)
+
+// shouldRollback reports whether the hosts joined by a failed scale-up should be removed.
+func (c *Applier) shouldRollback(err error) bool {
+	if processor.IsNoRollback(c.Context) {
+		return false
+	}
+	var checkError *processor.CheckError
+	// nothing has been changed yet if the check failed, the hosts joined before a failed guest
+	// are not ready to use, so they are removed as well.
+	return !errors.As(err, &checkError)
+}
+
+// scaleDown removes the hosts from the cluster, it's replaced in tests.
+var scaleDown = func(ctx context.Context, cluster *v2.Cluster, masters, nodes []string) error {
+	cf := clusterfile.NewClusterFile(constants.Clusterfile(cluster.Name))
+	scaleProcessor, err := processor.NewScaleProcessor(ctx, cf, cluster.Name, cluster.Spec.Image, nil, masters, nil, nodes)
+	if err != nil {
+		return err
+	}
+	return scaleProcessor.Execute(cluster)
+}
+
+// rollbackScale removes the hosts joined by the failed scale-up and restores the Clusterfile,
+// cause is returned if the rollback succeeded, so the run is still reported as failed.
+// The Clusterfile is restored to the previous hosts even if removing the hosts failed.
+func (c *Applier) rollbackScale(mastersToJoin, nodesToJoin []string, cause error) error {
+	joined := append(append([]string{}, mastersToJoin...), nodesToJoin...)
+	logger.Warn("failed to scale this cluster, start to roll back the joined hosts %v, use --no-rollback to keep them", joined)
+	previous := c.ClusterDesired.DeepCopy()
+	previous.Spec.Hosts = removeHosts(previous.Spec.Hosts, joined)
+
+	var (
+		preProcessError *processor.PreProcessError
+		rollbackErr     error
+	)
+	if !errors.As(cause, &preProcessError) {
+		// the delete pipeline reads the hosts to remove from the saved Clusterfile
+		c.saveClusterFile()
+		rollbackErr = scaleDown(c.Context, previous, mastersToJoin, nodesToJoin)
+	}
+	c.ClusterDesired.Spec.Hosts = previous.Spec.Hosts
+	if cp, err := processor.LoadCheckpoint(c.ClusterDesired.Name); err != nil {
+		logger.Warn("failed to load checkpoint: %v", err)
+	} else if err = cp.Remove(); err != nil {
+		logger.Warn("failed to remove checkpoint: %v", err)
+	}
+	if rollbackErr != nil {
+		// the Clusterfile is saved with the previous hosts after Apply returns
+		return fmt.Errorf("%w, and failed to roll back, the hosts %v may be still joined and need to be cleaned up manually: %v",
+			cause, joined, rollbackErr)
+	}
+	c.rolledBack = true
+	if preProcessError != nil {
+		// neither the status is updated nor the Clusterfile is saved after the pre-process failed,
+		// but the Clusterfile has been written with the hosts to join.
+		c.ClusterDesired.Status.Phase = c.ClusterCurrent.Status.Phase
+		c.saveClusterFile()
+	}
+	logger.Info("succeeded in rolling back the cluster to the previous hosts")
+	return cause
+}
+
+// removeHosts returns the hosts without the ips, the groups without any ip left are dropped.
+func removeHosts(hosts []v2.Host, ips []string) []v2.Host {
+	var ret []v2.Host
+	for _, host := range hosts {
+		host.IPS = stringsutil.RemoveSubSlice(host.IPS, ips)
+		if len(host.IPS) > 0 {
+			ret = append(ret, host)
+		}
+	}
+	return ret
+}
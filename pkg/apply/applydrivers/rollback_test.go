@@ -0,0 +1,181 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package applydrivers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/labring/sealos/pkg/apply/processor"
+	"github.com/labring/sealos/pkg/buildah"
+	"github.com/labring/sealos/pkg/clusterfile"
+	"github.com/labring/sealos/pkg/constants"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/file"
+)
+
+func TestShouldRollback(t *testing.T) {
+	guestErr := fmt.Errorf("%s: %w", processor.RunGuestFailed, errors.New("exit status 1"))
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"join failed", context.Background(), errors.New("failed to join node"), true},
+		{"guest failed", context.Background(), guestErr, true},
+		{"check failed", context.Background(), processor.NewCheckError(errors.New("ssh failed")), false},
+		{"no rollback", processor.WithNoRollback(context.Background(), true), guestErr, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Applier{Context: tt.ctx}
+			if got := c.shouldRollback(tt.err); got != tt.want {
+				t.Errorf("shouldRollback() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRollbackScale(t *testing.T) {
+	constants.DefaultRuntimeRootDir = t.TempDir()
+	newApplier := func() *Applier {
+		current := &v2.Cluster{
+			Spec: v2.ClusterSpec{
+				Hosts: []v2.Host{
+					{IPS: []string{"192.168.0.2:22"}, Roles: []string{v2.MASTER}},
+					{IPS: []string{"192.168.0.3:22"}, Roles: []string{v2.NODE}},
+				},
+			},
+		}
+		current.Name = "default"
+		current.Kind = "Cluster"
+		current.APIVersion = v2.SchemeGroupVersion.String()
+		current.Status.Phase = v2.ClusterSuccess
+		desired := current.DeepCopy()
+		desired.Spec.Hosts[1].IPS = append(desired.Spec.Hosts[1].IPS, "192.168.0.4:22", "192.168.0.5:22")
+		if _, err := processor.OpenCheckpoint(desired, processor.CheckpointKindScale, false, nil, []string{"192.168.0.4:22", "192.168.0.5:22"}); err != nil {
+			t.Fatal(err)
+		}
+		return &Applier{
+			Context:        context.Background(),
+			ClusterCurrent: current,
+			ClusterDesired: desired,
+			ClusterFile:    clusterfile.NewClusterFile(constants.Clusterfile(current.Name)),
+		}
+	}
+	nodesToJoin := []string{"192.168.0.4:22", "192.168.0.5:22"}
+	previousNodes := []string{"192.168.0.3:22"}
+	cause := fmt.Errorf("%s: %w", processor.RunGuestFailed, errors.New("exit status 1"))
+
+	var removed []string
+	defer func(fn func(context.Context, *v2.Cluster, []string, []string) error) {
+		scaleDown = fn
+	}(scaleDown)
+
+	// the joined hosts are removed
+	scaleDown = func(_ context.Context, cluster *v2.Cluster, _, nodes []string) error {
+		if got := cluster.GetNodeIPAndPortList(); !reflect.DeepEqual(got, previousNodes) {
+			t.Errorf("scale down to nodes %v, want %v", got, previousNodes)
+		}
+		removed = nodes
+		return nil
+	}
+	c := newApplier()
+	if err := c.rollbackScale(nil, nodesToJoin, cause); !errors.Is(err, cause) {
+		t.Errorf("expected the cause to be returned, got %v", err)
+	}
+	if !reflect.DeepEqual(removed, nodesToJoin) {
+		t.Errorf("removed %v, want %v", removed, nodesToJoin)
+	}
+	if !c.rolledBack || !reflect.DeepEqual(c.ClusterDesired.GetNodeIPAndPortList(), previousNodes) {
+		t.Errorf("cluster is not rolled back, nodes %v", c.ClusterDesired.GetNodeIPAndPortList())
+	}
+	if file.IsExist(processor.CheckpointFile(c.ClusterDesired.Name)) {
+		t.Error("checkpoint is not removed")
+	}
+
+	// the previous hosts are restored even if removing the joined hosts failed
+	scaleDown = func(context.Context, *v2.Cluster, []string, []string) error {
+		return errors.New("failed to delete node")
+	}
+	c = newApplier()
+	err := c.rollbackScale(nil, nodesToJoin, cause)
+	if !errors.Is(err, cause) || !strings.Contains(err.Error(), "failed to delete node") {
+		t.Errorf("unexpected error %v", err)
+	}
+	if c.rolledBack || !reflect.DeepEqual(c.ClusterDesired.GetNodeIPAndPortList(), previousNodes) {
+		t.Errorf("previous hosts are not restored, nodes %v", c.ClusterDesired.GetNodeIPAndPortList())
+	}
+	if file.IsExist(processor.CheckpointFile(c.ClusterDesired.Name)) {
+		t.Error("checkpoint is not removed")
+	}
+
+	// nothing is joined if the pre-process failed, only the Clusterfile is restored
+	scaleDown = func(context.Context, *v2.Cluster, []string, []string) error {
+		t.Error("unexpected scale down after the pre-process failed")
+		return nil
+	}
+	c = newApplier()
+	preProcessErr := processor.NewPreProcessError(errors.New("failed to mount"))
+	if err = c.rollbackScale(nil, nodesToJoin, preProcessErr); !errors.Is(err, preProcessErr) {
+		t.Errorf("expected the cause to be returned, got %v", err)
+	}
+	cf := clusterfile.NewClusterFile(constants.Clusterfile(c.ClusterDesired.Name))
+	if err = cf.Process(); err != nil {
+		t.Fatal(err)
+	}
+	if got := cf.GetCluster().GetNodeIPAndPortList(); !reflect.DeepEqual(got, previousNodes) {
+		t.Errorf("saved Clusterfile nodes %v, want %v", got, previousNodes)
+	}
+}
+
+type fakeBuilder struct {
+	buildah.Interface
+	deleted []string
+}
+
+func (b *fakeBuilder) Delete(name string) error {
+	b.deleted = append(b.deleted, name)
+	return nil
+}
+
+func TestRemoveApps(t *testing.T) {
+	cluster := &v2.Cluster{
+		Spec: v2.ClusterSpec{Image: []string{"kubernetes:v1.25.0", "helm:v3.8.2", "nginx:v1.0"}},
+		Status: v2.ClusterStatus{Mounts: []v2.MountImage{
+			{Name: "default-kubernetes", ImageName: "kubernetes:v1.25.0", Type: v2.RootfsImage},
+			{Name: "default-helm", ImageName: "helm:v3.8.2", Type: v2.AppImage},
+			{Name: "default-nginx", ImageName: "nginx:v1.0", Type: v2.AppImage},
+		}},
+	}
+	bder := &fakeBuilder{}
+	if err := removeApps(bder, cluster, []string{"helm:v3.8.2", "nginx:v1.0"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"default-helm", "default-nginx"}; !reflect.DeepEqual(bder.deleted, want) {
+		t.Errorf("deleted containers %v, want %v", bder.deleted, want)
+	}
+	if want := (v2.ImageList{"kubernetes:v1.25.0"}); !reflect.DeepEqual(cluster.Spec.Image, want) {
+		t.Errorf("images %v, want %v", cluster.Spec.Image, want)
+	}
+	if len(cluster.Status.Mounts) != 1 || cluster.Status.Mounts[0].ImageName != "kubernetes:v1.25.0" {
+		t.Errorf("unexpected mounts %+v", cluster.Status.Mounts)
+	}
+}
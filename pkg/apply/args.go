@@ -19,10 +19,13 @@ package apply
 import (
 	"fmt"
 	"path"
+	"strings"
 
 	"github.com/spf13/pflag"
 
+	"github.com/labring/sealos/pkg/checker"
 	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/utils/flags"
 )
 
 type Cluster struct {
@@ -38,11 +41,15 @@ func (c *Cluster) RegisterFlags(fs *pflag.FlagSet, verb, action string) {
 }
 
 type SSH struct {
-	User       string
-	Password   string
-	Pk         string
-	PkPassword string
-	Port       uint16
+	User          string
+	Password      string
+	Pk            string
+	PkPassword    string
+	Port          uint16
+	ProxyJump     string
+	IdentityAgent string
+	ForwardAgent  bool
+	Certificate   string
 }
 
 func (s *SSH) RegisterFlags(fs *pflag.FlagSet) {
@@ -52,6 +59,10 @@ func (s *SSH) RegisterFlags(fs *pflag.FlagSet) {
 		"selects a file from which the identity (private key) for public key authentication is read")
 	fs.StringVar(&s.PkPassword, "pk-passwd", "", "passphrase for decrypting a PEM encoded private key")
 	fs.Uint16Var(&s.Port, "port", 22, "port to connect to on the remote host")
+	fs.StringVarP(&s.ProxyJump, "proxy-jump", "J", "", "connect to the hosts through the comma separated jump hosts in the form of [user@]host[:port]")
+	fs.StringVar(&s.IdentityAgent, "identity-agent", "", "socket of ssh-agent whose keys are used to authenticate, default is $SSH_AUTH_SOCK, \"none\" to disable it")
+	fs.BoolVarP(&s.ForwardAgent, "forward-agent", "A", false, "enable forwarding of ssh-agent to the remote hosts")
+	fs.StringVar(&s.Certificate, "certificate", "", "OpenSSH certificate of the private key, default is <pk>-cert.pub if exists")
 }
 
 type RunArgs struct {
@@ -60,6 +71,31 @@ type RunArgs struct {
 	CustomEnv         []string
 	CustomCMD         []string
 	CustomConfigFiles []string
+	Resume            bool
+	SkipPreflight     []string
+	NoRollback        bool
+	RegistrySync
+}
+
+func registerSkipPreflightFlag(fs *pflag.FlagSet, p *[]string) {
+	fs.StringSliceVar(p, "skip-preflight", nil, fmt.Sprintf("names of the pre-flight checks to skip, one or more of %s|%s",
+		strings.Join(checker.PreflightNames(), "|"), checker.PreflightSkipAll))
+}
+
+func registerNoRollbackFlag(fs *pflag.FlagSet, p *bool) {
+	fs.BoolVar(p, "no-rollback", false, "keep the joined hosts and the updated Clusterfile if scaling up failed, so that it can be continued with --resume")
+}
+
+type RegistrySync struct {
+	Parallelism    int
+	BandwidthLimit flags.Bytes
+	FanOut         int
+}
+
+func (r *RegistrySync) RegisterFlags(fs *pflag.FlagSet) {
+	fs.IntVar(&r.Parallelism, "registry-sync-parallelism", 0, "number of registry hosts to sync images to concurrently, 0 for unlimited")
+	fs.Var(&r.BandwidthLimit, "registry-sync-bandwidth", "limit of the total bandwidth per second used to sync images to the registry hosts, eg. 50MB, 0 for unlimited")
+	fs.IntVar(&r.FanOut, "registry-sync-fan-out", 0, "number of registry hosts each synced host serves images to, 0 to sync all the hosts from this machine")
 }
 
 func (arg *RunArgs) RegisterFlags(fs *pflag.FlagSet) {
@@ -68,6 +104,10 @@ func (arg *RunArgs) RegisterFlags(fs *pflag.FlagSet) {
 	fs.StringSliceVarP(&arg.CustomEnv, "env", "e", []string{}, "environment variables to be set for images")
 	fs.StringSliceVar(&arg.CustomCMD, "cmd", []string{}, "override CMD directive in images")
 	fs.StringSliceVar(&arg.CustomConfigFiles, "config-file", []string{}, "path of custom config files, to use to replace the resource")
+	fs.BoolVar(&arg.Resume, "resume", false, "resume the last failed run from its checkpoint")
+	registerSkipPreflightFlag(fs, &arg.SkipPreflight)
+	registerNoRollbackFlag(fs, &arg.NoRollback)
+	arg.RegistrySync.RegisterFlags(fs)
 }
 
 type Args struct {
@@ -75,6 +115,10 @@ type Args struct {
 	Sets              []string
 	CustomEnv         []string
 	CustomConfigFiles []string
+	Resume            bool
+	SkipPreflight     []string
+	NoRollback        bool
+	RegistrySync
 }
 
 func (arg *Args) RegisterFlags(fs *pflag.FlagSet) {
@@ -82,6 +126,10 @@ func (arg *Args) RegisterFlags(fs *pflag.FlagSet) {
 	fs.StringSliceVar(&arg.Sets, "set", []string{}, "set values on the command line")
 	fs.StringSliceVar(&arg.CustomEnv, "env", []string{}, "environment variables to be set for images")
 	fs.StringSliceVar(&arg.CustomConfigFiles, "config-file", []string{}, "path of custom config files, to use to replace the resource")
+	fs.BoolVar(&arg.Resume, "resume", false, "resume the last failed apply from its checkpoint")
+	registerSkipPreflightFlag(fs, &arg.SkipPreflight)
+	registerNoRollbackFlag(fs, &arg.NoRollback)
+	arg.RegistrySync.RegisterFlags(fs)
 }
 
 type ResetArgs struct {
@@ -97,6 +145,8 @@ func (arg *ResetArgs) RegisterFlags(fs *pflag.FlagSet) {
 type ScaleArgs struct {
 	*Cluster
 	*SSH
+	SkipPreflight []string
+	NoRollback    bool
 }
 
 func (arg *ScaleArgs) RegisterFlags(fs *pflag.FlagSet, verb, action string) {
@@ -104,5 +154,7 @@ func (arg *ScaleArgs) RegisterFlags(fs *pflag.FlagSet, verb, action string) {
 	// delete cmd does not support setting ssh, it reads from clusterfile
 	if arg.SSH != nil {
 		arg.SSH.RegisterFlags(fs)
+		registerSkipPreflightFlag(fs, &arg.SkipPreflight)
+		registerNoRollbackFlag(fs, &arg.NoRollback)
 	}
 }
@@ -0,0 +1,287 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slices"
+
+	"github.com/labring/sealos/pkg/constants"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/file"
+	"github.com/labring/sealos/pkg/utils/logger"
+	"github.com/labring/sealos/pkg/utils/yaml"
+)
+
+const (
+	CheckpointKindCreate = "create"
+	CheckpointKindScale  = "scale"
+
+	checkpointFileName = "checkpoint.yaml"
+)
+
+// names of the pipelines which are recorded in checkpoint
+const (
+	PhaseMountRootfs    = "MountRootfs"
+	PhaseMirrorRegistry = "MirrorRegistry"
+	PhaseBootstrap      = "Bootstrap"
+	PhaseInit           = "Init"
+	PhaseJoin           = "Join"
+	PhaseRunGuest       = "RunGuest"
+)
+
+var ErrNoCheckpoint = errors.New("no checkpoint found to resume from")
+
+// Checkpoint records the progress of a create or scale pipeline in the cluster
+// workdir, so that a failed run can be continued with --resume.
+type Checkpoint struct {
+	Kind          string            `json:"kind"`
+	Fingerprint   string            `json:"fingerprint"`
+	MastersToJoin []string          `json:"mastersToJoin,omitempty"`
+	NodesToJoin   []string          `json:"nodesToJoin,omitempty"`
+	Phases        []PhaseCheckpoint `json:"phases,omitempty"`
+
+	mu   sync.Mutex
+	path string
+}
+
+type PhaseCheckpoint struct {
+	Name       string    `json:"name"`
+	Completed  bool      `json:"completed"`
+	Hosts      []string  `json:"hosts,omitempty"`
+	StartedAt  time.Time `json:"startedAt,omitempty"`
+	FinishedAt time.Time `json:"finishedAt,omitempty"`
+}
+
+func CheckpointFile(clusterName string) string {
+	return filepath.Join(constants.ClusterDir(clusterName), checkpointFileName)
+}
+
+// LoadCheckpoint returns the checkpoint stored in the cluster workdir, nil if not exists.
+func LoadCheckpoint(clusterName string) (*Checkpoint, error) {
+	path := CheckpointFile(clusterName)
+	if !file.IsExist(path) {
+		return nil, nil
+	}
+	cp := &Checkpoint{}
+	if err := yaml.UnmarshalFile(path, cp); err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint %s: %v", path, err)
+	}
+	cp.path = path
+	return cp, nil
+}
+
+// OpenCheckpoint loads the existing checkpoint if resume is true, otherwise a new
+// empty checkpoint is created and overrides the old one.
+func OpenCheckpoint(cluster *v2.Cluster, kind string, resume bool, mastersToJoin, nodesToJoin []string) (*Checkpoint, error) {
+	fingerprint := checkpointFingerprint(kind, cluster.Spec.Image, mastersToJoin, nodesToJoin)
+	if !resume {
+		cp := &Checkpoint{
+			Kind:          kind,
+			Fingerprint:   fingerprint,
+			MastersToJoin: mastersToJoin,
+			NodesToJoin:   nodesToJoin,
+			path:          CheckpointFile(cluster.Name),
+		}
+		return cp, cp.Save()
+	}
+	cp, err := LoadCheckpoint(cluster.Name)
+	if err != nil {
+		return nil, err
+	}
+	if cp == nil {
+		return nil, ErrNoCheckpoint
+	}
+	if cp.Kind != kind {
+		return nil, fmt.Errorf("cannot resume a %s pipeline from a %s checkpoint", kind, cp.Kind)
+	}
+	if cp.Fingerprint != fingerprint {
+		return nil, fmt.Errorf("cannot resume, images or hosts are changed since the last run")
+	}
+	cp.Report()
+	return cp, nil
+}
+
+func checkpointFingerprint(kind string, images []string, masters, nodes []string) string {
+	sorted := func(s []string) string {
+		c := slices.Clone(s)
+		sort.Strings(c)
+		return strings.Join(c, ",")
+	}
+	h := sha256.New()
+	for _, s := range []string{kind, sorted(images), sorted(masters), sorted(nodes)} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// lookupPhase returns nil if the phase is not recorded, it never changes the checkpoint.
+func (c *Checkpoint) lookupPhase(name string) *PhaseCheckpoint {
+	for i := range c.Phases {
+		if c.Phases[i].Name == name {
+			return &c.Phases[i]
+		}
+	}
+	return nil
+}
+
+// phase returns the recorded phase, or records a new one if not found.
+func (c *Checkpoint) phase(name string) *PhaseCheckpoint {
+	if p := c.lookupPhase(name); p != nil {
+		return p
+	}
+	c.Phases = append(c.Phases, PhaseCheckpoint{Name: name})
+	return &c.Phases[len(c.Phases)-1]
+}
+
+func (c *Checkpoint) IsCompleted(name string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p := c.lookupPhase(name)
+	return p != nil && p.Completed
+}
+
+// PendingHosts filters out the hosts which already finished the given phase.
+func (c *Checkpoint) PendingHosts(name string, hosts []string) []string {
+	if c == nil {
+		return hosts
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p := c.lookupPhase(name)
+	if p == nil {
+		return hosts
+	}
+	var ret []string
+	for _, host := range hosts {
+		if !slices.Contains(p.Hosts, host) {
+			ret = append(ret, host)
+		}
+	}
+	return ret
+}
+
+func (c *Checkpoint) MarkHosts(name string, hosts ...string) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	p := c.phase(name)
+	for _, host := range hosts {
+		if !slices.Contains(p.Hosts, host) {
+			p.Hosts = append(p.Hosts, host)
+		}
+	}
+	c.mu.Unlock()
+	return c.Save()
+}
+
+func (c *Checkpoint) start(name string) error {
+	c.mu.Lock()
+	p := c.phase(name)
+	if p.StartedAt.IsZero() {
+		p.StartedAt = time.Now()
+	}
+	c.mu.Unlock()
+	return c.Save()
+}
+
+func (c *Checkpoint) complete(name string) error {
+	c.mu.Lock()
+	p := c.phase(name)
+	p.Completed = true
+	p.FinishedAt = time.Now()
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// Wrap returns a pipeline func that is skipped if the phase has been completed,
+// and is recorded as completed once it succeeds.
+func (c *Checkpoint) Wrap(name string, fn func(cluster *v2.Cluster) error) func(cluster *v2.Cluster) error {
+	return func(cluster *v2.Cluster) error {
+		if c == nil {
+			return fn(cluster)
+		}
+		if c.IsCompleted(name) {
+			logger.Info("Skipping pipeline %s, it has been completed in the last run.", name)
+			return nil
+		}
+		if err := c.start(name); err != nil {
+			return err
+		}
+		if err := fn(cluster); err != nil {
+			return err
+		}
+		return c.complete(name)
+	}
+}
+
+func (c *Checkpoint) Save() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	return file.AtomicWriteFile(c.path, data, 0644)
+}
+
+// Remove deletes the checkpoint file once the whole pipeline is finished.
+func (c *Checkpoint) Remove() error {
+	if c == nil {
+		return nil
+	}
+	if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Report prints the phases and hosts which were already done in the last run.
+func (c *Checkpoint) Report() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	logger.Info("resuming %s pipeline from checkpoint %s", c.Kind, c.path)
+	for _, p := range c.Phases {
+		switch {
+		case p.Completed:
+			logger.Info("  phase %s: completed at %s", p.Name, p.FinishedAt.Format(time.RFC3339))
+		case len(p.Hosts) > 0:
+			logger.Info("  phase %s: partially completed on hosts %v", p.Name, p.Hosts)
+		default:
+			logger.Info("  phase %s: not completed", p.Name)
+		}
+	}
+}
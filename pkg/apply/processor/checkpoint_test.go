@@ -0,0 +1,106 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/labring/sealos/pkg/constants"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+func TestCheckpointResume(t *testing.T) {
+	constants.DefaultRuntimeRootDir = t.TempDir()
+	cluster := &v2.Cluster{}
+	cluster.Name = "default"
+	cluster.Spec.Image = []string{"labring/kubernetes:v1.25.0"}
+	masters := []string{"192.168.0.2:22"}
+	nodes := []string{"192.168.0.3:22", "192.168.0.4:22"}
+
+	if _, err := OpenCheckpoint(cluster, CheckpointKindCreate, true, masters, nodes); !errors.Is(err, ErrNoCheckpoint) {
+		t.Fatalf("expected ErrNoCheckpoint, got %v", err)
+	}
+
+	cp, err := OpenCheckpoint(cluster, CheckpointKindCreate, false, masters, nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var executed []string
+	run := func(name string, fail bool) func(*v2.Cluster) error {
+		return cp.Wrap(name, func(*v2.Cluster) error {
+			executed = append(executed, name)
+			if fail {
+				return errors.New("failed")
+			}
+			return nil
+		})
+	}
+	if err = run(PhaseMountRootfs, false)(cluster); err != nil {
+		t.Fatal(err)
+	}
+	if err = cp.MarkHosts(PhaseJoin, nodes[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err = run(PhaseJoin, true)(cluster); err == nil {
+		t.Fatal("expected error")
+	}
+
+	cp, err = OpenCheckpoint(cluster, CheckpointKindCreate, true, masters, nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	executed = nil
+	for _, name := range []string{PhaseMountRootfs, PhaseJoin} {
+		if err = run(name, false)(cluster); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(executed, []string{PhaseJoin}) {
+		t.Errorf("executed phases = %v, want %v", executed, []string{PhaseJoin})
+	}
+	if got := cp.PendingHosts(PhaseJoin, nodes); !reflect.DeepEqual(got, nodes[1:]) {
+		t.Errorf("PendingHosts() = %v, want %v", got, nodes[1:])
+	}
+
+	if _, err = OpenCheckpoint(cluster, CheckpointKindCreate, true, masters, nodes[:1]); err == nil {
+		t.Error("expected error when resuming with different hosts")
+	}
+	if _, err = OpenCheckpoint(cluster, CheckpointKindScale, true, masters, nodes); err == nil {
+		t.Error("expected error when resuming with different kind")
+	}
+
+	if err = cp.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if cp, err = LoadCheckpoint(cluster.Name); err != nil || cp != nil {
+		t.Errorf("LoadCheckpoint() = %v, %v, want nil", cp, err)
+	}
+}
+
+func TestCheckpointQueryReadOnly(t *testing.T) {
+	cp := &Checkpoint{}
+	if cp.IsCompleted(PhaseInit) {
+		t.Error("unknown phase should not be completed")
+	}
+	hosts := []string{"192.168.0.2:22"}
+	if got := cp.PendingHosts(PhaseJoin, hosts); !reflect.DeepEqual(got, hosts) {
+		t.Errorf("PendingHosts() = %v, want %v", got, hosts)
+	}
+	if len(cp.Phases) != 0 {
+		t.Errorf("queries should not record phases, got %+v", cp.Phases)
+	}
+}
@@ -16,7 +16,11 @@ limitations under the License.
 
 package processor
 
-import "context"
+import (
+	"context"
+
+	"github.com/labring/sealos/pkg/filesystem/registry"
+)
 
 var commandKey struct{}
 
@@ -45,3 +49,61 @@ func GetEnvs(ctx context.Context) map[string]string {
 	}
 	return nil
 }
+
+type resumeKey struct{}
+
+func WithResume(ctx context.Context, resume bool) context.Context {
+	return context.WithValue(ctx, resumeKey{}, resume)
+}
+
+func IsResume(ctx context.Context) bool {
+	v, _ := ctx.Value(resumeKey{}).(bool)
+	return v
+}
+
+type skipPreflightKey struct{}
+
+func WithSkipPreflight(ctx context.Context, names []string) context.Context {
+	return context.WithValue(ctx, skipPreflightKey{}, names)
+}
+
+func GetSkipPreflight(ctx context.Context) []string {
+	v, _ := ctx.Value(skipPreflightKey{}).([]string)
+	return v
+}
+
+type forceOverrideKey struct{}
+
+// WithForceOverride overrides the installed apps without confirmation, only for the processors created with ctx.
+func WithForceOverride(ctx context.Context, force bool) context.Context {
+	return context.WithValue(ctx, forceOverrideKey{}, force)
+}
+
+func IsForceOverride(ctx context.Context) bool {
+	v, _ := ctx.Value(forceOverrideKey{}).(bool)
+	return v
+}
+
+type noRollbackKey struct{}
+
+// WithNoRollback disables rolling back the joined hosts when scaling up failed.
+func WithNoRollback(ctx context.Context, noRollback bool) context.Context {
+	return context.WithValue(ctx, noRollbackKey{}, noRollback)
+}
+
+func IsNoRollback(ctx context.Context) bool {
+	v, _ := ctx.Value(noRollbackKey{}).(bool)
+	return v
+}
+
+type registrySyncKey struct{}
+
+// WithRegistrySyncOptions sets the parallelism, bandwidth limit and fan-out of syncing images to the registry hosts.
+func WithRegistrySyncOptions(ctx context.Context, opts registry.SyncOptions) context.Context {
+	return context.WithValue(ctx, registrySyncKey{}, opts)
+}
+
+func GetRegistrySyncOptions(ctx context.Context) registry.SyncOptions {
+	v, _ := ctx.Value(registrySyncKey{}).(registry.SyncOptions)
+	return v
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/labring/sealos/pkg/clusterfile"
 	"github.com/labring/sealos/pkg/config"
 	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/filesystem/registry"
 	"github.com/labring/sealos/pkg/filesystem/rootfs"
 	"github.com/labring/sealos/pkg/guest"
 	"github.com/labring/sealos/pkg/runtime"
@@ -42,9 +43,20 @@ type CreateProcessor struct {
 	Runtime     runtime.Interface
 	Guest       guest.Interface
 	ExtraEnvs   map[string]string // parsing from CLI arguments
+	Resume      bool              // continue from the last checkpoint
+	Checkpoint  *Checkpoint
+	// names of the pre-flight checks to skip
+	SkipPreflight []string
+	RegistrySync  registry.SyncOptions
 }
 
 func (c *CreateProcessor) Execute(cluster *v2.Cluster) error {
+	cp, err := OpenCheckpoint(cluster, CheckpointKindCreate, c.Resume,
+		cluster.GetMasterIPAndPortList(), cluster.GetNodeIPAndPortList())
+	if err != nil {
+		return err
+	}
+	c.Checkpoint = cp
 	pipeLine, err := c.GetPipeLine()
 	if err != nil {
 		return err
@@ -55,7 +67,7 @@ func (c *CreateProcessor) Execute(cluster *v2.Cluster) error {
 		}
 	}
 
-	return nil
+	return c.Checkpoint.Remove()
 }
 
 func (c *CreateProcessor) GetPipeLine() ([]func(cluster *v2.Cluster) error, error) {
@@ -65,14 +77,14 @@ func (c *CreateProcessor) GetPipeLine() ([]func(cluster *v2.Cluster) error, erro
 		c.Check,
 		c.PreProcess,
 		c.RunConfig,
-		c.MountRootfs,
-		c.MirrorRegistry,
-		c.Bootstrap,
+		c.Checkpoint.Wrap(PhaseMountRootfs, c.MountRootfs),
+		c.Checkpoint.Wrap(PhaseMirrorRegistry, c.MirrorRegistry),
+		c.Checkpoint.Wrap(PhaseBootstrap, c.Bootstrap),
 		// c.GetPhasePluginFunc(plugin.PhasePreInit),
-		c.Init,
-		c.Join,
+		c.Checkpoint.Wrap(PhaseInit, c.Init),
+		c.Checkpoint.Wrap(PhaseJoin, c.Join),
 		// c.GetPhasePluginFunc(plugin.PhasePreGuest),
-		c.RunGuest,
+		c.Checkpoint.Wrap(PhaseRunGuest, c.RunGuest),
 		// c.GetPhasePluginFunc(plugin.PhasePostInstall),
 	)
 
@@ -85,7 +97,13 @@ func (c *CreateProcessor) Check(cluster *v2.Cluster) error {
 	// the order doesn't matter
 	ips = append(ips, cluster.GetMasterIPAndPortList()...)
 	ips = append(ips, cluster.GetNodeIPAndPortList()...)
-	return NewCheckError(checker.RunCheckList([]checker.Interface{checker.NewIPsHostChecker(ips)}, cluster, checker.PhasePre))
+	skips := c.SkipPreflight
+	if c.Resume {
+		// the hosts are partially installed in the last run, e.g. the ports are in use.
+		logger.Info("skip pre-flight checks when resuming from checkpoint")
+		skips = []string{checker.PreflightSkipAll}
+	}
+	return NewCheckError(checker.RunCheckList([]checker.Interface{checker.NewPreflightHostChecker(ips, nil, skips)}, cluster, checker.PhasePre))
 }
 
 func (c *CreateProcessor) PreProcess(cluster *v2.Cluster) error {
@@ -137,7 +155,7 @@ func (c *CreateProcessor) MountRootfs(cluster *v2.Cluster) error {
 
 func (c *CreateProcessor) MirrorRegistry(cluster *v2.Cluster) error {
 	logger.Info("Executing pipeline MirrorRegistry in CreateProcessor.")
-	return MirrorRegistry(cluster, cluster.Status.Mounts)
+	return MirrorRegistry(cluster, cluster.Status.Mounts, c.RegistrySync)
 }
 
 func (c *CreateProcessor) Bootstrap(cluster *v2.Cluster) error {
@@ -155,11 +173,24 @@ func (c *CreateProcessor) Init(_ *v2.Cluster) error {
 
 func (c *CreateProcessor) Join(cluster *v2.Cluster) error {
 	logger.Info("Executing pipeline Join in CreateProcessor.")
-	err := c.Runtime.ScaleUp(cluster.GetMasterIPAndPortList()[1:], cluster.GetNodeIPAndPortList())
-	if err != nil {
-		return err
+	// masters are joined one by one, so that each of them can be checkpointed
+	for _, master := range c.Checkpoint.PendingHosts(PhaseJoin, cluster.GetMasterIPAndPortList()[1:]) {
+		if err := c.Runtime.ScaleUp([]string{master}, nil); err != nil {
+			return err
+		}
+		if err := c.Checkpoint.MarkHosts(PhaseJoin, master); err != nil {
+			return err
+		}
+	}
+	if nodes := c.Checkpoint.PendingHosts(PhaseJoin, cluster.GetNodeIPAndPortList()); len(nodes) > 0 {
+		if err := c.Runtime.ScaleUp(nil, nodes); err != nil {
+			return err
+		}
+		if err := c.Checkpoint.MarkHosts(PhaseJoin, nodes...); err != nil {
+			return err
+		}
 	}
-	err = c.Runtime.SyncNodeIPVS(cluster.GetMasterIPAndPortList(), cluster.GetNodeIPAndPortList())
+	err := c.Runtime.SyncNodeIPVS(cluster.GetMasterIPAndPortList(), cluster.GetNodeIPAndPortList())
 	if err != nil {
 		return err
 	}
@@ -186,9 +217,12 @@ func NewCreateProcessor(ctx context.Context, name string, clusterFile clusterfil
 	}
 
 	return &CreateProcessor{
-		ClusterFile: clusterFile,
-		Buildah:     bder,
-		Guest:       gs,
-		ExtraEnvs:   GetEnvs(ctx),
+		ClusterFile:   clusterFile,
+		Buildah:       bder,
+		Guest:         gs,
+		ExtraEnvs:     GetEnvs(ctx),
+		Resume:        IsResume(ctx),
+		SkipPreflight: GetSkipPreflight(ctx),
+		RegistrySync:  GetRegistrySyncOptions(ctx),
 	}, nil
 }
@@ -26,6 +26,7 @@ import (
 	"github.com/labring/sealos/pkg/buildah"
 	"github.com/labring/sealos/pkg/clusterfile"
 	"github.com/labring/sealos/pkg/config"
+	"github.com/labring/sealos/pkg/filesystem/registry"
 	"github.com/labring/sealos/pkg/filesystem/rootfs"
 	"github.com/labring/sealos/pkg/guest"
 	"github.com/labring/sealos/pkg/runtime"
@@ -38,6 +39,7 @@ import (
 	stringsutil "github.com/labring/sealos/pkg/utils/strings"
 )
 
+// ForceOverride is the default of InstallProcessor.ForceOverride, set by the flag of sealos run.
 var ForceOverride bool
 
 type InstallProcessor struct {
@@ -48,6 +50,8 @@ type InstallProcessor struct {
 	NewMounts        []v2.MountImage
 	NewImages        []string
 	ExtraEnvs        map[string]string // parsing from CLI arguments
+	RegistrySync     registry.SyncOptions
+	ForceOverride    bool // override the installed apps without confirmation
 	imagesToOverride []string
 }
 
@@ -106,7 +110,7 @@ func (c *InstallProcessor) SyncStatusAndCheck(_ *v2.Cluster) error {
 func (c *InstallProcessor) ConfirmOverrideApps(_ *v2.Cluster) error {
 	logger.Info("Executing ConfirmOverrideApps Pipeline in InstallProcessor")
 
-	if ForceOverride || len(c.imagesToOverride) == 0 {
+	if c.ForceOverride || len(c.imagesToOverride) == 0 {
 		return nil
 	}
 
@@ -120,7 +124,7 @@ func (c *InstallProcessor) ConfirmOverrideApps(_ *v2.Cluster) error {
 		// return a cancelled error to stop apply process.
 		return ErrCancelled
 	}
-	ForceOverride = true
+	c.ForceOverride = true
 	return nil
 }
 
@@ -163,7 +167,7 @@ func (c *InstallProcessor) PreProcess(cluster *v2.Cluster) error {
 		index, mount := cluster.FindImage(img)
 		var ctrName string
 		if mount != nil {
-			if !ForceOverride {
+			if !c.ForceOverride {
 				continue
 			}
 			logger.Debug("trying to override app %s", img)
@@ -260,7 +264,7 @@ func (c *InstallProcessor) MountRootfs(cluster *v2.Cluster) error {
 
 func (c *InstallProcessor) MirrorRegistry(cluster *v2.Cluster) error {
 	logger.Info("Executing pipeline MirrorRegistry in InstallProcessor.")
-	return MirrorRegistry(cluster, c.NewMounts)
+	return MirrorRegistry(cluster, c.NewMounts, c.RegistrySync)
 }
 
 func (c *InstallProcessor) RunGuest(cluster *v2.Cluster) error {
@@ -282,10 +286,12 @@ func NewInstallProcessor(ctx context.Context, clusterFile clusterfile.Interface,
 	}
 
 	return &InstallProcessor{
-		ClusterFile: clusterFile,
-		Buildah:     bder,
-		Guest:       gs,
-		NewImages:   images,
-		ExtraEnvs:   GetEnvs(ctx),
+		ClusterFile:   clusterFile,
+		Buildah:       bder,
+		Guest:         gs,
+		NewImages:     images,
+		ExtraEnvs:     GetEnvs(ctx),
+		RegistrySync:  GetRegistrySyncOptions(ctx),
+		ForceOverride: ForceOverride || IsForceOverride(ctx),
 	}, nil
 }
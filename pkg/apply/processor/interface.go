@@ -152,7 +152,7 @@ func ConfirmDeleteNodes() error {
 	return nil
 }
 
-func MirrorRegistry(cluster *v2.Cluster, mounts []v2.MountImage) error {
+func MirrorRegistry(cluster *v2.Cluster, mounts []v2.MountImage, opts registry.SyncOptions) error {
 	registries := cluster.GetRegistryIPAndPortList()
 	logger.Debug("registry nodes is: %+v", registries)
 	sshClient := ssh.NewCacheClientFromCluster(cluster, true)
@@ -160,7 +160,7 @@ func MirrorRegistry(cluster *v2.Cluster, mounts []v2.MountImage) error {
 	if err != nil {
 		return err
 	}
-	syncer := registry.New(constants.NewPathResolver(cluster.GetName()), execer, mounts)
+	syncer := registry.New(constants.NewPathResolver(cluster.GetName()), execer, mounts, opts)
 	return syncer.Sync(context.Background(), registries...)
 }
 
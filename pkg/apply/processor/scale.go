@@ -33,6 +33,7 @@ import (
 	v2 "github.com/labring/sealos/pkg/types/v1beta1"
 	fileutil "github.com/labring/sealos/pkg/utils/file"
 	"github.com/labring/sealos/pkg/utils/logger"
+	stringsutil "github.com/labring/sealos/pkg/utils/strings"
 	"github.com/labring/sealos/pkg/utils/yaml"
 )
 
@@ -47,9 +48,20 @@ type ScaleProcessor struct {
 	NodesToDelete   []string
 	IsScaleUp       bool
 	Guest           guest.Interface
+	Resume          bool // continue from the last checkpoint, only for scaling up
+	Checkpoint      *Checkpoint
+	// names of the pre-flight checks to skip
+	SkipPreflight []string
 }
 
 func (c *ScaleProcessor) Execute(cluster *v2.Cluster) error {
+	if c.IsScaleUp {
+		cp, err := OpenCheckpoint(cluster, CheckpointKindScale, c.Resume, c.MastersToJoin, c.NodesToJoin)
+		if err != nil {
+			return err
+		}
+		c.Checkpoint = cp
+	}
 	pipLine, err := c.GetPipeLine()
 	if err != nil {
 		return err
@@ -61,7 +73,7 @@ func (c *ScaleProcessor) Execute(cluster *v2.Cluster) error {
 		}
 	}
 
-	return nil
+	return c.Checkpoint.Remove()
 }
 
 func (c *ScaleProcessor) GetPipeLine() ([]func(cluster *v2.Cluster) error, error) {
@@ -72,11 +84,11 @@ func (c *ScaleProcessor) GetPipeLine() ([]func(cluster *v2.Cluster) error, error
 			c.PreProcess,
 			c.PreProcessImage,
 			c.RunConfig,
-			c.MountRootfs,
-			c.Bootstrap,
+			c.Checkpoint.Wrap(PhaseMountRootfs, c.MountRootfs),
+			c.Checkpoint.Wrap(PhaseBootstrap, c.Bootstrap),
 			//s.GetPhasePluginFunc(plugin.PhasePreJoin),
-			c.Join,
-			c.RunGuest,
+			c.Checkpoint.Wrap(PhaseJoin, c.Join),
+			c.Checkpoint.Wrap(PhaseRunGuest, c.RunGuest),
 			//s.GetPhasePluginFunc(plugin.PhasePostJoin),
 		)
 		return todoList, nil
@@ -128,9 +140,22 @@ func (c *ScaleProcessor) Delete(cluster *v2.Cluster) error {
 
 func (c *ScaleProcessor) Join(cluster *v2.Cluster) error {
 	logger.Info("Executing pipeline Join in ScaleProcessor.")
-	err := c.Runtime.ScaleUp(c.MastersToJoin, c.NodesToJoin)
-	if err != nil {
-		return err
+	// masters are joined one by one, so that each of them can be checkpointed
+	for _, master := range c.Checkpoint.PendingHosts(PhaseJoin, c.MastersToJoin) {
+		if err := c.Runtime.ScaleUp([]string{master}, nil); err != nil {
+			return err
+		}
+		if err := c.Checkpoint.MarkHosts(PhaseJoin, master); err != nil {
+			return err
+		}
+	}
+	if nodes := c.Checkpoint.PendingHosts(PhaseJoin, c.NodesToJoin); len(nodes) > 0 {
+		if err := c.Runtime.ScaleUp(nil, nodes); err != nil {
+			return err
+		}
+		if err := c.Checkpoint.MarkHosts(PhaseJoin, nodes...); err != nil {
+			return err
+		}
 	}
 	if len(c.MastersToJoin) > 0 {
 		return c.Runtime.SyncNodeIPVS(cluster.GetMasterIPAndPortList(), cluster.GetNodeIPAndPortList())
@@ -155,10 +180,17 @@ func (c ScaleProcessor) UnMountRootfs(cluster *v2.Cluster) error {
 func (c *ScaleProcessor) JoinCheck(cluster *v2.Cluster) error {
 	logger.Info("Executing pipeline JoinCheck in ScaleProcessor.")
 	var ips []string
-	ips = append(ips, cluster.GetMaster0IPAndPort())
 	ips = append(ips, c.MastersToJoin...)
 	ips = append(ips, c.NodesToJoin...)
-	return NewCheckError(checker.RunCheckList([]checker.Interface{checker.NewIPsHostChecker(ips)}, cluster, checker.PhasePre))
+	skips := c.SkipPreflight
+	if c.Resume {
+		// the hosts are partially joined in the last run, e.g. the ports are in use.
+		logger.Info("skip pre-flight checks when resuming from checkpoint")
+		skips = []string{checker.PreflightSkipAll}
+	}
+	// all the current hosts are compared with the new ones, e.g. time skew and duplicated hostnames.
+	peers := stringsutil.RemoveSubSlice(append(cluster.GetMasterIPAndPortList(), cluster.GetNodeIPAndPortList()...), ips)
+	return NewCheckError(checker.RunCheckList([]checker.Interface{checker.NewPreflightHostChecker(ips, peers, skips)}, cluster, checker.PhasePre))
 }
 
 func (c *ScaleProcessor) DeleteCheck(cluster *v2.Cluster) error {
@@ -289,7 +321,7 @@ func (c *ScaleProcessor) UndoBootstrap(_ *v2.Cluster) error {
 	return bs.Delete(hosts...)
 }
 
-func NewScaleProcessor(clusterFile clusterfile.Interface, name string, images v2.ImageList, masterToJoin, masterToDelete, nodeToJoin, nodeToDelete []string) (Interface, error) {
+func NewScaleProcessor(ctx context.Context, clusterFile clusterfile.Interface, name string, images v2.ImageList, masterToJoin, masterToDelete, nodeToJoin, nodeToDelete []string) (Interface, error) {
 	bder, err := buildah.New(name)
 	if err != nil {
 		return nil, err
@@ -309,5 +341,7 @@ func NewScaleProcessor(clusterFile clusterfile.Interface, name string, images v2
 		pullImages:      images,
 		IsScaleUp:       len(masterToJoin) > 0 || len(nodeToJoin) > 0,
 		Guest:           gs,
+		Resume:          IsResume(ctx),
+		SkipPreflight:   GetSkipPreflight(ctx),
 	}, nil
 }
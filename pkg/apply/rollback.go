@@ -0,0 +1,100 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apply
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
+
+	"github.com/labring/sealos/pkg/apply/applydrivers"
+	"github.com/labring/sealos/pkg/apply/processor"
+	"github.com/labring/sealos/pkg/clusterfile"
+	"github.com/labring/sealos/pkg/constants"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+// NewRollbackApplier returns an applier which re-applies the images and the hosts of a revision
+// saved in the cluster history. The configs and the runtime config of the revision are not
+// re-applied, the current ones are kept.
+func NewRollbackApplier(cmd *cobra.Command, clusterName string, revision int) (applydrivers.Interface, error) {
+	cf := clusterfile.NewClusterFile(constants.Clusterfile(clusterName))
+	if err := cf.Process(); err != nil {
+		return nil, fmt.Errorf("failed to load cluster %s: %v", clusterName, err)
+	}
+	current := cf.GetCluster()
+	r, err := clusterfile.NewHistory(clusterName).Get(revision)
+	if err != nil {
+		return nil, err
+	}
+	target, err := r.Cluster()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode revision %d: %v", r.Revision, err)
+	}
+	images, err := revisionImages(current, r)
+	if err != nil {
+		return nil, err
+	}
+	newerImages, err := imagesSinceRevision(current, r)
+	if err != nil {
+		return nil, err
+	}
+	desired := current.DeepCopy()
+	desired.Spec.Hosts = target.Spec.Hosts
+
+	return &applydrivers.Applier{
+		// the apps are overridden by the images of the revision
+		Context:        processor.WithForceOverride(withCommonContext(cmd.Context(), cmd), true),
+		ClusterDesired: desired,
+		ClusterFile:    cf,
+		ClusterCurrent: current,
+		RunNewImages:   images,
+		RemoveImages:   newerImages,
+	}, nil
+}
+
+// revisionImages returns the images of the revision to run again, the rootfs and patch images
+// which are installed already are skipped, and changing the rootfs image is not supported.
+func revisionImages(current *v2.Cluster, r *clusterfile.Revision) ([]string, error) {
+	if rootfs := current.GetRootfsImage(); rootfs != nil && !slices.Contains(r.Images, rootfs.ImageName) {
+		return nil, fmt.Errorf("the cluster image is changed to %s since revision %d, rolling it back is not supported", rootfs.ImageName, r.Revision)
+	}
+	var images []string
+	for _, img := range r.Images {
+		if _, mount := current.FindImage(img); mount != nil && (mount.IsRootFs() || mount.IsPatch()) {
+			continue
+		}
+		images = append(images, img)
+	}
+	return images, nil
+}
+
+// imagesSinceRevision returns the app images installed after the revision, which are removed by
+// the rollback. The rootfs and patch images can't be removed from the hosts, so rolling them back
+// is not supported.
+func imagesSinceRevision(current *v2.Cluster, r *clusterfile.Revision) ([]string, error) {
+	var images []string
+	for _, img := range current.Spec.Image {
+		if slices.Contains(r.Images, img) {
+			continue
+		}
+		if _, mount := current.FindImage(img); mount != nil && (mount.IsRootFs() || mount.IsPatch()) {
+			return nil, fmt.Errorf("the image %s is installed since revision %d, rolling it back is not supported", img, r.Revision)
+		}
+		images = append(images, img)
+	}
+	return images, nil
+}
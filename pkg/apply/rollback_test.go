@@ -0,0 +1,57 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apply
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/labring/sealos/pkg/clusterfile"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+func TestRollbackImages(t *testing.T) {
+	current := &v2.Cluster{
+		Spec: v2.ClusterSpec{Image: []string{"kubernetes:v1.25.0", "calico:v3.24.1", "helm:v3.8.2", "nginx:v1.0"}},
+		Status: v2.ClusterStatus{Mounts: []v2.MountImage{
+			{ImageName: "kubernetes:v1.25.0", Type: v2.RootfsImage},
+			{ImageName: "calico:v3.24.1", Type: v2.AppImage},
+			{ImageName: "helm:v3.8.2", Type: v2.AppImage},
+			{ImageName: "nginx:v1.0", Type: v2.AppImage},
+		}},
+	}
+	r := &clusterfile.Revision{Revision: 1, Images: []string{"kubernetes:v1.25.0", "calico:v3.24.1"}}
+
+	images, err := revisionImages(current, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"calico:v3.24.1"}; !reflect.DeepEqual(images, want) {
+		t.Errorf("revisionImages() = %v, want %v", images, want)
+	}
+	newer, err := imagesSinceRevision(current, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"helm:v3.8.2", "nginx:v1.0"}; !reflect.DeepEqual(newer, want) {
+		t.Errorf("imagesSinceRevision() = %v, want %v", newer, want)
+	}
+
+	current.Spec.Image = append(current.Spec.Image, "patch:v1")
+	current.Status.Mounts = append(current.Status.Mounts, v2.MountImage{ImageName: "patch:v1", Type: v2.PatchImage})
+	if _, err = imagesSinceRevision(current, r); err == nil {
+		t.Error("imagesSinceRevision() should fail when a patch image is installed since the revision")
+	}
+}
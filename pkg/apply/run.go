@@ -29,8 +29,10 @@ import (
 	"github.com/labring/sealos/pkg/clusterfile"
 	"github.com/labring/sealos/pkg/constants"
 	"github.com/labring/sealos/pkg/exec"
+	"github.com/labring/sealos/pkg/filesystem/registry"
 	"github.com/labring/sealos/pkg/ssh"
 	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/flags"
 	"github.com/labring/sealos/pkg/utils/iputils"
 	"github.com/labring/sealos/pkg/utils/logger"
 	"github.com/labring/sealos/pkg/utils/maps"
@@ -87,6 +89,30 @@ func withCommonContext(ctx context.Context, cmd *cobra.Command) context.Context
 		v, _ := cmd.Flags().GetStringSlice("env")
 		ctx = processor.WithEnvs(ctx, maps.FromSlice(v))
 	}
+	if flagChanged(cmd, "resume") {
+		v, _ := cmd.Flags().GetBool("resume")
+		ctx = processor.WithResume(ctx, v)
+	}
+	if flagChanged(cmd, "skip-preflight") {
+		v, _ := cmd.Flags().GetStringSlice("skip-preflight")
+		ctx = processor.WithSkipPreflight(ctx, v)
+	}
+	if flagChanged(cmd, "no-rollback") {
+		v, _ := cmd.Flags().GetBool("no-rollback")
+		ctx = processor.WithNoRollback(ctx, v)
+	}
+	if flagChanged(cmd, "registry-sync-parallelism") || flagChanged(cmd, "registry-sync-bandwidth") ||
+		flagChanged(cmd, "registry-sync-fan-out") {
+		var opts registry.SyncOptions
+		opts.Parallelism, _ = cmd.Flags().GetInt("registry-sync-parallelism")
+		if f := cmd.Flags().Lookup("registry-sync-bandwidth"); f != nil {
+			if v, ok := f.Value.(*flags.Bytes); ok {
+				opts.BandwidthLimit = int64(*v)
+			}
+		}
+		opts.FanOut, _ = cmd.Flags().GetInt("registry-sync-fan-out")
+		ctx = processor.WithRegistrySyncOptions(ctx, opts)
+	}
 	return ctx
 }
 
@@ -102,7 +128,7 @@ func (r *ClusterArgs) runArgs(cmd *cobra.Command, args *RunArgs, imageList []str
 		if len(args.Cluster.Masters) == 0 {
 			return errors.New("master ip(s) must specified")
 		}
-	} else {
+	} else if !args.Resume {
 		if r.cluster.Status.Phase != v2.ClusterSuccess {
 			return fmt.Errorf("cluster status is not %s", v2.ClusterSuccess)
 		}
@@ -150,7 +176,7 @@ func (r *ClusterArgs) setHostWithIpsPort(ips []string, roles []string) {
 	for i := range ips {
 		ip, port := iputils.GetHostIPAndPortOrDefault(ips[i], defaultPort)
 		logger.Debug("defaultPort: %s", defaultPort)
-		socket := fmt.Sprintf("%s:%s", ip, port)
+		socket := net.JoinHostPort(ip, port)
 		if slices.Contains(r.cluster.GetAllIPS(), socket) {
 			continue
 		}
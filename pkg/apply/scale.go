@@ -65,7 +65,7 @@ func NewScaleApplierFromArgs(cmd *cobra.Command, scaleArgs *ScaleArgs) (applydri
 		return nil, err
 	}
 
-	return applydrivers.NewDefaultScaleApplier(cmd.Context(), curr, cluster)
+	return applydrivers.NewDefaultScaleApplier(withCommonContext(cmd.Context(), cmd), curr, cluster)
 }
 
 func getSSHFromCommand(cmd *cobra.Command) *v2.SSH {
@@ -94,6 +94,23 @@ func getSSHFromCommand(cmd *cobra.Command) *v2.SSH {
 		ret.Port, _ = fs.GetUint16("port")
 		changed = true
 	}
+	if flagChanged(cmd, "proxy-jump") {
+		ret.ProxyJump, _ = fs.GetString("proxy-jump")
+		changed = true
+	}
+	if flagChanged(cmd, "identity-agent") {
+		ret.IdentityAgent, _ = fs.GetString("identity-agent")
+		changed = true
+	}
+	if flagChanged(cmd, "forward-agent") {
+		forwardAgent, _ := fs.GetBool("forward-agent")
+		ret.ForwardAgent = &forwardAgent
+		changed = true
+	}
+	if flagChanged(cmd, "certificate") {
+		ret.Certificate, _ = fs.GetString("certificate")
+		changed = true
+	}
 	if changed {
 		return ret
 	}
@@ -172,7 +189,7 @@ func verifyAndSetNodes(cmd *cobra.Command, cluster *v2.Cluster, scaleArgs *Scale
 			global := cluster.Spec.SSH.DeepCopy()
 			ssh.OverSSHConfig(global, override)
 
-			sshClient := ssh.MustNewClient(global, true)
+			sshClient := ssh.MustNewClient(global, true, ssh.WithHostKeyCallback(ssh.HostKeyCallback(cluster.Name)))
 			execer, err := exec.New(sshClient)
 			if err != nil {
 				return nil, err
@@ -295,7 +312,7 @@ func fillIPAndPort(ipList []string, defaultPort string) []string {
 			continue
 		}
 		targetIP, targetPort := iputils.GetHostIPAndPortOrDefault(ip, defaultPort)
-		ipAndPort := fmt.Sprintf("%s:%s", targetIP, targetPort)
+		ipAndPort := net.JoinHostPort(targetIP, targetPort)
 		ipAndPorts = append(ipAndPorts, ipAndPort)
 	}
 	return ipAndPorts
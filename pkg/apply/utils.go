@@ -145,7 +145,7 @@ func CheckAndInitialize(cluster *v2.Cluster) error {
 	}
 
 	if len(cluster.Spec.Hosts) == 0 {
-		sshClient := ssh.MustNewClient(cluster.Spec.SSH.DeepCopy(), true)
+		sshClient := ssh.MustNewClient(cluster.Spec.SSH.DeepCopy(), true, ssh.WithHostKeyCallback(ssh.HostKeyCallback(cluster.Name)))
 		execer, err := exec.New(sshClient)
 		if err != nil {
 			return err
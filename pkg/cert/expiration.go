@@ -0,0 +1,136 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cert
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Expiration is the expiry info of a certificate on a host.
+type Expiration struct {
+	Host       string    `json:"host"`
+	Name       string    `json:"name"`
+	Path       string    `json:"path"`
+	CommonName string    `json:"commonName"`
+	CA         bool      `json:"ca"`
+	NotAfter   time.Time `json:"notAfter"`
+	// Renewable is false for the CAs and the certs which are rotated by the owner, eg. kubelet client cert.
+	Renewable bool `json:"renewable"`
+}
+
+func NewExpiration(host, name, path string, cert *x509.Certificate) Expiration {
+	return Expiration{
+		Host:       host,
+		Name:       name,
+		Path:       path,
+		CommonName: cert.Subject.CommonName,
+		CA:         cert.IsCA,
+		NotAfter:   cert.NotAfter,
+	}
+}
+
+// ResidualTime returns the time left before the cert expires, negative if it's already expired.
+func (e Expiration) ResidualTime(now time.Time) time.Duration {
+	return e.NotAfter.Sub(now)
+}
+
+// ExpiresWithin reports whether the cert expires before now+d.
+func (e Expiration) ExpiresWithin(now time.Time, d time.Duration) bool {
+	return e.ResidualTime(now) <= d
+}
+
+// SortExpirations sorts the expirations by host and name.
+func SortExpirations(list []Expiration) {
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Host != list[j].Host {
+			return list[i].Host < list[j].Host
+		}
+		return list[i].Name < list[j].Name
+	})
+}
+
+// ParseCertPEM parses the first certificate of PEM encoded data.
+func ParseCertPEM(data []byte) (*x509.Certificate, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("no certificate found in PEM data")
+		}
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+}
+
+// ParseKubeConfigCert returns the client certificate of the current user in a kubeconfig. If the
+// certificate is not embedded, the referenced file path is returned instead.
+func ParseKubeConfigCert(data []byte) (*x509.Certificate, string, error) {
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, "", err
+	}
+	ctx, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return nil, "", fmt.Errorf("current context %q not found in kubeconfig", config.CurrentContext)
+	}
+	authInfo, ok := config.AuthInfos[ctx.AuthInfo]
+	if !ok {
+		return nil, "", fmt.Errorf("user %q not found in kubeconfig", ctx.AuthInfo)
+	}
+	if len(authInfo.ClientCertificateData) > 0 {
+		cert, err := ParseCertPEM(authInfo.ClientCertificateData)
+		return cert, "", err
+	}
+	if authInfo.ClientCertificate != "" {
+		return nil, authInfo.ClientCertificate, nil
+	}
+	return nil, "", fmt.Errorf("no client certificate found for user %q", ctx.AuthInfo)
+}
+
+// LocalExpirations reads all the certs under the local pki dir, names are relative to the dir.
+func LocalExpirations(host, dir string) ([]Expiration, error) {
+	var ret []Expiration
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".crt") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		cert, err := ParseCertPEM(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse cert %s: %v", path, err)
+		}
+		name, _ := filepath.Rel(dir, path)
+		ret = append(ret, NewExpiration(host, strings.TrimSuffix(name, ".crt"), path, cert))
+		return nil
+	})
+	return ret, err
+}
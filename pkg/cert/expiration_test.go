@@ -0,0 +1,82 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cert
+
+import (
+	"crypto/x509"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestExpirations(t *testing.T) {
+	dir := t.TempDir()
+	caCert, caKey, err := NewCaCertAndKey(Config{Path: dir, BaseName: "ca", CommonName: "kubernetes", Year: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = WriteCertAndKey(dir, "ca", caCert, caKey); err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{Path: filepath.Join(dir, "etcd"), BaseName: "server", CommonName: "etcd", Year: 1, Usages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}
+	serverCert, serverKey, err := NewCaCertAndKeyFromRoot(cfg, caCert, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = WriteCertAndKey(cfg.Path, cfg.BaseName, serverCert, serverKey); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := LocalExpirations("local", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SortExpirations(list)
+	if len(list) != 2 || list[0].Name != "ca" || !list[0].CA || list[1].Name != "etcd/server" || list[1].CommonName != "etcd" {
+		t.Fatalf("unexpected expirations %+v", list)
+	}
+	now := time.Now()
+	if list[1].ExpiresWithin(now, 30*24*time.Hour) || !list[1].ExpiresWithin(now, 2*365*24*time.Hour) {
+		t.Errorf("unexpected residual time %s", list[1].ResidualTime(now))
+	}
+
+	config := clientcmdapi.NewConfig()
+	config.AuthInfos["admin"] = &clientcmdapi.AuthInfo{ClientCertificateData: EncodeCertPEM(serverCert)}
+	config.Contexts["admin@kubernetes"] = &clientcmdapi.Context{AuthInfo: "admin", Cluster: "kubernetes"}
+	config.CurrentContext = "admin@kubernetes"
+	data, err := clientcmd.Write(*config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, file, err := ParseKubeConfigCert(data)
+	if err != nil || file != "" || !c.NotAfter.Equal(serverCert.NotAfter) {
+		t.Errorf("unexpected embedded cert %v %q %v", c, file, err)
+	}
+
+	config.AuthInfos["admin"] = &clientcmdapi.AuthInfo{ClientCertificate: "/var/lib/kubelet/pki/kubelet-client-current.pem"}
+	if data, err = clientcmd.Write(*config); err != nil {
+		t.Fatal(err)
+	}
+	if c, file, err = ParseKubeConfigCert(data); err != nil || c != nil || file != "/var/lib/kubelet/pki/kubelet-client-current.pem" {
+		t.Errorf("unexpected referenced cert %v %q %v", c, file, err)
+	}
+
+	if _, err = ParseCertPEM([]byte("invalid")); err == nil {
+		t.Error("expected error for invalid PEM data")
+	}
+}
@@ -16,6 +16,7 @@ package checker
 
 import (
 	"fmt"
+	"strings"
 
 	v2 "github.com/labring/sealos/pkg/types/v1beta1"
 )
@@ -30,6 +31,24 @@ type Interface interface {
 	Check(cluster *v2.Cluster, phase string) error
 }
 
+// Inspector is implemented by checkers which are able to return a typed result
+// instead of rendering it to stdout.
+type Inspector interface {
+	Interface
+	// Name is the category of the checker, e.g. registry, node, pod.
+	Name() string
+	// Inspect returns nil result if the checker is skipped in the phase.
+	Inspect(cluster *v2.Cluster, phase string) (*Result, error)
+}
+
+// Result is the typed output of a single checker.
+type Result struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+	Detail  any    `json:"detail,omitempty"`
+}
+
 func RunCheckList(list []Interface, cluster *v2.Cluster, phase string) error {
 	for _, l := range list {
 		if err := l.Check(cluster, phase); err != nil {
@@ -38,3 +57,37 @@ func RunCheckList(list []Interface, cluster *v2.Cluster, phase string) error {
 	}
 	return nil
 }
+
+// RunAllCheckList runs all the checkers even if some of them failed, the errors are aggregated.
+func RunAllCheckList(list []Interface, cluster *v2.Cluster, phase string) error {
+	var errs []string
+	for _, l := range list {
+		if err := l.Check(cluster, phase); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to run checkers: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// RunInspectList runs all the inspectors without stopping at the first error,
+// and aggregates their results into one report.
+func RunInspectList(list []Inspector, cluster *v2.Cluster, phase string) *Report {
+	report := &Report{Phase: phase, Healthy: true, Results: make([]Result, 0, len(list))}
+	for _, l := range list {
+		result, err := l.Inspect(cluster, phase)
+		if err != nil {
+			result = &Result{Name: l.Name(), Error: err.Error()}
+		}
+		if result == nil {
+			continue
+		}
+		if !result.Healthy {
+			report.Healthy = false
+		}
+		report.Results = append(report.Results, *result)
+	}
+	return report
+}
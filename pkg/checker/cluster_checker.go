@@ -34,32 +34,64 @@ type ClusterChecker struct {
 }
 
 type ClusterStatus struct {
-	IP                    string
-	Node                  string
-	KubeAPIServer         string
-	KubeControllerManager string
-	KubeScheduler         string
-	KubeletErr            string
+	IP                    string `json:"ip"`
+	Node                  string `json:"node"`
+	KubeAPIServer         string `json:"kubeAPIServer"`
+	KubeControllerManager string `json:"kubeControllerManager"`
+	KubeScheduler         string `json:"kubeScheduler"`
+	KubeletErr            string `json:"kubeletErr"`
+}
+
+func (s ClusterStatus) healthy() bool {
+	const running = "Running"
+	return s.KubeAPIServer == running && s.KubeControllerManager == running &&
+		s.KubeScheduler == running && s.KubeletErr == Nil
+}
+
+func (n *ClusterChecker) Name() string {
+	return ClusterCheckerName
 }
 
 func (n *ClusterChecker) Check(cluster *v2.Cluster, phase string) error {
+	status, err := n.inspect(cluster, phase)
+	if err != nil || status == nil {
+		return err
+	}
+	return n.Output(status)
+}
+
+func (n *ClusterChecker) Inspect(cluster *v2.Cluster, phase string) (*Result, error) {
+	status, err := n.inspect(cluster, phase)
+	if err != nil || status == nil {
+		return nil, err
+	}
+	healthy := true
+	for _, s := range status {
+		if !s.healthy() {
+			healthy = false
+		}
+	}
+	return &Result{Name: n.Name(), Healthy: healthy, Detail: status}, nil
+}
+
+func (n *ClusterChecker) inspect(cluster *v2.Cluster, phase string) ([]ClusterStatus, error) {
 	if phase != PhasePost {
-		return nil
+		return nil, nil
 	}
 
 	// checker if all the node is ready
 	data := constants.NewPathResolver(cluster.Name)
 	c, err := kubernetes.NewKubernetesClient(data.AdminFile(), "")
 	if err != nil {
-		return err
+		return nil, err
 	}
 	ke := kubernetes.NewKubeExpansion(c.Kubernetes())
 	nodes, err := c.Kubernetes().CoreV1().Nodes().List(context.Background(), v1.ListOptions{})
 	if err != nil {
-		return err
+		return nil, err
 	}
 	healthyClient := kubernetes.NewKubeHealthy(c.Kubernetes(), 30*time.Second)
-	var NodeList []ClusterStatus
+	NodeList := make([]ClusterStatus, 0, len(nodes.Items))
 	ctx := context.Background()
 	for _, node := range nodes.Items {
 		ip, _ := getNodeStatus(node)
@@ -69,19 +101,19 @@ func (n *ClusterChecker) Check(cluster *v2.Cluster, phase string) error {
 		}
 		apiPod, err := ke.FetchStaticPod(ctx, node.Name, kubernetes.KubeAPIServer)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		cStatus.KubeAPIServer = healthyClient.ForHealthyPod(apiPod)
 
 		controllerPod, err := ke.FetchStaticPod(ctx, node.Name, kubernetes.KubeControllerManager)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		cStatus.KubeControllerManager = healthyClient.ForHealthyPod(controllerPod)
 
 		schedulerPod, err := ke.FetchStaticPod(ctx, node.Name, kubernetes.KubeScheduler)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		cStatus.KubeScheduler = healthyClient.ForHealthyPod(schedulerPod)
 
@@ -93,7 +125,7 @@ func (n *ClusterChecker) Check(cluster *v2.Cluster, phase string) error {
 		NodeList = append(NodeList, cStatus)
 	}
 
-	return n.Output(NodeList)
+	return NodeList, nil
 }
 
 func (n *ClusterChecker) Output(clusterStatus []ClusterStatus) error {
@@ -119,6 +151,6 @@ Cluster Status
 	return tpl.Execute(os.Stdout, map[string][]ClusterStatus{"ClusterStatusList": clusterStatus})
 }
 
-func NewClusterChecker() Interface {
+func NewClusterChecker() Inspector {
 	return &ClusterChecker{}
 }
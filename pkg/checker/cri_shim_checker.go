@@ -34,22 +34,38 @@ type CRIShimChecker struct {
 }
 
 type CRIShimStatus struct {
-	Config    map[string]string
-	ImageList []string
-	Error     string
+	Config    map[string]string `json:"config,omitempty"`
+	ImageList []string          `json:"imageList,omitempty"`
+	Error     string            `json:"error"`
 }
 
-func (n *CRIShimChecker) Check(_ *v2.Cluster, phase string) error {
+func (n *CRIShimChecker) Name() string {
+	return CRIShimCheckerName
+}
+
+func (n *CRIShimChecker) Check(cluster *v2.Cluster, phase string) error {
+	status, err := n.inspect(cluster, phase)
+	if status != nil {
+		if oErr := n.Output(status); oErr != nil {
+			logger.Error("error output: %+v", oErr)
+		}
+	}
+	return err
+}
+
+func (n *CRIShimChecker) Inspect(cluster *v2.Cluster, phase string) (*Result, error) {
+	status, err := n.inspect(cluster, phase)
+	if err != nil || status == nil {
+		return nil, err
+	}
+	return &Result{Name: n.Name(), Healthy: status.Error == Nil, Detail: status}, nil
+}
+
+func (n *CRIShimChecker) inspect(_ *v2.Cluster, phase string) (*CRIShimStatus, error) {
 	if phase != PhasePost {
-		return nil
+		return nil, nil
 	}
 	status := &CRIShimStatus{}
-	defer func() {
-		err := n.Output(status)
-		if err != nil {
-			logger.Error("error output: %+v", err)
-		}
-	}()
 
 	if shimCfg, err := types.Unmarshal(types.DefaultImageCRIShimConfig); err != nil {
 		status.Error = fmt.Errorf("read image-cri-shim config error: %w", err).Error()
@@ -74,8 +90,10 @@ func (n *CRIShimChecker) Check(_ *v2.Cluster, phase string) error {
 		}
 	}
 
-	status.Error = Nil
-	return nil
+	if status.Error == "" {
+		status.Error = Nil
+	}
+	return status, nil
 }
 
 func (n *CRIShimChecker) Output(status *CRIShimStatus) error {
@@ -103,6 +121,6 @@ CRIShim Service Status
 	return tpl.Execute(os.Stdout, status)
 }
 
-func NewCRIShimChecker() Interface {
+func NewCRIShimChecker() Inspector {
 	return &CRIShimChecker{}
 }
@@ -42,33 +42,49 @@ type CRICtlChecker struct {
 }
 
 type Container struct {
-	Container string
-	State     string
-	Name      string
-	Attempt   int
-	PodName   string
+	Container string `json:"container"`
+	State     string `json:"state"`
+	Name      string `json:"name"`
+	Attempt   int    `json:"attempt"`
+	PodName   string `json:"podName"`
 }
 
 type CRICtlStatus struct {
-	Config              map[string]string
-	ImageList           []string
-	ContainerList       []Container
-	RegistryPullStatus  string
-	ImageShimPullStatus string
-	Error               string
+	Config              map[string]string `json:"config,omitempty"`
+	ImageList           []string          `json:"imageList,omitempty"`
+	ContainerList       []Container       `json:"containerList,omitempty"`
+	RegistryPullStatus  string            `json:"registryPullStatus"`
+	ImageShimPullStatus string            `json:"imageShimPullStatus"`
+	Error               string            `json:"error"`
+}
+
+func (n *CRICtlChecker) Name() string {
+	return CRICtlCheckerName
 }
 
 func (n *CRICtlChecker) Check(cluster *v2.Cluster, phase string) error {
+	status, err := n.inspect(cluster, phase)
+	if status != nil {
+		if oErr := n.Output(status); oErr != nil {
+			logger.Error("error output: %+v", oErr)
+		}
+	}
+	return err
+}
+
+func (n *CRICtlChecker) Inspect(cluster *v2.Cluster, phase string) (*Result, error) {
+	status, err := n.inspect(cluster, phase)
+	if err != nil || status == nil {
+		return nil, err
+	}
+	return &Result{Name: n.Name(), Healthy: status.Error == Nil, Detail: status}, nil
+}
+
+func (n *CRICtlChecker) inspect(cluster *v2.Cluster, phase string) (*CRICtlStatus, error) {
 	if phase != PhasePost {
-		return nil
+		return nil, nil
 	}
 	status := &CRICtlStatus{}
-	defer func() {
-		err := n.Output(status)
-		if err != nil {
-			logger.Error("error output: %+v", err)
-		}
-	}()
 
 	criShimConfig := "/etc/crictl.yaml"
 	if cfg, err := fileutil.ReadAll(criShimConfig); err != nil {
@@ -83,7 +99,7 @@ func (n *CRICtlChecker) Check(cluster *v2.Cluster, phase string) error {
 	crictlPath, err := execer.LookPath("crictl")
 	if err != nil {
 		status.Error = fmt.Errorf("error looking for path of crictl: %w", err).Error()
-		return nil
+		return status, nil
 	}
 
 	imageList, err := n.getCRICtlImageList(crictlPath)
@@ -110,7 +126,7 @@ func (n *CRICtlChecker) Check(cluster *v2.Cluster, phase string) error {
 	sshCtx := ssh.NewCacheClientFromCluster(cluster, false)
 	sshCtx, err = exec.New(sshCtx)
 	if err != nil {
-		return err
+		return status, err
 	}
 	root := constants.NewPathResolver(cluster.Name).RootFSPath()
 	regInfo := helpers.GetRegistryInfo(sshCtx, root, cluster.GetRegistryIPAndPort())
@@ -126,8 +142,10 @@ func (n *CRICtlChecker) Check(cluster *v2.Cluster, phase string) error {
 		status.Error = fmt.Errorf("pull shim image error: %w", err).Error()
 	}
 	status.ImageShimPullStatus = shimStatus
-	status.Error = Nil
-	return nil
+	if status.Error == "" {
+		status.Error = Nil
+	}
+	return status, nil
 }
 
 func (n *CRICtlChecker) Output(status *CRICtlStatus) error {
@@ -160,7 +178,7 @@ CRI Status
 	return tpl.Execute(os.Stdout, status)
 }
 
-func NewCRICtlChecker() Interface {
+func NewCRICtlChecker() Inspector {
 	return &CRICtlChecker{}
 }
 
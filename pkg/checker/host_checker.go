@@ -29,12 +29,22 @@ import (
 
 type HostChecker struct {
 	IPs []string
+	// Peers are the hosts already in the cluster, only used by the pre-flight checks.
+	Peers []string
+	// Skips are the names of the pre-flight checks to skip.
+	Skips     []string
+	preflight bool
 }
 
 func (a HostChecker) Check(cluster *v2.Cluster, _ string) error {
 	var ipList []string
 	if len(cluster.GetMasterIPList())&1 == 0 {
-		return confirmNonOddMasters()
+		if err := confirmNonOddMasters(); err != nil {
+			return err
+		}
+	}
+	if a.preflight {
+		return RunPreflight(cluster, a.IPs, a.Peers, a.Skips)
 	}
 	if len(a.IPs) != 0 {
 		ipList = a.IPs
@@ -54,6 +64,11 @@ func NewIPsHostChecker(ips []string) Interface {
 	return &HostChecker{IPs: ips}
 }
 
+// NewPreflightHostChecker returns a HostChecker which runs the pre-flight suite on ips.
+func NewPreflightHostChecker(ips, peers, skips []string) Interface {
+	return &HostChecker{IPs: ips, Peers: peers, Skips: skips, preflight: true}
+}
+
 func checkHostnameUnique(s exec.Interface, ipList []string) error {
 	logger.Info("checker:hostname %v", ipList)
 	hostnameList := map[string]bool{}
@@ -31,31 +31,46 @@ type InitSystemChecker struct {
 }
 
 type systemStatus struct {
-	Name   string
-	Status string
+	Name   string `json:"name"`
+	Status string `json:"status"`
 }
 
 type InitSystemStatus struct {
-	Error       string
-	ServiceList []systemStatus
+	Error       string         `json:"error"`
+	ServiceList []systemStatus `json:"serviceList,omitempty"`
 }
 
-func (n *InitSystemChecker) Check(_ *v2.Cluster, phase string) error {
+func (n *InitSystemChecker) Name() string {
+	return InitSystemCheckerName
+}
+
+func (n *InitSystemChecker) Check(cluster *v2.Cluster, phase string) error {
+	status, err := n.inspect(cluster, phase)
+	if status != nil {
+		if oErr := n.Output(status); oErr != nil {
+			logger.Error("error output: %+v", oErr)
+		}
+	}
+	return err
+}
+
+func (n *InitSystemChecker) Inspect(cluster *v2.Cluster, phase string) (*Result, error) {
+	status, err := n.inspect(cluster, phase)
+	if err != nil || status == nil {
+		return nil, err
+	}
+	return &Result{Name: n.Name(), Healthy: status.Error == Nil, Detail: status}, nil
+}
+
+func (n *InitSystemChecker) inspect(_ *v2.Cluster, phase string) (*InitSystemStatus, error) {
 	if phase != PhasePost {
-		return nil
+		return nil, nil
 	}
 	status := &InitSystemStatus{}
-
-	defer func() {
-		err := n.Output(status)
-		if err != nil {
-			logger.Error("error output: %+v", err)
-		}
-	}()
 	initsystemvar, err := initsystem.GetInitSystem()
 	if err != nil {
 		status.Error = fmt.Errorf("get initsystem error: %w", err).Error()
-		return nil
+		return status, nil
 	}
 
 	serviceNames := []string{"kubelet", "containerd", "cri-docker", "docker", "registry", "image-cri-shim"}
@@ -68,7 +83,7 @@ func (n *InitSystemChecker) Check(_ *v2.Cluster, phase string) error {
 	}
 
 	status.Error = Nil
-	return nil
+	return status, nil
 }
 
 func (n *InitSystemChecker) Output(status *InitSystemStatus) error {
@@ -92,7 +107,7 @@ Systemd Service Status
 	return tpl.Execute(os.Stdout, status)
 }
 
-func NewInitSystemChecker() Interface {
+func NewInitSystemChecker() Inspector {
 	return &InitSystemChecker{}
 }
 
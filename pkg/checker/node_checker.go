@@ -38,25 +38,45 @@ type NodeChecker struct {
 }
 
 type NodeClusterStatus struct {
-	ReadyCount       uint32
-	NotReadyCount    uint32
-	NodeCount        uint32
-	NotReadyNodeList []string
+	ReadyCount       uint32   `json:"readyCount"`
+	NotReadyCount    uint32   `json:"notReadyCount"`
+	NodeCount        uint32   `json:"nodeCount"`
+	NotReadyNodeList []string `json:"notReadyNodeList,omitempty"`
+}
+
+func (n *NodeChecker) Name() string {
+	return NodeCheckerName
 }
 
 func (n *NodeChecker) Check(cluster *v2.Cluster, phase string) error {
+	status, err := n.inspect(cluster, phase)
+	if err != nil || status == nil {
+		return err
+	}
+	return n.Output(*status)
+}
+
+func (n *NodeChecker) Inspect(cluster *v2.Cluster, phase string) (*Result, error) {
+	status, err := n.inspect(cluster, phase)
+	if err != nil || status == nil {
+		return nil, err
+	}
+	return &Result{Name: n.Name(), Healthy: status.NotReadyCount == 0, Detail: status}, nil
+}
+
+func (n *NodeChecker) inspect(cluster *v2.Cluster, phase string) (*NodeClusterStatus, error) {
 	if phase != PhasePost {
-		return nil
+		return nil, nil
 	}
 	// checker if all the node is ready
 	data := constants.NewPathResolver(cluster.Name)
 	c, err := kubernetes.NewKubernetesClient(data.AdminFile(), "")
 	if err != nil {
-		return err
+		return nil, err
 	}
 	nodes, err := c.Kubernetes().CoreV1().Nodes().List(context.Background(), v1.ListOptions{})
 	if err != nil {
-		return err
+		return nil, err
 	}
 	var notReadyNodeList []string
 	var readyCount uint32
@@ -72,13 +92,12 @@ func (n *NodeChecker) Check(cluster *v2.Cluster, phase string) error {
 		}
 	}
 	nodeCount = notReadyCount + readyCount
-	nodeClusterStatus := NodeClusterStatus{
+	return &NodeClusterStatus{
 		ReadyCount:       readyCount,
 		NotReadyCount:    notReadyCount,
 		NodeCount:        nodeCount,
 		NotReadyNodeList: notReadyNodeList,
-	}
-	return n.Output(nodeClusterStatus)
+	}, nil
 }
 
 func (n *NodeChecker) Output(nodeCLusterStatus NodeClusterStatus) error {
@@ -127,6 +146,6 @@ func getNodeStatus(node corev1.Node) (IP string, Phase string) {
 	return IP, Phase
 }
 
-func NewNodeChecker() Interface {
+func NewNodeChecker() Inspector {
 	return &NodeChecker{}
 }
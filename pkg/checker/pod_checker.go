@@ -34,48 +34,73 @@ type PodChecker struct {
 }
 
 type PodNamespaceStatus struct {
-	NamespaceName     string
-	RunningCount      uint32
-	NotRunningCount   uint32
-	PodCount          uint32
-	NotRunningPodList []*corev1.Pod
+	NamespaceName     string   `json:"namespace"`
+	RunningCount      uint32   `json:"runningCount"`
+	NotRunningCount   uint32   `json:"notRunningCount"`
+	PodCount          uint32   `json:"podCount"`
+	NotRunningPodList []string `json:"notRunningPodList,omitempty"`
 }
 
-var PodNamespaceStatusList []PodNamespaceStatus
+func (n *PodChecker) Name() string {
+	return PodCheckerName
+}
 
 func (n *PodChecker) Check(cluster *v2.Cluster, phase string) error {
+	status, err := n.inspect(cluster, phase)
+	if err != nil || status == nil {
+		return err
+	}
+	return n.Output(status)
+}
+
+func (n *PodChecker) Inspect(cluster *v2.Cluster, phase string) (*Result, error) {
+	status, err := n.inspect(cluster, phase)
+	if err != nil || status == nil {
+		return nil, err
+	}
+	healthy := true
+	for _, ns := range status {
+		if ns.NotRunningCount > 0 {
+			healthy = false
+		}
+	}
+	return &Result{Name: n.Name(), Healthy: healthy, Detail: status}, nil
+}
+
+func (n *PodChecker) inspect(cluster *v2.Cluster, phase string) ([]PodNamespaceStatus, error) {
 	if phase != PhasePost {
-		return nil
+		return nil, nil
 	}
 	// checker if all the node is ready
 	data := constants.NewPathResolver(cluster.Name)
 	c, err := kubernetes.NewKubernetesClient(data.AdminFile(), "")
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	n.client = c
 
 	nsList, err := n.client.Kubernetes().CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	podNamespaceStatusList := make([]PodNamespaceStatus, 0, len(nsList.Items))
+
 	for _, podNamespace := range nsList.Items {
 		var runningCount uint32
 		var notRunningCount uint32
 		var podCount uint32
-		var notRunningPodList []*corev1.Pod
+		var notRunningPodList []string
 		namespacePodList, err := n.client.Kubernetes().CoreV1().Pods(podNamespace.Name).List(context.TODO(), metav1.ListOptions{})
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		for _, pod := range namespacePodList.Items {
 			if err := getPodReadyStatus(pod); err != nil {
 				notRunningCount++
-				newPod := pod
-				notRunningPodList = append(notRunningPodList, &newPod)
+				notRunningPodList = append(notRunningPodList, pod.Name)
 			} else {
 				runningCount++
 			}
@@ -88,9 +113,9 @@ func (n *PodChecker) Check(cluster *v2.Cluster, phase string) error {
 			PodCount:          podCount,
 			NotRunningPodList: notRunningPodList,
 		}
-		PodNamespaceStatusList = append(PodNamespaceStatusList, podNamespaceStatus)
+		podNamespaceStatusList = append(podNamespaceStatusList, podNamespaceStatus)
 	}
-	return n.Output(PodNamespaceStatusList)
+	return podNamespaceStatusList, nil
 }
 
 func (n *PodChecker) Output(podNamespaceStatusList []PodNamespaceStatus) error {
@@ -101,7 +126,7 @@ func (n *PodChecker) Output(podNamespaceStatusList []PodNamespaceStatus) error {
   {{ if (gt .NotRunningCount 0) -}}
   Not Running Pod List:
     {{- range .NotRunningPodList }}
-    PodName: {{ .Name }}
+    PodName: {{ . }}
     {{- end }}
   {{ end }}
   {{- end }}
@@ -127,6 +152,6 @@ func getPodReadyStatus(pod corev1.Pod) error {
 	return &NotFindReadyTypeError{}
 }
 
-func NewPodChecker() Interface {
+func NewPodChecker() Inspector {
 	return &PodChecker{}
 }
@@ -0,0 +1,460 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checker
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"golang.org/x/exp/slices"
+
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/env"
+	"github.com/labring/sealos/pkg/exec"
+	"github.com/labring/sealos/pkg/registry/helpers"
+	"github.com/labring/sealos/pkg/ssh"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/logger"
+	"github.com/labring/sealos/pkg/utils/maps"
+)
+
+// names of the pre-flight checkers, used by --skip-preflight.
+const (
+	PreflightKernel  = "kernel"
+	PreflightSwap    = "swap"
+	PreflightTime    = "time"
+	PreflightDisk    = "disk"
+	PreflightPorts   = "ports"
+	PreflightUnique  = "unique"
+	PreflightCgroup  = "cgroup"
+	PreflightSkipAll = "all"
+)
+
+const (
+	minKernelVersion    = "3.10.0"
+	defaultRegistryPort = 5000
+	diskCheckPath       = "/var/lib"
+)
+
+var (
+	requiredKernelModules = []string{"br_netfilter", "ip_vs"}
+	masterPorts           = []int{6443, 2379, 2380, 10250, 10257, 10259}
+	nodePorts             = []int{10250}
+	maxTimeSkew           = time.Minute
+	minFreeDiskBytes      = int64(10 << 30)
+	kernelVersionRegexp   = regexp.MustCompile(`^(\d+)\.(\d+)(\.(\d+))?`)
+)
+
+// PreflightIssue is a problem found on a host, Host is empty if the issue is
+// about the hosts as a whole, e.g. duplicated hostnames.
+type PreflightIssue struct {
+	Host    string `json:"host,omitempty"`
+	Message string `json:"message"`
+}
+
+// preflightFunc checks hosts which are going to be installed, peers are the hosts already
+// in the cluster, they are only used to compare with, e.g. to find duplicated hostnames.
+type preflightFunc func(execer exec.Interface, cluster *v2.Cluster, hosts, peers []string) []PreflightIssue
+
+// PreflightChecker validates the hosts over ssh before they are installed.
+type PreflightChecker struct {
+	name   string
+	execer exec.Interface
+	hosts  []string
+	peers  []string
+	check  preflightFunc
+}
+
+func (p *PreflightChecker) Name() string {
+	return p.name
+}
+
+func (p *PreflightChecker) Inspect(cluster *v2.Cluster, phase string) (*Result, error) {
+	if phase != PhasePre || len(p.hosts) == 0 {
+		return nil, nil
+	}
+	result := &Result{Name: p.name, Healthy: true}
+	if issues := p.check(p.execer, cluster, p.hosts, p.peers); len(issues) > 0 {
+		result.Healthy, result.Detail = false, issues
+	}
+	return result, nil
+}
+
+func (p *PreflightChecker) Check(cluster *v2.Cluster, phase string) error {
+	result, err := p.Inspect(cluster, phase)
+	if err != nil || result == nil || result.Healthy {
+		return err
+	}
+	return fmt.Errorf("pre-flight check %s failed: %s", p.name, formatIssues(result.Detail.([]PreflightIssue)))
+}
+
+var preflightFuncs = []struct {
+	name  string
+	check preflightFunc
+}{
+	{PreflightKernel, checkKernel},
+	{PreflightSwap, checkSwap},
+	{PreflightTime, checkTimeSkew},
+	{PreflightDisk, checkDisk},
+	{PreflightPorts, checkPorts},
+	{PreflightUnique, checkUnique},
+	{PreflightCgroup, checkCgroup},
+}
+
+// PreflightNames returns the names of all the pre-flight checkers.
+func PreflightNames() []string {
+	ret := make([]string, 0, len(preflightFuncs))
+	for _, f := range preflightFuncs {
+		ret = append(ret, f.name)
+	}
+	return ret
+}
+
+// NewPreflightCheckers returns the pre-flight checkers for hosts, excluding the skipped ones.
+func NewPreflightCheckers(execer exec.Interface, hosts, peers, skips []string) ([]Inspector, error) {
+	names := PreflightNames()
+	for _, s := range skips {
+		if s != PreflightSkipAll && !slices.Contains(names, s) {
+			return nil, fmt.Errorf("unknown pre-flight check %q, must be one of %s|%s", s, strings.Join(names, "|"), PreflightSkipAll)
+		}
+	}
+	if slices.Contains(skips, PreflightSkipAll) {
+		return nil, nil
+	}
+	var ret []Inspector
+	for _, f := range preflightFuncs {
+		if slices.Contains(skips, f.name) {
+			logger.Info("skip pre-flight check %s", f.name)
+			continue
+		}
+		ret = append(ret, &PreflightChecker{name: f.name, execer: execer, hosts: hosts, peers: peers, check: f.check})
+	}
+	return ret, nil
+}
+
+// RunPreflight runs all the pre-flight checkers on hosts and prints a consolidated report,
+// an error is returned if any of them failed.
+func RunPreflight(cluster *v2.Cluster, hosts, peers, skips []string) error {
+	if len(hosts) == 0 {
+		return nil
+	}
+	execer, err := exec.New(ssh.NewCacheClientFromCluster(cluster, false))
+	if err != nil {
+		return err
+	}
+	list, err := NewPreflightCheckers(execer, hosts, peers, skips)
+	if err != nil || len(list) == 0 {
+		return err
+	}
+	logger.Info("running pre-flight checks on %v", hosts)
+	report := RunInspectList(list, cluster, PhasePre)
+	for _, result := range report.Results {
+		switch {
+		case result.Healthy:
+			logger.Info("pre-flight check %s: ok", result.Name)
+		case result.Error != "":
+			logger.Error("pre-flight check %s: %s", result.Name, result.Error)
+		default:
+			for _, issue := range result.Detail.([]PreflightIssue) {
+				if issue.Host == "" {
+					logger.Error("pre-flight check %s: %s", result.Name, issue.Message)
+				} else {
+					logger.Error("pre-flight check %s: %s: %s", result.Name, issue.Host, issue.Message)
+				}
+			}
+		}
+	}
+	if failed := report.Failed(); len(failed) > 0 {
+		return fmt.Errorf("pre-flight checks %s failed, fix them or ignore with --skip-preflight=%s",
+			strings.Join(failed, ", "), strings.Join(failed, ","))
+	}
+	return nil
+}
+
+func formatIssues(issues []PreflightIssue) string {
+	ret := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		if issue.Host == "" {
+			ret = append(ret, issue.Message)
+		} else {
+			ret = append(ret, fmt.Sprintf("%s: %s", issue.Host, issue.Message))
+		}
+	}
+	return strings.Join(ret, "; ")
+}
+
+// runOnHosts runs cmd on all hosts concurrently and returns the trimmed output of each host,
+// the hosts failed to run cmd are reported as issues.
+func runOnHosts(execer exec.Interface, hosts []string, cmd string) (map[string]string, []PreflightIssue) {
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		ret    = make(map[string]string, len(hosts))
+		issues []PreflightIssue
+	)
+	for _, host := range hosts {
+		host := host
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			out, err := execer.Cmd(host, cmd)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				issues = append(issues, PreflightIssue{Host: host, Message: fmt.Sprintf("failed to run %q: %v", cmd, err)})
+				return
+			}
+			ret[host] = strings.TrimSpace(string(out))
+		}()
+	}
+	wg.Wait()
+	sort.Slice(issues, func(i, j int) bool { return issues[i].Host < issues[j].Host })
+	return ret, issues
+}
+
+func mergeHosts(peers, hosts []string) []string {
+	ret := slices.Clone(peers)
+	for _, host := range hosts {
+		if !slices.Contains(ret, host) {
+			ret = append(ret, host)
+		}
+	}
+	return ret
+}
+
+func checkKernel(execer exec.Interface, _ *v2.Cluster, hosts, _ []string) []PreflightIssue {
+	minVersion := semver.MustParse(minKernelVersion)
+	releases, issues := runOnHosts(execer, hosts, "uname -r")
+	for _, host := range hosts {
+		release, ok := releases[host]
+		if !ok {
+			continue
+		}
+		v, err := parseKernelVersion(release)
+		if err != nil {
+			issues = append(issues, PreflightIssue{Host: host, Message: err.Error()})
+		} else if v.LessThan(minVersion) {
+			issues = append(issues, PreflightIssue{Host: host,
+				Message: fmt.Sprintf("kernel version %s is lower than the minimum %s", release, minKernelVersion)})
+		}
+	}
+	cmd := fmt.Sprintf(`for m in %s; do test -d /sys/module/$m || modinfo $m >/dev/null 2>&1 || echo $m; done`,
+		strings.Join(requiredKernelModules, " "))
+	missing, cmdIssues := runOnHosts(execer, hosts, cmd)
+	issues = append(issues, cmdIssues...)
+	for _, host := range hosts {
+		if modules := strings.Fields(missing[host]); len(modules) > 0 {
+			issues = append(issues, PreflightIssue{Host: host,
+				Message: fmt.Sprintf("required kernel modules %s are not available", strings.Join(modules, ", "))})
+		}
+	}
+	return issues
+}
+
+func parseKernelVersion(release string) (*semver.Version, error) {
+	m := kernelVersionRegexp.FindStringSubmatch(release)
+	if m == nil {
+		return nil, fmt.Errorf("failed to parse kernel version %q", release)
+	}
+	patch := m[4]
+	if patch == "" {
+		patch = "0"
+	}
+	return semver.NewVersion(fmt.Sprintf("%s.%s.%s", m[1], m[2], patch))
+}
+
+func checkSwap(execer exec.Interface, _ *v2.Cluster, hosts, _ []string) []PreflightIssue {
+	counts, issues := runOnHosts(execer, hosts, "awk 'NR>1' /proc/swaps | wc -l")
+	for _, host := range hosts {
+		if v, ok := counts[host]; ok && v != "0" {
+			issues = append(issues, PreflightIssue{Host: host, Message: "swap is enabled, disable it with `swapoff -a`"})
+		}
+	}
+	return issues
+}
+
+func checkTimeSkew(execer exec.Interface, _ *v2.Cluster, hosts, peers []string) []PreflightIssue {
+	hosts = mergeHosts(peers, hosts)
+	timestamps, issues := runOnHosts(execer, hosts, "date +%s")
+	var (
+		minHost, maxHost string
+		minTime, maxTime int64
+	)
+	for _, host := range hosts {
+		v, ok := timestamps[host]
+		if !ok {
+			continue
+		}
+		ts, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			issues = append(issues, PreflightIssue{Host: host, Message: fmt.Sprintf("failed to parse timestamp %q", v)})
+			continue
+		}
+		if minHost == "" || ts < minTime {
+			minHost, minTime = host, ts
+		}
+		if maxHost == "" || ts > maxTime {
+			maxHost, maxTime = host, ts
+		}
+	}
+	if skew := time.Duration(maxTime-minTime) * time.Second; skew > maxTimeSkew {
+		issues = append(issues, PreflightIssue{
+			Message: fmt.Sprintf("time skew between %s and %s is %s, more than %s", minHost, maxHost, skew, maxTimeSkew)})
+	}
+	return issues
+}
+
+func checkDisk(execer exec.Interface, _ *v2.Cluster, hosts, _ []string) []PreflightIssue {
+	available, issues := runOnHosts(execer, hosts, fmt.Sprintf("df -Pk %s | awk 'NR==2{print $4}'", diskCheckPath))
+	for _, host := range hosts {
+		v, ok := available[host]
+		if !ok {
+			continue
+		}
+		kb, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			issues = append(issues, PreflightIssue{Host: host, Message: fmt.Sprintf("failed to parse free disk %q", v)})
+			continue
+		}
+		if kb<<10 < minFreeDiskBytes {
+			issues = append(issues, PreflightIssue{Host: host,
+				Message: fmt.Sprintf("free disk under %s is %dMiB, less than %dMiB", diskCheckPath, kb>>10, minFreeDiskBytes>>20)})
+		}
+	}
+	return issues
+}
+
+// getRegistryPort resolves the port of the registry like helpers.GetRegistryInfo, the registry config in
+// the rootfs on the registry host wins, then the registryPort env of the cluster and the rootfs image.
+func getRegistryPort(execer exec.Interface, cluster *v2.Cluster) int {
+	registry := cluster.GetRegistryIPAndPort()
+	envs := env.NewEnvProcessor(cluster).Getenv(registry)
+	if rootfs := cluster.GetRootfsImage(); rootfs != nil {
+		envs = maps.Merge(rootfs.Env, envs)
+	}
+	port := envs["registryPort"]
+	if cfg, err := helpers.ReadRegistryConfig(execer, constants.NewPathResolver(cluster.Name).RootFSPath(), registry); err == nil && cfg.Port != "" {
+		port = cfg.Port
+	}
+	if v, err := strconv.Atoi(port); err == nil {
+		return v
+	}
+	return defaultRegistryPort
+}
+
+func checkPorts(execer exec.Interface, cluster *v2.Cluster, hosts, _ []string) []PreflightIssue {
+	registryPort := getRegistryPort(execer, cluster)
+	listening, issues := runOnHosts(execer, hosts,
+		`if command -v ss >/dev/null 2>&1; then ss -ltn | awk 'NR>1{print $4}'; else netstat -ltn | awk 'NR>2{print $4}'; fi`)
+	for _, host := range hosts {
+		out, ok := listening[host]
+		if !ok {
+			continue
+		}
+		ports := nodePorts
+		if slices.Contains(cluster.GetMasterIPAndPortList(), host) {
+			ports = masterPorts
+		}
+		if slices.Contains(cluster.GetRegistryIPAndPortList(), host) {
+			ports = append(slices.Clone(ports), registryPort)
+		}
+		var used []string
+		for _, addr := range strings.Fields(out) {
+			p, err := strconv.Atoi(addr[strings.LastIndex(addr, ":")+1:])
+			if err == nil && slices.Contains(ports, p) && !slices.Contains(used, strconv.Itoa(p)) {
+				used = append(used, strconv.Itoa(p))
+			}
+		}
+		if len(used) > 0 {
+			issues = append(issues, PreflightIssue{Host: host, Message: fmt.Sprintf("ports %s are already in use", strings.Join(used, ", "))})
+		}
+	}
+	return issues
+}
+
+func checkUnique(execer exec.Interface, _ *v2.Cluster, hosts, peers []string) []PreflightIssue {
+	hosts = mergeHosts(peers, hosts)
+	var issues []PreflightIssue
+	for _, item := range []struct {
+		name string
+		cmd  string
+	}{
+		{"hostname", "hostname"},
+		{"product_uuid", "cat /sys/class/dmi/id/product_uuid 2>/dev/null; true"},
+		// only the physical interfaces are checked, virtual ones may share the same address
+		{"MAC address", "for i in /sys/class/net/*; do test -e $i/device && cat $i/address; done; true"},
+	} {
+		out, cmdIssues := runOnHosts(execer, hosts, item.cmd)
+		issues = append(issues, cmdIssues...)
+		owners := make(map[string][]string)
+		for _, host := range hosts {
+			for _, v := range strings.Fields(out[host]) {
+				v = strings.ToLower(v)
+				if !slices.Contains(owners[v], host) {
+					owners[v] = append(owners[v], host)
+				}
+			}
+		}
+		values := make([]string, 0, len(owners))
+		for v := range owners {
+			values = append(values, v)
+		}
+		sort.Strings(values)
+		for _, v := range values {
+			if len(owners[v]) > 1 {
+				issues = append(issues, PreflightIssue{
+					Message: fmt.Sprintf("%s %s is duplicated on hosts %s", item.name, v, strings.Join(owners[v], ", "))})
+			}
+		}
+	}
+	return issues
+}
+
+func checkCgroup(execer exec.Interface, _ *v2.Cluster, hosts, peers []string) []PreflightIssue {
+	hosts = mergeHosts(peers, hosts)
+	var issues []PreflightIssue
+	for _, item := range []struct {
+		name string
+		cmd  string
+	}{
+		{"cgroup filesystem", "stat -fc %T /sys/fs/cgroup"},
+		// the cgroup driver of master0 is used by kubelet on all hosts
+		{"cgroup driver of the installed docker", "docker info --format '{{.CgroupDriver}}' 2>/dev/null; true"},
+	} {
+		out, cmdIssues := runOnHosts(execer, hosts, item.cmd)
+		issues = append(issues, cmdIssues...)
+		groups := make(map[string][]string)
+		for _, host := range hosts {
+			if v := out[host]; v != "" {
+				groups[v] = append(groups[v], host)
+			}
+		}
+		if len(groups) > 1 {
+			var desc []string
+			for v, hs := range groups {
+				desc = append(desc, fmt.Sprintf("%s on %s", v, strings.Join(hs, ", ")))
+			}
+			sort.Strings(desc)
+			issues = append(issues, PreflightIssue{Message: fmt.Sprintf("%s is inconsistent: %s", item.name, strings.Join(desc, "; "))})
+		}
+	}
+	return issues
+}
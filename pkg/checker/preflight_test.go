@@ -0,0 +1,122 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+// fakeExec returns the output by host and a keyword of the command, empty if no keyword matched.
+type fakeExec map[string]map[string]string
+
+func (f fakeExec) Copy(_, _, _ string) error                                          { return nil }
+func (f fakeExec) Fetch(_, _, _ string) error                                         { return nil }
+func (f fakeExec) CmdAsync(_ string, _ ...string) error                               { return nil }
+func (f fakeExec) CmdAsyncWithContext(_ context.Context, _ string, _ ...string) error { return nil }
+func (f fakeExec) Ping(_ string) error                                                { return nil }
+
+func (f fakeExec) Cmd(host, cmd string) ([]byte, error) {
+	outputs, ok := f[host]
+	if !ok {
+		return nil, fmt.Errorf("host %s is unreachable", host)
+	}
+	for keyword, out := range outputs {
+		if strings.Contains(cmd, keyword) {
+			return []byte(out), nil
+		}
+	}
+	return nil, nil
+}
+
+func (f fakeExec) CmdToString(host, cmd, _ string) (string, error) {
+	out, err := f.Cmd(host, cmd)
+	return string(out), err
+}
+
+func TestPreflightChecks(t *testing.T) {
+	cluster := &v2.Cluster{}
+	tests := []struct {
+		name   string
+		check  preflightFunc
+		exec   fakeExec
+		hosts  []string
+		peers  []string
+		issues int
+	}{
+		{"kernel ok", checkKernel, fakeExec{"a": {"uname": "5.15.0-91-generic\n"}}, []string{"a"}, nil, 0},
+		{"kernel too old", checkKernel, fakeExec{"a": {"uname": "2.6.32-754.el6.x86_64", "modinfo": "ip_vs"}}, []string{"a"}, nil, 2},
+		{"time skew with peer", checkTimeSkew, fakeExec{"a": {"date": "1700000000"}, "b": {"date": "1700000300"}}, []string{"b"}, []string{"a"}, 1},
+		{"time synced", checkTimeSkew, fakeExec{"a": {"date": "1700000000"}, "b": {"date": "1700000010"}}, []string{"a", "b"}, nil, 0},
+		{"disk full", checkDisk, fakeExec{"a": {"df": "1024"}}, []string{"a"}, nil, 1},
+		{"ports in use", checkPorts, fakeExec{"a": {"ss": "0.0.0.0:22\n[::]:10250"}}, []string{"a"}, nil, 1},
+		{"duplicated hostname", checkUnique, fakeExec{"a": {"hostname": "node"}, "b": {"hostname": "node"}}, []string{"a", "b"}, nil, 1},
+		{"unreachable host", checkSwap, fakeExec{"a": {"swaps": "0"}}, []string{"a", "b"}, nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := tt.check(tt.exec, cluster, tt.hosts, tt.peers)
+			if len(issues) != tt.issues {
+				t.Errorf("got issues %v, want %d issues", issues, tt.issues)
+			}
+		})
+	}
+}
+
+func TestNewPreflightCheckers(t *testing.T) {
+	if _, err := NewPreflightCheckers(fakeExec{}, nil, nil, []string{"unknown"}); err == nil {
+		t.Error("expected error for unknown pre-flight check")
+	}
+	list, err := NewPreflightCheckers(fakeExec{}, nil, nil, []string{PreflightSwap, PreflightPorts})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != len(PreflightNames())-2 {
+		t.Errorf("got %d checkers, want %d", len(list), len(PreflightNames())-2)
+	}
+	if list, _ = NewPreflightCheckers(fakeExec{}, nil, nil, []string{PreflightSkipAll}); len(list) != 0 {
+		t.Errorf("expected all checkers to be skipped, got %d", len(list))
+	}
+}
+
+func TestGetRegistryPort(t *testing.T) {
+	newCluster := func() *v2.Cluster {
+		return &v2.Cluster{
+			Spec: v2.ClusterSpec{Hosts: []v2.Host{{IPS: []string{"a"}, Roles: []string{v2.MASTER}}}},
+			Status: v2.ClusterStatus{Mounts: []v2.MountImage{
+				{Type: v2.RootfsImage, Env: map[string]string{"registryPort": "5001"}},
+			}},
+		}
+	}
+	// the registry is not installed yet
+	if port := getRegistryPort(fakeExec{}, newCluster()); port != 5001 {
+		t.Errorf("got port %d from the rootfs env, want 5001", port)
+	}
+	cluster := newCluster()
+	cluster.Spec.Env = []string{"registryPort=5002"}
+	if port := getRegistryPort(fakeExec{}, cluster); port != 5002 {
+		t.Errorf("got port %d from the cluster env, want 5002", port)
+	}
+	if port := getRegistryPort(fakeExec{"a": {"registry.yml": "port: \"5003\""}}, cluster); port != 5003 {
+		t.Errorf("got port %d from the registry config, want 5003", port)
+	}
+	if port := getRegistryPort(fakeExec{}, &v2.Cluster{}); port != defaultRegistryPort {
+		t.Errorf("got port %d, want the default %d", port, defaultRegistryPort)
+	}
+}
@@ -44,33 +44,49 @@ type RegistryChecker struct {
 }
 
 type RegistryStatus struct {
-	Port           string
-	DebugPort      string
-	Storage        string
-	Delete         bool
-	Htpasswd       string
-	RegistryDomain string
-	Auth           string
-	Ping           string
-	Error          string
+	Port           string `json:"port"`
+	DebugPort      string `json:"debugPort"`
+	Storage        string `json:"storage"`
+	Delete         bool   `json:"delete"`
+	Htpasswd       string `json:"-"`
+	RegistryDomain string `json:"registryDomain"`
+	Auth           string `json:"-"`
+	Ping           string `json:"ping"`
+	Error          string `json:"error"`
+}
+
+func (n *RegistryChecker) Name() string {
+	return RegistryCheckerName
 }
 
 func (n *RegistryChecker) Check(cluster *v2.Cluster, phase string) error {
+	status, err := n.inspect(cluster, phase)
+	if status != nil {
+		if oErr := n.Output(status); oErr != nil {
+			logger.Error("error output: %+v", oErr)
+		}
+	}
+	return err
+}
+
+func (n *RegistryChecker) Inspect(cluster *v2.Cluster, phase string) (*Result, error) {
+	status, err := n.inspect(cluster, phase)
+	if err != nil || status == nil {
+		return nil, err
+	}
+	return &Result{Name: n.Name(), Healthy: status.Error == Nil, Detail: status}, nil
+}
+
+func (n *RegistryChecker) inspect(cluster *v2.Cluster, phase string) (*RegistryStatus, error) {
 	if phase != PhasePost {
-		return nil
+		return nil, nil
 	}
 	localAddr, _ := iputils.ListLocalHostAddrs()
 	if !iputils.IsLocalIP(cluster.GetRegistryIP(), localAddr) {
 		logger.Info("current registry ip is %s,not local addr,skip check.", cluster.GetRegistryIP())
-		return nil
+		return nil, nil
 	}
 	status := &RegistryStatus{}
-	defer func() {
-		err := n.Output(status)
-		if err != nil {
-			logger.Error("error output: %+v", err)
-		}
-	}()
 
 	registryConfig := "/etc/registry/registry_config.yml"
 	if cfg, err := fileutil.ReadAll(registryConfig); err != nil {
@@ -91,7 +107,7 @@ func (n *RegistryChecker) Check(cluster *v2.Cluster, phase string) error {
 	sshCtx := ssh.NewCacheClientFromCluster(cluster, false)
 	execer, err := exec.New(sshCtx)
 	if err != nil {
-		return err
+		return status, err
 	}
 	root := constants.NewPathResolver(cluster.Name).RootFSPath()
 	regInfo := helpers.GetRegistryInfo(execer, root, cluster.GetRegistryIPAndPort())
@@ -104,11 +120,13 @@ func (n *RegistryChecker) Check(cluster *v2.Cluster, phase string) error {
 	_, err = crane.NewRegistry(status.RegistryDomain, cfg)
 	if err != nil {
 		status.Error = fmt.Errorf("get registry interface error: %w", err).Error()
-		return nil
+		return status, nil
 	}
 	status.Ping = "ok"
-	status.Error = Nil
-	return nil
+	if status.Error == "" {
+		status.Error = Nil
+	}
+	return status, nil
 }
 
 func (n *RegistryChecker) Output(status *RegistryStatus) error {
@@ -134,6 +152,6 @@ func (n *RegistryChecker) Output(status *RegistryStatus) error {
 	return tpl.Execute(os.Stdout, status)
 }
 
-func NewRegistryChecker() Interface {
+func NewRegistryChecker() Inspector {
 	return &RegistryChecker{}
 }
@@ -0,0 +1,132 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checker
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	OutputJSON = "json"
+	OutputYAML = "yaml"
+)
+
+// names of the checkers, each of them has its own bit in the exit code of a failed report.
+const (
+	RegistryCheckerName   = "registry"
+	CRIShimCheckerName    = "cri-shim"
+	CRICtlCheckerName     = "crictl"
+	InitSystemCheckerName = "initsystem"
+	NodeCheckerName       = "node"
+	PodCheckerName        = "pod"
+	SvcCheckerName        = "service"
+	ClusterCheckerName    = "cluster"
+)
+
+// exit codes are limited to 8 bits, which are all taken by the checkers above.
+var exitCodes = map[string]int{
+	RegistryCheckerName:   1 << 0,
+	CRIShimCheckerName:    1 << 1,
+	CRICtlCheckerName:     1 << 2,
+	InitSystemCheckerName: 1 << 3,
+	NodeCheckerName:       1 << 4,
+	PodCheckerName:        1 << 5,
+	SvcCheckerName:        1 << 6,
+	ClusterCheckerName:    1 << 7,
+}
+
+// unknownExitCode is used when a checker without its own bit failed, the failed
+// category can only be told from the report then.
+const unknownExitCode = 1<<8 - 1
+
+// Report aggregates the results of all checkers.
+type Report struct {
+	Phase   string   `json:"phase"`
+	Healthy bool     `json:"healthy"`
+	Results []Result `json:"results"`
+}
+
+// Failed returns the names of the failed checkers.
+func (r *Report) Failed() []string {
+	var ret []string
+	for _, result := range r.Results {
+		if !result.Healthy {
+			ret = append(ret, result.Name)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Print writes the report in json or yaml format.
+func (r *Report) Print(w io.Writer, format string) error {
+	var (
+		data []byte
+		err  error
+	)
+	switch format {
+	case OutputJSON:
+		data, err = json.MarshalIndent(r, "", "  ")
+		data = append(data, '\n')
+	case OutputYAML:
+		data, err = yaml.Marshal(r)
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Err returns a *ReportError if any of the checkers failed.
+func (r *Report) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	code := 0
+	for _, name := range failed {
+		c, ok := exitCodes[name]
+		if !ok {
+			code = unknownExitCode
+			break
+		}
+		code |= c
+	}
+	return &ReportError{failed: failed, code: code}
+}
+
+// ReportError is returned when some checkers failed, the exit code is a bitmask of
+// the failed categories.
+type ReportError struct {
+	failed []string
+	code   int
+}
+
+func (e *ReportError) Error() string {
+	return fmt.Sprintf("checkers failed: %s", strings.Join(e.failed, ", "))
+}
+
+func (e *ReportError) ExitCode() int {
+	return e.code
+}
@@ -0,0 +1,110 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checker
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+type fakeInspector struct {
+	name   string
+	result *Result
+	err    error
+}
+
+func (f *fakeInspector) Name() string { return f.name }
+
+func (f *fakeInspector) Check(_ *v2.Cluster, _ string) error { return f.err }
+
+func (f *fakeInspector) Inspect(_ *v2.Cluster, _ string) (*Result, error) {
+	return f.result, f.err
+}
+
+func TestRunInspectList(t *testing.T) {
+	list := []Inspector{
+		&fakeInspector{name: RegistryCheckerName},
+		&fakeInspector{name: NodeCheckerName, result: &Result{Name: NodeCheckerName, Healthy: true}},
+		&fakeInspector{name: PodCheckerName, err: errors.New("connection refused")},
+		&fakeInspector{name: SvcCheckerName, result: &Result{Name: SvcCheckerName}},
+	}
+	report := RunInspectList(list, &v2.Cluster{}, PhasePost)
+	if report.Healthy {
+		t.Error("expected unhealthy report")
+	}
+	if len(report.Results) != 3 {
+		t.Fatalf("expected 3 results, skipped checker should not be reported, got %d", len(report.Results))
+	}
+	var reportErr *ReportError
+	if err := report.Err(); !errors.As(err, &reportErr) {
+		t.Fatalf("expected ReportError, got %v", err)
+	}
+	if want := exitCodes[PodCheckerName] | exitCodes[SvcCheckerName]; reportErr.ExitCode() != want {
+		t.Errorf("ExitCode() = %d, want %d", reportErr.ExitCode(), want)
+	}
+	for _, format := range []string{OutputJSON, OutputYAML} {
+		if err := report.Print(&bytes.Buffer{}, format); err != nil {
+			t.Errorf("Print(%s) error: %v", format, err)
+		}
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	names := []string{
+		RegistryCheckerName, CRIShimCheckerName, CRICtlCheckerName, InitSystemCheckerName,
+		NodeCheckerName, PodCheckerName, SvcCheckerName, ClusterCheckerName,
+	}
+	var seen int
+	for _, name := range names {
+		code, ok := exitCodes[name]
+		if !ok {
+			t.Errorf("checker %s has no exit code", name)
+			continue
+		}
+		if code <= 0 || code > 1<<7 || code&(code-1) != 0 {
+			t.Errorf("exit code %d of checker %s is not a single bit of the 8-bit exit code", code, name)
+		}
+		if seen&code != 0 {
+			t.Errorf("exit code %d of checker %s is shared with another checker", code, name)
+		}
+		seen |= code
+	}
+	if len(exitCodes) != len(names) {
+		t.Errorf("exit codes of %d checkers, want %d", len(exitCodes), len(names))
+	}
+
+	report := &Report{Results: []Result{{Name: ClusterCheckerName}, {Name: "unknown"}}}
+	var reportErr *ReportError
+	if err := report.Err(); !errors.As(err, &reportErr) || reportErr.ExitCode() != unknownExitCode {
+		t.Errorf("expected exit code %d when an unknown checker failed, got %v", unknownExitCode, err)
+	}
+}
+
+func TestPrintRedactsRegistryCredentials(t *testing.T) {
+	status := &RegistryStatus{Port: ":5000", Htpasswd: "admin:$2y$05$hash", Auth: "admin:passw0rd"}
+	report := &Report{Results: []Result{{Name: RegistryCheckerName, Healthy: true, Detail: status}}}
+	for _, format := range []string{OutputJSON, OutputYAML} {
+		var buf bytes.Buffer
+		if err := report.Print(&buf, format); err != nil {
+			t.Fatalf("Print(%s) error: %v", format, err)
+		}
+		if bytes.Contains(buf.Bytes(), []byte("admin")) {
+			t.Errorf("Print(%s) leaks the registry credentials: %s", format, buf.String())
+		}
+	}
+}
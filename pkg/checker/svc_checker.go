@@ -36,25 +36,51 @@ type SvcChecker struct {
 }
 
 type SvcNamespaceStatus struct {
-	NamespaceName       string
-	ServiceCount        int
-	EndpointCount       int
-	UnhealthServiceList []string
+	NamespaceName       string   `json:"namespace"`
+	ServiceCount        int      `json:"serviceCount"`
+	EndpointCount       int      `json:"endpointCount"`
+	UnhealthServiceList []string `json:"unhealthServiceList,omitempty"`
 }
 
 type SvcClusterStatus struct {
 	SvcNamespaceStatusList []*SvcNamespaceStatus
 }
 
+func (n *SvcChecker) Name() string {
+	return SvcCheckerName
+}
+
 func (n *SvcChecker) Check(cluster *v2.Cluster, phase string) error {
+	status, err := n.inspect(cluster, phase)
+	if err != nil || status == nil {
+		return err
+	}
+	return n.Output(status)
+}
+
+func (n *SvcChecker) Inspect(cluster *v2.Cluster, phase string) (*Result, error) {
+	status, err := n.inspect(cluster, phase)
+	if err != nil || status == nil {
+		return nil, err
+	}
+	healthy := true
+	for _, ns := range status {
+		if len(ns.UnhealthServiceList) > 0 {
+			healthy = false
+		}
+	}
+	return &Result{Name: n.Name(), Healthy: healthy, Detail: status}, nil
+}
+
+func (n *SvcChecker) inspect(cluster *v2.Cluster, phase string) ([]*SvcNamespaceStatus, error) {
 	if phase != PhasePost {
-		return nil
+		return nil, nil
 	}
 	// checker if all the node is ready
 	data := constants.NewPathResolver(cluster.Name)
 	c, err := kubernetes.NewKubernetesClient(data.AdminFile(), "")
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	n.client = c
@@ -63,13 +89,10 @@ func (n *SvcChecker) Check(cluster *v2.Cluster, phase string) error {
 
 	nsList, err := n.client.Kubernetes().CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	var svcNamespaceStatusList []*SvcNamespaceStatus
-	if err != nil {
-		return err
-	}
+	svcNamespaceStatusList := make([]*SvcNamespaceStatus, 0, len(nsList.Items))
 	for _, svcNamespace := range nsList.Items {
 		namespaceSVCList, err := n.client.Kubernetes().CoreV1().Services(svcNamespace.Name).List(context.TODO(), metav1.ListOptions{})
 		if err != nil {
@@ -100,7 +123,7 @@ func (n *SvcChecker) Check(cluster *v2.Cluster, phase string) error {
 		}
 		svcNamespaceStatusList = append(svcNamespaceStatusList, &svcNamespaceStatus)
 	}
-	return n.Output(svcNamespaceStatusList)
+	return svcNamespaceStatusList, nil
 }
 
 func (n *SvcChecker) Output(svcNamespaceStatusList []*SvcNamespaceStatus) error {
@@ -135,6 +158,6 @@ func IsExistEndpoint(endpointList *corev1.EndpointsList, serviceName string) boo
 	return false
 }
 
-func NewSvcChecker() Interface {
+func NewSvcChecker() Inspector {
 	return &SvcChecker{}
 }
@@ -0,0 +1,203 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+
+	"github.com/labring/sealos/pkg/constants"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/file"
+	"github.com/labring/sealos/pkg/utils/logger"
+	"github.com/labring/sealos/pkg/utils/yaml"
+	"github.com/labring/sealos/pkg/version"
+)
+
+const (
+	historyDirName     = "history"
+	revisionFilePrefix = "revision-"
+	revisionFileExt    = ".yaml"
+	// DefaultHistoryLimit is the number of the latest revisions to keep
+	DefaultHistoryLimit = 20
+)
+
+// Revision is a snapshot of the Clusterfile saved after a successful apply.
+type Revision struct {
+	Revision      int       `json:"revision"`
+	CreatedAt     time.Time `json:"createdAt"`
+	SealosVersion string    `json:"sealosVersion"`
+	Images        []string  `json:"images"`
+	Masters       []string  `json:"masters,omitempty"`
+	Nodes         []string  `json:"nodes,omitempty"`
+	// Clusterfile contains the cluster without status, the runtime config and the configs.
+	Clusterfile string `json:"clusterfile"`
+}
+
+// Cluster decodes the cluster saved in the revision.
+func (r *Revision) Cluster() (*v2.Cluster, error) {
+	return GetClusterFromDataCompatV1([]byte(r.Clusterfile))
+}
+
+// History manages the revisions of a cluster in the cluster workdir.
+type History struct {
+	dir   string
+	limit int
+}
+
+func NewHistory(clusterName string) *History {
+	return &History{
+		dir:   filepath.Join(constants.ClusterDir(clusterName), historyDirName),
+		limit: DefaultHistoryLimit,
+	}
+}
+
+func (h *History) path(revision int) string {
+	return filepath.Join(h.dir, fmt.Sprintf("%s%d%s", revisionFilePrefix, revision, revisionFileExt))
+}
+
+// List returns the revisions ordered by revision number, the oldest first.
+func (h *History) List() ([]Revision, error) {
+	entries, err := os.ReadDir(h.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var ret []Revision
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, revisionFilePrefix) || !strings.HasSuffix(name, revisionFileExt) {
+			continue
+		}
+		if _, err = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, revisionFilePrefix), revisionFileExt)); err != nil {
+			continue
+		}
+		var r Revision
+		if err = yaml.UnmarshalFile(filepath.Join(h.dir, name), &r); err != nil {
+			return nil, fmt.Errorf("failed to load revision %s: %v", name, err)
+		}
+		ret = append(ret, r)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Revision < ret[j].Revision
+	})
+	return ret, nil
+}
+
+// Get returns the revision, the latest one if revision is 0.
+func (h *History) Get(revision int) (*Revision, error) {
+	if revision == 0 {
+		latest, err := h.Latest()
+		if err == nil && latest == nil {
+			err = fmt.Errorf("no revision found in %s", h.dir)
+		}
+		return latest, err
+	}
+	path := h.path(revision)
+	if !file.IsExist(path) {
+		return nil, fmt.Errorf("revision %d not found", revision)
+	}
+	r := &Revision{}
+	if err := yaml.UnmarshalFile(path, r); err != nil {
+		return nil, fmt.Errorf("failed to load revision %d: %v", revision, err)
+	}
+	return r, nil
+}
+
+// Latest returns the latest revision, nil if there is no revision.
+func (h *History) Latest() (*Revision, error) {
+	list, err := h.List()
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return &list[len(list)-1], nil
+}
+
+// Record saves the cluster and the other objects of the Clusterfile as a new revision. No revision
+// is created if nothing is changed since the latest one.
+func (h *History) Record(cluster *v2.Cluster, objects ...interface{}) (*Revision, error) {
+	c := cluster.DeepCopy()
+	c.Status = v2.ClusterStatus{}
+	data, err := yaml.MarshalConfigs(append([]interface{}{c}, objects...)...)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := h.Latest()
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil && latest.Clusterfile == string(data) {
+		logger.Debug("cluster is not changed since revision %d, skip recording", latest.Revision)
+		return latest, nil
+	}
+	r := &Revision{
+		Revision:      1,
+		CreatedAt:     time.Now(),
+		SealosVersion: version.Get().GitVersion,
+		Images:        c.Spec.Image,
+		Masters:       c.GetMasterIPAndPortList(),
+		Nodes:         c.GetNodeIPAndPortList(),
+		Clusterfile:   string(data),
+	}
+	if latest != nil {
+		r.Revision = latest.Revision + 1
+	}
+	out, err := yaml.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(h.dir, 0755); err != nil {
+		return nil, err
+	}
+	if err = file.AtomicWriteFile(h.path(r.Revision), out, 0644); err != nil {
+		return nil, err
+	}
+	return r, h.prune()
+}
+
+// prune removes the oldest revisions beyond the limit.
+func (h *History) prune() error {
+	list, err := h.List()
+	if err != nil || h.limit <= 0 || len(list) <= h.limit {
+		return err
+	}
+	for _, r := range list[:len(list)-h.limit] {
+		logger.Debug("removing revision %d", r.Revision)
+		if err = os.Remove(h.path(r.Revision)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// DiffRevisions returns the unified diff of the Clusterfiles of two revisions.
+func DiffRevisions(from, to *Revision) (string, error) {
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(from.Clusterfile),
+		B:        difflib.SplitLines(to.Clusterfile),
+		FromFile: fmt.Sprintf("revision %d", from.Revision),
+		ToFile:   fmt.Sprintf("revision %d", to.Revision),
+		Context:  3,
+	})
+}
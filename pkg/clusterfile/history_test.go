@@ -0,0 +1,77 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterfile
+
+import (
+	"strings"
+	"testing"
+
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+func TestHistory(t *testing.T) {
+	h := &History{dir: t.TempDir(), limit: 2}
+	if r, err := h.Latest(); err != nil || r != nil {
+		t.Fatalf("expected empty history, got %v %v", r, err)
+	}
+	cluster := &v2.Cluster{}
+	cluster.Name = "default"
+	cluster.Kind = "Cluster"
+	cluster.APIVersion = v2.SchemeGroupVersion.String()
+	cluster.Spec.Image = []string{"labring/kubernetes:v1.25.0"}
+	cluster.Spec.Hosts = []v2.Host{{IPS: []string{"192.168.0.2:22"}, Roles: []string{v2.MASTER}}}
+	cluster.Status.Phase = v2.ClusterSuccess
+
+	r1, err := h.Record(cluster)
+	if err != nil || r1.Revision != 1 {
+		t.Fatalf("unexpected revision %v %v", r1, err)
+	}
+	if strings.Contains(r1.Clusterfile, "phase:") {
+		t.Errorf("status should not be saved in revision:\n%s", r1.Clusterfile)
+	}
+	// nothing changed
+	if r, _ := h.Record(cluster); r.Revision != 1 {
+		t.Errorf("expected revision 1, got %d", r.Revision)
+	}
+
+	cluster.Spec.Hosts = append(cluster.Spec.Hosts, v2.Host{IPS: []string{"192.168.0.3:22"}, Roles: []string{v2.NODE}})
+	r2, err := h.Record(cluster)
+	if err != nil || r2.Revision != 2 || len(r2.Nodes) != 1 {
+		t.Fatalf("unexpected revision %v %v", r2, err)
+	}
+	diff, err := DiffRevisions(r1, r2)
+	if err != nil || !strings.Contains(diff, "+") || !strings.Contains(diff, "192.168.0.3:22") {
+		t.Errorf("unexpected diff %q %v", diff, err)
+	}
+	c, err := r2.Cluster()
+	if err != nil || len(c.GetNodeIPAndPortList()) != 1 {
+		t.Errorf("unexpected cluster %v %v", c, err)
+	}
+
+	cluster.Spec.Image = append(cluster.Spec.Image, "labring/helm:v3.8.2")
+	if _, err = h.Record(cluster); err != nil {
+		t.Fatal(err)
+	}
+	list, err := h.List()
+	if err != nil || len(list) != 2 || list[0].Revision != 2 || list[1].Revision != 3 {
+		t.Fatalf("unexpected revisions after pruning %v %v", list, err)
+	}
+	if _, err = h.Get(1); err == nil {
+		t.Error("expected revision 1 to be pruned")
+	}
+	if latest, _ := h.Get(0); latest.Revision != 3 || len(latest.Images) != 2 {
+		t.Errorf("unexpected latest revision %v", latest)
+	}
+}
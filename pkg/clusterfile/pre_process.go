@@ -22,9 +22,7 @@ import (
 	"helm.sh/helm/v3/pkg/getter"
 
 	"github.com/labring/sealos/pkg/constants"
-	"github.com/labring/sealos/pkg/runtime/decode"
-	"github.com/labring/sealos/pkg/runtime/k3s"
-	"github.com/labring/sealos/pkg/runtime/kubernetes/types"
+	"github.com/labring/sealos/pkg/runtime/factory"
 	"github.com/labring/sealos/pkg/template"
 	v2 "github.com/labring/sealos/pkg/types/v1beta1"
 	fileutil "github.com/labring/sealos/pkg/utils/file"
@@ -148,19 +146,17 @@ func (c *ClusterFile) DecodeConfigs(data []byte) error {
 }
 
 func (c *ClusterFile) DecodeRuntimeConfig(data []byte) error {
-	// TODO: handling more types of runtime configuration
-	cfg, _ := k3s.ParseConfig(data)
-	if cfg != nil {
-		c.runtimeConfig = cfg
-	} else {
-		kubeadmConfig, err := types.LoadKubeadmConfigs(string(data), false, decode.CRDFromString)
-		if err != nil {
-			return err
-		}
-		if kubeadmConfig == nil {
-			return ErrTypeNotFound
-		}
-		c.runtimeConfig = kubeadmConfig
+	var distribution string
+	if c.cluster != nil {
+		distribution = c.cluster.GetDistribution()
+	}
+	cfg, err := factory.ParseRuntimeConfig(distribution, data)
+	if err != nil {
+		return err
+	}
+	if cfg == nil {
+		return ErrTypeNotFound
 	}
+	c.runtimeConfig = cfg
 	return nil
 }
@@ -17,6 +17,7 @@ package config
 import (
 	"bytes"
 	"os"
+	"path/filepath"
 	"testing"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -148,7 +149,7 @@ func Test_getMergeConfig(t *testing.T) {
 				t.Error(err)
 				return
 			}
-			err = os.WriteFile("test_"+tt.args.path, got, 0644)
+			err = os.WriteFile(filepath.Join(t.TempDir(), "test_"+tt.args.path), got, 0644)
 			if err != nil {
 				t.Error(err)
 			}
@@ -218,7 +219,7 @@ func Test_getOverrideConfig(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			got := tt.args.data
 
-			output := "test_" + tt.args.path
+			output := filepath.Join(t.TempDir(), "test_"+tt.args.path)
 			err := os.WriteFile(output, got, 0644)
 			if err != nil {
 				t.Error(err)
@@ -0,0 +1,63 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"go.etcd.io/etcd/etcdutl/v3/snapshot"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultClientPort   = 2379
+	DefaultPeerPort     = 2380
+	DefaultClusterToken = "etcd-cluster"
+)
+
+// Member is an etcd member to restore a snapshot for.
+type Member struct {
+	Name    string
+	PeerURL string
+}
+
+// InitialCluster returns the value of --initial-cluster for members.
+func InitialCluster(members []Member) string {
+	ret := make([]string, 0, len(members))
+	for _, m := range members {
+		ret = append(ret, fmt.Sprintf("%s=%s", m.Name, m.PeerURL))
+	}
+	return strings.Join(ret, ",")
+}
+
+// RestoreSnapshot restores a snapshot file into dataDir of member, dataDir must not exist.
+func RestoreSnapshot(snapshotPath, dataDir string, member Member, members []Member) error {
+	return snapshot.NewV3(zap.NewNop()).Restore(snapshot.RestoreConfig{
+		SnapshotPath:        snapshotPath,
+		Name:                member.Name,
+		OutputDataDir:       dataDir,
+		OutputWALDir:        filepath.Join(dataDir, "member", "wal"),
+		PeerURLs:            []string{member.PeerURL},
+		InitialCluster:      InitialCluster(members),
+		InitialClusterToken: DefaultClusterToken,
+	})
+}
+
+// SnapshotStatus returns the revision and total keys of a snapshot file.
+func SnapshotStatus(snapshotPath string) (snapshot.Status, error) {
+	return snapshot.NewV3(zap.NewNop()).Status(snapshotPath)
+}
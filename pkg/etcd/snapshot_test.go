@@ -0,0 +1,149 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	clientsnapshot "go.etcd.io/etcd/client/v3/snapshot"
+	"go.etcd.io/etcd/server/v3/embed"
+	"go.uber.org/zap"
+
+	"github.com/labring/sealos/pkg/constants"
+)
+
+func freeURL(t *testing.T) url.URL {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return url.URL{Scheme: "http", Host: l.Addr().String()}
+}
+
+// startEtcd starts an embedded etcd member, the peer url must be the same as the restored one.
+func startEtcd(t *testing.T, dataDir, name string, peerURL url.URL) (*embed.Etcd, string) {
+	cfg := embed.NewConfig()
+	cfg.Dir = dataDir
+	cfg.Name = name
+	cfg.LogLevel = "error"
+	clientURL := freeURL(t)
+	cfg.LCUrls, cfg.ACUrls = []url.URL{clientURL}, []url.URL{clientURL}
+	cfg.LPUrls, cfg.APUrls = []url.URL{peerURL}, []url.URL{peerURL}
+	cfg.InitialCluster = fmt.Sprintf("%s=%s", name, peerURL.String())
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-e.Server.ReadyNotify():
+	case <-time.After(30 * time.Second):
+		e.Close()
+		t.Fatal("etcd took too long to start")
+	}
+	return e, clientURL.String()
+}
+
+func newClient(t *testing.T, endpoint string) *clientv3.Client {
+	cli, err := clientv3.New(clientv3.Config{Endpoints: []string{endpoint}, DialTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cli
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	dir := t.TempDir()
+	peerURL := freeURL(t)
+	e, endpoint := startEtcd(t, filepath.Join(dir, "origin"), "master0", peerURL)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	cli := newClient(t, endpoint)
+	if _, err := cli.Put(ctx, "/registry/foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	cli.Close()
+
+	snapshotPath := filepath.Join(dir, "snapshot.db")
+	if err := clientsnapshot.Save(ctx, zap.NewNop(), clientv3.Config{Endpoints: []string{endpoint}}, snapshotPath); err != nil {
+		t.Fatal(err)
+	}
+	e.Close()
+	status, err := SnapshotStatus(snapshotPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.TotalKey == 0 {
+		t.Errorf("expected keys in snapshot, got %+v", status)
+	}
+
+	member := Member{Name: "master0", PeerURL: peerURL.String()}
+	restored := filepath.Join(dir, "restored")
+	if err = RestoreSnapshot(snapshotPath, restored, member, []Member{member}); err != nil {
+		t.Fatal(err)
+	}
+	e, endpoint = startEtcd(t, restored, member.Name, peerURL)
+	defer e.Close()
+	cli = newClient(t, endpoint)
+	defer cli.Close()
+	resp, err := cli.Get(ctx, "/registry/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Kvs) != 1 || string(resp.Kvs[0].Value) != "bar" {
+		t.Errorf("unexpected restored value %v", resp.Kvs)
+	}
+}
+
+func TestStorePrune(t *testing.T) {
+	constants.DefaultRuntimeRootDir = t.TempDir()
+	store := NewStore("default")
+	if err := os.MkdirAll(store.Dir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for i := 0; i < 4; i++ {
+		path := store.NewPath(fmt.Sprintf("snap-%d", i))
+		if err := os.WriteFile(path, []byte("data"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		mtime := now.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Prune(2); err != nil {
+		t.Fatal(err)
+	}
+	snapshots, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 || snapshots[0].Name != "snap-3" || snapshots[1].Name != "snap-2" {
+		t.Errorf("unexpected snapshots after prune: %+v", snapshots)
+	}
+	if _, err = store.Get("snap-0"); err == nil {
+		t.Error("expected pruned snapshot to be removed")
+	}
+}
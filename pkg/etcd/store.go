@@ -0,0 +1,139 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+const (
+	snapshotDirName   = "etcd-snapshots"
+	snapshotExt       = ".db"
+	snapshotTimeFmt   = "20060102-150405"
+	DefaultRetainSize = 5
+)
+
+// Snapshot is a snapshot file stored in the cluster workdir.
+type Snapshot struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Store manages the snapshot files of a cluster.
+type Store struct {
+	dir string
+}
+
+func NewStore(clusterName string) *Store {
+	return &Store{dir: filepath.Join(constants.ClusterDir(clusterName), snapshotDirName)}
+}
+
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// NewPath returns the path of a new snapshot, the name is generated from the current time if empty.
+func (s *Store) NewPath(name string) string {
+	if name == "" {
+		name = fmt.Sprintf("snapshot-%s", time.Now().Format(snapshotTimeFmt))
+	}
+	if !strings.HasSuffix(name, snapshotExt) {
+		name += snapshotExt
+	}
+	return filepath.Join(s.dir, name)
+}
+
+// List returns the snapshots ordered by creation time, the newest first.
+func (s *Store) List() ([]Snapshot, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var ret []Snapshot
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), snapshotExt) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, Snapshot{
+			Name:      strings.TrimSuffix(entry.Name(), snapshotExt),
+			Path:      filepath.Join(s.dir, entry.Name()),
+			Size:      info.Size(),
+			CreatedAt: info.ModTime(),
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].CreatedAt.After(ret[j].CreatedAt)
+	})
+	return ret, nil
+}
+
+// Get finds a snapshot by name, or by path if it's not stored in the workdir.
+func (s *Store) Get(name string) (*Snapshot, error) {
+	path := name
+	if !strings.ContainsRune(name, os.PathSeparator) {
+		path = s.NewPath(name)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("snapshot %s not found", name)
+		}
+		return nil, err
+	}
+	return &Snapshot{
+		Name:      strings.TrimSuffix(filepath.Base(path), snapshotExt),
+		Path:      path,
+		Size:      info.Size(),
+		CreatedAt: info.ModTime(),
+	}, nil
+}
+
+// Prune removes the oldest snapshots and keeps at most retain snapshots, nothing is removed if retain <= 0.
+func (s *Store) Prune(retain int) error {
+	if retain <= 0 {
+		return nil
+	}
+	snapshots, err := s.List()
+	if err != nil {
+		return err
+	}
+	if len(snapshots) <= retain {
+		return nil
+	}
+	for _, snap := range snapshots[retain:] {
+		logger.Info("removing expired etcd snapshot %s", snap.Name)
+		if err = os.Remove(snap.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -17,6 +17,7 @@ package exec
 import (
 	"context"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"os/exec"
@@ -106,6 +107,34 @@ func (w *wrap) Copy(host string, src string, dest string) error {
 	return w.inner.Copy(host, src, dest)
 }
 
+func (w *wrap) CopyFrom(host string, r io.Reader, dest string, mode os.FileMode) error {
+	if w.isLocal(host) {
+		warnIfNotAbs(dest)
+		logger.Debug("copy stream to dst %s on %s locally", dest, host)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		destTmp := dest + ".tmp"
+		if err := func() error {
+			f, err := os.OpenFile(destTmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(f, r)
+			return err
+		}(); err != nil {
+			return err
+		}
+		return os.Rename(destTmp, dest)
+	}
+	copier, ok := w.inner.(ssh.StreamCopier)
+	if !ok {
+		return fmt.Errorf("copying a stream to %s is not supported", host)
+	}
+	return copier.CopyFrom(host, r, dest, mode)
+}
+
 func (w *wrap) Fetch(host string, src string, dest string) error {
 	if w.isLocal(host) {
 		warnIfNotAbs(src)
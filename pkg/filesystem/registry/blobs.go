@@ -0,0 +1,123 @@
+/*
+Copyright 2023 fengxsong@outlook.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/labring/sealos/pkg/exec"
+)
+
+const (
+	// blobs are stored by digest under this dir of the registry filesystem storage,
+	// so that the same path always has the same content.
+	blobsDir        = "docker/registry/v2/blobs"
+	repositoriesDir = "docker/registry/v2/repositories"
+	blobDataName    = "data"
+)
+
+// blobs maps the path of blob data relative to the blobs dir to its size.
+type blobs map[string]int64
+
+func localBlobs(registryDir string) (blobs, error) {
+	root := filepath.Join(registryDir, blobsDir)
+	ret := make(blobs)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root && errors.Is(err, fs.ErrNotExist) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !d.Type().IsRegular() || d.Name() != blobDataName {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		ret[filepath.ToSlash(rel)] = info.Size()
+		return nil
+	})
+	return ret, err
+}
+
+func getListBlobsCommand(registryDir string) string {
+	root := filepath.Join(registryDir, blobsDir)
+	return fmt.Sprintf("if [ -d %[1]s ]; then find %[1]s -type f -name %[2]s -exec stat -c '%%s %%n' {} +; fi", root, blobDataName)
+}
+
+// remoteBlobs lists the blobs of the registry dir on host.
+func remoteBlobs(execer exec.Interface, host string, registryDir string) (blobs, error) {
+	out, err := execer.Cmd(host, getListBlobsCommand(registryDir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list blobs of registry on %s: %v, %s", host, err, out)
+	}
+	return parseBlobs(string(out), filepath.Join(registryDir, blobsDir)), nil
+}
+
+// parseBlobs parses the lines of `stat -c '%s %n'`, the lines that can not be parsed are ignored.
+func parseBlobs(out string, root string) blobs {
+	ret := make(blobs)
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.SplitN(strings.TrimSpace(scanner.Text()), " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(root, fields[1])
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		ret[filepath.ToSlash(rel)] = size
+	}
+	return ret
+}
+
+// missing returns the blobs which are not in target, a blob of different size is
+// incompletely copied and it's missing too.
+func (b blobs) missing(target blobs) blobs {
+	ret := make(blobs)
+	for path, size := range b {
+		if s, ok := target[path]; !ok || s != size {
+			ret[path] = size
+		}
+	}
+	return ret
+}
+
+func (b blobs) size() int64 {
+	var total int64
+	for _, size := range b {
+		total += size
+	}
+	return total
+}
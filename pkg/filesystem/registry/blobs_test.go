@@ -0,0 +1,77 @@
+/*
+Copyright 2023 fengxsong@outlook.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMissingBlobs(t *testing.T) {
+	registryDir := t.TempDir()
+	for _, blob := range []struct {
+		digest string
+		data   string
+	}{
+		{"aa0001", "layer"},
+		{"bb0002", "config"},
+		{"cc0003", "manifest"},
+	} {
+		dir := filepath.Join(registryDir, blobsDir, "sha256", blob.digest[:2], blob.digest)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, blobDataName), []byte(blob.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	local, err := localBlobs(registryDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(local) != 3 || local.size() != int64(len("layer")+len("config")+len("manifest")) {
+		t.Fatalf("unexpected local blobs %v", local)
+	}
+	if empty, err := localBlobs(t.TempDir()); err != nil || len(empty) != 0 {
+		t.Errorf("expected no blobs, got %v %v", empty, err)
+	}
+
+	root := filepath.Join("/var/lib/sealos/data/default/rootfs/registry", blobsDir)
+	out := strings.Join([]string{
+		fmt.Sprintf("5 %s/sha256/aa/aa0001/data\r", root),
+		// incompletely copied
+		fmt.Sprintf("2 %s/sha256/bb/bb0002/data", root),
+		fmt.Sprintf("7 %s/sha256/dd/dd0004/data", root),
+		"stat: cannot stat 'foo': No such file or directory",
+		"",
+	}, "\n")
+	remote := parseBlobs(out, root)
+	if len(remote) != 3 || remote["sha256/aa/aa0001/data"] != 5 {
+		t.Fatalf("unexpected remote blobs %v", remote)
+	}
+
+	missing := local.missing(remote)
+	if len(missing) != 2 || missing.size() != int64(len("config")+len("manifest")) {
+		t.Errorf("unexpected missing blobs %v", missing)
+	}
+	if _, ok := missing["sha256/aa/aa0001/data"]; ok {
+		t.Errorf("existing blob should not be missing")
+	}
+}
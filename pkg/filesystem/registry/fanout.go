@@ -0,0 +1,147 @@
+/*
+Copyright 2023 fengxsong@outlook.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/utils/file"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+// syncNode is a host in the fan-out tree, it's synced from its parent and then serves its children.
+type syncNode struct {
+	opt      *syncOption
+	children []*syncNode
+}
+
+// fanOutTree returns the hosts synced from this machine, the hosts in http mode are organized as a
+// tree in which each host serves at most fanOut hosts in order. The hosts in ssh mode can't serve
+// the others since there's no registry running on them, so they are always synced from this machine.
+func fanOutTree(opts []*syncOption, fanOut int) []*syncNode {
+	var (
+		roots []*syncNode
+		nodes []*syncNode
+	)
+	for _, opt := range opts {
+		if opt.typ != httpMode || fanOut <= 0 {
+			roots = append(roots, &syncNode{opt: opt})
+			continue
+		}
+		node := &syncNode{opt: opt}
+		if i := len(nodes); i < fanOut {
+			roots = append(roots, node)
+		} else {
+			parent := nodes[i/fanOut-1]
+			parent.children = append(parent.children, node)
+		}
+		nodes = append(nodes, node)
+	}
+	return roots
+}
+
+// syncTree syncs the hosts from the root to the leaves, the children of a failed host are synced from this machine.
+func (s *impl) syncTree(ctx context.Context, roots []*syncNode) (map[*syncOption]*syncStats, error) {
+	var mu sync.Mutex
+	stats := make(map[*syncOption]*syncStats)
+	eg, _ := errgroup.WithContext(ctx)
+	var run func(source *syncOption, node *syncNode)
+	run = func(source *syncOption, node *syncNode) {
+		eg.Go(func() error {
+			st, err := s.syncFrom(ctx, source, node.opt)
+			if st != nil {
+				mu.Lock()
+				stats[node.opt] = st
+				mu.Unlock()
+			}
+			next := node.opt
+			if err != nil {
+				next = nil
+			}
+			for _, child := range node.children {
+				run(next, child)
+			}
+			return err
+		})
+	}
+	for _, root := range roots {
+		run(nil, root)
+	}
+	err := eg.Wait()
+	return stats, err
+}
+
+// syncFromPeer syncs the images in the registry of source to target, which are transferred between them directly.
+func (s *impl) syncFromPeer(ctx context.Context, source, target *syncOption) (*syncStats, error) {
+	stats := &syncStats{host: target.host, typ: target.typ, source: source.host}
+	existing, err := remoteBlobs(s.execer, target.host, s.pathResolver.RootFSRegistryPath())
+	if err != nil {
+		logger.Debug("%v, unable to count the missing blobs", err)
+		existing = make(blobs)
+	}
+	all := make(blobs)
+	for i := range s.mounts {
+		registryDir := filepath.Join(s.mounts[i].MountPoint, constants.RegistryDirName)
+		if !file.IsDir(registryDir) {
+			continue
+		}
+		local, err := localBlobs(registryDir)
+		if err != nil {
+			return nil, err
+		}
+		for path, size := range local {
+			all[path] = size
+		}
+	}
+	missing := all.missing(existing)
+	if err = s.peerSync(ctx, source, target); err != nil {
+		return nil, err
+	}
+	// the images that failed to sync are skipped by `sealctl registry sync`, the manifests and layers
+	// are all blobs, so the target is incomplete if any of them is missing.
+	synced, err := remoteBlobs(s.execer, target.host, s.pathResolver.RootFSRegistryPath())
+	if err != nil {
+		return nil, fmt.Errorf("unable to verify the synced images: %v", err)
+	}
+	if incomplete := all.missing(synced); len(incomplete) > 0 {
+		return nil, fmt.Errorf("%d blobs are still missing after syncing", len(incomplete))
+	}
+	stats.missing = len(missing)
+	stats.transferred = missing.size()
+	stats.skipped = len(all) - len(missing)
+	stats.skippedBytes = all.size() - missing.size()
+	return stats, nil
+}
+
+func getRegistrySyncCommand(pathResolver constants.PathResolver, source, target string) string {
+	return fmt.Sprintf("%s registry sync --all %s %s", pathResolver.RootFSSealctlPath(), source, target)
+}
+
+// syncViaPeer runs `sealctl registry sync` on source to push the images in its temporary registry to target.
+func (s *impl) syncViaPeer(_ context.Context, source, target *syncOption) error {
+	out, err := s.execer.Cmd(source.host, getRegistrySyncCommand(s.pathResolver, source.target, target.target))
+	if err != nil {
+		return fmt.Errorf("%v, %s", err, out)
+	}
+	return nil
+}
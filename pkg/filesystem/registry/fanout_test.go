@@ -0,0 +1,286 @@
+/*
+Copyright 2023 fengxsong@outlook.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	stdsync "sync"
+	"testing"
+	"time"
+
+	"github.com/containers/image/v5/copy"
+	"github.com/containers/image/v5/types"
+	"github.com/labring/sreg/pkg/registry/handler"
+	"github.com/labring/sreg/pkg/registry/sync"
+	"github.com/opencontainers/go-digest"
+
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/exec"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/file"
+	httputils "github.com/labring/sealos/pkg/utils/http"
+)
+
+// fakeExecer lists the blobs in the local registry dirs of the hosts instead of running the commands over ssh.
+type fakeExecer struct {
+	exec.Interface
+	registryDir string
+	dirs        map[string]string
+}
+
+func (f fakeExecer) Cmd(host, _ string) ([]byte, error) {
+	dir, ok := f.dirs[host]
+	if !ok {
+		return nil, fmt.Errorf("no ssh access to %s", host)
+	}
+	local, err := localBlobs(dir)
+	if err != nil {
+		return nil, err
+	}
+	var out strings.Builder
+	for path, size := range local {
+		fmt.Fprintf(&out, "%d %s\n", size, filepath.Join(f.registryDir, blobsDir, path))
+	}
+	return []byte(out.String()), nil
+}
+
+func startRegistry(t *testing.T, dir string) string {
+	t.Helper()
+	config, err := handler.NewConfig(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Log.AccessLog.Disabled = true
+	errCh := handler.Run(context.Background(), config)
+	t.Cleanup(func() { errCh <- nil })
+	addr := sync.ParseRegistryAddress(localhost, config.HTTP.Addr)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err = httputils.WaitUntilEndpointAlive(ctx, "http://"+addr); err != nil {
+		t.Fatal(err)
+	}
+	return addr
+}
+
+func pushBlob(t *testing.T, addr, repo string, data []byte) digest.Digest {
+	t.Helper()
+	resp, err := http.Post(fmt.Sprintf("http://%s/v2/%s/blobs/uploads/", addr, repo), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	location, err := resp.Location()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dgst := digest.FromBytes(data)
+	query := location.Query()
+	query.Set("digest", dgst.String())
+	location.RawQuery = query.Encode()
+	req, _ := http.NewRequest(http.MethodPut, location.String(), bytes.NewReader(data))
+	if resp, err = http.DefaultClient.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("failed to push blob: %s", resp.Status)
+	}
+	return dgst
+}
+
+// pushImage pushes a single layer image to the registry at addr.
+func pushImage(t *testing.T, addr, repo, tag string) {
+	t.Helper()
+	var layer bytes.Buffer
+	tw := tar.NewWriter(&layer)
+	content := []byte("sealos")
+	_ = tw.WriteHeader(&tar.Header{Name: "sealos", Mode: 0644, Size: int64(len(content))})
+	_, _ = tw.Write(content)
+	_ = tw.Close()
+	var compressed bytes.Buffer
+	gw := gzip.NewWriter(&compressed)
+	_, _ = gw.Write(layer.Bytes())
+	_ = gw.Close()
+	config, _ := json.Marshal(map[string]interface{}{
+		"architecture": "amd64",
+		"os":           "linux",
+		"rootfs":       map[string]interface{}{"type": "layers", "diff_ids": []string{digest.FromBytes(layer.Bytes()).String()}},
+	})
+	manifest, _ := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.docker.distribution.manifest.v2+json",
+		"config": map[string]interface{}{
+			"mediaType": "application/vnd.docker.container.image.v1+json",
+			"size":      len(config),
+			"digest":    pushBlob(t, addr, repo, config),
+		},
+		"layers": []interface{}{map[string]interface{}{
+			"mediaType": "application/vnd.docker.image.rootfs.diff.tar.gzip",
+			"size":      compressed.Len(),
+			"digest":    pushBlob(t, addr, repo, compressed.Bytes()),
+		}},
+	})
+	req, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("http://%s/v2/%s/manifests/%s", addr, repo, url.PathEscape(tag)), bytes.NewReader(manifest))
+	req.Header.Set("Content-Type", "application/vnd.docker.distribution.manifest.v2+json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("failed to push manifest: %s", resp.Status)
+	}
+}
+
+func TestFanOutTree(t *testing.T) {
+	var opts []*syncOption
+	for i := 0; i < 7; i++ {
+		typ := httpMode
+		if i == 2 {
+			typ = sshMode
+		}
+		opts = append(opts, &syncOption{host: fmt.Sprintf("host-%d", i), typ: typ})
+	}
+	parents := make(map[string]string)
+	var walk func(parent string, nodes []*syncNode)
+	walk = func(parent string, nodes []*syncNode) {
+		for _, node := range nodes {
+			parents[node.opt.host] = parent
+			walk(node.opt.host, node.children)
+		}
+	}
+	walk("", fanOutTree(opts, 2))
+	expected := map[string]string{
+		"host-0": "", "host-1": "", "host-2": "",
+		"host-3": "host-0", "host-4": "host-0", "host-5": "host-1", "host-6": "host-1",
+	}
+	for host, parent := range expected {
+		if p, ok := parents[host]; !ok || p != parent {
+			t.Errorf("expected parent of %s is %q, got %q", host, parent, p)
+		}
+	}
+	if roots := fanOutTree(opts, 0); len(roots) != len(opts) {
+		t.Errorf("expected all hosts are synced from origin without fan-out, got %d", len(roots))
+	}
+}
+
+func TestTemporaryRegistryAddress(t *testing.T) {
+	tests := map[string]string{
+		"192.168.0.2":      "192.168.0.2:5050",
+		"192.168.0.2:22":   "192.168.0.2:5050",
+		"[fd00::2]:22":     "[fd00::2]:5050",
+		"fd00::2":          "[fd00::2]:5050",
+		"[fd00::2]":        "[fd00::2]:5050",
+		"node-1.local:222": "node-1.local:5050",
+	}
+	for host, want := range tests {
+		if got := temporaryRegistryAddress(host); got != want {
+			t.Errorf("temporaryRegistryAddress(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestSyncFanOut(t *testing.T) {
+	mount := t.TempDir()
+	pushImage(t, startRegistry(t, filepath.Join(mount, constants.RegistryDirName)), "labring/sealos", "v1")
+	origin, err := localBlobs(filepath.Join(mount, constants.RegistryDirName))
+	if err != nil || len(origin) == 0 {
+		t.Fatalf("unexpected blobs of origin %v %v", origin, err)
+	}
+
+	var (
+		opts []*syncOption
+		dirs = make(map[string]string)
+	)
+	for i := 0; i < 5; i++ {
+		host := fmt.Sprintf("host-%d", i)
+		dirs[host] = t.TempDir()
+		opts = append(opts, &syncOption{host: host, target: startRegistry(t, dirs[host]), typ: httpMode})
+	}
+
+	pathResolver := constants.NewPathResolver("default")
+	s := New(pathResolver, fakeExecer{registryDir: pathResolver.RootFSRegistryPath(), dirs: dirs},
+		[]v2.MountImage{{MountPoint: mount}}, SyncOptions{FanOut: 2}).(*impl)
+	var (
+		mu    stdsync.Mutex
+		peers = make(map[string]string)
+	)
+	s.peerSync = func(ctx context.Context, source, target *syncOption) error {
+		mu.Lock()
+		peers[target.host] = source.host
+		mu.Unlock()
+		switch target.host {
+		case "host-3":
+			return fmt.Errorf("%s is unreachable from %s", target.host, source.host)
+		case "host-4":
+			// the images failed to sync are omitted
+			return nil
+		}
+		return sync.ToRegistry(ctx, &sync.Options{
+			SystemContext:    &types.SystemContext{DockerInsecureSkipTLSVerify: types.OptionalBoolTrue},
+			Source:           source.target,
+			Target:           target.target,
+			SelectionOptions: []copy.ImageListSelection{copy.CopyAllImages, copy.CopySystemImage},
+			OmitError:        true,
+		})
+	}
+	stats, err := s.syncTree(context.Background(), fanOutTree(opts, s.opts.FanOut))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// host-0 and host-1 are synced from origin, host-2 and host-3 from host-0, host-4 from host-1,
+	// host-3 falls back to origin as it's unreachable, and so does host-4 since the images are incomplete.
+	expectedPeers := map[string]string{"host-2": "host-0", "host-3": "host-0", "host-4": "host-1"}
+	if len(peers) != len(expectedPeers) {
+		t.Errorf("unexpected peer syncs %v", peers)
+	}
+	for target, source := range expectedPeers {
+		if peers[target] != source {
+			t.Errorf("expected %s is served by %s, got %q", target, source, peers[target])
+		}
+	}
+	expectedSources := map[string]string{"host-0": "", "host-1": "", "host-2": "host-0", "host-3": "", "host-4": ""}
+	for _, opt := range opts {
+		st, ok := stats[opt]
+		if !ok {
+			t.Fatalf("no stats of %s", opt.host)
+		}
+		if st.source != expectedSources[opt.host] {
+			t.Errorf("expected %s is synced from %q, got %q", opt.host, expectedSources[opt.host], st.source)
+		}
+		synced, err := localBlobs(dirs[opt.host])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if missing := origin.missing(synced); len(missing) != 0 {
+			t.Errorf("blobs %v are missing on %s", missing, opt.host)
+		}
+		if !file.IsDir(filepath.Join(dirs[opt.host], repositoriesDir, "labring/sealos/_manifests/tags/v1")) {
+			t.Errorf("tag v1 is missing on %s", opt.host)
+		}
+	}
+}
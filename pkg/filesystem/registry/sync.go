@@ -19,13 +19,18 @@ package registry
 import (
 	"context"
 	"fmt"
+	"net"
+	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/containers/image/v5/copy"
 	"github.com/containers/image/v5/types"
+	"github.com/docker/go-units"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
 
 	"github.com/labring/sreg/pkg/registry/handler"
 	"github.com/labring/sreg/pkg/registry/sync"
@@ -37,7 +42,9 @@ import (
 	v2 "github.com/labring/sealos/pkg/types/v1beta1"
 	"github.com/labring/sealos/pkg/utils/file"
 	httputils "github.com/labring/sealos/pkg/utils/http"
+	"github.com/labring/sealos/pkg/utils/iputils"
 	"github.com/labring/sealos/pkg/utils/logger"
+	stringsutil "github.com/labring/sealos/pkg/utils/strings"
 )
 
 const (
@@ -50,10 +57,59 @@ const (
 	sshMode
 )
 
+// number of blobs copied to a host concurrently in ssh mode
+const defaultBlobConcurrency = 4
+
+// SyncOptions controls how the images are synced to the registry hosts.
+type SyncOptions struct {
+	// Parallelism is the number of hosts to sync concurrently, 0 means unlimited.
+	Parallelism int
+	// BandwidthLimit is the total bytes per second sent to the hosts, 0 means unlimited.
+	BandwidthLimit int64
+	// FanOut is the number of hosts served by each synced host, 0 means all the hosts are
+	// synced from this machine.
+	FanOut int
+}
+
 type impl struct {
 	pathResolver constants.PathResolver
 	execer       exec.Interface
 	mounts       []v2.MountImage
+	opts         SyncOptions
+	limiter      *rate.Limiter
+	// limits the number of hosts synced from this machine concurrently
+	originSlots chan struct{}
+	peerSync    func(ctx context.Context, source, target *syncOption) error
+}
+
+type syncOption struct {
+	host   string
+	target string
+	typ    int
+}
+
+// syncStats is the summary of syncing images to a host.
+type syncStats struct {
+	host string
+	typ  int
+	// the peer host which served the images, empty if it's this machine
+	source       string
+	transferred  int64
+	missing      int
+	skipped      int
+	skippedBytes int64
+}
+
+func (st *syncStats) String() string {
+	mode := "http"
+	switch {
+	case st.source != "":
+		mode = "peer " + st.source
+	case st.typ == sshMode:
+		mode = "ssh"
+	}
+	return fmt.Sprintf("synced images to %s via %s: %s transferred for %d missing blobs, %d blobs (%s) already exist",
+		st.host, mode, units.HumanSize(float64(st.transferred)), st.missing, st.skipped, units.HumanSize(float64(st.skippedBytes)))
 }
 
 func shouldSkip(mounts []v2.MountImage) bool {
@@ -69,6 +125,8 @@ func (s *impl) Sync(ctx context.Context, hosts ...string) error {
 	if shouldSkip(s.mounts) {
 		return nil
 	}
+	// every host takes one slot of the options below
+	hosts = stringsutil.RemoveDuplicate(hosts)
 	logger.Info("trying default http mode to sync images to hosts %v", hosts)
 	// run `sealctl registry serve` to start a temporary registry
 	for i := range hosts {
@@ -86,58 +144,106 @@ func (s *impl) Sync(ctx context.Context, hosts ...string) error {
 		}(cmdCtx, hosts[i])
 	}
 
-	type syncOption struct {
-		target string
-		typ    int
-	}
-
 	syncOptionChan := make(chan *syncOption, len(hosts))
 	go func() {
 		for i := range hosts {
 			go func(target string) {
 				probeCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
 				defer cancel()
-				ep := sync.ParseRegistryAddress(trimPortStr(target), defaultTemporaryPort)
+				ep := temporaryRegistryAddress(target)
 				if err := httputils.WaitUntilEndpointAlive(probeCtx, "http://"+ep); err != nil {
 					logger.Warn("cannot connect to remote temporary registry %s: %v, fallback using ssh mode instead", ep, err)
-					syncOptionChan <- &syncOption{target: target, typ: sshMode}
+					syncOptionChan <- &syncOption{host: target, target: target, typ: sshMode}
 				} else {
-					syncOptionChan <- &syncOption{target: ep, typ: httpMode}
+					syncOptionChan <- &syncOption{host: target, target: ep, typ: httpMode}
 				}
 			}(hosts[i])
 		}
 	}()
 
-	eg, _ := errgroup.WithContext(ctx)
+	// keep the order of hosts, so that the fan-out tree is always the same
+	index := make(map[string]int, len(hosts))
+	for i := range hosts {
+		index[hosts[i]] = i
+	}
+	opts := make([]*syncOption, len(hosts))
 	for i := 0; i < len(hosts); i++ {
-		opt, ok := <-syncOptionChan
-		if !ok {
-			break
+		opt := <-syncOptionChan
+		opts[index[opt.host]] = opt
+	}
+	if s.opts.FanOut > 0 {
+		logger.Info("syncing images to hosts with fan-out %d, the synced hosts serve the others", s.opts.FanOut)
+	}
+	stats, err := s.syncTree(ctx, fanOutTree(opts, s.opts.FanOut))
+	for _, opt := range opts {
+		if st, ok := stats[opt]; ok {
+			logger.Info(st.String())
 		}
-		for j := range s.mounts {
-			registryDir := filepath.Join(s.mounts[j].MountPoint, constants.RegistryDirName)
-			if !file.IsDir(registryDir) {
-				continue
-			}
-			eg.Go(func() (err error) {
-				switch opt.typ {
-				case httpMode:
-					err = syncViaHTTP(ctx, opt.target, registryDir)
-				case sshMode:
-					err = syncViaSSH(ctx, s, opt.target, registryDir)
-				}
-				return
-			})
+	}
+	return err
+}
+
+// syncFrom syncs target from the peer source, or from this machine if source is nil or failed.
+func (s *impl) syncFrom(ctx context.Context, source, target *syncOption) (*syncStats, error) {
+	if source != nil {
+		stats, err := s.syncFromPeer(ctx, source, target)
+		if err == nil {
+			return stats, nil
 		}
+		logger.Warn("failed to sync images from %s to %s: %v, fallback to sync from origin", source.host, target.host, err)
 	}
-	return eg.Wait()
+	if s.originSlots != nil {
+		s.originSlots <- struct{}{}
+		defer func() { <-s.originSlots }()
+	}
+	return s.syncHost(ctx, target)
 }
 
-func trimPortStr(s string) string {
-	if idx := strings.Index(s, ":"); idx > 0 {
-		return s[:idx]
+// syncHost syncs the registry dirs of mounts to host, only the blobs that are missing on host are transferred.
+func (s *impl) syncHost(ctx context.Context, opt *syncOption) (*syncStats, error) {
+	stats := &syncStats{host: opt.host, typ: opt.typ}
+	existing, err := remoteBlobs(s.execer, opt.host, s.pathResolver.RootFSRegistryPath())
+	if err != nil {
+		logger.Warn("%v, all the blobs will be synced", err)
+		existing = make(blobs)
 	}
-	return s
+	for i := range s.mounts {
+		registryDir := filepath.Join(s.mounts[i].MountPoint, constants.RegistryDirName)
+		if !file.IsDir(registryDir) {
+			continue
+		}
+		local, err := localBlobs(registryDir)
+		if err != nil {
+			return stats, err
+		}
+		missing := local.missing(existing)
+		stats.missing += len(missing)
+		stats.skipped += len(local) - len(missing)
+		stats.skippedBytes += local.size() - missing.size()
+		logger.Debug("%d of %d blobs in %s are missing on %s", len(missing), len(local), registryDir, opt.host)
+
+		var n int64
+		switch opt.typ {
+		case httpMode:
+			n, err = s.syncViaHTTP(ctx, opt.target, registryDir)
+		case sshMode:
+			n, err = s.syncViaSSH(ctx, opt.target, registryDir, missing)
+		}
+		stats.transferred += n
+		if err != nil {
+			return stats, err
+		}
+		// the blobs shared by the following images are not transferred again
+		for path, size := range missing {
+			existing[path] = size
+		}
+	}
+	return stats, nil
+}
+
+// temporaryRegistryAddress returns the address of the temporary registry served on host.
+func temporaryRegistryAddress(host string) string {
+	return net.JoinHostPort(iputils.GetHostIP(host), defaultTemporaryPort)
 }
 
 func getRegistryServeCommand(pathResolver constants.PathResolver, port string) string {
@@ -146,18 +252,78 @@ func getRegistryServeCommand(pathResolver constants.PathResolver, port string) s
 	)
 }
 
-func syncViaSSH(_ context.Context, s *impl, target string, localDir string) error {
-	return ssh.CopyDir(s.execer, target, localDir, s.pathResolver.RootFSRegistryPath(), nil)
+// syncViaSSH copies the missing blobs and the metadata of repositories to target. With the bandwidth
+// limit, the blobs are streamed through the limiter, so the limit is shared by the concurrent copies.
+func (s *impl) syncViaSSH(ctx context.Context, target string, localDir string, missing blobs) (int64, error) {
+	remoteDir := s.pathResolver.RootFSRegistryPath()
+	var sent atomic.Int64
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.SetLimit(defaultBlobConcurrency)
+	for path, size := range missing {
+		path, size := path, size
+		eg.Go(func() error {
+			src := filepath.Join(localDir, blobsDir, path)
+			dst := filepath.Join(remoteDir, blobsDir, path)
+			if err := s.copyBlob(ctx, target, src, dst, size, &sent); err != nil {
+				return fmt.Errorf("failed to copy blob %s to %s: %v", src, target, err)
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return sent.Load(), err
+	}
+	// the metadata is small and the tags might be changed, so it's always copied
+	repositories := filepath.Join(localDir, repositoriesDir)
+	if !file.IsDir(repositories) {
+		return sent.Load(), nil
+	}
+	size, err := file.GetFileSize(repositories)
+	if err != nil {
+		return sent.Load(), err
+	}
+	if err = ssh.CopyDir(s.execer, target, repositories, filepath.Join(remoteDir, repositoriesDir), nil); err != nil {
+		return sent.Load(), err
+	}
+	return sent.Load() + size, nil
+}
+
+// copyBlob copies the blob src to dst on target and counts the bytes sent.
+func (s *impl) copyBlob(ctx context.Context, target, src, dst string, size int64, sent *atomic.Int64) error {
+	copier, ok := s.execer.(ssh.StreamCopier)
+	if s.limiter == nil || !ok {
+		// the blob is paced as a whole if it can't be streamed through the limiter
+		if err := waitN(ctx, s.limiter, size); err != nil {
+			return err
+		}
+		if err := s.execer.Copy(target, src, dst); err != nil {
+			return err
+		}
+		sent.Add(size)
+		return nil
+	}
+	f, err := os.Open(filepath.Clean(src))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return copier.CopyFrom(target, &limitedReader{ctx: ctx, r: f, limiter: s.limiter, sent: sent}, dst, info.Mode())
 }
 
-func syncViaHTTP(ctx context.Context, target string, localDir string) error {
+// syncViaHTTP pushes the images to the temporary registry on target through a local proxy, which counts
+// the bytes sent and limits the bandwidth. The blobs that exist in target are skipped by the registry client.
+func (s *impl) syncViaHTTP(ctx context.Context, target string, localDir string) (int64, error) {
 	sys := &types.SystemContext{
 		DockerInsecureSkipTLSVerify: types.OptionalBoolTrue,
 	}
 
 	config, err := handler.NewConfig(localDir, 0)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	config.Log.AccessLog.Disabled = true
 	errCh := handler.Run(ctx, config)
@@ -170,12 +336,17 @@ func syncViaHTTP(ctx context.Context, target string, localDir string) error {
 	probeCtx, cancel := context.WithTimeout(ctx, time.Second*3)
 	defer cancel()
 	if err = httputils.WaitUntilEndpointAlive(probeCtx, "http://"+src); err != nil {
-		return err
+		return 0, err
+	}
+	p, err := startProxy(ctx, target, s.limiter)
+	if err != nil {
+		return 0, err
 	}
+	defer p.Close()
 	opts := &sync.Options{
 		SystemContext: sys,
 		Source:        src,
-		Target:        target,
+		Target:        p.Addr(),
 		SelectionOptions: []copy.ImageListSelection{
 			copy.CopyAllImages, copy.CopySystemImage,
 		},
@@ -183,11 +354,22 @@ func syncViaHTTP(ctx context.Context, target string, localDir string) error {
 	}
 
 	if err = sync.ToRegistry(ctx, opts); err != nil && !strings.Contains(err.Error(), "manifest unknown") {
-		return err
+		return p.sent.Load(), err
 	}
-	return nil
+	return p.sent.Load(), nil
 }
 
-func New(pathResolver constants.PathResolver, execer exec.Interface, mounts []v2.MountImage) filesystem.RegistrySyncer {
-	return &impl{pathResolver, execer, mounts}
+func New(pathResolver constants.PathResolver, execer exec.Interface, mounts []v2.MountImage, opts SyncOptions) filesystem.RegistrySyncer {
+	s := &impl{
+		pathResolver: pathResolver,
+		execer:       execer,
+		mounts:       mounts,
+		opts:         opts,
+		limiter:      newLimiter(opts.BandwidthLimit),
+	}
+	if opts.Parallelism > 0 {
+		s.originSlots = make(chan struct{}, opts.Parallelism)
+	}
+	s.peerSync = s.syncViaPeer
+	return s
 }
@@ -0,0 +1,133 @@
+/*
+Copyright 2023 fengxsong@outlook.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync/atomic"
+
+	"golang.org/x/time/rate"
+)
+
+// maximum bytes to send at once when the bandwidth is limited
+const maxBurst = 256 * 1024
+
+// newLimiter returns the limiter of bytesPerSecond, nil if it's unlimited.
+func newLimiter(bytesPerSecond int64) *rate.Limiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	burst := bytesPerSecond
+	if burst > maxBurst {
+		burst = maxBurst
+	}
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), int(burst))
+}
+
+// waitN blocks until n bytes are allowed to be sent.
+func waitN(ctx context.Context, limiter *rate.Limiter, n int64) error {
+	if limiter == nil {
+		return nil
+	}
+	for n > 0 {
+		chunk := n
+		if burst := int64(limiter.Burst()); chunk > burst {
+			chunk = burst
+		}
+		if err := limiter.WaitN(ctx, int(chunk)); err != nil {
+			return err
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+type limitedReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *rate.Limiter
+	sent    *atomic.Int64
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if r.limiter != nil && len(p) > r.limiter.Burst() {
+		p = p[:r.limiter.Burst()]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.sent.Add(int64(n))
+		if werr := waitN(r.ctx, r.limiter, int64(n)); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// proxy forwards the local connections to the target registry, it counts the bytes
+// sent to target and limits the bandwidth.
+type proxy struct {
+	listener net.Listener
+	target   string
+	limiter  *rate.Limiter
+	sent     atomic.Int64
+}
+
+func startProxy(ctx context.Context, target string, limiter *rate.Limiter) (*proxy, error) {
+	l, err := net.Listen("tcp", net.JoinHostPort(localhost, "0"))
+	if err != nil {
+		return nil, err
+	}
+	p := &proxy{listener: l, target: target, limiter: limiter}
+	go func() {
+		<-ctx.Done()
+		_ = l.Close()
+	}()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go p.forward(ctx, conn)
+		}
+	}()
+	return p, nil
+}
+
+func (p *proxy) forward(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	target, err := net.Dial("tcp", p.target)
+	if err != nil {
+		return
+	}
+	defer target.Close()
+	go func() {
+		_, _ = io.Copy(conn, target)
+		_ = conn.Close()
+	}()
+	_, _ = io.Copy(target, &limitedReader{ctx: ctx, r: conn, limiter: p.limiter, sent: &p.sent})
+}
+
+func (p *proxy) Addr() string {
+	return p.listener.Addr().String()
+}
+
+func (p *proxy) Close() error {
+	return p.listener.Close()
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2023 fengxsong@outlook.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labring/sealos/pkg/exec"
+)
+
+// streamExecer records the streams copied to remote.
+type streamExecer struct {
+	exec.Interface
+	copied  bytes.Buffer
+	maxRead int
+}
+
+func (s *streamExecer) CopyFrom(_ string, r io.Reader, _ string, _ os.FileMode) error {
+	buf := make([]byte, 1024*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > s.maxRead {
+			s.maxRead = n
+		}
+		s.copied.Write(buf[:n])
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func TestCopyBlobLimited(t *testing.T) {
+	const limit = 100 * 1024
+	data := bytes.Repeat([]byte("sealos"), 25*1024)
+	src := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(src, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	execer := &streamExecer{}
+	s := &impl{execer: execer, limiter: newLimiter(limit)}
+
+	var sent atomic.Int64
+	start := time.Now()
+	if err := s.copyBlob(context.Background(), "192.168.0.2:22", src, "/var/lib/registry/data", int64(len(data)), &sent); err != nil {
+		t.Fatal(err)
+	}
+	// the burst is sent at once, the rest is paced by the limit
+	if elapsed, want := time.Since(start), time.Duration(len(data)-limit)*time.Second/limit; elapsed < want*8/10 {
+		t.Errorf("copied in %s, expected at least %s", elapsed, want)
+	}
+	if !bytes.Equal(execer.copied.Bytes(), data) {
+		t.Error("copied data differs from the blob")
+	}
+	if execer.maxRead > limit {
+		t.Errorf("read %d bytes at once, more than the burst %d", execer.maxRead, limit)
+	}
+	if sent.Load() != int64(len(data)) {
+		t.Errorf("sent %d bytes, want %d", sent.Load(), len(data))
+	}
+}
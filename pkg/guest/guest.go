@@ -43,25 +43,24 @@ func NewGuestManager() (Interface, error) {
 }
 
 func (d *Default) Apply(cluster *v2.Cluster, mounts []v2.MountImage, targetHosts []string) error {
-	envGetter := env.NewEnvProcessor(cluster)
 	sshClient := ssh.NewCacheClientFromCluster(cluster, true)
 	execer, err := exec.New(sshClient)
 	if err != nil {
 		return err
 	}
+	return apply(execer, cluster, mounts, targetHosts)
+}
 
+func apply(execer exec.Interface, cluster *v2.Cluster, mounts []v2.MountImage, targetHosts []string) error {
+	envGetter := env.NewEnvProcessor(cluster)
 	for i, m := range mounts {
 		switch {
 		case m.IsRootFs(), m.IsPatch():
 			eg, ctx := errgroup.WithContext(context.Background())
-			for j := range targetHosts {
-				node := targetHosts[j]
-				envs := maps.Merge(m.Env, envGetter.Getenv(node))
-				cmds := formalizeImageCommands(cluster, i, m, envs)
+			for _, cmd := range renderImageCommands(cluster, envGetter, i, m, targetHosts) {
+				cmd := cmd
 				eg.Go(func() error {
-					return execer.CmdAsyncWithContext(ctx, node,
-						stringsutil.RenderShellWithEnv(strings.Join(cmds, "; "), envs),
-					)
+					return execer.CmdAsyncWithContext(ctx, cmd.host, cmd.command)
 				})
 			}
 			if err := eg.Wait(); err != nil {
@@ -69,18 +68,77 @@ func (d *Default) Apply(cluster *v2.Cluster, mounts []v2.MountImage, targetHosts
 			}
 		case m.IsApplication():
 			// on run on the first master
-			envs := maps.Merge(m.Env, envGetter.Getenv(cluster.GetMaster0IP()))
-			cmds := formalizeImageCommands(cluster, i, m, envs)
-			if err := execer.CmdAsync(cluster.GetMaster0IPAndPort(),
-				stringsutil.RenderShellWithEnv(strings.Join(cmds, "; "), envs),
-			); err != nil {
-				return err
+			for _, cmd := range renderImageCommands(cluster, envGetter, i, m, targetHosts) {
+				if err := execer.CmdAsync(cmd.host, cmd.command); err != nil {
+					return err
+				}
 			}
 		}
 	}
 	return nil
 }
 
+type hostCommand struct {
+	host    string
+	command string
+	empty   bool
+}
+
+// renderImageCommands renders the commands of the index-th mount on the hosts it's executed on,
+// rootfs and patch images run on all the target hosts, application images run on the first master.
+// It's shared by Apply and Commands, so that the planned commands are the same as the executed ones.
+func renderImageCommands(cluster *v2.Cluster, envGetter env.Interface, index int, m v2.MountImage, targetHosts []string) []hostCommand {
+	render := func(host, envHost string) hostCommand {
+		envs := maps.Merge(m.Env, envGetter.Getenv(envHost))
+		cmds := formalizeImageCommands(cluster, index, m, envs)
+		return hostCommand{
+			host:    host,
+			command: stringsutil.RenderShellWithEnv(strings.Join(cmds, "; "), envs),
+			empty:   len(cmds) == 0,
+		}
+	}
+	switch {
+	case m.IsRootFs(), m.IsPatch():
+		ret := make([]hostCommand, 0, len(targetHosts))
+		for _, host := range targetHosts {
+			ret = append(ret, render(host, host))
+		}
+		return ret
+	case m.IsApplication():
+		return []hostCommand{render(cluster.GetMaster0IPAndPort(), cluster.GetMaster0IP())}
+	}
+	return nil
+}
+
+// Command is a rendered guest command and the hosts it would be executed on.
+type Command struct {
+	Image   string   `json:"image"`
+	Hosts   []string `json:"hosts"`
+	Command string   `json:"command"`
+}
+
+// Commands renders the commands that Apply would execute, without connecting to any host.
+// Hosts sharing the same rendered command are grouped together.
+func Commands(cluster *v2.Cluster, mounts []v2.MountImage, targetHosts []string) []Command {
+	envGetter := env.NewEnvProcessor(cluster)
+	ret := make([]Command, 0)
+	for i, m := range mounts {
+		indexes := make(map[string]int)
+		for _, cmd := range renderImageCommands(cluster, envGetter, i, m, targetHosts) {
+			if cmd.empty {
+				continue
+			}
+			if idx, ok := indexes[cmd.command]; ok {
+				ret[idx].Hosts = append(ret[idx].Hosts, cmd.host)
+				continue
+			}
+			indexes[cmd.command] = len(ret)
+			ret = append(ret, Command{Image: m.ImageName, Hosts: []string{cmd.host}, Command: cmd.command})
+		}
+	}
+	return ret
+}
+
 func formalizeWorkingCommand(clusterName string, imageName string, t v2.ImageType, cmd string) string {
 	if cmd == "" {
 		return ""
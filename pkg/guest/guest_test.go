@@ -17,8 +17,12 @@ limitations under the License.
 package guest
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/labring/sealos/pkg/constants"
@@ -355,3 +359,70 @@ func TestDefault_getGuestCmd_dollarVariable(t *testing.T) {
 		})
 	}
 }
+
+type fakeExecer struct {
+	mu   sync.Mutex
+	cmds []string
+}
+
+func (f *fakeExecer) Copy(string, string, string) error  { return nil }
+func (f *fakeExecer) Fetch(string, string, string) error { return nil }
+func (f *fakeExecer) CmdAsync(host string, cmds ...string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, cmd := range cmds {
+		f.cmds = append(f.cmds, fmt.Sprintf("%s: %s", host, cmd))
+	}
+	return nil
+}
+func (f *fakeExecer) CmdAsyncWithContext(_ context.Context, host string, cmds ...string) error {
+	return f.CmdAsync(host, cmds...)
+}
+func (f *fakeExecer) Cmd(string, string) ([]byte, error)                 { return nil, nil }
+func (f *fakeExecer) CmdToString(string, string, string) (string, error) { return "", nil }
+func (f *fakeExecer) Ping(string) error                                  { return nil }
+
+func TestCommandsMatchApply(t *testing.T) {
+	cluster := &v2.Cluster{
+		Spec: v2.ClusterSpec{
+			Env: []string{"ROLE=worker"},
+			Hosts: []v2.Host{
+				{IPS: []string{"192.168.0.2"}, Roles: []string{v2.MASTER}, Env: []string{"ROLE=master"}},
+				{IPS: []string{"192.168.0.3", "192.168.0.4"}, Roles: []string{v2.NODE}},
+			},
+		},
+	}
+	cluster.Name = "default"
+	mounts := []v2.MountImage{
+		{Name: "rootfs", ImageName: "labring/kubernetes:v1.25.0", Type: v2.RootfsImage, Cmd: []string{"bash init.sh $(ROLE)"}},
+		{Name: "helm", ImageName: "labring/helm:v3.8.2", Type: v2.AppImage, Cmd: []string{"cp helm /usr/bin"}},
+		{Name: "calico", ImageName: "labring/calico:v3.24.1", Type: v2.AppImage, Cmd: []string{"helm install calico on $(ROLE)"}},
+	}
+	hosts := cluster.GetAllIPS()
+
+	execer := &fakeExecer{}
+	if err := apply(execer, cluster, mounts, hosts); err != nil {
+		t.Fatal(err)
+	}
+	var planned []string
+	for _, cmd := range Commands(cluster, mounts, hosts) {
+		for _, host := range cmd.Hosts {
+			planned = append(planned, fmt.Sprintf("%s: %s", host, cmd.Command))
+		}
+	}
+	sort.Strings(execer.cmds)
+	sort.Strings(planned)
+	if !reflect.DeepEqual(planned, execer.cmds) {
+		t.Errorf("planned commands differ from the applied ones\nplanned: %q\napplied: %q", planned, execer.cmds)
+	}
+	// application images run on master0 with its env
+	for _, cmd := range planned {
+		if strings.Contains(cmd, "helm install calico") && !strings.HasPrefix(cmd, "192.168.0.2: ") ||
+			strings.Contains(cmd, "on worker") && strings.HasPrefix(cmd, "192.168.0.2: ") {
+			t.Errorf("unexpected command %q", cmd)
+		}
+	}
+	if !strings.Contains(strings.Join(planned, "\n"), "helm install calico on master") {
+		t.Errorf("application image is not rendered with the env of master0: %q", planned)
+	}
+}
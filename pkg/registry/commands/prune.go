@@ -0,0 +1,43 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/labring/sealos/pkg/registry/prune"
+)
+
+func NewRegistryPruneCmd(examplePrefix string) *cobra.Command {
+	opts := prune.Options{}
+
+	var registryPruneCmd = &cobra.Command{
+		Use:   "prune",
+		Short: "delete the images that are not used by the cluster from registry",
+		Long: `Delete the images in registry which are neither carried by the images of cluster nor used by any pod,
+then run garbage collection of registry to free the disk space of the blobs. Images should not be pushed to
+registry during pruning.`,
+		Example: fmt.Sprintf(`%[1]s prune --dry-run
+%[1]s prune --force`, examplePrefix),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return opts.Run(cmd.Context(), cmd.OutOrStdout())
+		},
+	}
+	opts.RegisterFlags(registryPruneCmd.Flags())
+	return registryPruneCmd
+}
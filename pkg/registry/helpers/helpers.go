@@ -40,17 +40,9 @@ func GetRegistryInfo(execer exec.Interface, rootfs, defaultRegistry string) *v1b
 		Password: constants.DefaultRegistryPassword,
 		Data:     constants.DefaultRegistryData,
 	}
-	etcPath := path.Join(rootfs, constants.EtcDirName, RegistryCustomConfig)
-	out, err := execer.Cmd(defaultRegistry, fmt.Sprintf("cat %s", etcPath))
+	readConfig, err := ReadRegistryConfig(execer, rootfs, defaultRegistry)
 	if err != nil {
-		logger.Warn("load registry config error: %+v, using default registry config", err)
-		return DefaultConfig
-	}
-	logger.Debug("registry config data info: %s", string(out))
-	readConfig := &v1beta1.RegistryConfig{}
-	err = yaml.Unmarshal(out, &readConfig)
-	if err != nil {
-		logger.Warn("read registry config path error: %+v, using default registry config", err)
+		logger.Warn("%+v, using default registry config", err)
 		return DefaultConfig
 	}
 	if readConfig.IP == "" {
@@ -66,6 +58,21 @@ func GetRegistryInfo(execer exec.Interface, rootfs, defaultRegistry string) *v1b
 	return readConfig
 }
 
+// ReadRegistryConfig reads the registry config in the rootfs on host, the empty fields are not defaulted.
+func ReadRegistryConfig(execer exec.Interface, rootfs, host string) (*v1beta1.RegistryConfig, error) {
+	etcPath := path.Join(rootfs, constants.EtcDirName, RegistryCustomConfig)
+	out, err := execer.Cmd(host, fmt.Sprintf("cat %s", etcPath))
+	if err != nil {
+		return nil, fmt.Errorf("load registry config error: %w", err)
+	}
+	logger.Debug("registry config data info: %s", string(out))
+	readConfig := &v1beta1.RegistryConfig{}
+	if err = yaml.Unmarshal(out, &readConfig); err != nil {
+		return nil, fmt.Errorf("read registry config path error: %w", err)
+	}
+	return readConfig, nil
+}
+
 func GetImageCRIShimInfo(execer exec.Interface, config, defaultIP string) *types.Config {
 	out, _ := execer.Cmd(defaultIP, fmt.Sprintf("cat %s", config))
 	logger.Debug("image shim data info: %s", string(out))
@@ -33,6 +33,11 @@ import (
 	"github.com/labring/sealos/pkg/utils/passwd"
 )
 
+// reloadImageShimCmd reloads image-cri-shim by SIGHUP without interrupting the in-flight pulls,
+// the shim which doesn't support reload (without --reload-interval) is killed by SIGHUP, so it's restarted.
+const reloadImageShimCmd = "if image-cri-shim --help 2>/dev/null | grep -q -- --reload-interval; " +
+	"then systemctl kill -s HUP image-cri-shim; else systemctl restart image-cri-shim; fi"
+
 type RegistryType string
 
 const (
@@ -90,7 +95,7 @@ func (m *upgrade) UpdateImageShimConfig(rc *types.Config, target, host string) e
 		if err = m.SSHInterface.Copy(host, configPath, target); err != nil {
 			return err
 		}
-		if err = m.SSHInterface.CmdAsync(host, "systemctl restart image-cri-shim"); err != nil {
+		if err = m.SSHInterface.CmdAsync(host, reloadImageShimCmd); err != nil {
 			return err
 		}
 	}
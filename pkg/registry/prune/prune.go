@@ -0,0 +1,268 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prune
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/docker/docker/api/types"
+	"github.com/spf13/pflag"
+
+	"github.com/labring/sealos/pkg/client-go/kubernetes"
+	"github.com/labring/sealos/pkg/clusterfile"
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/exec"
+	"github.com/labring/sealos/pkg/registry/helpers"
+	"github.com/labring/sealos/pkg/registry/password"
+	"github.com/labring/sealos/pkg/ssh"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/confirm"
+	fileutil "github.com/labring/sealos/pkg/utils/file"
+	"github.com/labring/sealos/pkg/utils/iputils"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+const unused = "unused"
+
+// Item is a tag of manifest in the registry.
+type Item struct {
+	Repository string
+	Tag        string
+	Digest     string
+	Prune      bool
+	// who references the manifest
+	Reason string
+}
+
+// Plan returns the tags in registry, the ones whose manifests are not referenced are pruned.
+// A manifest shared by a referenced tag is always kept.
+func Plan(ctx context.Context, registry Registry, refs *References) ([]Item, error) {
+	repos, err := registry.Repositories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories: %v", err)
+	}
+	sort.Strings(repos)
+	var items []Item
+	kept := make(map[string]string)
+	for _, repo := range repos {
+		tags, err := registry.Tags(ctx, repo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags of %s: %v", repo, err)
+		}
+		sort.Strings(tags)
+		for _, tag := range tags {
+			digest, err := registry.Digest(ctx, repo, tag)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get digest of %s:%s: %v", repo, tag, err)
+			}
+			item := Item{Repository: repo, Tag: tag, Digest: digest, Prune: true, Reason: unused}
+			if referrer, ok := refs.Referrer(repo, tag, digest); ok {
+				item.Prune, item.Reason = false, referrer
+				kept[repo+"@"+digest] = fmt.Sprintf("same manifest as %s:%s", repo, tag)
+			}
+			items = append(items, item)
+		}
+	}
+	for i := range items {
+		if reason, ok := kept[items[i].Repository+"@"+items[i].Digest]; ok && items[i].Prune {
+			items[i].Prune, items[i].Reason = false, reason
+		}
+	}
+	return items, nil
+}
+
+// Prune deletes the manifests of the pruned items, and returns the number of deleted manifests.
+func Prune(ctx context.Context, registry Registry, items []Item) (int, error) {
+	deleted := make(map[string]bool)
+	for _, item := range items {
+		key := item.Repository + "@" + item.Digest
+		if !item.Prune || deleted[key] {
+			continue
+		}
+		logger.Debug("deleting manifest %s", key)
+		if err := registry.Delete(ctx, item.Repository, item.Digest); err != nil {
+			return len(deleted), fmt.Errorf("failed to delete %s:%s: %v", item.Repository, item.Tag, err)
+		}
+		deleted[key] = true
+	}
+	return len(deleted), nil
+}
+
+func shortDigest(digest string) string {
+	if len(digest) > 19 {
+		return digest[:19]
+	}
+	return digest
+}
+
+// PrintItems prints the plan of the registry on host.
+func PrintItems(out io.Writer, host string, items []Item) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "REPOSITORY\tTAG\tDIGEST\tACTION\tREASON\n")
+	var pruned int
+	for _, item := range items {
+		action := "keep"
+		if item.Prune {
+			action = "prune"
+			pruned++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", item.Repository, item.Tag, shortDigest(item.Digest), action, item.Reason)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(out, "%d of %d tags in registry %s are unused\n", pruned, len(items), host)
+	return err
+}
+
+// GarbageCollectCommand returns the command to delete the blobs which are not referenced by any manifest.
+func GarbageCollectCommand(registryType password.RegistryType, config string) string {
+	switch registryType {
+	case password.RegistryTypeDocker, password.RegistryTypeContainerd:
+		if config == "" {
+			config = "/etc/docker/registry/config.yml"
+		}
+		runtime := "docker"
+		if registryType == password.RegistryTypeContainerd {
+			runtime = "nerdctl"
+		}
+		return fmt.Sprintf("%s exec sealos-registry registry garbage-collect %s", runtime, config)
+	default:
+		if config == "" {
+			config = "/etc/registry/registry_config.yml"
+		}
+		return fmt.Sprintf("registry garbage-collect %s", config)
+	}
+}
+
+type Options struct {
+	ClusterName    string
+	DryRun         bool
+	Force          bool
+	RegistryType   string
+	RegistryConfig string
+}
+
+func (o *Options) RegisterFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&o.ClusterName, "cluster-name", "c", "default", "cluster name")
+	fs.BoolVar(&o.DryRun, "dry-run", false, "only print the tags to be pruned")
+	fs.BoolVar(&o.Force, "force", false, "prune without confirmation")
+	fs.StringVar(&o.RegistryType, "registry-type", string(password.RegistryTypeRegistry),
+		fmt.Sprintf("how the registry runs, one of %s|%s|%s", password.RegistryTypeRegistry, password.RegistryTypeDocker, password.RegistryTypeContainerd))
+	fs.StringVar(&o.RegistryConfig, "registry-config", "", "config file of registry used by garbage collection, default depends on the registry type")
+}
+
+func (o *Options) loadCluster() (*v2.Cluster, error) {
+	if o.ClusterName == "" {
+		return nil, errors.New("cluster name is empty")
+	}
+	switch password.RegistryType(o.RegistryType) {
+	case password.RegistryTypeRegistry, password.RegistryTypeDocker, password.RegistryTypeContainerd:
+	default:
+		return nil, fmt.Errorf("invalid registry type %s", o.RegistryType)
+	}
+	clusterPath := constants.Clusterfile(o.ClusterName)
+	if !fileutil.IsExist(clusterPath) {
+		return nil, fmt.Errorf("cluster %s not exist", o.ClusterName)
+	}
+	clusterFile := clusterfile.NewClusterFile(clusterPath)
+	if err := clusterFile.Process(); err != nil {
+		return nil, fmt.Errorf("cluster %s process error: %+v", o.ClusterName, err)
+	}
+	return clusterFile.GetCluster(), nil
+}
+
+// references returns the images of the cluster images and the pods.
+func (o *Options) references(ctx context.Context, cluster *v2.Cluster) (*References, error) {
+	refs := NewReferences()
+	if err := refs.AddMounts(cluster.Status.Mounts); err != nil {
+		return nil, err
+	}
+	apiServer := fmt.Sprintf("https://%s", net.JoinHostPort(cluster.GetMaster0IP(), fmt.Sprint(constants.DefaultAPIServerPort)))
+	cli, err := kubernetes.NewKubernetesClient(constants.NewPathResolver(cluster.Name).AdminFile(), apiServer)
+	if err != nil {
+		return nil, err
+	}
+	if err = refs.AddPods(ctx, cli.Kubernetes()); err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+func (o *Options) Run(ctx context.Context, out io.Writer) error {
+	cluster, err := o.loadCluster()
+	if err != nil {
+		return err
+	}
+	refs, err := o.references(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	execer, err := exec.New(ssh.NewCacheClientFromCluster(cluster, true))
+	if err != nil {
+		return err
+	}
+	info := helpers.GetRegistryInfo(execer, constants.NewPathResolver(cluster.Name).RootFSPath(), cluster.GetRegistryIPAndPort())
+	auth := types.AuthConfig{Username: info.Username, Password: info.Password}
+
+	hosts := cluster.GetRegistryIPAndPortList()
+	registries := make([]Registry, len(hosts))
+	plans := make([][]Item, len(hosts))
+	var total int
+	for i, host := range hosts {
+		addr := net.JoinHostPort(iputils.GetHostIP(host), info.Port)
+		if registries[i], err = NewRegistry(addr, auth); err != nil {
+			return fmt.Errorf("failed to connect registry %s: %v", addr, err)
+		}
+		if plans[i], err = Plan(ctx, registries[i], refs); err != nil {
+			return err
+		}
+		if err = PrintItems(out, addr, plans[i]); err != nil {
+			return err
+		}
+		for _, item := range plans[i] {
+			if item.Prune {
+				total++
+			}
+		}
+	}
+	if o.DryRun || total == 0 {
+		return nil
+	}
+	if !o.Force {
+		prompt := fmt.Sprintf("are you sure to prune %d tags and their blobs?", total)
+		if yes, err := confirm.Confirm(prompt, "you have canceled to prune registry !"); err != nil || !yes {
+			return err
+		}
+	}
+	gc := GarbageCollectCommand(password.RegistryType(o.RegistryType), o.RegistryConfig)
+	for i, host := range hosts {
+		deleted, err := Prune(ctx, registries[i], plans[i])
+		if err != nil {
+			return err
+		}
+		logger.Info("deleted %d manifests in registry on %s, running garbage collection", deleted, host)
+		if err = execer.CmdAsync(host, gc); err != nil {
+			return fmt.Errorf("failed to run garbage collection on %s: %v", host, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prune
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/labring/sealos/pkg/constants"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+// d returns the digest of s.
+func d(s string) string {
+	return digest.FromString(s).String()
+}
+
+type fakeRegistry struct {
+	// repository to tag to digest
+	manifests map[string]map[string]string
+	deleted   []string
+}
+
+func (r *fakeRegistry) Repositories(_ context.Context) ([]string, error) {
+	var repos []string
+	for repo := range r.manifests {
+		repos = append(repos, repo)
+	}
+	return repos, nil
+}
+
+func (r *fakeRegistry) Tags(_ context.Context, repo string) ([]string, error) {
+	var tags []string
+	for tag := range r.manifests[repo] {
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+func (r *fakeRegistry) Digest(_ context.Context, repo, tag string) (string, error) {
+	return r.manifests[repo][tag], nil
+}
+
+func (r *fakeRegistry) Delete(_ context.Context, repo, digest string) error {
+	r.deleted = append(r.deleted, repo+"@"+digest)
+	return nil
+}
+
+func TestPrune(t *testing.T) {
+	mount := t.TempDir()
+	link := filepath.Join(mount, constants.RegistryDirName, repositoriesDir, "labring/calico/_manifests/tags/v3.24/current/link")
+	if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(link, []byte(d("calico324")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	refs := NewReferences()
+	if err := refs.AddMounts([]v2.MountImage{{ImageName: "labring/calico:v3.24", MountPoint: mount}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := refs.AddMounts([]v2.MountImage{{ImageName: "labring/helm:v3", MountPoint: filepath.Join(mount, "absent")}}); err == nil {
+		t.Error("expected error of absent mount point")
+	}
+
+	cli := fake.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "coredns"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Image: "registry.k8s.io/coredns/coredns:v1.9.3"}}},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+			{ImageID: "sealos.hub:5000/coredns/coredns@" + d("coredns193")},
+		}},
+	}, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nginx"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Image: "nginx"}}},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+			{ImageID: "docker-pullable://nginx@" + d("nginxlatest")},
+		}},
+	})
+	if err := refs.AddPods(context.Background(), cli); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := &fakeRegistry{manifests: map[string]map[string]string{
+		"labring/calico":  {"v3.24": d("calico324"), "v3.22": d("calico322")},
+		"coredns/coredns": {"v1.9.3": d("coredns193"), "v1.8.6": d("coredns186")},
+		"library/nginx":   {"latest": d("nginxlatest"), "1.25": d("nginxlatest"), "1.23": d("nginx123")},
+		"library/busybox": {"1.36": d("busybox136"), "latest": d("busybox136")},
+	}}
+	items, err := Plan(context.Background(), registry, refs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pruned []string
+	reasons := make(map[string]string)
+	for _, item := range items {
+		reasons[item.Repository+":"+item.Tag] = item.Reason
+		if item.Prune {
+			pruned = append(pruned, item.Repository+":"+item.Tag)
+		}
+	}
+	expected := []string{"coredns/coredns:v1.8.6", "labring/calico:v3.22", "library/busybox:1.36", "library/busybox:latest", "library/nginx:1.23"}
+	if !reflect.DeepEqual(pruned, expected) {
+		t.Errorf("expected pruned tags %v, got %v", expected, pruned)
+	}
+	for tag, reason := range map[string]string{
+		"labring/calico:v3.24":   "image labring/calico:v3.24",
+		"coredns/coredns:v1.9.3": "pod kube-system/coredns",
+		"library/nginx:latest":   "pod default/nginx",
+		"library/nginx:1.25":     "pod default/nginx",
+	} {
+		if reasons[tag] != reason {
+			t.Errorf("expected reason of %s is %q, got %q", tag, reason, reasons[tag])
+		}
+	}
+
+	deleted, err := Prune(context.Background(), registry, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(registry.deleted)
+	expected = []string{"coredns/coredns@" + d("coredns186"), "labring/calico@" + d("calico322"), "library/busybox@" + d("busybox136"), "library/nginx@" + d("nginx123")}
+	if deleted != len(expected) || !reflect.DeepEqual(registry.deleted, expected) {
+		t.Errorf("expected deleted manifests %v, got %d %v", expected, deleted, registry.deleted)
+	}
+}
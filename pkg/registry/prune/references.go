@@ -0,0 +1,162 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prune
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/containers/image/v5/docker/reference"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/labring/sealos/pkg/constants"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/file"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+const repositoriesDir = "docker/registry/v2/repositories"
+
+// References are the images which must be kept in the registry, they are tracked by the
+// repository path without domain, since image-cri-shim redirects all the domains to the registry.
+type References struct {
+	// repository:tag or digest to the referrer of it
+	tags    map[string]string
+	digests map[string]string
+}
+
+func NewReferences() *References {
+	return &References{tags: make(map[string]string), digests: make(map[string]string)}
+}
+
+func (r *References) AddTag(repo, tag, digest, referrer string) {
+	if tag != "" {
+		r.tags[repo+":"+tag] = referrer
+	}
+	if digest != "" {
+		r.digests[digest] = referrer
+	}
+}
+
+// AddImage adds the image name or image id reported by container runtime, eg. docker.io/library/nginx:1.25
+// or docker-pullable://nginx@sha256:xxx.
+func (r *References) AddImage(image, referrer string) error {
+	image = strings.TrimPrefix(image, "docker-pullable://")
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return fmt.Errorf("failed to parse image %s: %v", image, err)
+	}
+	var tag, digest string
+	if tagged, ok := named.(reference.Tagged); ok {
+		tag = tagged.Tag()
+	}
+	if canonical, ok := named.(reference.Canonical); ok {
+		digest = canonical.Digest().String()
+	} else if tag == "" {
+		tag = "latest"
+	}
+	r.AddTag(reference.Path(named), tag, digest, referrer)
+	return nil
+}
+
+// Referrer returns who references the manifest of repo:tag.
+func (r *References) Referrer(repo, tag, digest string) (string, bool) {
+	if referrer, ok := r.tags[repo+":"+tag]; ok {
+		return referrer, true
+	}
+	referrer, ok := r.digests[digest]
+	return referrer, ok
+}
+
+// AddMounts adds the tags in the registry dirs of the cluster images, the mount points must exist,
+// otherwise the images in them would be pruned.
+func (r *References) AddMounts(mounts []v2.MountImage) error {
+	for _, m := range mounts {
+		if !file.IsDir(m.MountPoint) {
+			return fmt.Errorf("mount point %s of image %s does not exist", m.MountPoint, m.ImageName)
+		}
+		root := filepath.Join(m.MountPoint, constants.RegistryDirName, repositoriesDir)
+		if !file.IsDir(root) {
+			continue
+		}
+		referrer := "image " + m.ImageName
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || d.Name() != "link" || filepath.Base(filepath.Dir(path)) != "current" {
+				return err
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			// <repository>/_manifests/tags/<tag>/current/link
+			repo, tag, ok := strings.Cut(strings.TrimSuffix(filepath.ToSlash(rel), "/current/link"), "/_manifests/tags/")
+			if !ok {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			r.AddTag(repo, tag, strings.TrimSpace(string(data)), referrer)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddPods adds the images of all the containers in the cluster.
+func (r *References) AddPods(ctx context.Context, cli kubernetes.Interface) error {
+	pods, err := cli.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list pods: %v", err)
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		referrer := fmt.Sprintf("pod %s/%s", pod.Namespace, pod.Name)
+		var images []string
+		for _, c := range pod.Spec.InitContainers {
+			images = append(images, c.Image)
+		}
+		for _, c := range pod.Spec.Containers {
+			images = append(images, c.Image)
+		}
+		for _, c := range pod.Spec.EphemeralContainers {
+			images = append(images, c.Image)
+		}
+		for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+			for _, s := range statuses {
+				// the image id might be the id of config which is not a manifest digest
+				if s.ImageID != "" && !strings.HasPrefix(s.ImageID, "sha256:") {
+					images = append(images, s.ImageID)
+				}
+			}
+		}
+		for _, image := range images {
+			if err := r.AddImage(image, referrer); err != nil {
+				logger.Debug("skip image of %s: %v", referrer, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prune
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/docker/docker/api/types"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/labring/sreg/pkg/registry/crane"
+	httputils "github.com/labring/sreg/pkg/utils/http"
+)
+
+// Registry is the API of the registry to prune.
+type Registry interface {
+	Repositories(ctx context.Context) ([]string, error)
+	Tags(ctx context.Context, repo string) ([]string, error)
+	Digest(ctx context.Context, repo, tag string) (string, error)
+	Delete(ctx context.Context, repo, digest string) error
+}
+
+type remoteRegistry struct {
+	registry name.Registry
+	options  []remote.Option
+}
+
+// NewRegistry returns the registry at addr, which is either http or https.
+func NewRegistry(addr string, authConfig types.AuthConfig) (Registry, error) {
+	registry, err := crane.NewRegistry(addr, authConfig)
+	if err != nil {
+		return nil, err
+	}
+	var auth authn.Authenticator = authn.Anonymous
+	if authConfig.Username != "" {
+		auth = &authn.Basic{Username: authConfig.Username, Password: authConfig.Password}
+	}
+	return &remoteRegistry{
+		registry: registry,
+		options:  []remote.Option{remote.WithAuth(auth), remote.WithTransport(httputils.DefaultSkipVerify)},
+	}, nil
+}
+
+func (r *remoteRegistry) withContext(ctx context.Context) []remote.Option {
+	return append([]remote.Option{remote.WithContext(ctx)}, r.options...)
+}
+
+func (r *remoteRegistry) Repositories(ctx context.Context) ([]string, error) {
+	return remote.Catalog(ctx, r.registry, r.options...)
+}
+
+func (r *remoteRegistry) Tags(ctx context.Context, repo string) ([]string, error) {
+	return remote.ListWithContext(ctx, r.registry.Repo(repo), r.options...)
+}
+
+func (r *remoteRegistry) Digest(ctx context.Context, repo, tag string) (string, error) {
+	desc, err := remote.Head(r.registry.Repo(repo).Tag(tag), r.withContext(ctx)...)
+	if err != nil {
+		return "", err
+	}
+	return desc.Digest.String(), nil
+}
+
+func (r *remoteRegistry) Delete(ctx context.Context, repo, digest string) error {
+	err := remote.Delete(r.registry.Repo(repo).Digest(digest), r.withContext(ctx)...)
+	var terr *transport.Error
+	if errors.As(err, &terr) && terr.StatusCode == http.StatusMethodNotAllowed {
+		return fmt.Errorf("deletion is disabled, storage.delete.enabled must be set in the config of registry: %v", err)
+	}
+	return err
+}
@@ -17,34 +17,144 @@ package factory
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/labring/sealos/pkg/runtime"
-	"github.com/labring/sealos/pkg/runtime/k3s"
-	"github.com/labring/sealos/pkg/runtime/kubernetes"
-	"github.com/labring/sealos/pkg/runtime/kubernetes/types"
 	"github.com/labring/sealos/pkg/types/v1beta1"
 )
 
+// Distribution is the registration of a kubernetes distribution.
+type Distribution struct {
+	// New creates the runtime of the cluster, cfg is nil or created by NewConfig/ParseConfig.
+	New func(cluster *v1beta1.Cluster, cfg runtime.Config) (runtime.Interface, error)
+	// NewConfig returns an empty runtime config.
+	NewConfig func() runtime.Config
+	// ParseConfig parses the runtime config from the documents of a Clusterfile,
+	// it returns nil without error if no config of the distribution is found.
+	ParseConfig func(data []byte) (runtime.Config, error)
+	// DetectConfig is like ParseConfig, but returns nil if data may be a config of other distributions.
+	// It's used instead of ParseConfig if the distribution is unknown, ParseConfig is used if it's nil.
+	DetectConfig func(data []byte) (runtime.Config, error)
+}
+
+var (
+	mu            sync.RWMutex
+	distributions = map[string]Distribution{}
+	// the registration order of the primary names
+	order []string
+)
+
+// Register makes a distribution available by the names, the first name is the primary one.
+// It panics if any name is registered twice or the registration is incomplete.
+func Register(d Distribution, names ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if d.New == nil || d.NewConfig == nil {
+		panic("runtime factory: New and NewConfig must be provided")
+	}
+	if len(names) == 0 {
+		panic("runtime factory: distribution name is required")
+	}
+	for _, name := range names {
+		if _, ok := distributions[name]; ok {
+			panic(fmt.Sprintf("runtime factory: distribution %q is registered twice", name))
+		}
+		distributions[name] = d
+	}
+	order = append(order, names[0])
+}
+
+// Distributions returns the registered distribution names, aliases included.
+func Distributions() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	ret := make([]string, 0, len(distributions))
+	for name := range distributions {
+		if name != "" {
+			ret = append(ret, name)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func getDistribution(name string) (Distribution, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	d, ok := distributions[name]
+	if !ok {
+		return Distribution{}, fmt.Errorf("unsupported distribution %s", name)
+	}
+	return d, nil
+}
+
 func New(cluster *v1beta1.Cluster, cfg runtime.Config) (runtime.Interface, error) {
 	if cluster == nil {
 		return nil, errors.New("cluster cannot be null")
 	}
-	distribution := cluster.GetDistribution()
-	switch distribution {
-	case kubernetes.Distribution, "kubeadm", "":
-		return kubernetes.New(cluster, cfg)
-	case k3s.Distribution:
-		return k3s.New(cluster, cfg)
+	d, err := getDistribution(cluster.GetDistribution())
+	if err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("unsupported distribution %s", distribution)
+	return d.New(cluster, cfg)
 }
 
 func NewRuntimeConfig(distribution string) (runtime.Config, error) {
-	switch distribution {
-	case kubernetes.Distribution, "kubeadm", "":
-		return types.NewKubeadmConfig(), nil
-	case k3s.Distribution:
-		return &k3s.Config{}, nil
+	d, err := getDistribution(distribution)
+	if err != nil {
+		return nil, err
+	}
+	return d.NewConfig(), nil
+}
+
+// ParseRuntimeConfig parses the runtime config of the distribution from data. If the distribution
+// is empty or unknown, the config is detected by all the registered distributions, and it fails
+// if more than one of them matched instead of guessing. The default distribution parses the config
+// if none of them matched.
+func ParseRuntimeConfig(distribution string, data []byte) (runtime.Config, error) {
+	if distribution != "" {
+		if d, err := getDistribution(distribution); err == nil && d.ParseConfig != nil {
+			return d.ParseConfig(data)
+		}
+	}
+	mu.RLock()
+	parsers := make(map[string]func([]byte) (runtime.Config, error), len(order))
+	for _, name := range order {
+		d := distributions[name]
+		if d.DetectConfig != nil {
+			parsers[name] = d.DetectConfig
+		} else if d.ParseConfig != nil {
+			parsers[name] = d.ParseConfig
+		}
+	}
+	mu.RUnlock()
+	var (
+		matched []string
+		ret     runtime.Config
+	)
+	for name, parse := range parsers {
+		cfg, err := parse(data)
+		if err != nil {
+			return nil, err
+		}
+		if cfg != nil {
+			matched = append(matched, name)
+			ret = cfg
+		}
+	}
+	switch len(matched) {
+	case 0:
+		// fall back to the default distribution
+		if d, err := getDistribution(""); err == nil && d.ParseConfig != nil {
+			return d.ParseConfig(data)
+		}
+		return nil, nil
+	case 1:
+		return ret, nil
 	}
-	return nil, fmt.Errorf("unsupported distribution %s", distribution)
+	sort.Strings(matched)
+	return nil, fmt.Errorf("the runtime config can be a config of %s, the distribution of cluster is required to tell which one it is",
+		strings.Join(matched, ", "))
 }
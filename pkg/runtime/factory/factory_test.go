@@ -0,0 +1,65 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package factory
+
+import (
+	"testing"
+
+	"github.com/labring/sealos/pkg/runtime/k3s"
+	"github.com/labring/sealos/pkg/runtime/kubernetes/types"
+	"github.com/labring/sealos/pkg/runtime/rke2"
+)
+
+func TestParseRuntimeConfig(t *testing.T) {
+	flatConfig := []byte("cluster-cidr:\n- 10.42.0.0/16\ntls-san:\n- sealos.io\n")
+	k3sConfig := []byte("cluster-cidr:\n- 10.42.0.0/16\nflannel-backend: vxlan\n")
+	rke2Config := []byte("cluster-cidr:\n- 10.42.0.0/16\ncni:\n- calico\nprofile: cis\n")
+	kubeadmConfig := []byte(`apiVersion: kubeadm.k8s.io/v1beta3
+kind: ClusterConfiguration
+networking:
+  podSubnet: 100.64.0.0/10
+`)
+	tests := []struct {
+		name         string
+		distribution string
+		data         []byte
+		check        func(cfg any) bool
+	}{
+		{"rke2", rke2.Distribution, flatConfig, func(cfg any) bool { _, ok := cfg.(*rke2.Config); return ok }},
+		{"k3s", k3s.Distribution, flatConfig, func(cfg any) bool { _, ok := cfg.(*k3s.Config); return ok }},
+		{"unknown k3s config", "", k3sConfig, func(cfg any) bool { _, ok := cfg.(*k3s.Config); return ok }},
+		{"unknown rke2 config", "", rke2Config, func(cfg any) bool { _, ok := cfg.(*rke2.Config); return ok }},
+		{"unknown kubeadm config", "", kubeadmConfig, func(cfg any) bool { _, ok := cfg.(*types.KubeadmConfig); return ok }},
+		{"kubernetes", "kubeadm", kubeadmConfig, func(cfg any) bool { _, ok := cfg.(*types.KubeadmConfig); return ok }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := ParseRuntimeConfig(tt.distribution, tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(cfg) {
+				t.Errorf("unexpected config type %T", cfg)
+			}
+		})
+	}
+	// the fields shared by k3s and rke2 can't tell which one it is
+	if _, err := ParseRuntimeConfig("", flatConfig); err == nil {
+		t.Error("expected error for the flat config of unknown distribution")
+	}
+	if _, err := NewRuntimeConfig("k0s"); err == nil {
+		t.Error("expected error for unregistered distribution")
+	}
+}
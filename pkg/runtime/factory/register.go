@@ -0,0 +1,91 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package factory
+
+import (
+	"reflect"
+
+	"github.com/labring/sealos/pkg/runtime"
+	"github.com/labring/sealos/pkg/runtime/decode"
+	"github.com/labring/sealos/pkg/runtime/k3s"
+	"github.com/labring/sealos/pkg/runtime/kubernetes"
+	"github.com/labring/sealos/pkg/runtime/kubernetes/types"
+	"github.com/labring/sealos/pkg/runtime/rke2"
+	"github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+// the flat configs of k3s and rke2 are detected by the fields which are unknown to the other one
+func init() {
+	Register(Distribution{
+		New: func(cluster *v1beta1.Cluster, cfg runtime.Config) (runtime.Interface, error) {
+			return k3s.New(cluster, cfg)
+		},
+		NewConfig: func() runtime.Config { return &k3s.Config{} },
+		ParseConfig: func(data []byte) (runtime.Config, error) {
+			// data may contain the multiple documents of kubeadm, which are not a k3s config
+			if cfg, _ := k3s.ParseConfig(data); cfg != nil {
+				return cfg, nil
+			}
+			return nil, nil
+		},
+		DetectConfig: func(data []byte) (runtime.Config, error) {
+			if cfg, _ := k3s.ParseConfigStrict(data); cfg != nil {
+				return cfg, nil
+			}
+			return nil, nil
+		},
+	}, k3s.Distribution)
+
+	Register(Distribution{
+		New: func(cluster *v1beta1.Cluster, cfg runtime.Config) (runtime.Interface, error) {
+			return rke2.New(cluster, cfg)
+		},
+		NewConfig: func() runtime.Config { return &rke2.Config{} },
+		ParseConfig: func(data []byte) (runtime.Config, error) {
+			if cfg, _ := rke2.ParseConfig(data); cfg != nil {
+				return cfg, nil
+			}
+			return nil, nil
+		},
+		DetectConfig: func(data []byte) (runtime.Config, error) {
+			if cfg, _ := rke2.ParseConfigStrict(data); cfg != nil {
+				return cfg, nil
+			}
+			return nil, nil
+		},
+	}, rke2.Distribution)
+
+	Register(Distribution{
+		New: func(cluster *v1beta1.Cluster, cfg runtime.Config) (runtime.Interface, error) {
+			return kubernetes.New(cluster, cfg)
+		},
+		NewConfig: func() runtime.Config { return types.NewKubeadmConfig() },
+		ParseConfig: func(data []byte) (runtime.Config, error) {
+			cfg, err := types.LoadKubeadmConfigs(string(data), false, decode.CRDFromString)
+			if err != nil || cfg == nil {
+				return nil, err
+			}
+			return cfg, nil
+		},
+		DetectConfig: func(data []byte) (runtime.Config, error) {
+			cfg, err := types.LoadKubeadmConfigs(string(data), false, decode.CRDFromString)
+			// none of the kubeadm documents is found
+			if err != nil || cfg == nil || reflect.DeepEqual(cfg, &types.KubeadmConfig{}) {
+				return nil, err
+			}
+			return cfg, nil
+		},
+	}, kubernetes.Distribution, "kubeadm", "")
+}
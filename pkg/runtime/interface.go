@@ -14,6 +14,12 @@
 
 package runtime
 
+import (
+	"time"
+
+	"github.com/labring/sealos/pkg/cert"
+)
+
 type Interface interface {
 	Ruler
 	Init() error
@@ -29,10 +35,21 @@ type Ruler interface {
 }
 
 type CertManager interface {
-	Renew() error
+	// CheckExpiration returns the expiry of the certs in the local workdir and on all masters.
+	CheckExpiration() ([]cert.Expiration, error)
+	// Renew renews the certs which expire within before.
+	Renew(before time.Duration) error
 	UpdateCertSANs(certSANs []string) error
 }
 
+// EtcdManager is implemented by runtimes which own a stacked etcd on the masters.
+type EtcdManager interface {
+	// SnapshotSave takes a snapshot from master0 and writes it into the local file.
+	SnapshotSave(file string) error
+	// SnapshotRestore restores the local snapshot file on all masters.
+	SnapshotRestore(file string) error
+}
+
 type Config interface {
 	GetComponents() []any
 }
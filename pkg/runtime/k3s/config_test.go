@@ -0,0 +1,104 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k3s
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/exec"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+func newTestK3s(t *testing.T, config *Config, execer exec.Interface) *K3s {
+	constants.DefaultRuntimeRootDir = t.TempDir()
+	constants.DefaultClusterRootFsDir = t.TempDir()
+	cluster := &v2.Cluster{}
+	cluster.Name = "default"
+	cluster.Spec.Hosts = []v2.Host{
+		{IPS: []string{"192.168.0.2:22", "192.168.0.3:22"}, Roles: []string{v2.MASTER}},
+		{IPS: []string{"192.168.0.4:22"}, Roles: []string{v2.NODE}},
+	}
+	return newK3s(cluster, config, execer)
+}
+
+func TestGetRawJoinConfig(t *testing.T) {
+	k := newTestK3s(t, &Config{ClusterCIDR: []string{"100.64.0.0/10"}}, nil)
+	tokenFile := filepath.Join(k.pathResolver.ConfigsPath(), "token")
+	agentTokenFile := filepath.Join(k.pathResolver.ConfigsPath(), "agent-token")
+	serverURL := fmt.Sprintf("https://%s:%d", constants.DefaultAPIServerDomain, constants.DefaultAPIServerPort)
+
+	tests := []struct {
+		name      string
+		runMode   string
+		callbacks []callback
+		check     func(t *testing.T, c *Config)
+	}{
+		{
+			name:    "server",
+			runMode: serverMode,
+			check: func(t *testing.T, c *Config) {
+				if !reflect.DeepEqual(c.ClusterCIDR, []string{"100.64.0.0/10"}) {
+					t.Errorf("provided cluster-cidr is not merged: %v", c.ClusterCIDR)
+				}
+				if !reflect.DeepEqual(c.ClusterDNS, []string{"10.96.0.10"}) {
+					t.Errorf("unexpected cluster-dns %v", c.ClusterDNS)
+				}
+				wantSans := []string{"127.0.0.1", constants.DefaultAPIServerDomain, "10.103.97.2", "192.168.0.2", "192.168.0.3", "10.96.0.0/16", constants.DefaultDNSDomain}
+				if !reflect.DeepEqual(c.TLSSan, wantSans) {
+					t.Errorf("tls-san = %v, want %v", c.TLSSan, wantSans)
+				}
+				if c.TokenFile != tokenFile || c.AgentTokenFile != agentTokenFile {
+					t.Errorf("unexpected token files %s, %s", c.TokenFile, c.AgentTokenFile)
+				}
+				if c.ServerURL != serverURL {
+					t.Errorf("unexpected server %s", c.ServerURL)
+				}
+			},
+		},
+		{
+			name:      "agent",
+			runMode:   agentMode,
+			callbacks: []callback{removeServerFlagsInAgentConfig},
+			check: func(t *testing.T, c *Config) {
+				if len(c.ClusterCIDR) != 0 || len(c.TLSSan) != 0 || c.AgentTokenFile != "" {
+					t.Errorf("server flags are left in agent config: %+v", c)
+				}
+				if c.TokenFile != agentTokenFile {
+					t.Errorf("unexpected token file %s", c.TokenFile)
+				}
+				if !reflect.DeepEqual(c.ExtraKubeProxyArgs, []string{"ipvs-exclude-cidrs=10.103.97.2/32", "proxy-mode=ipvs"}) {
+					t.Errorf("unexpected kube-proxy-arg %v", c.ExtraKubeProxyArgs)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := k.getRawJoinConfig(tt.runMode, tt.callbacks...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, err := ParseConfig(raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, c)
+		})
+	}
+}
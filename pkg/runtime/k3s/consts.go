@@ -14,6 +14,8 @@
 
 package k3s
 
+import "github.com/labring/sealos/pkg/runtime/rancher"
+
 const Distribution = "k3s"
 
 const (
@@ -32,3 +34,18 @@ const (
 	serverMode = "server"
 	agentMode  = "agent"
 )
+
+var distribution = rancher.Distribution{
+	Name:                Distribution,
+	ConfigPath:          defaultK3sConfigPath,
+	KubeConfigPath:      defaultKubeConfigPath,
+	KubeConfigServer:    "https://0.0.0.0",
+	StaticPodPath:       k3sEtcStaticPod,
+	BinaryPath:          "/usr/bin/k3s",
+	Kubectl:             "kubectl",
+	ServerService:       "k3s",
+	AgentService:        "k3s",
+	InitFilename:        defaultInitFilename,
+	JoinMastersFilename: defaultJoinMastersFilename,
+	JoinNodesFilename:   defaultJoinNodesFilename,
+}
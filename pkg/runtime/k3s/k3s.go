@@ -15,17 +15,10 @@
 package k3s
 
 import (
-	"context"
-	"fmt"
-
-	"golang.org/x/sync/errgroup"
-
-	"github.com/labring/sealos/pkg/utils/iputils"
-	"github.com/labring/sealos/pkg/utils/strings"
-
 	"github.com/labring/sealos/pkg/constants"
 	"github.com/labring/sealos/pkg/env"
 	"github.com/labring/sealos/pkg/exec"
+	"github.com/labring/sealos/pkg/runtime/rancher"
 	"github.com/labring/sealos/pkg/ssh"
 	v2 "github.com/labring/sealos/pkg/types/v1beta1"
 	"github.com/labring/sealos/pkg/utils/logger"
@@ -38,8 +31,7 @@ type K3s struct {
 
 	envInterface env.Interface
 	pathResolver constants.PathResolver
-	remoteUtil   *ssh.Remote
-	execer       exec.Interface
+	bootstrapper *rancher.Bootstrapper
 }
 
 func New(cluster *v2.Cluster, config any) (*K3s, error) {
@@ -48,17 +40,20 @@ func New(cluster *v2.Cluster, config any) (*K3s, error) {
 	if err != nil {
 		return nil, err
 	}
+	return newK3s(cluster, config, execer), nil
+}
+
+func newK3s(cluster *v2.Cluster, config any, execer exec.Interface) *K3s {
 	k := &K3s{
 		cluster:      cluster,
 		pathResolver: constants.NewPathResolver(cluster.GetName()),
-		execer:       execer,
 		envInterface: env.NewEnvProcessor(cluster),
-		remoteUtil:   ssh.NewRemoteFromSSH(cluster.GetName(), execer),
 	}
 	if v, ok := config.(*Config); ok {
 		k.config = v
 	}
-	return k, nil
+	k.bootstrapper = rancher.New(distribution, cluster, execer, k.getAPIServerPort)
+	return k
 }
 
 func (k *K3s) Init() error {
@@ -66,13 +61,7 @@ func (k *K3s) Init() error {
 }
 
 func (k *K3s) Reset() error {
-	if err := k.resetNodes(k.cluster.GetNodeIPAndPortList()); err != nil {
-		logger.Error("resetting nodes: %v", err)
-	}
-	if err := k.resetNodes(k.cluster.GetMasterIPAndPortList()); err != nil {
-		logger.Error("resetting masters: %v", err)
-	}
-	return nil
+	return k.bootstrapper.Reset()
 }
 
 func (k *K3s) ScaleUp(masters []string, nodes []string) error {
@@ -92,22 +81,11 @@ func (k *K3s) ScaleUp(masters []string, nodes []string) error {
 }
 
 func (k *K3s) ScaleDown(masters []string, nodes []string) error {
-	if len(masters) != 0 {
-		logger.Info("master %s will be deleted", masters)
-		if err := k.removeNodes(masters); err != nil {
-			return err
-		}
-	}
-	if len(nodes) != 0 {
-		logger.Info("worker %s will be deleted", nodes)
-		return k.removeNodes(nodes)
-	}
-	return nil
+	return k.bootstrapper.ScaleDown(masters, nodes)
 }
 
 func (k *K3s) Upgrade(version string) error {
-	logger.Info("trying to upgrade to version %s", version)
-	return nil
+	return k.bootstrapper.Upgrade(version)
 }
 
 func (k *K3s) GetRawConfig() ([]byte, error) {
@@ -122,34 +100,5 @@ func (k *K3s) GetRawConfig() ([]byte, error) {
 }
 
 func (k *K3s) SyncNodeIPVS(mastersIPList, nodeIPList []string) error {
-	apiPort := k.getAPIServerPort()
-	mastersIPList = strings.RemoveDuplicate(mastersIPList)
-	masters := make([]string, 0)
-	for _, master := range mastersIPList {
-		masters = append(masters, fmt.Sprintf("%s:%d", iputils.GetHostIP(master), apiPort))
-	}
-	image := k.cluster.GetLvscareImage()
-	eg, _ := errgroup.WithContext(context.Background())
-	for _, node := range nodeIPList {
-		node := node
-		eg.Go(func() error {
-			logger.Info("start to sync lvscare static pod to node: %s master: %+v", node, masters)
-			err := k.remoteUtil.StaticPod(node, k.getVipAndPort(), constants.LvsCareStaticPodName, image, masters, k3sEtcStaticPod, "--health-status", "401")
-			if err != nil {
-				return fmt.Errorf("update lvscare static pod failed %s %v", node, err)
-			}
-			return nil
-		})
-	}
-	return eg.Wait()
-}
-
-func (k *K3s) runPipelines(phase string, pipelines ...func() error) error {
-	logger.Info("starting %s", phase)
-	for i := range pipelines {
-		if err := pipelines[i](); err != nil {
-			return fmt.Errorf("failed to %s: %v", phase, err)
-		}
-	}
-	return nil
+	return k.bootstrapper.SyncNodeIPVS(mastersIPList, nodeIPList)
 }
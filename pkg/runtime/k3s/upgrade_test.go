@@ -0,0 +1,147 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k3s
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slices"
+
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+// fakeExecer records the commands run on the hosts, the nodes report the target
+// version as soon as they are restarted.
+type fakeExecer struct {
+	version string
+	cmds    []string
+}
+
+func (f *fakeExecer) record(host, cmd string) {
+	f.cmds = append(f.cmds, fmt.Sprintf("%s: %s", host, cmd))
+}
+
+func (f *fakeExecer) Copy(host, src, dst string) error {
+	f.record(host, fmt.Sprintf("copy %s %s", src, dst))
+	return nil
+}
+
+func (f *fakeExecer) Fetch(host, src, dst string) error {
+	f.record(host, fmt.Sprintf("fetch %s %s", src, dst))
+	return nil
+}
+
+func (f *fakeExecer) CmdAsync(host string, cmds ...string) error {
+	for _, cmd := range cmds {
+		f.record(host, cmd)
+	}
+	return nil
+}
+
+func (f *fakeExecer) CmdAsyncWithContext(_ context.Context, host string, cmds ...string) error {
+	return f.CmdAsync(host, cmds...)
+}
+
+func (f *fakeExecer) Cmd(host, cmd string) ([]byte, error) {
+	out, err := f.CmdToString(host, cmd, "")
+	return []byte(out), err
+}
+
+func (f *fakeExecer) CmdToString(host, cmd, _ string) (string, error) {
+	f.record(host, cmd)
+	switch {
+	case strings.HasSuffix(cmd, " hostname"):
+		return "Node-" + strings.NewReplacer(".", "-", ":", "-").Replace(host), nil
+	case strings.Contains(cmd, "kubeletVersion"):
+		return f.version, nil
+	}
+	return "", fmt.Errorf("command %s on %s return nil", cmd, host)
+}
+
+func (f *fakeExecer) Ping(string) error { return nil }
+
+func TestUpgrade(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		version string
+		wantErr bool
+		// hosts upgraded in order
+		upgraded []string
+	}{
+		{
+			name:     "patch release",
+			current:  "v1.25.6+k3s1",
+			version:  "v1.25.7+k3s1",
+			upgraded: []string{"192.168.0.2:22", "192.168.0.3:22", "192.168.0.4:22"},
+		},
+		{
+			name:     "minor release",
+			current:  "v1.25.6+k3s1",
+			version:  "v1.26.3+k3s1",
+			upgraded: []string{"192.168.0.2:22", "192.168.0.3:22", "192.168.0.4:22"},
+		},
+		{
+			name:     "k3s release of the same kubernetes version",
+			current:  "v1.25.6+k3s1",
+			version:  "v1.25.6+k3s2",
+			upgraded: []string{"192.168.0.2:22", "192.168.0.3:22", "192.168.0.4:22"},
+		},
+		{name: "same version", current: "v1.25.6+k3s1", version: "v1.25.6+k3s1"},
+		{name: "older version", current: "v1.25.6+k3s1", version: "v1.24.10+k3s1", wantErr: true},
+		{name: "across two minor releases", current: "v1.25.6+k3s1", version: "v1.27.1+k3s1", wantErr: true},
+		{name: "invalid version", current: "v1.25.6+k3s1", version: "latest", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			execer := &fakeExecer{version: tt.version}
+			k := newTestK3s(t, nil, execer)
+			k.cluster.Status.Mounts = []v2.MountImage{{
+				Type:   v2.RootfsImage,
+				Labels: map[string]string{v2.ImageKubeVersionKey: tt.current},
+			}}
+			if err := k.Upgrade(tt.version); (err != nil) != tt.wantErr {
+				t.Fatalf("Upgrade() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			master0 := k.cluster.GetMaster0IPAndPort()
+			install := fmt.Sprintf("cp -rf %s /usr/bin/k3s", filepath.Join(k.pathResolver.RootFSBinPath(), "k3s"))
+			var drained, installed []string
+			for _, cmd := range execer.cmds {
+				host, c, _ := strings.Cut(cmd, ": ")
+				switch {
+				case host == master0 && strings.HasPrefix(c, "kubectl drain "):
+					drained = append(drained, strings.Fields(c)[2])
+				case c == install:
+					installed = append(installed, host)
+				}
+			}
+			var wantDrained []string
+			for _, host := range tt.upgraded {
+				wantDrained = append(wantDrained, "node-"+strings.NewReplacer(".", "-", ":", "-").Replace(host))
+			}
+			if !slices.Equal(drained, wantDrained) {
+				t.Errorf("drained nodes %v, want %v", drained, wantDrained)
+			}
+			if !slices.Equal(installed, tt.upgraded) {
+				t.Errorf("k3s is installed on %v, want %v", installed, tt.upgraded)
+			}
+		})
+	}
+}
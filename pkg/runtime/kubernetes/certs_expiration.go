@@ -0,0 +1,282 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/labring/sealos/pkg/cert"
+	"github.com/labring/sealos/pkg/utils/logger"
+	stringsutil "github.com/labring/sealos/pkg/utils/strings"
+)
+
+const (
+	localCertHost = "local"
+
+	catIfExistsCmd      = "if [ -f %[1]s ]; then cat %[1]s; fi"
+	renewCertCmd        = "kubeadm certs renew %s"
+	restartStaticPodCmd = "for p in $(crictl pods -q --namespace kube-system --name '^%[1]s-'); do crictl --timeout=10s stopp $p && crictl rmp $p; done"
+	countRunningCmd     = "crictl ps -q --state running --name '^%s$' | wc -l"
+)
+
+// kubeadmCert is a cert of the control plane, name is the same as the argument of kubeadm certs renew.
+type kubeadmCert struct {
+	name string
+	// path is relative to /etc/kubernetes
+	path       string
+	kubeConfig bool
+	renewable  bool
+	// staticPods are restarted to load the renewed cert
+	staticPods []string
+}
+
+var kubeadmCerts = []kubeadmCert{
+	{name: "ca", path: "pki/ca.crt"},
+	{name: "front-proxy-ca", path: "pki/front-proxy-ca.crt"},
+	{name: "etcd-ca", path: "pki/etcd/ca.crt"},
+	{name: "apiserver", path: "pki/apiserver.crt", renewable: true, staticPods: []string{"kube-apiserver"}},
+	{name: "apiserver-kubelet-client", path: "pki/apiserver-kubelet-client.crt", renewable: true, staticPods: []string{"kube-apiserver"}},
+	{name: "apiserver-etcd-client", path: "pki/apiserver-etcd-client.crt", renewable: true, staticPods: []string{"kube-apiserver"}},
+	{name: "front-proxy-client", path: "pki/front-proxy-client.crt", renewable: true, staticPods: []string{"kube-apiserver"}},
+	{name: "etcd-server", path: "pki/etcd/server.crt", renewable: true, staticPods: []string{"etcd"}},
+	{name: "etcd-peer", path: "pki/etcd/peer.crt", renewable: true, staticPods: []string{"etcd"}},
+	{name: "etcd-healthcheck-client", path: "pki/etcd/healthcheck-client.crt", renewable: true},
+	{name: AdminConf, path: AdminConf, kubeConfig: true, renewable: true},
+	{name: ControllerConf, path: ControllerConf, kubeConfig: true, renewable: true, staticPods: []string{"kube-controller-manager"}},
+	{name: SchedulerConf, path: SchedulerConf, kubeConfig: true, renewable: true, staticPods: []string{"kube-scheduler"}},
+	// kubelet client cert is rotated by kubelet itself
+	{name: KubeletConf, path: KubeletConf, kubeConfig: true},
+}
+
+func findKubeadmCert(name string) (kubeadmCert, bool) {
+	for _, c := range kubeadmCerts {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return kubeadmCert{}, false
+}
+
+// CheckExpiration returns the expiry of the certs in the local pki dir and on all masters.
+func (k *KubeadmRuntime) CheckExpiration() ([]cert.Expiration, error) {
+	ret, err := cert.LocalExpirations(localCertHost, k.pathResolver.PkiPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read local certs: %v", err)
+	}
+	var mu sync.Mutex
+	eg, _ := errgroup.WithContext(context.Background())
+	for _, master := range k.getMasterIPAndPortList() {
+		m := master
+		eg.Go(func() error {
+			list, err := k.masterExpirations(m)
+			if err != nil {
+				return fmt.Errorf("failed to check certs on %s: %v", m, err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			ret = append(ret, list...)
+			return nil
+		})
+	}
+	if err = eg.Wait(); err != nil {
+		return nil, err
+	}
+	cert.SortExpirations(ret)
+	return ret, nil
+}
+
+func (k *KubeadmRuntime) readRemoteFile(host, file string) ([]byte, error) {
+	return k.execer.Cmd(host, fmt.Sprintf(catIfExistsCmd, file))
+}
+
+func (k *KubeadmRuntime) masterExpirations(master string) ([]cert.Expiration, error) {
+	var ret []cert.Expiration
+	for _, kc := range kubeadmCerts {
+		file := path.Join(kubernetesEtc, kc.path)
+		data, err := k.readRemoteFile(master, file)
+		if err != nil {
+			return nil, err
+		}
+		// external etcd has no etcd certs on masters
+		if len(strings.TrimSpace(string(data))) == 0 {
+			logger.Debug("cert %s not found on %s, skip it", file, master)
+			continue
+		}
+		var c *cert.Expiration
+		if kc.kubeConfig {
+			c, err = k.kubeConfigExpiration(master, kc.name, file, data)
+		} else {
+			c, err = parseExpiration(master, kc.name, file, data)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if c == nil {
+			continue
+		}
+		c.Renewable = kc.renewable
+		ret = append(ret, *c)
+	}
+	return ret, nil
+}
+
+func (k *KubeadmRuntime) kubeConfigExpiration(master, name, file string, data []byte) (*cert.Expiration, error) {
+	c, certFile, err := cert.ParseKubeConfigCert(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig %s: %v", file, err)
+	}
+	if c != nil {
+		e := cert.NewExpiration(master, name, file, c)
+		return &e, nil
+	}
+	// the cert is referenced by path, eg. /var/lib/kubelet/pki/kubelet-client-current.pem
+	if data, err = k.readRemoteFile(master, certFile); err != nil {
+		return nil, err
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		logger.Debug("cert %s referenced by %s not found on %s, skip it", certFile, file, master)
+		return nil, nil
+	}
+	return parseExpiration(master, name, certFile, data)
+}
+
+func parseExpiration(host, name, file string, data []byte) (*cert.Expiration, error) {
+	c, err := cert.ParseCertPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cert %s: %v", file, err)
+	}
+	e := cert.NewExpiration(host, name, file, c)
+	return &e, nil
+}
+
+// Renew renews the renewable certs which expire within before on all masters, the masters are processed one
+// at a time and the static pods using the renewed certs are restarted before moving to the next one.
+func (k *KubeadmRuntime) Renew(before time.Duration) error {
+	expirations, err := k.CheckExpiration()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	due := make(map[string][]string)
+	for _, e := range expirations {
+		if e.Host == localCertHost || !e.Renewable || !e.ExpiresWithin(now, before) {
+			continue
+		}
+		due[e.Host] = append(due[e.Host], e.Name)
+	}
+	if len(due) == 0 {
+		logger.Info("no certificate expires within %s, nothing to renew", before)
+		return nil
+	}
+	master0 := k.getMaster0IPAndPort()
+	for _, master := range k.getMasterIPAndPortList() {
+		names, ok := due[master]
+		if !ok {
+			continue
+		}
+		if err = k.renewMasterCerts(master, names); err != nil {
+			return fmt.Errorf("failed to renew certs on %s: %v", master, err)
+		}
+		if master == master0 {
+			if err = k.syncRenewedCerts(names); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (k *KubeadmRuntime) renewMasterCerts(master string, names []string) error {
+	logger.Info("renewing certs %v on %s", names, master)
+	var staticPods []string
+	for _, name := range names {
+		if err := k.sshCmdAsync(master, fmt.Sprintf(renewCertCmd, name)); err != nil {
+			return err
+		}
+		kc, _ := findKubeadmCert(name)
+		staticPods = append(staticPods, kc.staticPods...)
+		if name == AdminConf {
+			if err := k.copyMasterKubeConfig(master); err != nil {
+				return err
+			}
+		}
+	}
+	for _, pod := range stringsutil.RemoveDuplicate(staticPods) {
+		logger.Info("restarting static pod %s on %s", pod, master)
+		if err := k.sshCmdAsync(master, fmt.Sprintf(restartStaticPodCmd, pod)); err != nil {
+			return err
+		}
+		if err := k.waitStaticPodRunning(master, pod); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncRenewedCerts fetches the renewed certs of master0 back to the local workdir and redistributes
+// the admin kubeconfig to the nodes.
+func (k *KubeadmRuntime) syncRenewedCerts(names []string) error {
+	master0 := k.getMaster0IPAndPort()
+	adminRenewed := false
+	for _, name := range names {
+		kc, _ := findKubeadmCert(name)
+		if kc.kubeConfig {
+			adminRenewed = adminRenewed || name == AdminConf
+			continue
+		}
+		rel := strings.TrimPrefix(kc.path, "pki/")
+		for _, ext := range []string{".crt", ".key"} {
+			src := path.Join(kubernetesEtcPKI, strings.TrimSuffix(rel, ".crt")+ext)
+			dst := path.Join(k.pathResolver.PkiPath(), strings.TrimSuffix(rel, ".crt")+ext)
+			if err := k.execer.Fetch(master0, src, dst); err != nil {
+				return fmt.Errorf("failed to fetch renewed cert %s: %v", src, err)
+			}
+		}
+	}
+	if !adminRenewed {
+		return nil
+	}
+	if err := k.execer.Fetch(master0, path.Join(kubernetesEtc, AdminConf), k.pathResolver.AdminFile()); err != nil {
+		return fmt.Errorf("failed to fetch renewed %s: %v", AdminConf, err)
+	}
+	// the cached client uses the old admin kubeconfig
+	k.cli = nil
+	if nodes := k.getNodeIPAndPortList(); len(nodes) > 0 {
+		logger.Info("redistributing kubeconfig to nodes %v", nodes)
+		return k.copyKubeConfigFileToNodes(nodes...)
+	}
+	return nil
+}
+
+func (k *KubeadmRuntime) waitStaticPodRunning(master, pod string) error {
+	timeout := time.Now().Add(waitControlPlaneTimeout)
+	for {
+		out, err := k.sshCmdToString(master, fmt.Sprintf(countRunningCmd, pod))
+		if err == nil && strings.TrimSpace(out) != "0" {
+			return nil
+		}
+		if time.Now().After(timeout) {
+			return fmt.Errorf("wait for static pod %s on %s to run timeout, last error: %v", pod, master, err)
+		}
+		time.Sleep(waitControlPlaneInterval)
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labring/sealos/pkg/etcd"
+	"github.com/labring/sealos/pkg/utils/iputils"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+const (
+	etcdSnapshotFileName = "sealos-snapshot.db"
+
+	// etcdctl runs in the etcd container if it's not installed on the host, the snapshot is saved into
+	// the data dir which is mounted into the container, and the certs dir is mounted as well.
+	saveEtcdSnapshotCmd = "if command -v etcdctl >/dev/null 2>&1; then ETCDCTL=etcdctl; " +
+		"else ETCDCTL=\"crictl exec $(crictl ps -q --name '^etcd$' | head -n 1) etcdctl\"; fi && " +
+		"$ETCDCTL --endpoints=%[1]s --cacert=%[2]s/ca.crt --cert=%[2]s/healthcheck-client.crt " +
+		"--key=%[2]s/healthcheck-client.key snapshot save %[3]s"
+
+	// static pods are moved out of the manifests dir to stop the control plane
+	stopStaticPodsCmd    = "mkdir -p %[2]s && mv -f %[1]s/*.yaml %[2]s/"
+	startStaticPodsCmd   = "if [ -d %[2]s ]; then mv -f %[2]s/*.yaml %[1]s/ && rmdir %[2]s; fi"
+	countControlPlaneCmd = "crictl ps -q --name '^(etcd|kube-apiserver)$' | wc -l"
+	replaceEtcdDataCmd   = "if [ -d %[1]s ]; then mv %[1]s %[1]s.bak-%[3]s; fi && mv %[2]s %[1]s"
+	revertEtcdDataCmd    = "if [ -d %[1]s.bak-%[2]s ]; then rm -rf %[1]s && mv %[1]s.bak-%[2]s %[1]s; fi"
+)
+
+var (
+	waitControlPlaneTimeout  = 5 * time.Minute
+	waitControlPlaneInterval = 5 * time.Second
+)
+
+// SnapshotSave saves the etcd snapshot on master0 and fetches it back to file, so that etcd
+// doesn't need to be reachable from the local host.
+func (k *KubeadmRuntime) SnapshotSave(file string) error {
+	if err := k.MergeKubeadmConfig(); err != nil {
+		return err
+	}
+	master0 := k.getMaster0IPAndPort()
+	endpoint := "https://" + iputils.JoinHostPort(k.getMaster0IP(), etcd.DefaultClientPort)
+	remoteFile := path.Join(k.getEtcdDataDir(), etcdSnapshotFileName)
+	logger.Info("start to save etcd snapshot on %s", master0)
+	if err := k.sshCmdAsync(master0, fmt.Sprintf(saveEtcdSnapshotCmd, endpoint, path.Join(kubernetesEtcPKI, "etcd"), remoteFile)); err != nil {
+		return fmt.Errorf("failed to save etcd snapshot on %s: %v", master0, err)
+	}
+	defer func() {
+		if err := k.sshCmdAsync(master0, "rm -f "+remoteFile); err != nil {
+			logger.Warn("failed to remove etcd snapshot %s on %s: %v", remoteFile, master0, err)
+		}
+	}()
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	// fetched to a temporary file first, the existing snapshot is replaced only if it's completed
+	tmpFile := file + ".part"
+	if err := os.RemoveAll(tmpFile); err != nil {
+		return err
+	}
+	if err := k.execer.Fetch(master0, remoteFile, tmpFile); err != nil {
+		return fmt.Errorf("failed to fetch etcd snapshot from %s: %v", master0, err)
+	}
+	return os.Rename(tmpFile, file)
+}
+
+func (k *KubeadmRuntime) SnapshotRestore(file string) error {
+	if err := k.MergeKubeadmConfig(); err != nil {
+		return err
+	}
+	masters := k.getMasterIPAndPortList()
+	members := make([]etcd.Member, 0, len(masters))
+	for _, master := range masters {
+		hostname, err := k.remoteUtil.Hostname(master)
+		if err != nil {
+			return err
+		}
+		// etcd member name is the node name, the lower case of hostname
+		members = append(members, etcd.Member{
+			Name:    strings.ToLower(hostname),
+			PeerURL: "https://" + net.JoinHostPort(iputils.GetHostIP(master), strconv.Itoa(etcd.DefaultPeerPort)),
+		})
+	}
+	if err := os.MkdirAll(k.pathResolver.TmpPath(), 0755); err != nil {
+		return err
+	}
+	tmpDir, err := os.MkdirTemp(k.pathResolver.TmpPath(), "etcd-restore-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dataDir := k.getEtcdDataDir()
+	stagingDir := dataDir + ".restore"
+	for i, master := range masters {
+		localDir := path.Join(tmpDir, members[i].Name)
+		logger.Info("restoring etcd snapshot for member %s", members[i].Name)
+		if err = etcd.RestoreSnapshot(file, localDir, members[i], members); err != nil {
+			return fmt.Errorf("failed to restore snapshot for member %s: %v", members[i].Name, err)
+		}
+		if err = k.sshCmdAsync(master, fmt.Sprintf("rm -rf %s", stagingDir)); err != nil {
+			return err
+		}
+		if err = k.sshCopy(master, localDir, stagingDir); err != nil {
+			return fmt.Errorf("failed to copy restored data to %s: %v", master, err)
+		}
+	}
+
+	manifestsBackup := path.Join(kubernetesEtc, "manifests.etcd-restore")
+	logger.Info("stopping control plane on masters %v", masters)
+	for _, master := range masters {
+		if err = k.sshCmdAsync(master, fmt.Sprintf(stopStaticPodsCmd, kubernetesEtcStaticPod, manifestsBackup)); err != nil {
+			k.startControlPlane(masters, manifestsBackup)
+			return fmt.Errorf("failed to stop control plane on %s: %v", master, err)
+		}
+	}
+	// the control plane is always started again, with the restored or the old data
+	defer k.startControlPlane(masters, manifestsBackup)
+	for _, master := range masters {
+		if err = k.waitControlPlaneStopped(master); err != nil {
+			return err
+		}
+	}
+	return k.replaceEtcdData(masters, dataDir, stagingDir, time.Now().Format("20060102150405"))
+}
+
+// replaceEtcdData replaces the etcd data dir with the restored one on all masters, the old data dirs are
+// moved back if it failed on any of them, so that etcd is never started with mixed member data.
+func (k *KubeadmRuntime) replaceEtcdData(masters []string, dataDir, stagingDir, suffix string) error {
+	for i, master := range masters {
+		if err := k.sshCmdAsync(master, fmt.Sprintf(replaceEtcdDataCmd, dataDir, stagingDir, suffix)); err != nil {
+			// the failed one may have been moved to the backup as well
+			for _, replaced := range masters[:i+1] {
+				if rErr := k.sshCmdAsync(replaced, fmt.Sprintf(revertEtcdDataCmd, dataDir, suffix)); rErr != nil {
+					logger.Error("failed to move the old etcd data dir back on %s, move %s.bak-%s to %s manually: %v", replaced, dataDir, suffix, dataDir, rErr)
+				}
+			}
+			return fmt.Errorf("failed to replace etcd data dir on %s, the old data dirs are moved back: %v", master, err)
+		}
+		logger.Info("etcd data dir on %s is restored, the old one is moved to %s.bak-%s", master, dataDir, suffix)
+	}
+	return nil
+}
+
+func (k *KubeadmRuntime) startControlPlane(masters []string, manifestsBackup string) {
+	for _, master := range masters {
+		if err := k.sshCmdAsync(master, fmt.Sprintf(startStaticPodsCmd, kubernetesEtcStaticPod, manifestsBackup)); err != nil {
+			logger.Error("failed to start control plane on %s, move the manifests back from %s manually: %v", master, manifestsBackup, err)
+		}
+	}
+}
+
+func (k *KubeadmRuntime) waitControlPlaneStopped(master string) error {
+	timeout := time.Now().Add(waitControlPlaneTimeout)
+	for {
+		out, err := k.sshCmdToString(master, countControlPlaneCmd)
+		if err == nil && strings.TrimSpace(out) == "0" {
+			return nil
+		}
+		if time.Now().After(timeout) {
+			return fmt.Errorf("wait for control plane on %s to stop timeout, last error: %v", master, err)
+		}
+		time.Sleep(waitControlPlaneInterval)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/labring/sealos/pkg/runtime/kubernetes/types"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+type fakeExecer struct {
+	cmds    []string
+	fetched string
+	// the commands run on the host containing failCmd fail
+	failHost string
+	failCmd  string
+}
+
+func (f *fakeExecer) Copy(string, string, string) error { return nil }
+func (f *fakeExecer) Fetch(host, src, dst string) error {
+	f.cmds = append(f.cmds, fmt.Sprintf("%s: fetch %s", host, src))
+	return os.WriteFile(dst, []byte(f.fetched), 0600)
+}
+func (f *fakeExecer) CmdAsync(host string, cmds ...string) error {
+	for _, cmd := range cmds {
+		f.cmds = append(f.cmds, fmt.Sprintf("%s: %s", host, cmd))
+		if host == f.failHost && strings.Contains(cmd, f.failCmd) {
+			return fmt.Errorf("failed to run %s", cmd)
+		}
+	}
+	return nil
+}
+func (f *fakeExecer) CmdAsyncWithContext(_ context.Context, host string, cmds ...string) error {
+	return f.CmdAsync(host, cmds...)
+}
+func (f *fakeExecer) Cmd(string, string) ([]byte, error)                 { return nil, nil }
+func (f *fakeExecer) CmdToString(string, string, string) (string, error) { return "", nil }
+func (f *fakeExecer) Ping(string) error                                  { return nil }
+
+func TestKubeadmRuntime_SnapshotSave(t *testing.T) {
+	// the kubeadm config is set below
+	mergeOnce.Do(func() {})
+	cluster := &v2.Cluster{}
+	cluster.Spec.Hosts = []v2.Host{{IPS: []string{"192.168.0.2:22"}, Roles: []string{v2.MASTER}}}
+	execer := &fakeExecer{fetched: "snapshot"}
+	k := &KubeadmRuntime{cluster: cluster, kubeadmConfig: types.NewKubeadmConfig(), execer: execer}
+
+	file := filepath.Join(t.TempDir(), "backup", "etcd.db")
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// the existing snapshot is replaced
+	if err := os.WriteFile(file, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.SnapshotSave(file); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "snapshot" {
+		t.Errorf("unexpected snapshot %q", data)
+	}
+	if len(execer.cmds) != 3 {
+		t.Fatalf("unexpected commands %q", execer.cmds)
+	}
+	// etcd is connected on master0, not from the local host
+	if save := execer.cmds[0]; !strings.HasPrefix(save, "192.168.0.2:22: ") ||
+		!strings.Contains(save, "--endpoints=https://192.168.0.2:2379") ||
+		!strings.Contains(save, "snapshot save /var/lib/etcd/"+etcdSnapshotFileName) {
+		t.Errorf("unexpected save command %q", save)
+	}
+	if want := "192.168.0.2:22: fetch /var/lib/etcd/" + etcdSnapshotFileName; execer.cmds[1] != want {
+		t.Errorf("got %q, want %q", execer.cmds[1], want)
+	}
+	if want := "192.168.0.2:22: rm -f /var/lib/etcd/" + etcdSnapshotFileName; execer.cmds[2] != want {
+		t.Errorf("got %q, want %q", execer.cmds[2], want)
+	}
+}
+
+func TestKubeadmRuntime_ReplaceEtcdData(t *testing.T) {
+	masters := []string{"192.168.0.2:22", "192.168.0.3:22", "192.168.0.4:22"}
+	replace := func(master string) string {
+		return master + ": " + fmt.Sprintf(replaceEtcdDataCmd, "/var/lib/etcd", "/var/lib/etcd.restore", "1")
+	}
+	revert := func(master string) string {
+		return master + ": " + fmt.Sprintf(revertEtcdDataCmd, "/var/lib/etcd", "1")
+	}
+
+	execer := &fakeExecer{}
+	k := &KubeadmRuntime{execer: execer}
+	if err := k.replaceEtcdData(masters, "/var/lib/etcd", "/var/lib/etcd.restore", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{replace(masters[0]), replace(masters[1]), replace(masters[2])}; !reflect.DeepEqual(execer.cmds, want) {
+		t.Errorf("got commands %q, want %q", execer.cmds, want)
+	}
+
+	// the masters already switched and the failed one are moved back
+	execer = &fakeExecer{failHost: masters[1], failCmd: "/var/lib/etcd.restore"}
+	k = &KubeadmRuntime{execer: execer}
+	if err := k.replaceEtcdData(masters, "/var/lib/etcd", "/var/lib/etcd.restore", "1"); err == nil {
+		t.Fatal("expected error when the data dir failed to be replaced")
+	}
+	want := []string{replace(masters[0]), replace(masters[1]), revert(masters[0]), revert(masters[1])}
+	if !reflect.DeepEqual(execer.cmds, want) {
+		t.Errorf("got commands %q, want %q", execer.cmds, want)
+	}
+}
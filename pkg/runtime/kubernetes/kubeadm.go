@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"path"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -27,6 +28,7 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/util/json"
 	"k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm"
+	netutils "k8s.io/utils/net"
 
 	"github.com/labring/sealos/pkg/constants"
 	"github.com/labring/sealos/pkg/runtime/kubernetes/types"
@@ -118,6 +120,9 @@ func (k *KubeadmRuntime) MergeKubeadmConfig() error {
 			}
 			k.setKubeadmAPIVersion()
 			k.setFeatureGatesConfiguration()
+			if err := k.validateNetworking(); err != nil {
+				return err
+			}
 			return k.validateVIP(k.getVip())
 		}()
 	})
@@ -125,14 +130,38 @@ func (k *KubeadmRuntime) MergeKubeadmConfig() error {
 }
 
 func (k *KubeadmRuntime) validateVIP(ip string) error {
-	for k, sub := range map[string]string{
+	for field, sub := range map[string]string{
 		"podSubnet":     k.kubeadmConfig.ClusterConfiguration.Networking.PodSubnet,
 		"serviceSubnet": k.kubeadmConfig.ClusterConfiguration.Networking.ServiceSubnet,
 	} {
 		if contains, err := iputils.Contains(sub, ip); err != nil {
 			return err
 		} else if contains {
-			return fmt.Errorf("ensure IP %s is not in %s range", ip, k)
+			return fmt.Errorf("ensure IP %s is not in %s range", ip, field)
+		}
+	}
+	return nil
+}
+
+// validateNetworking checks the pod and service subnets are a single CIDR,
+// or a pair of IPv4 and IPv6 CIDRs for dual-stack, like 100.64.0.0/10,fd00:100:64::/48.
+func (k *KubeadmRuntime) validateNetworking() error {
+	for field, sub := range map[string]string{
+		"podSubnet":     k.kubeadmConfig.ClusterConfiguration.Networking.PodSubnet,
+		"serviceSubnet": k.kubeadmConfig.ClusterConfiguration.Networking.ServiceSubnet,
+	} {
+		if sub == "" {
+			continue
+		}
+		cidrs, err := netutils.ParseCIDRs(strings.Split(sub, ","))
+		if err != nil {
+			return fmt.Errorf("invalid %s %s: %v", field, sub, err)
+		}
+		if len(cidrs) > 2 {
+			return fmt.Errorf("invalid %s %s: at most one IPv4 and one IPv6 CIDR are allowed", field, sub)
+		}
+		if dualStack, err := netutils.IsDualStackCIDRs(cidrs); len(cidrs) == 2 && (err != nil || !dualStack) {
+			return fmt.Errorf("invalid %s %s: dual-stack requires one IPv4 and one IPv6 CIDR", field, sub)
 		}
 	}
 	return nil
@@ -154,7 +183,7 @@ func (k *KubeadmRuntime) getAPIServerPort() int32 {
 }
 
 func (k *KubeadmRuntime) getVipAndPort() string {
-	return fmt.Sprintf("%s:%d", k.getVip(), k.getAPIServerPort())
+	return iputils.JoinHostPort(k.getVip(), k.getAPIServerPort())
 }
 
 func (k *KubeadmRuntime) getAPIServerDomain() string {
@@ -374,7 +403,7 @@ func (k *KubeadmRuntime) setInitTaints() {
 
 func (k *KubeadmRuntime) setExcludeCIDRs() {
 	k.kubeadmConfig.KubeProxyConfiguration.IPVS.ExcludeCIDRs = append(
-		k.kubeadmConfig.KubeProxyConfiguration.IPVS.ExcludeCIDRs, fmt.Sprintf("%s/32", k.getVip()))
+		k.kubeadmConfig.KubeProxyConfiguration.IPVS.ExcludeCIDRs, iputils.HostCIDR(k.getVip()))
 	k.kubeadmConfig.KubeProxyConfiguration.IPVS.ExcludeCIDRs = stringsutil.RemoveDuplicate(k.kubeadmConfig.KubeProxyConfiguration.IPVS.ExcludeCIDRs)
 }
 
@@ -495,7 +524,7 @@ func (k *KubeadmRuntime) generateJoinMasterConfigs(masterIP string) ([]byte, err
 		return nil, err
 	}
 	k.setJoinAdvertiseAddress(iputils.GetHostIP(masterIP))
-	k.setAPIServerEndpoint(fmt.Sprintf("%s:%d", k.getMaster0IP(), k.getAPIServerPort()))
+	k.setAPIServerEndpoint(iputils.JoinHostPort(k.getMaster0IP(), k.getAPIServerPort()))
 
 	conversion, err := k.kubeadmConfig.ToConvertedKubeadmConfig()
 	if err != nil {
@@ -0,0 +1,68 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/labring/sealos/pkg/runtime/kubernetes/types"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+func TestKubeadmRuntime_validateNetworking(t *testing.T) {
+	tests := []struct {
+		name          string
+		podSubnet     string
+		serviceSubnet string
+		wantErr       bool
+	}{
+		{name: "empty"},
+		{name: "ipv4", podSubnet: "100.64.0.0/10", serviceSubnet: "10.96.0.0/22"},
+		{name: "ipv6", podSubnet: "fd00:100:64::/48", serviceSubnet: "fd00:10:96::/112"},
+		{name: "dual-stack", podSubnet: "100.64.0.0/10,fd00:100:64::/48", serviceSubnet: "fd00:10:96::/112,10.96.0.0/22"},
+		{name: "invalid cidr", podSubnet: "100.64.0.0/33", wantErr: true},
+		{name: "two ipv4 cidrs", podSubnet: "100.64.0.0/10,10.244.0.0/16", wantErr: true},
+		{name: "two ipv6 cidrs", serviceSubnet: "fd00:10:96::/112,fd00:10:97::/112", wantErr: true},
+		{name: "more than two cidrs", podSubnet: "100.64.0.0/10,fd00:100:64::/48,10.244.0.0/16", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := &KubeadmRuntime{kubeadmConfig: types.NewKubeadmConfig()}
+			k.kubeadmConfig.ClusterConfiguration.Networking.PodSubnet = tt.podSubnet
+			k.kubeadmConfig.ClusterConfiguration.Networking.ServiceSubnet = tt.serviceSubnet
+			if err := k.validateNetworking(); (err != nil) != tt.wantErr {
+				t.Errorf("validateNetworking() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKubeadmRuntime_getMaster0IPAPIServer(t *testing.T) {
+	tests := []struct {
+		master0 string
+		want    string
+	}{
+		{"192.168.0.2:22", "https://192.168.0.2:6443"},
+		{"[fd00::2]:22", "https://[fd00::2]:6443"},
+	}
+	for _, tt := range tests {
+		cluster := &v2.Cluster{}
+		cluster.Spec.Hosts = []v2.Host{{IPS: []string{tt.master0}, Roles: []string{v2.MASTER}}}
+		k := &KubeadmRuntime{cluster: cluster, kubeadmConfig: types.NewKubeadmConfig()}
+		if got := k.getMaster0IPAPIServer(); got != tt.want {
+			t.Errorf("getMaster0IPAPIServer() = %s, want %s", got, tt.want)
+		}
+	}
+}
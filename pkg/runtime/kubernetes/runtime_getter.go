@@ -55,7 +55,7 @@ func (k *KubeadmRuntime) getMasterIPList() []string {
 func (k *KubeadmRuntime) getMasterIPListAndHTTPSPort() []string {
 	masters := make([]string, 0)
 	for _, master := range k.getMasterIPList() {
-		masters = append(masters, fmt.Sprintf("%s:%d", master, k.getAPIServerPort()))
+		masters = append(masters, iputils.JoinHostPort(master, k.getAPIServerPort()))
 	}
 	return masters
 }
@@ -78,7 +78,7 @@ func (k *KubeadmRuntime) getMaster0IPAndPort() string {
 
 func (k *KubeadmRuntime) getMaster0IPAPIServer() string {
 	master0 := k.getMaster0IP()
-	return fmt.Sprintf("https://%s:%d", master0, k.getAPIServerPort())
+	return "https://" + iputils.JoinHostPort(master0, k.getAPIServerPort())
 }
 
 func (k *KubeadmRuntime) execIPVS(ip string, masters []string) error {
@@ -92,7 +92,7 @@ func (k *KubeadmRuntime) execIPVSClean(ip string) error {
 func (k *KubeadmRuntime) syncNodeIPVSYaml(masterIPs, nodesIPs []string) error {
 	masters := make([]string, 0)
 	for _, master := range masterIPs {
-		masters = append(masters, fmt.Sprintf("%s:%d", iputils.GetHostIP(master), k.getAPIServerPort()))
+		masters = append(masters, iputils.JoinHostPort(iputils.GetHostIP(master), k.getAPIServerPort()))
 	}
 
 	eg, _ := errgroup.WithContext(context.Background())
@@ -0,0 +1,220 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rancher
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/utils/file"
+	"github.com/labring/sealos/pkg/utils/iputils"
+	"github.com/labring/sealos/pkg/utils/logger"
+	"github.com/labring/sealos/pkg/utils/rand"
+	stringsutil "github.com/labring/sealos/pkg/utils/strings"
+)
+
+// InitMaster0 starts the first server, the init config is only generated if it does not exist yet.
+func (b *Bootstrapper) InitMaster0(initConfig func() ([]byte, error)) error {
+	master0 := b.cluster.GetMaster0IPAndPort()
+	return RunPipelines("init master0",
+		func() error { return b.generateAndSendTokenFiles(master0, TokenFile, AgentTokenFile) },
+		func() error { return b.generateAndSendInitConfig(initConfig) },
+		func() error { return b.enableService(master0, b.ServerService) },
+		b.pullKubeConfigFromMaster0,
+		func() error {
+			return b.remoteUtil.HostsAdd(master0, iputils.GetHostIP(master0), constants.DefaultAPIServerDomain)
+		},
+		func() error { return b.copyKubeConfigFileToNodes(master0) },
+	)
+}
+
+func (b *Bootstrapper) JoinMasters(masters []string, joinConfig []byte) error {
+	if err := b.writeConfig(b.JoinMastersFilename, joinConfig); err != nil {
+		return err
+	}
+	for _, master := range masters {
+		if err := b.joinMaster(master); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Bootstrapper) JoinNodes(nodes []string, joinConfig []byte) error {
+	if err := b.writeConfig(b.JoinNodesFilename, joinConfig); err != nil {
+		return err
+	}
+	for i := range nodes {
+		if err := b.joinNode(nodes[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Bootstrapper) writeConfig(filename string, raw []byte) error {
+	return file.WriteFile(filepath.Join(b.pathResolver.EtcPath(), filename), raw)
+}
+
+func (b *Bootstrapper) joinMaster(master string) error {
+	return RunPipelines(fmt.Sprintf("join master %s", master),
+		func() error {
+			// the rest masters are also running in agent mode, so agent-token file is needed.
+			return b.generateAndSendTokenFiles(master, TokenFile, AgentTokenFile)
+		},
+		func() error {
+			return b.execer.Copy(master, filepath.Join(b.pathResolver.EtcPath(), b.JoinMastersFilename), b.ConfigPath)
+		},
+		func() error { return b.enableService(master, b.ServerService) },
+		func() error {
+			return b.remoteUtil.HostsAdd(master, iputils.GetHostIP(master), constants.DefaultAPIServerDomain)
+		},
+		func() error { return b.copyKubeConfigFileToNodes(master) },
+	)
+}
+
+func (b *Bootstrapper) joinNode(node string) error {
+	return RunPipelines(fmt.Sprintf("join node %s", node),
+		func() error {
+			return b.remoteUtil.IPVS(node, b.getVipAndPort(), b.getMasterIPListAndHTTPSPort())
+		},
+		func() error { return b.generateAndSendTokenFiles(node, AgentTokenFile) },
+		func() error {
+			return b.execer.Copy(node, filepath.Join(b.pathResolver.EtcPath(), b.JoinNodesFilename), b.ConfigPath)
+		},
+		func() error { return b.enableService(node, b.AgentService) },
+		func() error { return b.copyKubeConfigFileToNodes(node) },
+	)
+}
+
+func (b *Bootstrapper) getMasterIPListAndHTTPSPort() []string {
+	apiPort := b.apiServerPort()
+	masters := make([]string, 0)
+	for _, master := range b.cluster.GetMasterIPList() {
+		masters = append(masters, iputils.JoinHostPort(master, apiPort))
+	}
+	return masters
+}
+
+func (b *Bootstrapper) getVipAndPort() string {
+	return iputils.JoinHostPort(b.cluster.GetVIP(), b.apiServerPort())
+}
+
+func (b *Bootstrapper) SyncNodeIPVS(mastersIPList, nodeIPList []string) error {
+	apiPort := b.apiServerPort()
+	mastersIPList = stringsutil.RemoveDuplicate(mastersIPList)
+	masters := make([]string, 0)
+	for _, master := range mastersIPList {
+		masters = append(masters, iputils.JoinHostPort(iputils.GetHostIP(master), apiPort))
+	}
+	image := b.cluster.GetLvscareImage()
+	eg, _ := errgroup.WithContext(context.Background())
+	for _, node := range nodeIPList {
+		node := node
+		eg.Go(func() error {
+			logger.Info("start to sync lvscare static pod to node: %s master: %+v", node, masters)
+			err := b.remoteUtil.StaticPod(node, b.getVipAndPort(), constants.LvsCareStaticPodName, image, masters, b.StaticPodPath, "--health-status", "401")
+			if err != nil {
+				return fmt.Errorf("update lvscare static pod failed %s %v", node, err)
+			}
+			return nil
+		})
+	}
+	return eg.Wait()
+}
+
+func (b *Bootstrapper) generateRandomTokenFileIfNotExists(filename string) (string, error) {
+	fp := filepath.Join(b.pathResolver.EtcPath(), filepath.Base(filename))
+	if !file.IsExist(fp) {
+		logger.Debug("token file %s not exists, create new one", fp)
+		token, err := rand.CreateCertificateKey()
+		if err != nil {
+			return "", err
+		}
+		return fp, file.WriteFile(fp, []byte(token))
+	}
+	return fp, nil
+}
+
+func (b *Bootstrapper) generateAndSendTokenFiles(host string, filenames ...string) error {
+	for _, filename := range filenames {
+		src, err := b.generateRandomTokenFileIfNotExists(filename)
+		if err != nil {
+			return fmt.Errorf("generate token: %v", err)
+		}
+		if err = b.execer.Copy(host, src, b.TokenPath(filename)); err != nil {
+			return fmt.Errorf("copy token file: %v", err)
+		}
+	}
+	return nil
+}
+
+func (b *Bootstrapper) generateAndSendInitConfig(initConfig func() ([]byte, error)) error {
+	src := filepath.Join(b.pathResolver.EtcPath(), b.InitFilename)
+	if !file.IsExist(src) {
+		raw, err := initConfig()
+		if err != nil {
+			return err
+		}
+		if err = file.WriteFile(src, raw); err != nil {
+			return err
+		}
+	}
+	return b.execer.Copy(b.cluster.GetMaster0IPAndPort(), src, b.ConfigPath)
+}
+
+func (b *Bootstrapper) enableService(host, service string) error {
+	logger.Info("enable %s service on %s", service, host)
+	if err := b.remoteUtil.InitSystem(host).ServiceEnable(service); err != nil {
+		return err
+	}
+	return b.remoteUtil.InitSystem(host).ServiceStart(service)
+}
+
+func (b *Bootstrapper) pullKubeConfigFromMaster0() error {
+	dest := b.pathResolver.AdminFile()
+	return b.execer.Fetch(b.cluster.GetMaster0IPAndPort(), b.KubeConfigPath, dest)
+}
+
+func (b *Bootstrapper) copyKubeConfigFileToNodes(hosts ...string) error {
+	src := b.pathResolver.AdminFile()
+	data, err := file.ReadAll(src)
+	if err != nil {
+		return errors.WithMessage(err, "read admin.config file failed")
+	}
+	newData := strings.ReplaceAll(string(data), b.KubeConfigServer, fmt.Sprintf("https://%s", constants.DefaultAPIServerDomain))
+	if err = file.WriteFile(src, []byte(newData)); err != nil {
+		return errors.WithMessage(err, "write admin.config file failed")
+	}
+	eg, _ := errgroup.WithContext(context.Background())
+	for _, node := range hosts {
+		node := node
+		eg.Go(func() error {
+			home, err := b.execer.CmdToString(node, "echo $HOME", "")
+			if err != nil {
+				return err
+			}
+			dst := filepath.Join(home, ".kube", "config")
+			return b.execer.Copy(node, src, dst)
+		})
+	}
+	return eg.Wait()
+}
@@ -0,0 +1,135 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rancher
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/emirpasic/gods/sets/linkedhashset"
+	"github.com/imdario/mergo"
+	netutils "k8s.io/utils/net"
+	sigsyaml "sigs.k8s.io/yaml"
+
+	"github.com/labring/sealos/pkg/constants"
+	fileutils "github.com/labring/sealos/pkg/utils/file"
+	"github.com/labring/sealos/pkg/utils/iputils"
+	"github.com/labring/sealos/pkg/utils/logger"
+	"github.com/labring/sealos/pkg/utils/yaml"
+)
+
+var defaultMergeOpts = []func(*mergo.Config){
+	mergo.WithOverride,
+}
+
+func merge[T any](dst, src *T) error {
+	if src == nil || dst == nil {
+		return nil
+	}
+	return mergo.Merge(dst, src, defaultMergeOpts...)
+}
+
+// Merge merges the config file in the rootfs and then the provided config into c.
+func Merge[T any](c *T, rootfsFile string, provided *T) *T {
+	if err := func() error {
+		if !fileutils.IsExist(rootfsFile) {
+			return nil
+		}
+		data, err := os.ReadFile(rootfsFile)
+		if err != nil {
+			return err
+		}
+		parseCfg, err := ParseConfig[T](data)
+		if err != nil {
+			return err
+		}
+		return merge(c, parseCfg)
+	}(); err != nil {
+		logger.Error("failed to merge in place config file: %v", err)
+	}
+	if err := merge(c, provided); err != nil {
+		logger.Error("failed to merge provide config: %v", err)
+	}
+	return c
+}
+
+// ParseConfig return nil if data structure is not matched
+func ParseConfig[T any](data []byte) (*T, error) {
+	var cfg T
+	if err := yaml.Unmarshal(bytes.NewBuffer(data), &cfg); err != nil {
+		return nil, err
+	}
+	out, err := yaml.Marshal(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	isNil, err := yaml.IsNil(out)
+	if err != nil {
+		return nil, err
+	}
+	if isNil {
+		return nil, nil
+	}
+	return &cfg, nil
+}
+
+// ParseConfigStrict is like ParseConfig, but fails if data has any field unknown to T,
+// so that the configs of k3s and rke2 can be told apart.
+func ParseConfigStrict[T any](data []byte) (*T, error) {
+	if err := sigsyaml.UnmarshalStrict(data, new(T)); err != nil {
+		return nil, err
+	}
+	return ParseConfig[T](data)
+}
+
+// KubeProxyArgs forces the ipvs mode of kube-proxy, and excludes the vip which is managed by lvscare.
+func KubeProxyArgs(args []string, vip string) []string {
+	kubeProxy := linkedhashset.New()
+	for _, v := range args {
+		kubeProxy.Add(v)
+	}
+	kubeProxy.Add(fmt.Sprintf("%s=%s", "ipvs-exclude-cidrs", iputils.HostCIDR(vip)))
+	kubeProxy.Add(fmt.Sprintf("%s=%s", "proxy-mode", "ipvs"))
+
+	var allArgs []string
+	for _, v := range kubeProxy.Values() {
+		allArgs = append(allArgs, v.(string))
+	}
+	return allArgs
+}
+
+// ClusterDNS returns the 10th address of the first service CIDR, nil if the CIDRs are invalid.
+func ClusterDNS(serviceCIDR []string) []string {
+	svcSubnetCIDR, err := netutils.ParseCIDRs(serviceCIDR)
+	if err != nil || len(svcSubnetCIDR) == 0 {
+		return nil
+	}
+	clusterDNS, err := netutils.GetIndexedIP(svcSubnetCIDR[0], 10)
+	if err != nil {
+		return nil
+	}
+	return []string{clusterDNS.String()}
+}
+
+// CertSANs returns the SANs of the apiserver certificate shared by all the servers.
+func (b *Bootstrapper) CertSANs() []string {
+	var certSans []string
+	certSans = append(certSans, "127.0.0.1")
+	certSans = append(certSans, constants.DefaultAPIServerDomain)
+	certSans = append(certSans, b.cluster.GetVIP())
+	certSans = append(certSans, iputils.GetHostIPs(b.cluster.GetMasterIPList())...)
+	return certSans
+}
@@ -0,0 +1,96 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rancher
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type testConfig struct {
+	ClusterCIDR []string `json:"cluster-cidr,omitempty"`
+	ServiceCIDR []string `json:"service-cidr,omitempty"`
+	BindAddress string   `json:"bind-address,omitempty"`
+}
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want *testConfig
+	}{
+		{"flat config", "cluster-cidr:\n- 10.42.0.0/16\n", &testConfig{ClusterCIDR: []string{"10.42.0.0/16"}}},
+		{"unmatched config", "apiVersion: kubeadm.k8s.io/v1beta3\nkind: ClusterConfiguration\n", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the documents of other struct are rejected with an error
+			got, _ := ParseConfig[testConfig]([]byte(tt.data))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseConfig() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	rootfsFile := filepath.Join(t.TempDir(), "rke2.yml")
+	if err := os.WriteFile(rootfsFile, []byte("cluster-cidr:\n- 100.64.0.0/10\nbind-address: 127.0.0.1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c := &testConfig{ClusterCIDR: []string{"10.42.0.0/16"}, ServiceCIDR: []string{"10.96.0.0/16"}, BindAddress: "0.0.0.0"}
+	got := Merge(c, rootfsFile, &testConfig{BindAddress: "192.168.0.2"})
+	want := &testConfig{ClusterCIDR: []string{"100.64.0.0/10"}, ServiceCIDR: []string{"10.96.0.0/16"}, BindAddress: "192.168.0.2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge() = %+v, want %+v", got, want)
+	}
+	if got = Merge(want, filepath.Join(t.TempDir(), "missing.yml"), nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge() without rootfs file and provided config = %+v, want %+v", got, want)
+	}
+}
+
+func TestKubeProxyArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		vip  string
+		want []string
+	}{
+		{[]string{"proxy-mode=ipvs", "v=2"}, "10.103.97.2", []string{"proxy-mode=ipvs", "v=2", "ipvs-exclude-cidrs=10.103.97.2/32"}},
+		{nil, "fd00::2", []string{"ipvs-exclude-cidrs=fd00::2/128", "proxy-mode=ipvs"}},
+	}
+	for _, tt := range tests {
+		if got := KubeProxyArgs(tt.args, tt.vip); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("KubeProxyArgs(%v, %s) = %v, want %v", tt.args, tt.vip, got, tt.want)
+		}
+	}
+}
+
+func TestClusterDNS(t *testing.T) {
+	tests := []struct {
+		serviceCIDR []string
+		want        []string
+	}{
+		{[]string{"10.96.0.0/16"}, []string{"10.96.0.10"}},
+		{[]string{"fd00:10:96::/112", "10.96.0.0/16"}, []string{"fd00:10:96::a"}},
+		{[]string{"invalid"}, nil},
+	}
+	for _, tt := range tests {
+		if got := ClusterDNS(tt.serviceCIDR); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ClusterDNS(%v) = %v, want %v", tt.serviceCIDR, got, tt.want)
+		}
+	}
+}
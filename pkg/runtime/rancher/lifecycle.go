@@ -0,0 +1,124 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rancher
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/exp/slices"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/labring/sealos/pkg/utils/iputils"
+	"github.com/labring/sealos/pkg/utils/logger"
+	"github.com/labring/sealos/pkg/utils/strings"
+)
+
+func (b *Bootstrapper) Reset() error {
+	if err := b.resetNodes(b.cluster.GetNodeIPAndPortList()); err != nil {
+		logger.Error("resetting nodes: %v", err)
+	}
+	if err := b.resetNodes(b.cluster.GetMasterIPAndPortList()); err != nil {
+		logger.Error("resetting masters: %v", err)
+	}
+	return nil
+}
+
+func (b *Bootstrapper) ScaleDown(masters []string, nodes []string) error {
+	if len(masters) != 0 {
+		logger.Info("master %s will be deleted", masters)
+		if err := b.removeNodes(masters); err != nil {
+			return err
+		}
+	}
+	if len(nodes) != 0 {
+		logger.Info("worker %s will be deleted", nodes)
+		return b.removeNodes(nodes)
+	}
+	return nil
+}
+
+func (b *Bootstrapper) resetNodes(nodes []string) error {
+	eg, _ := errgroup.WithContext(context.Background())
+	for i := range nodes {
+		node := nodes[i]
+		eg.Go(func() error {
+			return b.resetNode(node)
+		})
+	}
+	return eg.Wait()
+}
+
+func (b *Bootstrapper) removeNodes(nodes []string) error {
+	eg, _ := errgroup.WithContext(context.Background())
+	for i := range nodes {
+		node := nodes[i]
+		eg.Go(func() error {
+			if err := b.deleteNode(node); err != nil {
+				return err
+			}
+			return b.resetNode(node)
+		})
+	}
+	return eg.Wait()
+}
+
+func (b *Bootstrapper) resetNode(host string) error {
+	logger.Info("start to reset node: %s", host)
+	removeKubeConfig := "rm -rf $HOME/.kube"
+	if err := b.execer.CmdAsync(host, removeKubeConfig); err != nil {
+		logger.Error("failed to clean node, exec command %s failed, %v", removeKubeConfig, err)
+	}
+	if slices.Contains(b.cluster.GetNodeIPAndPortList(), host) {
+		if err := b.remoteUtil.IPVSClean(host, b.getVipAndPort()); err != nil {
+			logger.Error("failed to clear ipvs rules for node %s: %v", host, err)
+		}
+	}
+	if b.UninstallScript != "" {
+		// stops the services and removes the data, the script is missing if the host was never joined
+		uninstall := fmt.Sprintf("if command -v %[1]s >/dev/null 2>&1; then %[1]s; fi", b.UninstallScript)
+		if err := b.execer.CmdAsync(host, uninstall); err != nil {
+			logger.Error("failed to uninstall %s on %s: %v", b.Name, host, err)
+		}
+	}
+	return nil
+}
+
+// TODO: remove from API
+func (b *Bootstrapper) deleteNode(node string) error {
+	// there is no apiserver to remove the node from once the last master is gone
+	masterIPs := b.cluster.GetMasterIPList()
+	if slices.Contains(b.cluster.GetMasterIPAndPortList(), node) {
+		masterIPs = strings.RemoveFromSlice(masterIPs, iputils.GetHostIP(node))
+	}
+	if len(masterIPs) > 0 {
+		// TODO: do we need draining first?
+		if err := b.removeNode(node); err != nil {
+			logger.Warn(fmt.Errorf("delete nodes %s failed %v", node, err))
+		}
+	}
+	return nil
+}
+
+func (b *Bootstrapper) removeNode(ip string) error {
+	logger.Info("start to remove node from %s %s", b.Name, ip)
+	master0 := b.cluster.GetMaster0IPAndPort()
+	nodeName, err := b.execer.CmdToString(master0, fmt.Sprintf("%s get nodes -o wide | awk '$6==\"%s\" {print $1}'", b.Kubectl, iputils.GetHostIP(ip)), "")
+	if err != nil {
+		return fmt.Errorf("cannot get node with ip address %s: %v", ip, err)
+	}
+	logger.Debug("found node name is %s, we will delete it", nodeName)
+	return b.execer.CmdAsync(master0, fmt.Sprintf("%s delete node %s --ignore-not-found=true", b.Kubectl, nodeName))
+}
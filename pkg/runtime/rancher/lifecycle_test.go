@@ -0,0 +1,167 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rancher
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/labring/sealos/pkg/constants"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+type fakeExecer struct {
+	mu   sync.Mutex
+	cmds []string
+	// output of CmdToString, matched by the substring of the command
+	outputs map[string]string
+}
+
+func (f *fakeExecer) record(host, cmd string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cmds = append(f.cmds, fmt.Sprintf("%s: %s", host, cmd))
+}
+
+func (f *fakeExecer) Copy(host, src, dst string) error {
+	f.record(host, fmt.Sprintf("copy %s %s", src, dst))
+	return nil
+}
+
+func (f *fakeExecer) Fetch(host, src, dst string) error {
+	f.record(host, fmt.Sprintf("fetch %s %s", src, dst))
+	return nil
+}
+
+func (f *fakeExecer) CmdAsync(host string, cmds ...string) error {
+	for _, cmd := range cmds {
+		f.record(host, cmd)
+	}
+	return nil
+}
+
+func (f *fakeExecer) CmdAsyncWithContext(_ context.Context, host string, cmds ...string) error {
+	return f.CmdAsync(host, cmds...)
+}
+
+func (f *fakeExecer) Cmd(host, cmd string) ([]byte, error) {
+	out, err := f.CmdToString(host, cmd, "")
+	return []byte(out), err
+}
+
+func (f *fakeExecer) CmdToString(host, cmd, _ string) (string, error) {
+	f.record(host, cmd)
+	for k, v := range f.outputs {
+		if strings.Contains(cmd, k) {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("command %s on %s return nil", cmd, host)
+}
+
+func (f *fakeExecer) Ping(string) error { return nil }
+
+func (f *fakeExecer) commands(host string) []string {
+	var ret []string
+	for _, cmd := range f.cmds {
+		if strings.HasPrefix(cmd, host+": ") {
+			ret = append(ret, strings.TrimPrefix(cmd, host+": "))
+		}
+	}
+	return ret
+}
+
+func newTestCluster(masters, nodes []string) *v2.Cluster {
+	cluster := &v2.Cluster{}
+	cluster.Name = "default"
+	cluster.Spec.Hosts = []v2.Host{
+		{IPS: masters, Roles: []string{v2.MASTER}},
+		{IPS: nodes, Roles: []string{v2.NODE}},
+	}
+	return cluster
+}
+
+func newTestBootstrapper(t *testing.T, d Distribution, cluster *v2.Cluster, execer *fakeExecer) *Bootstrapper {
+	constants.DefaultRuntimeRootDir = t.TempDir()
+	constants.DefaultClusterRootFsDir = t.TempDir()
+	return New(d, cluster, execer, func() int { return 6443 })
+}
+
+func TestScaleDown(t *testing.T) {
+	tests := []struct {
+		name    string
+		masters []string
+		remove  []string
+		// whether the node is deleted through the apiserver on master0
+		deleted bool
+	}{
+		{"remove one of the masters", []string{"192.168.0.2:22", "192.168.0.3:22"}, []string{"192.168.0.3:22"}, true},
+		{"remove the last master", []string{"192.168.0.2:22"}, []string{"192.168.0.2:22"}, false},
+		{"remove ipv6 master", []string{"[fd00::2]:22", "[fd00::3]:22"}, []string{"[fd00::3]:22"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			execer := &fakeExecer{outputs: map[string]string{"get nodes": "node"}}
+			b := newTestBootstrapper(t, Distribution{Name: "rke2", Kubectl: "kubectl"}, newTestCluster(tt.masters, nil), execer)
+			if err := b.ScaleDown(tt.remove, nil); err != nil {
+				t.Fatal(err)
+			}
+			var deleted bool
+			for _, cmd := range execer.commands(tt.masters[0]) {
+				if strings.HasPrefix(cmd, "kubectl delete node") {
+					deleted = true
+				}
+			}
+			if deleted != tt.deleted {
+				t.Errorf("node deleted = %v, want %v, commands: %v", deleted, tt.deleted, execer.cmds)
+			}
+		})
+	}
+}
+
+func TestResetNode(t *testing.T) {
+	tests := []struct {
+		name      string
+		uninstall string
+		want      string
+	}{
+		{"without uninstall script", "", ""},
+		{"with uninstall script", "rke2-uninstall.sh", "if command -v rke2-uninstall.sh >/dev/null 2>&1; then rke2-uninstall.sh; fi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			execer := &fakeExecer{}
+			d := Distribution{Name: "rke2", UninstallScript: tt.uninstall}
+			b := newTestBootstrapper(t, d, newTestCluster([]string{"192.168.0.2:22"}, []string{"192.168.0.3:22"}), execer)
+			if err := b.Reset(); err != nil {
+				t.Fatal(err)
+			}
+			for _, host := range []string{"192.168.0.2:22", "192.168.0.3:22"} {
+				var found bool
+				for _, cmd := range execer.commands(host) {
+					if strings.Contains(cmd, "uninstall") {
+						found = cmd == tt.want
+					}
+				}
+				if found != (tt.want != "") {
+					t.Errorf("uninstall on %s: got commands %v, want %q", host, execer.commands(host), tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rancher
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"golang.org/x/exp/slices"
+
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/exec"
+	"github.com/labring/sealos/pkg/ssh"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+// token files are generated once per cluster and shared by all the servers and agents
+const (
+	TokenFile      = "token"
+	AgentTokenFile = "agent-token"
+)
+
+// Distribution is what differs between the distributions bootstrapped by the rancher supervisor,
+// a server is started on master0 and the rest servers and agents join it with the token files.
+type Distribution struct {
+	Name string
+	// the config file read by the server and agent on every host
+	ConfigPath string
+	// the kubeconfig written by the server and the apiserver address in it
+	KubeConfigPath   string
+	KubeConfigServer string
+	StaticPodPath    string
+	// the binary in the rootfs is installed to BinaryPath during upgrade
+	BinaryPath    string
+	Kubectl       string
+	ServerService string
+	AgentService  string
+	// optional, run when a host is reset
+	UninstallScript string
+
+	InitFilename        string
+	JoinMastersFilename string
+	JoinNodesFilename   string
+}
+
+type Bootstrapper struct {
+	Distribution
+
+	cluster       *v2.Cluster
+	pathResolver  constants.PathResolver
+	remoteUtil    *ssh.Remote
+	execer        exec.Interface
+	apiServerPort func() int
+}
+
+func New(d Distribution, cluster *v2.Cluster, execer exec.Interface, apiServerPort func() int) *Bootstrapper {
+	return &Bootstrapper{
+		Distribution:  d,
+		cluster:       cluster,
+		pathResolver:  constants.NewPathResolver(cluster.GetName()),
+		remoteUtil:    ssh.NewRemoteFromSSH(cluster.GetName(), execer),
+		execer:        execer,
+		apiServerPort: apiServerPort,
+	}
+}
+
+// TokenPath returns where the token file is sent to on the hosts.
+func (b *Bootstrapper) TokenPath(filename string) string {
+	return filepath.Join(b.pathResolver.ConfigsPath(), filename)
+}
+
+func (b *Bootstrapper) serviceOf(host string) string {
+	if slices.Contains(b.cluster.GetMasterIPAndPortList(), host) {
+		return b.ServerService
+	}
+	return b.AgentService
+}
+
+func RunPipelines(phase string, pipelines ...func() error) error {
+	logger.Info("starting %s", phase)
+	for i := range pipelines {
+		if err := pipelines[i](); err != nil {
+			return fmt.Errorf("failed to %s: %v", phase, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rancher
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+const (
+	drainNodeCmd        = "%s drain %s --ignore-daemonsets --delete-emptydir-data --force --timeout=%s"
+	uncordonNodeCmd     = "%s uncordon %s"
+	kubeletVersionCmd   = "%s get node %s -o jsonpath='{.status.nodeInfo.kubeletVersion}'"
+	installBinaryCmd    = "cp -rf %s %s"
+	defaultDrainTimeout = "5m"
+)
+
+var (
+	waitNodeReadyTimeout  = 5 * time.Minute
+	waitNodeRetryInterval = 5 * time.Second
+)
+
+// Upgrade upgrades master0 first and then the rest hosts one by one with the binary in the rootfs.
+func (b *Bootstrapper) Upgrade(version string) error {
+	currVersion := b.getVersionFromImage()
+	v0, err := semver.NewVersion(currVersion)
+	if err != nil {
+		return err
+	}
+	v1, err := semver.NewVersion(version)
+	if err != nil {
+		return err
+	}
+	if isSameVersion(currVersion, version) {
+		logger.Info("skip upgrade because of same version")
+		return nil
+	}
+	if v0.GreaterThan(v1) {
+		return fmt.Errorf("cannot apply an older version %s than %s", version, currVersion)
+	}
+	if v0.Minor()+1 < v1.Minor() {
+		return fmt.Errorf("cannot be upgraded across more than one minor releases, %s -> %s", currVersion, version)
+	}
+	logger.Info("start to upgrade %s cluster from %s to %s", b.Name, currVersion, version)
+	return b.upgradeCluster(version)
+}
+
+func (b *Bootstrapper) getVersionFromImage() string {
+	img := b.cluster.GetRootfsImage()
+	if img == nil || img.Labels == nil {
+		return ""
+	}
+	return img.Labels[v2.ImageKubeVersionKey]
+}
+
+func (b *Bootstrapper) upgradeCluster(version string) error {
+	master0 := b.cluster.GetMaster0IPAndPort()
+	hosts := []string{master0}
+	for _, host := range append(b.cluster.GetMasterIPAndPortList(), b.cluster.GetNodeIPAndPortList()...) {
+		if host == master0 {
+			continue
+		}
+		hosts = append(hosts, host)
+	}
+	for _, host := range hosts {
+		if err := b.upgradeNode(host, version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Bootstrapper) upgradeNode(host, version string) error {
+	hostname, err := b.remoteUtil.Hostname(host)
+	if err != nil {
+		return err
+	}
+	//default nodeName in k8s is the lower case of their hostname because of DNS protocol.
+	nodeName := strings.ToLower(hostname)
+	master0 := b.cluster.GetMaster0IPAndPort()
+	return RunPipelines(fmt.Sprintf("upgrade node %s to %s", nodeName, version),
+		func() error {
+			return b.execer.CmdAsync(master0, fmt.Sprintf(drainNodeCmd, b.Kubectl, nodeName, defaultDrainTimeout))
+		},
+		func() error {
+			return b.execer.CmdAsync(host, fmt.Sprintf(installBinaryCmd, filepath.Join(b.pathResolver.RootFSBinPath(), b.Name), b.BinaryPath))
+		},
+		func() error { return b.remoteUtil.InitSystem(host).ServiceRestart(b.serviceOf(host)) },
+		func() error { return b.waitForKubeletVersion(nodeName, version) },
+		func() error { return b.tryUncordonNode(nodeName) },
+	)
+}
+
+func (b *Bootstrapper) waitForKubeletVersion(nodeName, version string) error {
+	master0 := b.cluster.GetMaster0IPAndPort()
+	timeout := time.Now().Add(waitNodeReadyTimeout)
+	for {
+		current, err := b.execer.CmdToString(master0, fmt.Sprintf(kubeletVersionCmd, b.Kubectl, nodeName), "")
+		if err == nil && isSameVersion(strings.TrimSpace(current), version) {
+			logger.Info("node %s is running version %s", nodeName, version)
+			return nil
+		}
+		if time.Now().After(timeout) {
+			return fmt.Errorf("wait for node %s to report version %s timeout, current version is %q, last error: %v", nodeName, version, current, err)
+		}
+		time.Sleep(waitNodeRetryInterval)
+	}
+}
+
+func (b *Bootstrapper) tryUncordonNode(nodeName string) error {
+	master0 := b.cluster.GetMaster0IPAndPort()
+	timeout := time.Now().Add(waitNodeReadyTimeout)
+	for {
+		err := b.execer.CmdAsync(master0, fmt.Sprintf(uncordonNodeCmd, b.Kubectl, nodeName))
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(timeout) {
+			return fmt.Errorf("try uncordon node %s timeout: %v", nodeName, err)
+		}
+		time.Sleep(waitNodeRetryInterval)
+	}
+}
+
+// isSameVersion compares two versions including the build metadata,
+// e.g. v1.25.6+k3s1 is not the same as v1.25.6+k3s2.
+func isSameVersion(a, b string) bool {
+	va, err := semver.NewVersion(a)
+	if err != nil {
+		return false
+	}
+	vb, err := semver.NewVersion(b)
+	if err != nil {
+		return false
+	}
+	return va.Equal(vb) && va.Metadata() == vb.Metadata()
+}
@@ -0,0 +1,65 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rke2
+
+import (
+	"github.com/labring/sealos/pkg/utils/yaml"
+)
+
+func (r *RKE2) initMaster0() error {
+	return r.bootstrapper.InitMaster0(func() ([]byte, error) {
+		return r.getRawInitConfig(defaultingConfig, r.merge, r.sealosCfg, r.overrideCertSans, r.overrideServerConfig)
+	})
+}
+
+func (r *RKE2) joinMasters(masters []string) error {
+	raw, err := r.getRawJoinConfig(serverMode)
+	if err != nil {
+		return err
+	}
+	return r.bootstrapper.JoinMasters(masters, raw)
+}
+
+func (r *RKE2) joinNodes(nodes []string) error {
+	raw, err := r.getRawJoinConfig(agentMode, removeServerFlagsInAgentConfig)
+	if err != nil {
+		return err
+	}
+	return r.bootstrapper.JoinNodes(nodes, raw)
+}
+
+func (r *RKE2) getRawJoinConfig(runMode string, callbacks ...callback) ([]byte, error) {
+	defaultCallbacks := []callback{defaultingConfig, r.merge, r.sealosCfg, r.overrideCertSans}
+	switch runMode {
+	case serverMode:
+		defaultCallbacks = append(defaultCallbacks, r.overrideServerConfig)
+	case agentMode:
+		defaultCallbacks = append(defaultCallbacks, r.overrideAgentConfig)
+	}
+	defaultCallbacks = append(defaultCallbacks, r.setServerURL)
+	return r.getRawInitConfig(append(defaultCallbacks, callbacks...)...)
+}
+
+func (r *RKE2) getAPIServerPort() int {
+	return defaultAPIServerPort
+}
+
+func (r *RKE2) getRawInitConfig(callbacks ...callback) ([]byte, error) {
+	cfg, err := r.getInitConfig(callbacks...)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.MarshalConfigs(cfg)
+}
@@ -0,0 +1,111 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rke2
+
+import (
+	"path/filepath"
+
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/runtime/rancher"
+	"github.com/labring/sealos/pkg/utils/iputils"
+)
+
+func defaultingConfig(c *Config) *Config {
+	c.BindAddress = "0.0.0.0"
+	c.ClusterCIDR = []string{"10.42.0.0/16"}
+	c.ServiceCIDR = []string{"10.96.0.0/16"}
+	c.ClusterDomain = constants.DefaultDNSDomain
+	c.DisableCloudController = true
+	c.KubeConfigMode = "0644"
+	c.Disable = []string{"rke2-ingress-nginx", "rke2-metrics-server"}
+	defaultingAgentConfig(c)
+	return c
+}
+
+func defaultingAgentConfig(c *Config) *Config {
+	if c.AgentConfig == nil {
+		c.AgentConfig = &AgentConfig{}
+	}
+	c.AgentConfig.DataDir = defaultDataDir
+	c.AgentConfig.ExtraKubeProxyArgs = []string{}
+	c.AgentConfig.ExtraKubeletArgs = []string{}
+	c.AgentConfig.PrivateRegistry = defaultRegistryConfigPath
+	c.AgentConfig.Labels = []string{"sealos.io/distribution=rke2"}
+	return c
+}
+
+// avoid unknown flags
+func removeServerFlagsInAgentConfig(c *Config) *Config {
+	agentConfig := *c.AgentConfig
+	return &Config{AgentConfig: &agentConfig}
+}
+
+type callback func(*Config) *Config
+
+func (r *RKE2) merge(c *Config) *Config {
+	return rancher.Merge(c, filepath.Join(r.pathResolver.RootFSEtcPath(), defaultRootFsRKE2FileName), r.config)
+}
+
+func (r *RKE2) overrideCertSans(c *Config) *Config {
+	certSans := r.bootstrapper.CertSANs()
+	certSans = append(certSans, c.TLSSan...)
+	certSans = append(certSans, c.ClusterDomain)
+	c.TLSSan = certSans
+	return c
+}
+
+func (r *RKE2) sealosCfg(c *Config) *Config {
+	c.AgentConfig.ExtraKubeProxyArgs = rancher.KubeProxyArgs(c.AgentConfig.ExtraKubeProxyArgs, r.cluster.GetVIP())
+	return c
+}
+
+func (r *RKE2) overrideServerConfig(c *Config) *Config {
+	c.AgentConfig.TokenFile = r.bootstrapper.TokenPath(rancher.TokenFile)
+	c.AgentTokenFile = r.bootstrapper.TokenPath(rancher.AgentTokenFile)
+
+	if len(c.ClusterDNS) == 0 && len(c.ServiceCIDR) > 0 {
+		c.ClusterDNS = rancher.ClusterDNS(c.ServiceCIDR)
+	}
+	return c
+}
+
+func (r *RKE2) overrideAgentConfig(c *Config) *Config {
+	c.AgentConfig.TokenFile = r.bootstrapper.TokenPath(rancher.AgentTokenFile)
+	return c
+}
+
+// the new servers and agents register through the supervisor port of master0
+func (r *RKE2) setServerURL(c *Config) *Config {
+	c.AgentConfig.ServerURL = "https://" + iputils.JoinHostPort(r.cluster.GetMaster0IP(), defaultSupervisorPort)
+	return c
+}
+
+func (r *RKE2) getInitConfig(callbacks ...callback) (*Config, error) {
+	cfg := &Config{}
+	for i := range callbacks {
+		cfg = callbacks[i](cfg)
+	}
+	return cfg, nil
+}
+
+// ParseConfig return nil if data structure is not matched
+func ParseConfig(data []byte) (*Config, error) {
+	return rancher.ParseConfig[Config](data)
+}
+
+// ParseConfigStrict returns error if data has any field which is not a rke2 flag.
+func ParseConfigStrict(data []byte) (*Config, error) {
+	return rancher.ParseConfigStrict[Config](data)
+}
@@ -0,0 +1,136 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rke2
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/labring/sealos/pkg/constants"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+func newTestRKE2(t *testing.T, config *Config) *RKE2 {
+	constants.DefaultRuntimeRootDir = t.TempDir()
+	constants.DefaultClusterRootFsDir = t.TempDir()
+	cluster := &v2.Cluster{}
+	cluster.Name = "default"
+	cluster.Spec.Hosts = []v2.Host{
+		{IPS: []string{"192.168.0.2:22", "192.168.0.3:22"}, Roles: []string{v2.MASTER}},
+		{IPS: []string{"192.168.0.4:22"}, Roles: []string{v2.NODE}},
+	}
+	return newRKE2(cluster, config, nil)
+}
+
+func TestGetRawJoinConfig(t *testing.T) {
+	r := newTestRKE2(t, &Config{ClusterCIDR: []string{"100.64.0.0/10"}})
+	tokenFile := filepath.Join(r.pathResolver.ConfigsPath(), "token")
+	agentTokenFile := filepath.Join(r.pathResolver.ConfigsPath(), "agent-token")
+	kubeProxyArgs := []string{"ipvs-exclude-cidrs=10.103.97.2/32", "proxy-mode=ipvs"}
+
+	tests := []struct {
+		name      string
+		runMode   string
+		callbacks []callback
+		check     func(t *testing.T, c *Config)
+	}{
+		{
+			name:    "server",
+			runMode: serverMode,
+			check: func(t *testing.T, c *Config) {
+				if !reflect.DeepEqual(c.ClusterCIDR, []string{"100.64.0.0/10"}) {
+					t.Errorf("provided cluster-cidr is not merged: %v", c.ClusterCIDR)
+				}
+				if !reflect.DeepEqual(c.ClusterDNS, []string{"10.96.0.10"}) {
+					t.Errorf("unexpected cluster-dns %v", c.ClusterDNS)
+				}
+				wantSans := []string{"127.0.0.1", constants.DefaultAPIServerDomain, "10.103.97.2", "192.168.0.2", "192.168.0.3", constants.DefaultDNSDomain}
+				if !reflect.DeepEqual(c.TLSSan, wantSans) {
+					t.Errorf("tls-san = %v, want %v", c.TLSSan, wantSans)
+				}
+				if c.TokenFile != tokenFile || c.AgentTokenFile != agentTokenFile {
+					t.Errorf("unexpected token files %s, %s", c.TokenFile, c.AgentTokenFile)
+				}
+				if c.ServerURL != "https://192.168.0.2:9345" {
+					t.Errorf("unexpected server %s", c.ServerURL)
+				}
+				if !reflect.DeepEqual(c.ExtraKubeProxyArgs, kubeProxyArgs) {
+					t.Errorf("kube-proxy-arg = %v, want %v", c.ExtraKubeProxyArgs, kubeProxyArgs)
+				}
+			},
+		},
+		{
+			name:      "agent",
+			runMode:   agentMode,
+			callbacks: []callback{removeServerFlagsInAgentConfig},
+			check: func(t *testing.T, c *Config) {
+				if len(c.ClusterCIDR) != 0 || len(c.TLSSan) != 0 || c.AgentTokenFile != "" {
+					t.Errorf("server flags are left in agent config: %+v", c)
+				}
+				if c.TokenFile != agentTokenFile {
+					t.Errorf("unexpected token file %s", c.TokenFile)
+				}
+				if c.ServerURL != "https://192.168.0.2:9345" {
+					t.Errorf("unexpected server %s", c.ServerURL)
+				}
+				if !reflect.DeepEqual(c.Labels, []string{"sealos.io/distribution=rke2"}) {
+					t.Errorf("unexpected node-label %v", c.Labels)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := r.getRawJoinConfig(tt.runMode, tt.callbacks...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, err := ParseConfig(raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, c)
+		})
+	}
+}
+
+func TestGetRawConfig(t *testing.T) {
+	r := newTestRKE2(t, nil)
+	raw, err := r.GetRawConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := ParseConfig(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c == nil || c.ServerURL != "" || c.TokenFile == "" {
+		t.Errorf("unexpected init config %+v", c)
+	}
+}
+
+func TestSetServerURL(t *testing.T) {
+	r := newTestRKE2(t, nil)
+	for master0, want := range map[string]string{
+		"192.168.0.2:22": "https://192.168.0.2:9345",
+		"[fd00::2]:22":   "https://[fd00::2]:9345",
+	} {
+		r.cluster.Spec.Hosts[0].IPS = []string{master0}
+		if got := r.setServerURL(defaultingConfig(&Config{})).ServerURL; got != want {
+			t.Errorf("setServerURL() with master0 %s = %s, want %s", master0, got, want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rke2
+
+import "github.com/labring/sealos/pkg/runtime/rancher"
+
+const Distribution = "rke2"
+
+const (
+	defaultRKE2ConfigPath      = "/etc/rancher/rke2/config.yaml"
+	defaultRegistryConfigPath  = "/etc/rancher/rke2/registries.yaml"
+	defaultKubeConfigPath      = "/etc/rancher/rke2/rke2.yaml"
+	defaultDataDir             = "/var/lib/rancher/rke2"
+	defaultRootFsRKE2FileName  = "rke2.yml"
+	defaultInitFilename        = "rke2-init.yaml"
+	defaultJoinMastersFilename = "rke2-join-master.yaml"
+	defaultJoinNodesFilename   = "rke2-join-node.yaml"
+	rke2EtcStaticPod           = "/var/lib/rancher/rke2/agent/pod-manifests"
+	// rke2 servers listen on the supervisor port for the registration of new servers and agents
+	defaultSupervisorPort = 9345
+	defaultAPIServerPort  = 6443
+	// kubectl is not installed into PATH by rke2
+	kubectlCmd = "/var/lib/rancher/rke2/bin/kubectl --kubeconfig " + defaultKubeConfigPath
+	// the default location of the tarball installation
+	defaultBinaryPath = "/usr/local/bin/rke2"
+	uninstallScript   = "rke2-uninstall.sh"
+)
+
+const (
+	serverMode = "server"
+	agentMode  = "agent"
+
+	serverService = "rke2-server"
+	agentService  = "rke2-agent"
+)
+
+var distribution = rancher.Distribution{
+	Name:                Distribution,
+	ConfigPath:          defaultRKE2ConfigPath,
+	KubeConfigPath:      defaultKubeConfigPath,
+	KubeConfigServer:    "https://127.0.0.1",
+	StaticPodPath:       rke2EtcStaticPod,
+	BinaryPath:          defaultBinaryPath,
+	Kubectl:             kubectlCmd,
+	ServerService:       serverService,
+	AgentService:        agentService,
+	UninstallScript:     uninstallScript,
+	InitFilename:        defaultInitFilename,
+	JoinMastersFilename: defaultJoinMastersFilename,
+	JoinNodesFilename:   defaultJoinNodesFilename,
+}
@@ -0,0 +1,104 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rke2
+
+import (
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/env"
+	"github.com/labring/sealos/pkg/exec"
+	"github.com/labring/sealos/pkg/runtime/rancher"
+	"github.com/labring/sealos/pkg/ssh"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/logger"
+	"github.com/labring/sealos/pkg/utils/yaml"
+)
+
+type RKE2 struct {
+	cluster *v2.Cluster
+	config  *Config
+
+	envInterface env.Interface
+	pathResolver constants.PathResolver
+	bootstrapper *rancher.Bootstrapper
+}
+
+func New(cluster *v2.Cluster, config any) (*RKE2, error) {
+	sshClient := ssh.NewCacheClientFromCluster(cluster, true)
+	execer, err := exec.New(sshClient)
+	if err != nil {
+		return nil, err
+	}
+	return newRKE2(cluster, config, execer), nil
+}
+
+func newRKE2(cluster *v2.Cluster, config any, execer exec.Interface) *RKE2 {
+	r := &RKE2{
+		cluster:      cluster,
+		pathResolver: constants.NewPathResolver(cluster.GetName()),
+		envInterface: env.NewEnvProcessor(cluster),
+	}
+	if v, ok := config.(*Config); ok {
+		r.config = v
+	}
+	r.bootstrapper = rancher.New(distribution, cluster, execer, r.getAPIServerPort)
+	return r
+}
+
+func (r *RKE2) Init() error {
+	return r.initMaster0()
+}
+
+func (r *RKE2) Reset() error {
+	return r.bootstrapper.Reset()
+}
+
+func (r *RKE2) ScaleUp(masters []string, nodes []string) error {
+	if len(masters) != 0 {
+		logger.Info("%s will be added as master", masters)
+		if err := r.joinMasters(masters); err != nil {
+			return err
+		}
+	}
+	if len(nodes) != 0 {
+		logger.Info("%s will be added as worker", nodes)
+		if err := r.joinNodes(nodes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *RKE2) ScaleDown(masters []string, nodes []string) error {
+	return r.bootstrapper.ScaleDown(masters, nodes)
+}
+
+func (r *RKE2) Upgrade(version string) error {
+	return r.bootstrapper.Upgrade(version)
+}
+
+func (r *RKE2) GetRawConfig() ([]byte, error) {
+	defaultCallbacks := []callback{defaultingConfig, r.sealosCfg, r.overrideCertSans, r.overrideServerConfig}
+	cfg, err := r.getInitConfig(defaultCallbacks...)
+	if err != nil {
+		return nil, err
+	}
+	cluster := r.cluster.DeepCopy()
+	cluster.Status = v2.ClusterStatus{}
+	return yaml.MarshalConfigs(cluster, cfg)
+}
+
+func (r *RKE2) SyncNodeIPVS(mastersIPList, nodeIPList []string) error {
+	return r.bootstrapper.SyncNodeIPVS(mastersIPList, nodeIPList)
+}
@@ -0,0 +1,74 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rke2
+
+// from https://docs.rke2.io/reference/server_config, only the commonly used flags are listed,
+// the rest can be passed through the config file in the rootfs image.
+type Config struct {
+	AgentToken             string   `json:"agent-token,omitempty"`
+	AgentTokenFile         string   `json:"agent-token-file,omitempty"`
+	BindAddress            string   `json:"bind-address,omitempty"`
+	AdvertiseAddress       string   `json:"advertise-address,omitempty"`
+	TLSSan                 []string `json:"tls-san,omitempty"`
+	ClusterCIDR            []string `json:"cluster-cidr,omitempty"`
+	ServiceCIDR            []string `json:"service-cidr,omitempty"`
+	ServiceNodePortRange   string   `json:"service-node-port-range,omitempty"`
+	ClusterDNS             []string `json:"cluster-dns,omitempty"`
+	ClusterDomain          string   `json:"cluster-domain,omitempty"`
+	CNI                    []string `json:"cni,omitempty"`
+	Disable                []string `json:"disable,omitempty"`
+	DisableScheduler       bool     `json:"disable-scheduler,omitempty"`
+	DisableCloudController bool     `json:"disable-cloud-controller,omitempty"`
+	DisableKubeProxy       bool     `json:"disable-kube-proxy,omitempty"`
+	KubeConfigOutput       string   `json:"write-kubeconfig,omitempty"`
+	KubeConfigMode         string   `json:"write-kubeconfig-mode,omitempty"`
+	SystemDefaultRegistry  string   `json:"system-default-registry,omitempty"`
+	ExtraAPIArgs           []string `json:"kube-apiserver-arg,omitempty"`
+	ExtraEtcdArgs          []string `json:"etcd-arg,omitempty"`
+	ExtraSchedulerArgs     []string `json:"kube-scheduler-arg,omitempty"`
+	ExtraControllerArgs    []string `json:"kube-controller-manager-arg,omitempty"`
+	EtcdDisableSnapshots   bool     `json:"etcd-disable-snapshots,omitempty"`
+	EtcdSnapshotCron       string   `json:"etcd-snapshot-schedule-cron,omitempty"`
+	EtcdSnapshotRetention  int      `json:"etcd-snapshot-retention,omitempty"`
+	EtcdSnapshotDir        string   `json:"etcd-snapshot-dir,omitempty"`
+	EncryptSecrets         bool     `json:"secrets-encryption,omitempty"`
+	Profile                string   `json:"profile,omitempty"`
+	*AgentConfig
+}
+
+// AgentConfig is the config shared by servers and agents.
+type AgentConfig struct {
+	Token                    string   `json:"token,omitempty"`
+	TokenFile                string   `json:"token-file,omitempty"`
+	ServerURL                string   `json:"server,omitempty"`
+	DataDir                  string   `json:"data-dir,omitempty"`
+	NodeName                 string   `json:"node-name,omitempty"`
+	NodeIP                   []string `json:"node-ip,omitempty"`
+	NodeExternalIP           []string `json:"node-external-ip,omitempty"`
+	Labels                   []string `json:"node-label,omitempty"`
+	Taints                   []string `json:"node-taint,omitempty"`
+	PrivateRegistry          string   `json:"private-registry,omitempty"`
+	ContainerRuntimeEndpoint string   `json:"container-runtime-endpoint,omitempty"`
+	Snapshotter              string   `json:"snapshotter,omitempty"`
+	ResolvConf               string   `json:"resolv-conf,omitempty"`
+	ExtraKubeletArgs         []string `json:"kubelet-arg,omitempty"`
+	ExtraKubeProxyArgs       []string `json:"kube-proxy-arg,omitempty"`
+	EnableSELinux            bool     `json:"selinux,omitempty"`
+	ProtectKernelDefaults    bool     `json:"protect-kernel-defaults,omitempty"`
+}
+
+func (c *Config) GetComponents() []any {
+	return []any{c}
+}
@@ -0,0 +1,185 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ssh
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/containers/storage/pkg/homedir"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	fileutils "github.com/labring/sealos/pkg/utils/file"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+const (
+	identityAgentNone = "none"
+	certificateSuffix = "-cert.pub"
+)
+
+// the private keys under ~/.ssh which are tried if no private key is found, same as OpenSSH
+var defaultIdentityFiles = []string{"id_rsa", "id_ecdsa", "id_ed25519", "id_dsa"}
+
+var (
+	agentsMu sync.Mutex
+	// connections to the agents by socket, they are shared by the clients
+	agents = make(map[string]agent.ExtendedAgent)
+)
+
+// agentSocket returns the socket of ssh-agent, SSH_AUTH_SOCK is used if not set, empty if disabled.
+func (o *Option) agentSocket() string {
+	socket := o.identityAgent
+	switch {
+	case socket == identityAgentNone:
+		return ""
+	case socket == "":
+		return os.Getenv("SSH_AUTH_SOCK")
+	case strings.HasPrefix(socket, "~/"):
+		return filepath.Join(homedir.Get(), socket[2:])
+	}
+	return socket
+}
+
+func getAgent(socket string) (agent.ExtendedAgent, error) {
+	agentsMu.Lock()
+	defer agentsMu.Unlock()
+	if a, ok := agents[socket]; ok {
+		return a, nil
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	a := agent.NewClient(conn)
+	agents[socket] = a
+	return a, nil
+}
+
+// publicKeysCallback returns the signers of the agent and the private keys, the certificate signers
+// go before the plain ones.
+func (o *Option) publicKeysCallback() (ssh.AuthMethod, error) {
+	signers, err := o.keySigners()
+	if err != nil {
+		return nil, err
+	}
+	var a agent.ExtendedAgent
+	if socket := o.agentSocket(); socket != "" {
+		if a, err = getAgent(socket); err != nil {
+			logger.Warn("failed to connect ssh-agent %s, skip it: %v", socket, err)
+		}
+	}
+	if a == nil && len(signers) == 0 {
+		return nil, nil
+	}
+	return ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+		if a == nil {
+			return signers, nil
+		}
+		agentSigners, err := a.Signers()
+		if err != nil {
+			logger.Warn("failed to list the keys of ssh-agent: %v", err)
+			return signers, nil
+		}
+		return append(agentSigners, signers...), nil
+	}), nil
+}
+
+func (o *Option) keySigners() ([]ssh.Signer, error) {
+	if len(o.rawPrivateKeyData) > 0 {
+		signer, err := parsePrivateKey([]byte(o.rawPrivateKeyData), []byte(o.passphrase))
+		if err != nil {
+			return nil, err
+		}
+		return withCertificate(signer, o.certificate)
+	}
+	if len(o.privateKey) > 0 && fileutils.IsExist(o.privateKey) {
+		signer, err := parsePrivateKeyFile(o.privateKey, o.passphrase)
+		if err != nil {
+			return nil, err
+		}
+		cert := o.certificate
+		if cert == "" && fileutils.IsExist(o.privateKey+certificateSuffix) {
+			cert = o.privateKey + certificateSuffix
+		}
+		return withCertificate(signer, cert)
+	}
+	logger.Debug("private key %q not found, trying the default keys", o.privateKey)
+	var signers []ssh.Signer
+	for _, name := range defaultIdentityFiles {
+		keyFile := filepath.Join(homedir.Get(), ".ssh", name)
+		if !fileutils.IsExist(keyFile) {
+			continue
+		}
+		signer, err := parsePrivateKeyFile(keyFile, o.passphrase)
+		if err != nil {
+			logger.Debug("skip private key %s: %v", keyFile, err)
+			continue
+		}
+		cert := ""
+		if fileutils.IsExist(keyFile + certificateSuffix) {
+			cert = keyFile + certificateSuffix
+		}
+		list, err := withCertificate(signer, cert)
+		if err != nil {
+			logger.Debug("skip certificate of %s: %v", keyFile, err)
+			list = []ssh.Signer{signer}
+		}
+		signers = append(signers, list...)
+	}
+	return signers, nil
+}
+
+// withCertificate returns the certificate signer of the OpenSSH certificate file and the plain signer.
+func withCertificate(signer ssh.Signer, certFile string) ([]ssh.Signer, error) {
+	if certFile == "" {
+		return []ssh.Signer{signer}, nil
+	}
+	data, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate %s: %v", certFile, err)
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate %s: %v", certFile, err)
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an OpenSSH certificate", certFile)
+	}
+	certSigner, err := ssh.NewCertSigner(cert, signer)
+	if err != nil {
+		return nil, fmt.Errorf("certificate %s does not match the private key: %v", certFile, err)
+	}
+	return []ssh.Signer{certSigner, signer}, nil
+}
+
+// forwardAgentToRemote forwards the agent to the remote host of client if it's enabled, the forwarding
+// is requested on each session.
+func (c *Client) forwardAgentToRemote(client *ssh.Client) error {
+	if !c.Option.forwardAgent {
+		return nil
+	}
+	socket := c.agentSocket()
+	if socket == "" {
+		return fmt.Errorf("no ssh-agent to forward, SSH_AUTH_SOCK is not set")
+	}
+	return agent.ForwardToRemote(client, socket)
+}
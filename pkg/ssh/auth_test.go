@@ -0,0 +1,132 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func generateKey(t *testing.T) (ed25519.PrivateKey, ssh.Signer) {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv, signer
+}
+
+func TestAgentAuth(t *testing.T) {
+	t.Cleanup(pool.closeAll)
+	server := startTestServer(t, false)
+	priv, signer := generateKey(t)
+	server.authorizedKeys = []ssh.PublicKey{signer.PublicKey()}
+
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: priv}); err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() { _ = agent.ServeAgent(keyring, conn) }()
+		}
+	}()
+
+	t.Setenv("SSH_AUTH_SOCK", socket)
+	client, err := New(nil, WithPrivateKeyAndPhrase("", ""), WithHostKeyCallback(ssh.InsecureIgnoreHostKey()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, err := client.Cmd(server.addr, "hostname"); err != nil || string(out) != "ran hostname" {
+		t.Fatalf("unexpected output %q %v", out, err)
+	}
+
+	pool.closeAll()
+	disabled, err := New(nil, WithPrivateKeyAndPhrase("", ""), WithIdentityAgent(identityAgentNone),
+		WithHostKeyCallback(ssh.InsecureIgnoreHostKey()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = disabled.Cmd(server.addr, "hostname"); err == nil {
+		t.Error("expected authentication failure with the agent disabled")
+	}
+}
+
+func TestCertificateAuth(t *testing.T) {
+	t.Cleanup(pool.closeAll)
+	t.Setenv("SSH_AUTH_SOCK", "")
+	server := startTestServer(t, false)
+	_, ca := generateKey(t)
+	server.userCA = ca.PublicKey()
+
+	// the certificate next to the private key is used by default
+	priv, signer := generateKey(t)
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "id_ed25519")
+	if err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cert := &ssh.Certificate{
+		Key:             signer.PublicKey(),
+		CertType:        ssh.UserCert,
+		ValidPrincipals: []string{defaultUsername},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	if err = cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(keyFile+certificateSuffix, ssh.MarshalAuthorizedKey(cert), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := New(nil, WithPrivateKeyAndPhrase(keyFile, ""), WithHostKeyCallback(ssh.InsecureIgnoreHostKey()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, err := client.Cmd(server.addr, "hostname"); err != nil || string(out) != "ran hostname" {
+		t.Fatalf("unexpected output %q %v", out, err)
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.keyTypes) == 0 || server.keyTypes[0] != ssh.CertAlgoED25519v01 {
+		t.Errorf("expected certificate authentication, got %v", server.keyTypes)
+	}
+}
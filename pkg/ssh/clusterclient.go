@@ -16,6 +16,9 @@ package ssh
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"os"
 	"strings"
 	"sync"
 
@@ -50,6 +53,19 @@ func OverSSHConfig(original, override *v1beta1.SSH) {
 		if override.Port > 0 {
 			original.Port = override.Port
 		}
+		if override.ProxyJump != "" {
+			original.ProxyJump = override.ProxyJump
+		}
+		if override.IdentityAgent != "" {
+			original.IdentityAgent = override.IdentityAgent
+		}
+		if override.ForwardAgent != nil {
+			forwardAgent := *override.ForwardAgent
+			original.ForwardAgent = &forwardAgent
+		}
+		if override.Certificate != "" {
+			original.Certificate = override.Certificate
+		}
 	}
 }
 
@@ -70,6 +86,7 @@ func (cc *clusterClient) getSSHOptionForHost(host string) (*Option, error) {
 	}
 
 	opt := newOptionFromSSH(sshConfig, cc.isStdout)
+	opt.hostKeyCallback = HostKeyCallback(cc.cluster.Name)
 	cc.mutex.Lock()
 	cc.configs[host] = opt
 	cc.mutex.Unlock()
@@ -105,6 +122,18 @@ func (cc *clusterClient) Copy(host, src, dst string) error {
 	return client.Copy(host, src, dst)
 }
 
+func (cc *clusterClient) CopyFrom(host string, r io.Reader, dst string, mode os.FileMode) error {
+	client, err := cc.getClientForHost(host)
+	if err != nil {
+		return err
+	}
+	copier, ok := client.(StreamCopier)
+	if !ok {
+		return fmt.Errorf("copying a stream to %s is not supported", host)
+	}
+	return copier.CopyFrom(host, r, dst, mode)
+}
+
 func (cc *clusterClient) Fetch(host, src, dst string) error {
 	client, err := cc.getClientForHost(host)
 	if err != nil {
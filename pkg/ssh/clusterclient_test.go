@@ -0,0 +1,45 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ssh
+
+import (
+	"testing"
+
+	"github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+func TestOverSSHConfigForwardAgent(t *testing.T) {
+	enabled, disabled := true, false
+	tests := []struct {
+		name     string
+		original *bool
+		override *bool
+		want     *bool
+	}{
+		{"unset keeps the cluster's", &enabled, nil, &enabled},
+		{"host disables it", &enabled, &disabled, &disabled},
+		{"host enables it", nil, &enabled, &enabled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := &v1beta1.SSH{ForwardAgent: tt.original}
+			OverSSHConfig(original, &v1beta1.SSH{ForwardAgent: tt.override})
+			if (original.ForwardAgent == nil) != (tt.want == nil) ||
+				(tt.want != nil && *original.ForwardAgent != *tt.want) {
+				t.Errorf("ForwardAgent = %v, want %v", original.ForwardAgent, tt.want)
+			}
+		})
+	}
+}
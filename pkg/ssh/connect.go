@@ -17,42 +17,30 @@ package ssh
 import (
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"strings"
-	"sync"
 	"time"
 
-	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
 
 	"github.com/labring/sealos/pkg/utils/iputils"
 	"github.com/labring/sealos/pkg/utils/logger"
 )
 
-type HostClientMap struct {
-	ClientMap map[string]HostClient
-	Mux       sync.Mutex
-}
-
-type HostClient struct {
-	SSHClient  *ssh.Client
-	SftpClient *sftp.Client
-}
-
-var hostsClientMap = &HostClientMap{
-	ClientMap: make(map[string]HostClient),
-}
-
 func (c *Client) connect(host string) (*ssh.Client, error) {
 	ip, port := iputils.GetSSHHostIPAndPort(host)
 	addr := formalizeAddr(ip, port)
+	if len(c.proxyJump) > 0 {
+		return c.dialThroughJumpHosts(addr)
+	}
 	return ssh.Dial("tcp", addr, c.ClientConfig)
 }
 
 func newSession(client *ssh.Client) (*ssh.Session, error) {
 	session, err := client.NewSession()
 	if err != nil {
-		_ = client.Close()
 		return nil, err
 	}
 	modes := ssh.TerminalModes{
@@ -62,18 +50,76 @@ func newSession(client *ssh.Client) (*ssh.Session, error) {
 	}
 	if err := session.RequestPty("xterm", 80, 40, modes); err != nil {
 		_ = session.Close()
-		_ = client.Close()
 		return nil, err
 	}
 	return session, nil
 }
 
-func (c *Client) Connect(host string) (sshClient *ssh.Client, session *ssh.Session, err error) {
+// poolKey identifies the pooled connection of the user to host through the jump hosts,
+// the connections forwarding the agent are not shared with the others.
+func (c *Client) poolKey(host string) string {
+	ip, port := iputils.GetSSHHostIPAndPort(host)
+	key := fmt.Sprintf("%s@%s|%s", c.user, formalizeAddr(ip, port), strings.Join(c.proxyJump, ","))
+	if c.Option.forwardAgent {
+		key += "|forward-agent"
+	}
+	return key
+}
+
+func (c *Client) dial(host string) (client *ssh.Client, err error) {
 	err = exponentialBackOffRetry(defaultMaxRetry, time.Millisecond*100, 2, func() error {
-		sshClient, session, err = c.newClientAndSession(host)
+		client, err = c.connect(host)
 		return err
 	}, isErrorWorthRetry)
-	return
+	if err != nil {
+		return nil, err
+	}
+	if err = c.forwardAgentToRemote(client); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to forward ssh-agent to %s: %v", host, err)
+	}
+	return client, nil
+}
+
+// acquire returns the pooled connection to host, which must be released by pool.put.
+func (c *Client) acquire(host string) (*pooledConn, error) {
+	return pool.get(c.poolKey(host), func() (*ssh.Client, error) {
+		return c.dial(host)
+	})
+}
+
+// session opens a session on the pooled connection to host, the connection is established
+// again if it's broken. release must be called once the session is done.
+func (c *Client) session(host string) (*ssh.Session, func(), error) {
+	key := c.poolKey(host)
+	for i := 0; ; i++ {
+		pc, err := c.acquire(host)
+		if err != nil {
+			return nil, nil, err
+		}
+		pc.sessions <- struct{}{}
+		session, err := newSession(pc.client)
+		if err == nil && c.Option.forwardAgent {
+			if err = agent.RequestAgentForwarding(session); err != nil {
+				_ = session.Close()
+			}
+		}
+		if err == nil {
+			return session, func() {
+				_ = session.Close()
+				<-pc.sessions
+				pool.put(pc)
+			}, nil
+		}
+		<-pc.sessions
+		pool.put(pc)
+		if i > 0 || !isBrokenConnError(err) {
+			return nil, nil, err
+		}
+		logger.Debug("ssh connection to %s is broken, reconnecting: %v", host, err)
+		pool.discard(key, pc)
+		pool.reconnects.Add(1)
+	}
 }
 
 func isErrorWorthRetry(err error) bool {
@@ -102,15 +148,6 @@ func exponentialBackOffRetry(steps int, interval time.Duration, factor int,
 	return err
 }
 
-func (c *Client) newClientAndSession(host string) (*ssh.Client, *ssh.Session, error) {
-	sshClient, err := c.connect(host)
-	if err != nil {
-		return nil, nil, err
-	}
-	session, err := newSession(sshClient)
-	return sshClient, session, err
-}
-
 func parsePrivateKey(pemBytes []byte, password []byte) (ssh.Signer, error) {
 	if len(password) == 0 {
 		return ssh.ParsePrivateKey(pemBytes)
@@ -127,8 +164,5 @@ func parsePrivateKeyFile(filename string, password string) (ssh.Signer, error) {
 }
 
 func formalizeAddr(host, port string) string {
-	if !strings.Contains(host, ":") {
-		host = fmt.Sprintf("%s:%s", host, port)
-	}
-	return host
+	return net.JoinHostPort(iputils.GetHostIPAndPortOrDefault(host, port))
 }
@@ -0,0 +1,180 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/containers/storage/pkg/homedir"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"golang.org/x/exp/slices"
+
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/utils/file"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+const pinnedHostKeysFileName = "known_hosts"
+
+// InsecureSkipHostKeyCheck accepts any host key without verification.
+var InsecureSkipHostKeyCheck bool
+
+var (
+	verifiersMu sync.Mutex
+	verifiers   = make(map[string]*hostKeyVerifier)
+)
+
+// PinnedHostKeysFile returns the file of the host keys pinned for the cluster.
+func PinnedHostKeysFile(clusterName string) string {
+	return filepath.Join(constants.ClusterDir(clusterName), pinnedHostKeysFileName)
+}
+
+// HostKeyCallback returns a callback which verifies the host keys against ~/.ssh/known_hosts and the
+// keys pinned for the cluster. The key of an unknown host is pinned on first use, any mismatch fails.
+// If clusterName is empty, the unknown hosts are accepted without pinning.
+func HostKeyCallback(clusterName string) ssh.HostKeyCallback {
+	verifiersMu.Lock()
+	defer verifiersMu.Unlock()
+	v, ok := verifiers[clusterName]
+	if !ok {
+		v = &hostKeyVerifier{knownHostsFile: filepath.Join(homedir.Get(), ".ssh", "known_hosts")}
+		if clusterName != "" {
+			v.pinnedFile = PinnedHostKeysFile(clusterName)
+		}
+		verifiers[clusterName] = v
+	}
+	return v.verify
+}
+
+type hostKeyVerifier struct {
+	// mu serializes the appends to the pinned file by concurrent connections
+	mu             sync.Mutex
+	knownHostsFile string
+	pinnedFile     string
+}
+
+func (v *hostKeyVerifier) verify(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	if InsecureSkipHostKeyCheck {
+		return nil
+	}
+	known, err := checkKnownHosts(v.knownHostsFile, hostname, remote, key)
+	if known || err != nil {
+		return err
+	}
+	if v.pinnedFile == "" {
+		logger.Debug("host key of %s is unknown, accept it", hostname)
+		return nil
+	}
+	known, err = checkKnownHosts(v.pinnedFile, hostname, remote, key)
+	if known || err != nil {
+		return err
+	}
+	return v.pin(hostname, remote, key)
+}
+
+// pin appends the key of hostname to the pinned file, unless a concurrent connection to the same host
+// has pinned it in the meantime, in which case the key is checked against that one.
+func (v *hostKeyVerifier) pin(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if known, err := checkKnownHosts(v.pinnedFile, hostname, remote, key); known || err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(v.pinnedFile), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(v.pinnedFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to pin host key of %s: %v", hostname, err)
+	}
+	defer f.Close()
+	if _, err = fmt.Fprintln(f, knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key)); err != nil {
+		return fmt.Errorf("failed to pin host key of %s: %v", hostname, err)
+	}
+	logger.Info("pinned %s host key %s of %s", key.Type(), ssh.FingerprintSHA256(key), hostname)
+	return nil
+}
+
+// checkKnownHosts returns true if the key of host is found in the known_hosts file, an error
+// is returned if the host is known with a different key or the key is revoked.
+func checkKnownHosts(path, hostname string, remote net.Addr, key ssh.PublicKey) (bool, error) {
+	if !file.IsExist(path) {
+		return false, nil
+	}
+	callback, err := knownhosts.New(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to load known hosts %s: %v", path, err)
+	}
+	err = callback(hostname, remote, key)
+	if err == nil {
+		return true, nil
+	}
+	var keyErr *knownhosts.KeyError
+	if errors.As(err, &keyErr) && len(keyErr.Want) == 0 {
+		return false, nil
+	}
+	if keyErr != nil {
+		return false, fmt.Errorf("host key verification failed: %s host key %s of %s does not match the one in %s line %d, "+
+			"it might be a man-in-the-middle attack, if the host is reinstalled, run `sealos ssh-keys refresh` to rotate it",
+			key.Type(), ssh.FingerprintSHA256(key), hostname, path, keyErr.Want[0].Line)
+	}
+	return false, fmt.Errorf("host key verification failed for %s: %v", hostname, err)
+}
+
+// RemovePinnedHostKeys removes the pinned keys of hosts, all the pinned keys are removed if hosts is empty.
+func RemovePinnedHostKeys(clusterName string, hosts ...string) error {
+	path := PinnedHostKeysFile(clusterName)
+	if !file.IsExist(path) {
+		return nil
+	}
+	if len(hosts) == 0 {
+		return os.Remove(path)
+	}
+	var normalized []string
+	for _, host := range hosts {
+		normalized = append(normalized, knownhosts.Normalize(host))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		_, lineHosts, _, _, _, err := ssh.ParseKnownHosts([]byte(line))
+		if err == nil && slices.ContainsFunc(lineHosts, func(h string) bool {
+			return slices.Contains(normalized, h)
+		}) {
+			logger.Debug("removing pinned host key %s", strings.TrimSpace(line))
+			continue
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+	return file.AtomicWriteFile(path, buf.Bytes(), 0600)
+}
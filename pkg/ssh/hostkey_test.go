@@ -0,0 +1,150 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ssh
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/labring/sealos/pkg/constants"
+)
+
+func TestHostKeyVerifier(t *testing.T) {
+	const host = "192.168.0.2:22"
+	remote := &net.TCPAddr{IP: net.ParseIP("192.168.0.2"), Port: 22}
+	_, signer := generateKey(t)
+	_, other := generateKey(t)
+	key, otherKey := signer.PublicKey(), other.PublicKey()
+
+	writeKnownHosts := func(t *testing.T, key ssh.PublicKey) string {
+		path := filepath.Join(t.TempDir(), "known_hosts")
+		if err := os.WriteFile(path, []byte(knownhosts.Line([]string{knownhosts.Normalize(host)}, key)+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name       string
+		knownHosts ssh.PublicKey
+		pin        bool
+		// keys presented on the consecutive connections and whether each is accepted
+		connects []ssh.PublicKey
+		accepted []bool
+		pinned   bool
+	}{
+		{
+			name:       "known host",
+			knownHosts: key,
+			pin:        true,
+			connects:   []ssh.PublicKey{key},
+			accepted:   []bool{true},
+		},
+		{
+			name:       "known hosts mismatch",
+			knownHosts: key,
+			pin:        true,
+			connects:   []ssh.PublicKey{otherKey},
+			accepted:   []bool{false},
+		},
+		{
+			name:     "unknown host is pinned on first use",
+			pin:      true,
+			connects: []ssh.PublicKey{key, key, otherKey},
+			accepted: []bool{true, true, false},
+			pinned:   true,
+		},
+		{
+			name:     "unknown host without cluster is not pinned",
+			connects: []ssh.PublicKey{key, otherKey},
+			accepted: []bool{true, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &hostKeyVerifier{knownHostsFile: filepath.Join(t.TempDir(), "missing")}
+			if tt.knownHosts != nil {
+				v.knownHostsFile = writeKnownHosts(t, tt.knownHosts)
+			}
+			if tt.pin {
+				v.pinnedFile = filepath.Join(t.TempDir(), "cluster", pinnedHostKeysFileName)
+			}
+			for i, k := range tt.connects {
+				err := v.verify(host, remote, k)
+				if (err == nil) != tt.accepted[i] {
+					t.Fatalf("connect %d: accepted = %v, want %v, err: %v", i, err == nil, tt.accepted[i], err)
+				}
+			}
+			_, err := os.Stat(v.pinnedFile)
+			if pinned := tt.pin && err == nil; pinned != tt.pinned {
+				t.Errorf("pinned = %v, want %v", pinned, tt.pinned)
+			}
+		})
+	}
+}
+
+func TestRemovePinnedHostKeys(t *testing.T) {
+	constants.DefaultRuntimeRootDir = t.TempDir()
+	const cluster = "default"
+	_, signer := generateKey(t)
+	v := &hostKeyVerifier{knownHostsFile: filepath.Join(t.TempDir(), "missing"), pinnedFile: PinnedHostKeysFile(cluster)}
+	hosts := []string{"192.168.0.2:22", "192.168.0.3:2222", "[fd00::4]:22"}
+	for _, host := range hosts {
+		addr, _ := net.ResolveTCPAddr("tcp", host)
+		if err := v.verify(host, addr, signer.PublicKey()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		remove []string
+		want   []string
+	}{
+		{name: "unknown host", remove: []string{"192.168.0.5:22"}, want: []string{"192.168.0.2", "[192.168.0.3]:2222", "[fd00::4]"}},
+		{name: "some hosts", remove: []string{"192.168.0.2:22", "[fd00::4]:22"}, want: []string{"[192.168.0.3]:2222"}},
+		{name: "all hosts", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := RemovePinnedHostKeys(cluster, tt.remove...); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(PinnedHostKeysFile(cluster))
+			if tt.want == nil {
+				if !os.IsNotExist(err) {
+					t.Fatalf("pinned file is not removed: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+				got = append(got, strings.Fields(line)[0])
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("pinned hosts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
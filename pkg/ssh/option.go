@@ -15,7 +15,6 @@
 package ssh
 
 import (
-	"net"
 	"path"
 	"time"
 
@@ -36,6 +35,13 @@ type Option struct {
 	passphrase        string
 	timeout           time.Duration
 	hostKeyCallback   ssh.HostKeyCallback
+	// jump hosts to connect through in order
+	proxyJump []string
+	// socket of ssh-agent, SSH_AUTH_SOCK is used if empty and "none" disables the agent
+	identityAgent string
+	forwardAgent  bool
+	// OpenSSH certificate of the private key
+	certificate string
 }
 
 func (o *Option) BindFlags(fs *pflag.FlagSet) {
@@ -47,6 +53,10 @@ func (o *Option) BindFlags(fs *pflag.FlagSet) {
 		"selects a file from which the identity (private key) for public key authentication is read")
 	fs.StringVar(&o.passphrase, "passphrase", o.passphrase, "passphrase for decrypting a PEM encoded private key")
 	fs.DurationVar(&o.timeout, "timeout", o.timeout, "ssh connection establish timeout")
+	fs.StringVar(&o.identityAgent, "identity-agent", o.identityAgent, "socket of ssh-agent, default is $SSH_AUTH_SOCK, \"none\" to disable it")
+	fs.BoolVarP(&o.forwardAgent, "forward-agent", "A", o.forwardAgent, "enable forwarding of ssh-agent to the remote hosts")
+	fs.StringVar(&o.certificate, "certificate", o.certificate, "OpenSSH certificate of the private key, default is <private-key>-cert.pub if exists")
+	fs.StringSliceVar(&o.proxyJump, "proxy-jump", o.proxyJump, "connect through the comma separated jump hosts in the form of [user@]host[:port]")
 }
 
 const (
@@ -65,12 +75,10 @@ func NewOption() *Option {
 		return ""
 	}
 	opt := &Option{
-		user:       defaultUsername,
-		privateKey: getSSHFile("id_rsa", "id_dsa"),
-		timeout:    10 * time.Second,
-		hostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-			return nil
-		},
+		user:            defaultUsername,
+		privateKey:      getSSHFile(defaultIdentityFiles...),
+		timeout:         10 * time.Second,
+		hostKeyCallback: HostKeyCallback(""),
 	}
 	return opt
 }
@@ -129,3 +137,30 @@ func WithHostKeyCallback(fn ssh.HostKeyCallback) OptionFunc {
 		o.hostKeyCallback = fn
 	}
 }
+
+// WithProxyJump sets the comma separated jump hosts, "none" clears them.
+func WithProxyJump(proxyJump string) OptionFunc {
+	return func(o *Option) {
+		o.proxyJump = parseProxyJump(proxyJump)
+	}
+}
+
+// WithIdentityAgent sets the socket of ssh-agent, "none" disables the agent.
+func WithIdentityAgent(socket string) OptionFunc {
+	return func(o *Option) {
+		o.identityAgent = socket
+	}
+}
+
+func WithForwardAgent(b bool) OptionFunc {
+	return func(o *Option) {
+		o.forwardAgent = b
+	}
+}
+
+// WithCertificate sets the OpenSSH certificate of the private key.
+func WithCertificate(cert string) OptionFunc {
+	return func(o *Option) {
+		o.certificate = cert
+	}
+}
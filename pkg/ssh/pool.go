@@ -0,0 +1,260 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ssh
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+var (
+	// the pooled connection is closed after it's not used for the idle timeout
+	defaultIdleTimeout = 5 * time.Minute
+	// interval of the keepalive requests, the connection is dropped if any of them fails
+	defaultKeepAliveInterval = 30 * time.Second
+	// sessions opened at the same time on a connection, sshd allows 10 by default (MaxSessions)
+	defaultMaxSessions = 8
+)
+
+// PoolStats is the counters of the ssh connection pool.
+type PoolStats struct {
+	// Dials is the number of the connections established
+	Dials int64 `json:"dials"`
+	// Reuses is the number of the times a pooled connection is reused
+	Reuses int64 `json:"reuses"`
+	// Reconnects is the number of the broken connections which are established again
+	Reconnects int64 `json:"reconnects"`
+	// Evictions is the number of the idle or broken connections closed by the pool
+	Evictions int64 `json:"evictions"`
+	// Active is the number of the connections in the pool
+	Active int `json:"active"`
+}
+
+// GetPoolStats returns the counters of the ssh connection pool.
+func GetPoolStats() PoolStats {
+	return pool.statsSnapshot()
+}
+
+type pooledConn struct {
+	client *ssh.Client
+	// slots of the sessions
+	sessions chan struct{}
+
+	mu       sync.Mutex
+	sftp     *sftp.Client
+	refs     int
+	lastUsed time.Time
+	broken   bool
+}
+
+func (pc *pooledConn) close() {
+	if pc.sftp != nil {
+		_ = pc.sftp.Close()
+	}
+	_ = pc.client.Close()
+}
+
+type poolEntry struct {
+	// held while dialing, so that a host is not dialed concurrently
+	mu   sync.Mutex
+	conn *pooledConn
+}
+
+// connPool keeps one connection per user and host, the sessions are multiplexed on it.
+type connPool struct {
+	mu         sync.Mutex
+	entries    map[string]*poolEntry
+	janitor    sync.Once
+	dials      atomic.Int64
+	reuses     atomic.Int64
+	reconnects atomic.Int64
+	evictions  atomic.Int64
+}
+
+var pool = &connPool{entries: make(map[string]*poolEntry)}
+
+func (p *connPool) entry(key string) *poolEntry {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e, ok := p.entries[key]
+	if !ok {
+		e = &poolEntry{}
+		p.entries[key] = e
+	}
+	return e
+}
+
+// get returns the pooled connection of key, a new connection is dialed if there is none
+// or the pooled one is broken. The connection must be released by put.
+func (p *connPool) get(key string, dial func() (*ssh.Client, error)) (*pooledConn, error) {
+	p.janitor.Do(func() {
+		go p.keepAlive()
+	})
+	e := p.entry(key)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if pc := e.conn; pc != nil {
+		pc.mu.Lock()
+		if !pc.broken {
+			pc.refs++
+			pc.mu.Unlock()
+			p.reuses.Add(1)
+			return pc, nil
+		}
+		pc.mu.Unlock()
+	}
+	client, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	p.dials.Add(1)
+	pc := &pooledConn{
+		client:   client,
+		sessions: make(chan struct{}, defaultMaxSessions),
+		refs:     1,
+	}
+	e.conn = pc
+	return pc, nil
+}
+
+// put releases the connection, the discarded connection is closed once the last user releases it.
+func (p *connPool) put(pc *pooledConn) {
+	pc.mu.Lock()
+	pc.refs--
+	pc.lastUsed = time.Now()
+	closing := pc.broken && pc.refs == 0
+	pc.mu.Unlock()
+	if closing {
+		pc.close()
+	}
+}
+
+// discard drops the broken connection from the pool. It's closed right away if it's not used,
+// otherwise it's closed by put when the last user releases it.
+func (p *connPool) discard(key string, pc *pooledConn) {
+	e := p.entry(key)
+	e.mu.Lock()
+	if e.conn == pc {
+		e.conn = nil
+	}
+	e.mu.Unlock()
+	pc.mu.Lock()
+	closing := !pc.broken && pc.refs == 0
+	pc.broken = true
+	pc.mu.Unlock()
+	if closing {
+		pc.close()
+	}
+	p.evictions.Add(1)
+}
+
+func (p *connPool) statsSnapshot() PoolStats {
+	stats := PoolStats{
+		Dials:      p.dials.Load(),
+		Reuses:     p.reuses.Load(),
+		Reconnects: p.reconnects.Load(),
+		Evictions:  p.evictions.Load(),
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, e := range p.entries {
+		if e.conn != nil {
+			stats.Active++
+		}
+	}
+	return stats
+}
+
+// keepAlive sends keepalive requests on the pooled connections and evicts the idle and broken ones.
+func (p *connPool) keepAlive() {
+	ticker := time.NewTicker(defaultKeepAliveInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		p.sweep(time.Now())
+	}
+}
+
+func (p *connPool) sweep(now time.Time) {
+	p.mu.Lock()
+	entries := make(map[string]*poolEntry, len(p.entries))
+	for key, e := range p.entries {
+		entries[key] = e
+	}
+	p.mu.Unlock()
+	for key, e := range entries {
+		e.mu.Lock()
+		pc := e.conn
+		idle := false
+		if pc != nil {
+			pc.mu.Lock()
+			if pc.refs == 0 && now.Sub(pc.lastUsed) > defaultIdleTimeout {
+				idle = true
+				pc.broken = true
+				e.conn = nil
+			}
+			pc.mu.Unlock()
+		}
+		e.mu.Unlock()
+		switch {
+		case pc == nil:
+		case idle:
+			logger.Debug("closing idle ssh connection %s", key)
+			pc.close()
+			p.evictions.Add(1)
+		default:
+			if _, _, err := pc.client.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+				logger.Debug("ssh connection %s is broken: %v", key, err)
+				p.discard(key, pc)
+			}
+		}
+	}
+}
+
+// closeAll closes all the pooled connections.
+func (p *connPool) closeAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, e := range p.entries {
+		e.mu.Lock()
+		if e.conn != nil {
+			e.conn.close()
+		}
+		e.mu.Unlock()
+		delete(p.entries, key)
+	}
+}
+
+func isBrokenConnError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "broken pipe") ||
+		strings.Contains(msg, "connection reset by peer") ||
+		strings.Contains(msg, "use of closed network connection")
+}
@@ -0,0 +1,132 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ssh
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConnPool(t *testing.T) {
+	t.Cleanup(pool.closeAll)
+	server := startTestServer(t, false)
+	client := newTestClient(t, "")
+	before := GetPoolStats()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2*defaultMaxSessions; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.Cmd(server.addr, "hostname"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	stats := GetPoolStats()
+	if dials := stats.Dials - before.Dials; dials != 1 {
+		t.Errorf("expected 1 dial, got %d", dials)
+	}
+	if reuses := stats.Reuses - before.Reuses; reuses != int64(2*defaultMaxSessions-1) {
+		t.Errorf("expected %d reuses, got %d", 2*defaultMaxSessions-1, reuses)
+	}
+
+	// the broken connection is established again
+	server.dropConns()
+	time.Sleep(100 * time.Millisecond)
+	if out, err := client.Cmd(server.addr, "hostname"); err != nil || string(out) != "ran hostname" {
+		t.Fatalf("unexpected output %q %v", out, err)
+	}
+	stats = GetPoolStats()
+	if reconnects := stats.Reconnects - before.Reconnects; reconnects != 1 {
+		t.Errorf("expected 1 reconnect, got %d", reconnects)
+	}
+
+	// the idle connection is evicted
+	pool.sweep(time.Now().Add(defaultIdleTimeout + time.Second))
+	if stats = GetPoolStats(); stats.Active != 0 || stats.Evictions-before.Evictions != 2 {
+		t.Errorf("unexpected stats after eviction %+v", stats)
+	}
+	if err := client.Ping(server.addr); err != nil {
+		t.Fatal(err)
+	}
+	if dials := GetPoolStats().Dials - before.Dials; dials != 3 {
+		t.Errorf("expected 3 dials, got %d", dials)
+	}
+}
+
+func TestDiscardInUse(t *testing.T) {
+	t.Cleanup(pool.closeAll)
+	server := startTestServer(t, false)
+	client := newTestClient(t, "")
+
+	pc, err := client.acquire(server.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the connection is still used by the other user, so it's not closed
+	pool.discard(client.poolKey(server.addr), pc)
+	session, err := pc.client.NewSession()
+	if err != nil {
+		t.Fatalf("discarded connection is closed while in use: %v", err)
+	}
+	_ = session.Close()
+	// closed once the last user releases it
+	pool.put(pc)
+	if _, err = pc.client.NewSession(); err == nil {
+		t.Error("expected the discarded connection to be closed")
+	}
+}
+
+func TestSftpRetry(t *testing.T) {
+	t.Cleanup(pool.closeAll)
+	server := startTestServer(t, false)
+	client := newTestClient(t, "")
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := os.WriteFile(src, []byte("sealos"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	remote := filepath.Join(dir, "remote", "dst")
+	if err := client.Copy(server.addr, src, remote); err != nil {
+		t.Fatal(err)
+	}
+	// the cached sftp session is lost, but the ssh connection is alive
+	pc, err := client.acquire(server.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lost := pc.sftp
+	_ = lost.Close()
+	pool.put(pc)
+
+	if err = client.Fetch(server.addr, remote, filepath.Join(dir, "fetched")); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "fetched")); string(data) != "sealos" {
+		t.Errorf("unexpected fetched data %q", data)
+	}
+	if pc.sftp == nil || pc.sftp == lost {
+		t.Error("expected the lost sftp client to be replaced")
+	}
+	if n := len(pc.sessions); n != 1 {
+		t.Errorf("expected 1 session slot taken by sftp, got %d", n)
+	}
+}
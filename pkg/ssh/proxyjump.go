@@ -0,0 +1,95 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ssh
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	stringsutil "github.com/labring/sealos/pkg/utils/strings"
+)
+
+const proxyJumpNone = "none"
+
+func parseProxyJump(proxyJump string) []string {
+	if strings.TrimSpace(proxyJump) == proxyJumpNone {
+		return nil
+	}
+	return stringsutil.FilterNonEmptyFromString(proxyJump, ",")
+}
+
+// parseJumpHost splits a jump host in the form of [user@]host[:port], the port is 22 if omitted.
+func parseJumpHost(jumpHost string) (user, addr string) {
+	if i := strings.LastIndex(jumpHost, "@"); i >= 0 {
+		user, jumpHost = jumpHost[:i], jumpHost[i+1:]
+	}
+	if host, port, err := net.SplitHostPort(jumpHost); err == nil {
+		return user, net.JoinHostPort(host, port)
+	}
+	return user, net.JoinHostPort(strings.Trim(jumpHost, "[]"), "22")
+}
+
+// dialThroughJumpHosts connects to addr through the jump hosts in order like ssh -J, the jump hosts
+// are authenticated as the same user with the same credentials unless the user is specified.
+// The connections to the jump hosts are closed after the returned client is closed.
+func (c *Client) dialThroughJumpHosts(addr string) (*ssh.Client, error) {
+	var client *ssh.Client
+	for _, jumpHost := range c.proxyJump {
+		user, jumpAddr := parseJumpHost(jumpHost)
+		config := *c.ClientConfig
+		if user != "" {
+			config.User = user
+		}
+		next, err := dialVia(client, jumpAddr, &config)
+		if err != nil {
+			if client != nil {
+				_ = client.Close()
+			}
+			return nil, fmt.Errorf("failed to connect jump host %s: %v", jumpHost, err)
+		}
+		client = next
+	}
+	target, err := dialVia(client, addr, c.ClientConfig)
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return target, nil
+}
+
+// dialVia connects to addr through the jump client, or directly if jump is nil.
+func dialVia(jump *ssh.Client, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	if jump == nil {
+		return ssh.Dial("tcp", addr, config)
+	}
+	conn, err := jump.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	clientConn, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	client := ssh.NewClient(clientConn, chans, reqs)
+	go func() {
+		_ = client.Wait()
+		_ = jump.Close()
+	}()
+	return client, nil
+}
@@ -0,0 +1,302 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ssh
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+const testPassword = "sealos"
+
+// testServer is an in-process ssh server, it forwards the direct-tcpip channels if it's a bastion,
+// otherwise it runs the sessions with exec and sftp.
+type testServer struct {
+	addr      string
+	bastion   bool
+	forwarded atomic.Int32
+	mu        sync.Mutex
+	users     []string
+	conns     []net.Conn
+	// the public keys and the user CA allowed to authenticate, and the types of the accepted keys
+	authorizedKeys []ssh.PublicKey
+	userCA         ssh.PublicKey
+	keyTypes       []string
+}
+
+func startTestServer(t *testing.T, bastion bool) *testServer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testServer{bastion: bastion}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if string(password) != testPassword {
+				return nil, fmt.Errorf("password rejected for %s", conn.User())
+			}
+			s.mu.Lock()
+			s.users = append(s.users, conn.User())
+			s.mu.Unlock()
+			return nil, nil
+		},
+		PublicKeyCallback: s.checkPublicKey,
+	}
+	config.AddHostKey(signer)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	s.addr = l.Addr().String()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+			go s.serveConn(conn, config)
+		}
+	}()
+	return s
+}
+
+func (s *testServer) checkPublicKey(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+	checker := &ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return s.userCA != nil && bytes.Equal(auth.Marshal(), s.userCA.Marshal())
+		},
+		UserKeyFallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			for _, authorized := range s.authorizedKeys {
+				if bytes.Equal(authorized.Marshal(), key.Marshal()) {
+					return nil, nil
+				}
+			}
+			return nil, fmt.Errorf("public key rejected for %s", conn.User())
+		},
+	}
+	perms, err := checker.Authenticate(conn, key)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.users = append(s.users, conn.User())
+	s.keyTypes = append(s.keyTypes, key.Type())
+	s.mu.Unlock()
+	return perms, nil
+}
+
+func (s *testServer) serveConn(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		switch {
+		case s.bastion && newChannel.ChannelType() == "direct-tcpip":
+			go s.forward(newChannel)
+		case !s.bastion && newChannel.ChannelType() == "session":
+			go s.session(newChannel)
+		default:
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+		}
+	}
+}
+
+func (s *testServer) forward(newChannel ssh.NewChannel) {
+	var payload struct {
+		DestAddr string
+		DestPort uint32
+		OrigAddr string
+		OrigPort uint32
+	}
+	if err := ssh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+		_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	target, err := net.Dial("tcp", net.JoinHostPort(payload.DestAddr, fmt.Sprint(payload.DestPort)))
+	if err != nil {
+		_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, reqs, err := newChannel.Accept()
+	if err != nil {
+		_ = target.Close()
+		return
+	}
+	s.forwarded.Add(1)
+	go ssh.DiscardRequests(reqs)
+	go func() {
+		_, _ = io.Copy(target, channel)
+		_ = target.Close()
+	}()
+	_, _ = io.Copy(channel, target)
+	_ = channel.Close()
+}
+
+func (s *testServer) session(newChannel ssh.NewChannel) {
+	channel, reqs, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+	for req := range reqs {
+		switch req.Type {
+		case "pty-req":
+			_ = req.Reply(true, nil)
+		case "exec":
+			var payload struct{ Command string }
+			_ = ssh.Unmarshal(req.Payload, &payload)
+			_ = req.Reply(true, nil)
+			_, _ = fmt.Fprintf(channel, "ran %s", payload.Command)
+			_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+			return
+		case "subsystem":
+			_ = req.Reply(true, nil)
+			server, err := sftp.NewServer(channel)
+			if err != nil {
+				return
+			}
+			_ = server.Serve()
+			return
+		default:
+			_ = req.Reply(false, nil)
+		}
+	}
+}
+
+// dropConns closes all the accepted connections.
+func (s *testServer) dropConns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.conns = nil
+}
+
+func (s *testServer) firstUser() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.users) == 0 {
+		return ""
+	}
+	return s.users[0]
+}
+
+func newTestClient(t *testing.T, proxyJump string) *Client {
+	t.Helper()
+	client, err := New(nil, WithUsername(defaultUsername), WithPassword(testPassword),
+		WithProxyJump(proxyJump), WithHostKeyCallback(ssh.InsecureIgnoreHostKey()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestProxyJump(t *testing.T) {
+	t.Cleanup(pool.closeAll)
+	target := startTestServer(t, false)
+	bastion1 := startTestServer(t, true)
+	bastion2 := startTestServer(t, true)
+
+	client := newTestClient(t, "jumper@"+bastion1.addr+","+bastion2.addr)
+	out, err := client.Cmd(target.addr, "hostname")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "ran hostname" {
+		t.Errorf("unexpected output %q", out)
+	}
+	if err = client.CmdAsync(target.addr, "uptime"); err != nil {
+		t.Fatal(err)
+	}
+	// the connection through the jump hosts is reused
+	if bastion1.forwarded.Load() != 1 || bastion2.forwarded.Load() != 1 {
+		t.Errorf("expected 1 forwarded connection, got %d and %d", bastion1.forwarded.Load(), bastion2.forwarded.Load())
+	}
+	if bastion1.firstUser() != "jumper" || bastion2.firstUser() != defaultUsername {
+		t.Errorf("unexpected users of jump hosts %q %q", bastion1.firstUser(), bastion2.firstUser())
+	}
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err = os.WriteFile(src, []byte("sealos"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	remote := filepath.Join(dir, "remote", "dst")
+	if err = client.Copy(target.addr, src, remote); err != nil {
+		t.Fatal(err)
+	}
+	fetched := filepath.Join(dir, "fetched")
+	if err = client.Fetch(target.addr, remote, fetched); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(fetched); string(data) != "sealos" {
+		t.Errorf("unexpected fetched data %q", data)
+	}
+
+	if _, err = newTestClient(t, "127.0.0.1:1").Cmd(target.addr, "hostname"); err == nil || !strings.Contains(err.Error(), "jump host") {
+		t.Errorf("expected jump host error, got %v", err)
+	}
+}
+
+func TestParseProxyJump(t *testing.T) {
+	if hosts := parseProxyJump(" none "); hosts != nil {
+		t.Errorf("expected no jump hosts, got %v", hosts)
+	}
+	if hosts := parseProxyJump("a,,b@c:2222"); len(hosts) != 2 {
+		t.Errorf("unexpected jump hosts %v", hosts)
+	}
+	tests := []struct {
+		in, user, addr string
+	}{
+		{"10.0.0.1", "", "10.0.0.1:22"},
+		{"admin@10.0.0.1:2222", "admin", "10.0.0.1:2222"},
+		{"[fd00::1]", "", "[fd00::1]:22"},
+		{"admin@[fd00::1]:2222", "admin", "[fd00::1]:2222"},
+	}
+	for _, tt := range tests {
+		if user, addr := parseJumpHost(tt.in); user != tt.user || addr != tt.addr {
+			t.Errorf("parseJumpHost(%q) = %q, %q, want %q, %q", tt.in, user, addr, tt.user, tt.addr)
+		}
+	}
+}
@@ -15,17 +15,16 @@
 package ssh
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/pkg/sftp"
 	"github.com/schollz/progressbar/v3"
-	"golang.org/x/crypto/ssh"
 
 	"github.com/labring/sealos/pkg/utils/file"
 	"github.com/labring/sealos/pkg/utils/logger"
@@ -59,103 +58,176 @@ func (c *Client) CmdToString(host, cmd, sep string) (string, error) {
 	return getOnelineResult(data, sep), nil
 }
 
-func (c *Client) newClientAndSftpClient(host string) (*ssh.Client, *sftp.Client, error) {
+// sftpConnect returns the sftp client on the pooled connection to host, the connection is established
+// again if it's broken. The connection must be released by pool.put once the client is not used.
+func (c *Client) sftpConnect(host string) (*sftp.Client, *pooledConn, error) {
+	key := c.poolKey(host)
+	for i := 0; ; i++ {
+		pc, err := c.acquire(host)
+		if err != nil {
+			return nil, nil, err
+		}
+		sftpClient, err := c.pooledSftpClient(pc)
+		if err == nil {
+			return sftpClient, pc, nil
+		}
+		pool.put(pc)
+		if i > 0 || !isBrokenConnError(err) {
+			return nil, nil, err
+		}
+		logger.Debug("ssh connection to %s is broken, reconnecting: %v", host, err)
+		pool.discard(key, pc)
+		pool.reconnects.Add(1)
+	}
+}
+
+// withSftp runs fn with the sftp client of host. If the sftp session is lost, the cached client
+// is evicted and fn runs once again with a new one.
+func (c *Client) withSftp(host string, fn func(*sftp.Client) error) error {
+	for i := 0; ; i++ {
+		sftpClient, pc, err := c.sftpConnect(host)
+		if err != nil {
+			return fmt.Errorf("failed to connect: %w", err)
+		}
+		err = fn(sftpClient)
+		lost := isSftpConnLost(err)
+		if lost {
+			pc.evictSftp(sftpClient)
+		}
+		pool.put(pc)
+		if !lost || i > 0 {
+			return err
+		}
+		logger.Debug("sftp session to %s is lost, retrying: %v", host, err)
+	}
+}
+
+// pooledSftpClient returns the sftp client shared by the users of the pooled connection,
+// it takes one of the session slots of the connection.
+func (c *Client) pooledSftpClient(pc *pooledConn) (*sftp.Client, error) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.sftp != nil {
+		return pc.sftp, nil
+	}
+	pc.sessions <- struct{}{}
 	var (
-		sshClient  *ssh.Client
 		sftpClient *sftp.Client
 		err        error
 	)
-
-	hostsClientMap.Mux.Lock()
-	defer hostsClientMap.Mux.Unlock()
-	if hc, ok := hostsClientMap.ClientMap[host]; ok {
-		return hc.SSHClient, hc.SftpClient, err
-	}
-	sshClient, err = c.connect(host)
-	if err != nil {
-		return nil, nil, err
-	}
-	// create sftp client
 	if c.Option.sudo || c.Option.user != defaultUsername {
-		sftpClient, err = NewSudoSftpClient(sshClient, c.password)
+		sftpClient, err = NewSudoSftpClient(pc.client, c.password)
 	} else {
-		sftpClient, err = sftp.NewClient(sshClient)
+		sftpClient, err = sftp.NewClient(pc.client)
 	}
-
-	if err == nil {
-		hc := HostClient{
-			SSHClient:  sshClient,
-			SftpClient: sftpClient,
-		}
-		hostsClientMap.ClientMap[host] = hc
+	if err != nil {
+		<-pc.sessions
+		return nil, err
 	}
+	pc.sftp = sftpClient
+	return sftpClient, nil
+}
 
-	return sshClient, sftpClient, err
+// evictSftp closes the lost sftp client and releases its session slot, unless it's already
+// replaced by another user of the connection.
+func (pc *pooledConn) evictSftp(client *sftp.Client) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.sftp != client {
+		return
+	}
+	_ = client.Close()
+	pc.sftp = nil
+	<-pc.sessions
 }
 
-func (c *Client) sftpConnect(host string) (sshClient *ssh.Client, sftpClient *sftp.Client, err error) {
-	err = exponentialBackOffRetry(defaultMaxRetry, time.Millisecond*100, 2, func() error {
-		sshClient, sftpClient, err = c.newClientAndSftpClient(host)
-		return err
-	}, isErrorWorthRetry)
-	return
+func isSftpConnLost(err error) bool {
+	return errors.Is(err, sftp.ErrSSHFxConnectionLost) || isBrokenConnError(err)
 }
 
 // Copy is copy file or dir to remotePath, add md5 validate
 func (c *Client) Copy(host, localPath, remotePath string) error {
 	logger.Debug("remote copy files src %s to dst %s", localPath, remotePath)
-	_, sftpClient, err := c.sftpConnect(host)
-	if err != nil {
-		return fmt.Errorf("failed to connect: %s", err)
-	}
-
 	f, err := os.Stat(localPath)
 	if err != nil {
 		return fmt.Errorf("get file stat failed %s", err)
 	}
-
-	remoteDir := filepath.Dir(remotePath)
-	rfp, err := sftpClient.Stat(remoteDir)
-	if err != nil {
-		if !os.IsNotExist(err) {
-			return err
-		}
-		if err = sftpClient.MkdirAll(remoteDir); err != nil {
-			return fmt.Errorf("failed to Mkdir remote: %v", err)
-		}
-	} else if !rfp.IsDir() {
-		return fmt.Errorf("dir of remote file %s is not a directory", remotePath)
-	}
 	number := 1
 	if f.IsDir() {
 		number = file.CountDirFiles(localPath)
+	}
+
+	return c.withSftp(host, func(sftpClient *sftp.Client) error {
+		remoteDir := filepath.Dir(remotePath)
+		rfp, err := sftpClient.Stat(remoteDir)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				return err
+			}
+			if err = sftpClient.MkdirAll(remoteDir); err != nil {
+				return fmt.Errorf("failed to Mkdir remote: %w", err)
+			}
+		} else if !rfp.IsDir() {
+			return fmt.Errorf("dir of remote file %s is not a directory", remotePath)
+		}
 		// no files in local dir, but still need to create remote dir
-		if number == 0 {
+		if f.IsDir() && number == 0 {
 			return sftpClient.MkdirAll(remotePath)
 		}
-	}
-	bar := progress.Simple("copying files to "+host, number)
-	defer func() {
-		_ = bar.Close()
-	}()
+		bar := progress.Simple("copying files to "+host, number)
+		defer func() {
+			_ = bar.Close()
+		}()
 
-	return c.doCopy(sftpClient, host, localPath, remotePath, bar)
+		return c.doCopy(sftpClient, host, localPath, remotePath, bar)
+	})
 }
 
-func (c *Client) Fetch(host, src, dst string) error {
-	logger.Debug("fetch remote file %s to %s", src, dst)
-	_, sftpClient, err := c.sftpConnect(host)
+// CopyFrom copies the content of r to the remote file dst, it's not retried like Copy since r
+// can't be read again.
+func (c *Client) CopyFrom(host string, r io.Reader, dst string, mode os.FileMode) error {
+	logger.Debug("remote copy stream to dst %s", dst)
+	sftpClient, pc, err := c.sftpConnect(host)
 	if err != nil {
-		return fmt.Errorf("failed to connect: %s", err)
+		return fmt.Errorf("failed to connect: %w", err)
 	}
+	if err = copyFrom(sftpClient, r, dst, mode); isSftpConnLost(err) {
+		pc.evictSftp(sftpClient)
+	}
+	pool.put(pc)
+	return err
+}
 
-	rfp, err := sftpClient.Open(src)
-	if err != nil {
-		return fmt.Errorf("failed to open remote file %s: %v", src, err)
+func copyFrom(client *sftp.Client, r io.Reader, dst string, mode os.FileMode) error {
+	if err := client.MkdirAll(path.Dir(dst)); err != nil {
+		return fmt.Errorf("failed to Mkdir remote: %w", err)
 	}
-	defer func() {
-		_ = rfp.Close()
-	}()
+	dstTmp := dst + ".tmp"
+	if err := func() error {
+		dstfp, err := client.Create(dstTmp)
+		if err != nil {
+			return fmt.Errorf("failed to create: %w", err)
+		}
+		defer dstfp.Close()
+
+		if err = dstfp.Chmod(mode); err != nil {
+			return fmt.Errorf("failed to Chmod dst: %w", err)
+		}
+		if _, err = io.Copy(dstfp, r); err != nil {
+			return fmt.Errorf("failed to Copy: %w", err)
+		}
+		return nil
+	}(); err != nil {
+		return err
+	}
+	if err := client.PosixRename(dstTmp, dst); err != nil {
+		return fmt.Errorf("failed to rename %s to %s: %w", dstTmp, dst, err)
+	}
+	return nil
+}
+
+func (c *Client) Fetch(host, src, dst string) error {
+	logger.Debug("fetch remote file %s to %s", src, dst)
 	if file.IsDir(dst) {
 		dst = filepath.Join(dst, filepath.Base(src))
 	} else if file.IsFile(dst) {
@@ -166,27 +238,37 @@ func (c *Client) Fetch(host, src, dst string) error {
 		}
 	}
 
-	created, err := os.Create(dst)
-	if err != nil {
+	return c.withSftp(host, func(sftpClient *sftp.Client) error {
+		rfp, err := sftpClient.Open(src)
+		if err != nil {
+			return fmt.Errorf("failed to open remote file %s: %w", src, err)
+		}
+		defer func() {
+			_ = rfp.Close()
+		}()
+
+		created, err := os.Create(dst)
+		if err != nil {
+			return err
+		}
+		defer created.Close()
+		_, err = io.Copy(created, rfp)
 		return err
-	}
-	defer created.Close()
-	_, err = io.Copy(created, rfp)
-	return err
+	})
 }
 
 func (c *Client) doCopy(client *sftp.Client, host, src, dest string, epu *progressbar.ProgressBar) error {
 	lfp, err := os.Stat(src)
 	if err != nil {
-		return fmt.Errorf("failed to Stat local: %v", err)
+		return fmt.Errorf("failed to Stat local: %w", err)
 	}
 	if lfp.IsDir() {
 		entries, err := os.ReadDir(src)
 		if err != nil {
-			return fmt.Errorf("failed to ReadDir: %v", err)
+			return fmt.Errorf("failed to ReadDir: %w", err)
 		}
 		if err = client.MkdirAll(dest); err != nil {
-			return fmt.Errorf("failed to Mkdir remote: %v", err)
+			return fmt.Errorf("failed to Mkdir remote: %w", err)
 		}
 		for _, entry := range entries {
 			if err = c.doCopy(client, host, path.Join(src, entry.Name()), path.Join(dest, entry.Name()), epu); err != nil {
@@ -196,7 +278,7 @@ func (c *Client) doCopy(client *sftp.Client, host, src, dest string, epu *progre
 	} else {
 		lf, err := os.Open(filepath.Clean(src))
 		if err != nil {
-			return fmt.Errorf("failed to open: %v", err)
+			return fmt.Errorf("failed to open: %w", err)
 		}
 		defer lf.Close()
 
@@ -204,15 +286,15 @@ func (c *Client) doCopy(client *sftp.Client, host, src, dest string, epu *progre
 		if err = func(tmpName string) error {
 			dstfp, err := client.Create(tmpName)
 			if err != nil {
-				return fmt.Errorf("failed to create: %v", err)
+				return fmt.Errorf("failed to create: %w", err)
 			}
 			defer dstfp.Close()
 
 			if err = dstfp.Chmod(lfp.Mode()); err != nil {
-				return fmt.Errorf("failed to Chmod dst: %v", err)
+				return fmt.Errorf("failed to Chmod dst: %w", err)
 			}
 			if _, err = io.Copy(dstfp, lf); err != nil {
-				return fmt.Errorf("failed to Copy: %v", err)
+				return fmt.Errorf("failed to Copy: %w", err)
 			}
 			return nil
 		}(destTmp); err != nil {
@@ -220,7 +302,7 @@ func (c *Client) doCopy(client *sftp.Client, host, src, dest string, epu *progre
 		}
 
 		if err = client.PosixRename(destTmp, dest); err != nil {
-			return fmt.Errorf("failed to rename %s to %s: %v", destTmp, dest, err)
+			return fmt.Errorf("failed to rename %s to %s: %w", destTmp, dest, err)
 		}
 
 		_ = epu.Add(1)
@@ -0,0 +1,46 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ssh
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCopyFrom(t *testing.T) {
+	t.Cleanup(pool.closeAll)
+	server := startTestServer(t, false)
+	client := newTestClient(t, "")
+
+	dst := filepath.Join(t.TempDir(), "blobs", "data")
+	if err := client.CopyFrom(server.addr, strings.NewReader("sealos"), dst, 0600); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("unexpected mode %v", info.Mode())
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "sealos" {
+		t.Errorf("unexpected data %q", data)
+	}
+	if _, err = os.Stat(dst + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file is left: %v", err)
+	}
+}
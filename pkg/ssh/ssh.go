@@ -16,6 +16,8 @@ package ssh
 
 import (
 	"context"
+	"io"
+	"os"
 	"time"
 
 	"github.com/spf13/pflag"
@@ -23,7 +25,6 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	v2 "github.com/labring/sealos/pkg/types/v1beta1"
-	fileutils "github.com/labring/sealos/pkg/utils/file"
 	"github.com/labring/sealos/pkg/utils/logger"
 )
 
@@ -62,6 +63,13 @@ type Interface interface {
 	Ping(host string) error
 }
 
+// StreamCopier is implemented by the clients which copy a stream to remote, so that the caller
+// controls how the content is read, e.g. to limit the bandwidth.
+type StreamCopier interface {
+	// CopyFrom copies the content of r to the file dst on host, the file is replaced once it's completed.
+	CopyFrom(host string, r io.Reader, dst string, mode os.FileMode) error
+}
+
 type Client struct {
 	*ssh.ClientConfig
 	*Option
@@ -102,22 +110,12 @@ func newFromOptions(opt *Option, opts ...OptionFunc) (*Client, error) {
 	if len(opt.password) > 0 {
 		config.Auth = append(config.Auth, ssh.Password(opt.password))
 	}
-	if len(opt.rawPrivateKeyData) > 0 {
-		signer, err := parsePrivateKey([]byte(opt.rawPrivateKeyData), []byte(opt.passphrase))
-		if err != nil {
-			return nil, err
-		}
-		config.Auth = append(config.Auth, ssh.PublicKeys(signer))
-	} else if len(opt.privateKey) > 0 {
-		if !fileutils.IsExist(opt.privateKey) {
-			logger.Debug("not trying to parse private key file cause it's not exists")
-		} else {
-			signer, err := parsePrivateKeyFile(opt.privateKey, opt.passphrase)
-			if err != nil {
-				return nil, err
-			}
-			config.Auth = append(config.Auth, ssh.PublicKeys(signer))
-		}
+	publicKeys, err := opt.publicKeysCallback()
+	if err != nil {
+		return nil, err
+	}
+	if publicKeys != nil {
+		config.Auth = append(config.Auth, publicKeys)
 	}
 	return &Client{ClientConfig: config, Option: opt}, nil
 }
@@ -138,6 +136,18 @@ func newOptionFromSSH(ssh *v2.SSH, isStdout bool) *Option {
 	if len(ssh.PkData) > 0 {
 		opts = append(opts, WithRawPrivateKeyDataAndPhrase(ssh.PkData, ssh.PkPasswd))
 	}
+	if len(ssh.ProxyJump) > 0 {
+		opts = append(opts, WithProxyJump(ssh.ProxyJump))
+	}
+	if len(ssh.IdentityAgent) > 0 {
+		opts = append(opts, WithIdentityAgent(ssh.IdentityAgent))
+	}
+	if ssh.ForwardAgent != nil {
+		opts = append(opts, WithForwardAgent(*ssh.ForwardAgent))
+	}
+	if len(ssh.Certificate) > 0 {
+		opts = append(opts, WithCertificate(ssh.Certificate))
+	}
 	if ssh.User != "" && ssh.User != defaultUsername {
 		opts = append(opts, WithSudoEnable(true))
 	}
@@ -149,12 +159,12 @@ func newOptionFromSSH(ssh *v2.SSH, isStdout bool) *Option {
 	return opt
 }
 
-func newFromSSH(ssh *v2.SSH, isStdout bool) (Interface, error) {
-	return New(newOptionFromSSH(ssh, isStdout))
+func newFromSSH(ssh *v2.SSH, isStdout bool, opts ...OptionFunc) (Interface, error) {
+	return New(newOptionFromSSH(ssh, isStdout), opts...)
 }
 
-func MustNewClient(ssh *v2.SSH, isStdout bool) Interface {
-	client, err := newFromSSH(ssh, isStdout)
+func MustNewClient(ssh *v2.SSH, isStdout bool, opts ...OptionFunc) Interface {
+	client, err := newFromSSH(ssh, isStdout, opts...)
 	if err != nil {
 		logger.Fatal("failed to create ssh client: %v", err)
 	}
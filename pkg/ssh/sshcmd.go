@@ -30,11 +30,12 @@ import (
 )
 
 func (c *Client) Ping(host string) error {
-	client, _, err := c.Connect(host)
+	_, release, err := c.session(host)
 	if err != nil {
 		return fmt.Errorf("failed to connect %s: %v", host, err)
 	}
-	return client.Close()
+	release()
+	return nil
 }
 
 func (c *Client) wrapCommands(cmds ...string) string {
@@ -47,12 +48,11 @@ func (c *Client) wrapCommands(cmds ...string) string {
 func (c *Client) CmdAsyncWithContext(ctx context.Context, host string, cmds ...string) error {
 	cmd := c.wrapCommands(cmds...)
 	logger.Debug("start to exec `%s` on %s", cmd, host)
-	client, session, err := c.Connect(host)
+	session, release, err := c.session(host)
 	if err != nil {
 		return fmt.Errorf("connect error: %v", err)
 	}
-	defer client.Close()
-	defer session.Close()
+	defer release()
 	stdout, err := session.StdoutPipe()
 	if err != nil {
 		return fmt.Errorf("stdout pipe %s: %v", host, err)
@@ -107,12 +107,11 @@ func (c *Client) CmdAsync(host string, cmds ...string) error {
 func (c *Client) Cmd(host, cmd string) ([]byte, error) {
 	cmd = c.wrapCommands(cmd)
 	logger.Debug("start to exec `%s` on %s", cmd, host)
-	client, session, err := c.Connect(host)
+	session, release, err := c.session(host)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create ssh session for %s: %v", host, err)
 	}
-	defer client.Close()
-	defer session.Close()
+	defer release()
 	in, err := session.StdinPipe()
 	if err != nil {
 		return nil, err
@@ -223,6 +223,18 @@ type SSH struct {
 	Pk       string `json:"pk,omitempty"`
 	PkPasswd string `json:"pkPasswd,omitempty"`
 	Port     uint16 `json:"port,omitempty"`
+	// ProxyJump is the comma separated jump hosts in the form of [user@]host[:port] to connect
+	// the hosts through in order, same as the ProxyJump of OpenSSH, "none" disables the jump hosts
+	// inherited from the cluster.
+	ProxyJump string `json:"proxyJump,omitempty"`
+	// IdentityAgent is the socket of ssh-agent whose keys are used to authenticate, SSH_AUTH_SOCK
+	// is used if empty and "none" disables the agent.
+	IdentityAgent string `json:"identityAgent,omitempty"`
+	// ForwardAgent enables forwarding of ssh-agent to the hosts, the one of the host
+	// overrides the cluster's if it is set.
+	ForwardAgent *bool `json:"forwardAgent,omitempty"`
+	// Certificate is the OpenSSH certificate of the private key, "<pk>-cert.pub" is used if exists.
+	Certificate string `json:"certificate,omitempty"`
 }
 
 func (s *SSH) DefaultPort() uint16 {
@@ -76,7 +76,7 @@ func (in *ClusterSpec) DeepCopyInto(out *ClusterSpec) {
 		*out = make(ImageList, len(*in))
 		copy(*out, *in)
 	}
-	out.SSH = in.SSH
+	in.SSH.DeepCopyInto(&out.SSH)
 	if in.Hosts != nil {
 		in, out := &in.Hosts, &out.Hosts
 		*out = make([]Host, len(*in))
@@ -263,7 +263,7 @@ func (in *Host) DeepCopyInto(out *Host) {
 	if in.SSH != nil {
 		in, out := &in.SSH, &out.SSH
 		*out = new(SSH)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	return
 }
@@ -357,6 +357,11 @@ func (in *RegistryConfig) DeepCopy() *RegistryConfig {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SSH) DeepCopyInto(out *SSH) {
 	*out = *in
+	if in.ForwardAgent != nil {
+		in, out := &in.ForwardAgent, &out.ForwardAgent
+		*out = new(bool)
+		**out = **in
+	}
 	return
 }
 
@@ -0,0 +1,39 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flags
+
+import (
+	"fmt"
+
+	"github.com/docker/go-units"
+)
+
+// Bytes is a size flag in bytes which also accepts human readable sizes, eg. 512k or 50MB.
+type Bytes int64
+
+func (b *Bytes) String() string {
+	return units.HumanSize(float64(*b))
+}
+
+func (b *Bytes) Set(s string) error {
+	v, err := units.FromHumanSize(s)
+	if err != nil || v < 0 {
+		return fmt.Errorf("invalid size %q", s)
+	}
+	*b = Bytes(v)
+	return nil
+}
+
+func (b *Bytes) Type() string { return "bytes" }
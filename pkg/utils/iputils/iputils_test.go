@@ -0,0 +1,82 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iputils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetHostIPAndPortOrDefault(t *testing.T) {
+	tests := []struct {
+		host, ip, port string
+	}{
+		{"192.168.0.2", "192.168.0.2", "22"},
+		{"192.168.0.2:2222", "192.168.0.2", "2222"},
+		{"fd00::2", "fd00::2", "22"},
+		{"[fd00::2]", "fd00::2", "22"},
+		{"[fd00::2]:2222", "fd00::2", "2222"},
+	}
+	for _, tt := range tests {
+		ip, port := GetHostIPAndPortOrDefault(tt.host, "22")
+		if ip != tt.ip || port != tt.port {
+			t.Errorf("GetHostIPAndPortOrDefault(%s) = %s, %s, want %s, %s", tt.host, ip, port, tt.ip, tt.port)
+		}
+		if got := GetHostIP(tt.host); got != tt.ip {
+			t.Errorf("GetHostIP(%s) = %s, want %s", tt.host, got, tt.ip)
+		}
+	}
+	got := GetHostIPAndPortSlice([]string{"192.168.0.2", "fd00::2", "[fd00::3]:2222"}, "22")
+	want := []string{"192.168.0.2:22", "[fd00::2]:22", "[fd00::3]:2222"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetHostIPAndPortSlice() = %v, want %v", got, want)
+	}
+}
+
+func TestParseIPList(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    []string
+		wantErr bool
+	}{
+		{s: "192.168.0.254-192.168.1.1", want: []string{"192.168.0.254", "192.168.0.255", "192.168.1.0", "192.168.1.1"}},
+		{s: "fd00::fe-fd00::101", want: []string{"fd00::fe", "fd00::ff", "fd00::100", "fd00::101"}},
+		{s: "fd00::2,[fd00::3]:2222,192.168.0.2", want: []string{"fd00::2", "[fd00::3]:2222", "192.168.0.2"}},
+		{s: "fd00::/126", want: []string{"fd00::", "fd00::1", "fd00::2", "fd00::3"}},
+		{s: "192.168.0.2-fd00::2", wantErr: true},
+		{s: "fd00::3-fd00::2", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseIPList(tt.s)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseIPList(%s) error = %v, wantErr %v", tt.s, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseIPList(%s) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestHostCIDR(t *testing.T) {
+	for ip, want := range map[string]string{
+		"10.103.97.2": "10.103.97.2/32",
+		"fd00::2":     "fd00::2/128",
+	} {
+		if got := HostCIDR(ip); got != want {
+			t.Errorf("HostCIDR(%s) = %s, want %s", ip, got, want)
+		}
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"math/big"
 	"net"
+	"strconv"
 	"strings"
 
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -29,10 +30,8 @@ import (
 
 // use only one
 func GetHostIP(host string) string {
-	if !strings.ContainsRune(host, ':') {
-		return host
-	}
-	return strings.Split(host, ":")[0]
+	ip, _ := GetHostIPAndPortOrDefault(host, "")
+	return ip
 }
 
 func GetDiffHosts(hostsOld, hostsNew []string) (add, sub []string) {
@@ -72,12 +71,26 @@ func GetHostIPs(hosts []string) []string {
 	return ips
 }
 
+// GetHostIPAndPortOrDefault splits host like 192.168.0.2:22 or [fd00::2]:22 into ip and port,
+// host without a port, such as 192.168.0.2, fd00::2 or [fd00::2], gets the Default port.
 func GetHostIPAndPortOrDefault(host, Default string) (string, string) {
-	if !strings.ContainsRune(host, ':') {
-		return host, Default
+	if ip, port, err := net.SplitHostPort(host); err == nil {
+		return ip, port
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(host, "["), "]"), Default
+}
+
+// JoinHostPort combines host and a numeric port into host:port, IPv6 hosts are enclosed in square brackets.
+func JoinHostPort[T ~int | ~int32 | ~uint16](host string, port T) string {
+	return net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
+
+// HostCIDR returns the CIDR which contains the single ip, /32 for IPv4 and /128 for IPv6.
+func HostCIDR(ip string) string {
+	if IsIpv4(ip) {
+		return ip + "/32"
 	}
-	split := strings.Split(host, ":")
-	return split[0], split[1]
+	return ip + "/128"
 }
 
 func GetSSHHostIPAndPort(host string) (string, string) {
@@ -86,8 +99,7 @@ func GetSSHHostIPAndPort(host string) (string, string) {
 
 func GetHostIPAndPortSlice(hosts []string, Default string) (res []string) {
 	for _, ip := range hosts {
-		_ip, port := GetHostIPAndPortOrDefault(ip, Default)
-		res = append(res, fmt.Sprintf("%s:%s", _ip, port))
+		res = append(res, net.JoinHostPort(GetHostIPAndPortOrDefault(ip, Default)))
 	}
 	return
 }
@@ -122,24 +134,34 @@ func ListLocalHostAddrs() (*[]net.Addr, error) {
 }
 
 func IsLocalIP(ip string, addrs *[]net.Addr) bool {
-	if defaultIP, _, err := net.SplitHostPort(ip); err == nil {
-		ip = defaultIP
+	netIP := net.ParseIP(GetHostIP(ip))
+	if netIP == nil {
+		return false
 	}
 	for _, address := range *addrs {
-		if ipnet, ok := address.(*net.IPNet); ok && !ipnet.IP.IsLoopback() && ipnet.IP.To4() != nil && ipnet.IP.String() == ip {
+		if ipnet, ok := address.(*net.IPNet); ok && !ipnet.IP.IsLoopback() && ipnet.IP.Equal(netIP) {
 			return true
 		}
 	}
 	return false
 }
 
+// LocalIP returns the first IPv4 address of the host, or the first global IPv6 address on IPv6 only hosts.
 func LocalIP(addrs *[]net.Addr) string {
+	var ipv6 string
 	for _, address := range *addrs {
-		if ipnet, ok := address.(*net.IPNet); ok && !ipnet.IP.IsLoopback() && ipnet.IP.To4() != nil {
+		ipnet, ok := address.(*net.IPNet)
+		if !ok || ipnet.IP.IsLoopback() {
+			continue
+		}
+		if ipnet.IP.To4() != nil {
 			return ipnet.IP.String()
 		}
+		if ipv6 == "" && ipnet.IP.IsGlobalUnicast() {
+			ipv6 = ipnet.IP.String()
+		}
 	}
-	return ""
+	return ipv6
 }
 
 func GetLocalIpv4() string {
@@ -214,15 +236,16 @@ func ParseIPList(s string) ([]string, error) {
 	return ret, nil
 }
 
+// CheckIP returns if i is an IPv4 or IPv6 address without a port.
 func CheckIP(i string) bool {
-	if !strings.Contains(i, ":") {
-		return net.ParseIP(i) != nil
-	}
-	return false
+	return net.ParseIP(i) != nil
 }
 
 func IPToInt(v string) *big.Int {
-	ip := net.ParseIP(v).To4()
+	ip := net.ParseIP(v)
+	if ip == nil {
+		return nil
+	}
 	if val := ip.To4(); val != nil {
 		return big.NewInt(0).SetBytes(val)
 	}
@@ -236,12 +259,24 @@ func CompareIP(v1, v2 string) (int, error) {
 	if i == nil || j == nil {
 		return 2, fmt.Errorf("ip is invalid，check you command args")
 	}
+	if IsIpv4(v1) != IsIpv4(v2) {
+		return 2, fmt.Errorf("ip %s and %s are not of the same family", v1, v2)
+	}
 	return i.Cmp(j), nil
 }
 
 func NextIP(ip string) net.IP {
+	size := net.IPv6len
+	if IsIpv4(ip) {
+		size = net.IPv4len
+	}
 	i := IPToInt(ip)
-	return i.Add(i, big.NewInt(1)).Bytes()
+	next := i.Add(i, big.NewInt(1)).Bytes()
+	if len(next) > size {
+		// overflowed
+		return nil
+	}
+	return append(make(net.IP, size-len(next)), next...)
 }
 
 func Contains(subnetStr, s string) (bool, error) {
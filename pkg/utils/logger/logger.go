@@ -16,8 +16,10 @@ package logger
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -27,8 +29,38 @@ import (
 
 var (
 	defaultLogger *zap.Logger
+	// console is where the console logs are written to, stdout by default
+	console = &consoleWriter{w: os.Stdout}
 )
 
+type consoleWriter struct {
+	mu sync.RWMutex
+	w  io.Writer
+}
+
+func (c *consoleWriter) Write(p []byte) (int, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.w.Write(p)
+}
+
+func (c *consoleWriter) Sync() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if f, ok := c.w.(*os.File); ok {
+		return f.Sync()
+	}
+	return nil
+}
+
+// SetConsoleOutput changes where the console logs are written to, e.g. os.Stderr
+// when the stdout is used for the machine-readable output.
+func SetConsoleOutput(w io.Writer) {
+	console.mu.Lock()
+	defer console.mu.Unlock()
+	console.w = w
+}
+
 // init default logger with only console output info above
 func init() {
 	zc := zapcore.NewTee(newConsoleCore(zap.InfoLevel))
@@ -76,7 +108,7 @@ func genConfigs(debugMode bool, showPath bool) (zapcore.LevelEnabler, []zap.Opti
 }
 
 func newConsoleCore(le zapcore.LevelEnabler) zapcore.Core {
-	consoleLogger := zapcore.Lock(os.Stdout)
+	consoleLogger := zapcore.Lock(console)
 
 	zec := zap.NewProductionEncoderConfig()
 	zec.EncodeLevel = zapcore.LowercaseColorLevelEncoder
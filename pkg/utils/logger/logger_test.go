@@ -15,6 +15,7 @@
 package logger
 
 import (
+	"bytes"
 	"errors"
 	"os"
 	"os/exec"
@@ -43,6 +44,18 @@ func TestInfoLog(t *testing.T) {
 	}
 }
 
+func TestSetConsoleOutput(t *testing.T) {
+	CfgConsoleLogger(false, false)
+	var buf bytes.Buffer
+	SetConsoleOutput(&buf)
+	defer SetConsoleOutput(os.Stdout)
+
+	Info("written to the buffer")
+	if !bytes.Contains(buf.Bytes(), []byte("written to the buffer")) {
+		t.Errorf("console log is not redirected, got %q", buf.String())
+	}
+}
+
 func TestFatalLog(t *testing.T) {
 	if os.Getenv("LOG_FATAL") == "1" {
 		Fatal("this is fatal")
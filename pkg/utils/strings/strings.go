@@ -22,12 +22,14 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"unicode"
 
 	"github.com/labring/sealos/pkg/constants"
 	"github.com/labring/sealos/pkg/template"
 	"github.com/labring/sealos/pkg/utils/file"
+	"github.com/labring/sealos/pkg/utils/iputils"
 
 	"golang.org/x/exp/slices"
 
@@ -36,7 +38,7 @@ import (
 
 func NotInIPList(slice []string, key string) bool {
 	for _, s := range slice {
-		if key == strings.Split(s, ":")[0] {
+		if key == iputils.GetHostIP(s) {
 			return false
 		}
 	}
@@ -142,8 +144,14 @@ func IsLetterOrNumber(k string) bool {
 
 func RenderShellWithEnv(shell string, envs map[string]string) string {
 	var env string
-	for k, v := range envs {
-		env = fmt.Sprintf("%s%s=\"%s\" ", env, k, v)
+	// sorted, so that the same envs are always rendered the same
+	keys := make([]string, 0, len(envs))
+	for k := range envs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = fmt.Sprintf("%s%s=\"%s\" ", env, k, envs[k])
 	}
 	if env == "" {
 		return shell
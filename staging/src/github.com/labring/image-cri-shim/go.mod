@@ -8,6 +8,7 @@ require (
 	github.com/labring/sealos v0.0.0
 	github.com/labring/sreg v0.1.6
 	github.com/pelletier/go-toml v1.9.5
+	github.com/prometheus/client_golang v1.16.0
 	google.golang.org/grpc v1.50.1
 	k8s.io/apimachinery v0.27.4
 	k8s.io/cri-api v0.27.4
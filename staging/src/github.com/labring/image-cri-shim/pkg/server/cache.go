@@ -0,0 +1,102 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/labring/sreg/pkg/registry/crane"
+	"k8s.io/apimachinery/pkg/util/cache"
+)
+
+type lookupResult struct {
+	image string
+	auth  *types.AuthConfig
+	err   error
+}
+
+// manifestCache caches the image manifest lookups against the registries, images not found
+// are cached too but for a shorter time. A nil *manifestCache always asks the registries.
+type manifestCache struct {
+	cache       *cache.LRUExpireCache
+	ttl         time.Duration
+	negativeTTL time.Duration
+}
+
+func newManifestCache(size int, ttl, negativeTTL time.Duration) *manifestCache {
+	if size <= 0 {
+		return nil
+	}
+	return &manifestCache{
+		cache:       cache.NewLRUExpireCache(size),
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+	}
+}
+
+func lookupKey(image string, authConfig map[string]types.AuthConfig) string {
+	registries := make([]string, 0, len(authConfig))
+	for domain, c := range authConfig {
+		registries = append(registries, domain+"="+c.ServerAddress)
+	}
+	sort.Strings(registries)
+	return image + "|" + strings.Join(registries, ",")
+}
+
+// getImageManifest resolves image against the registries of authConfig like crane.GetImageManifestFromAuth.
+func (c *manifestCache) getImageManifest(image string, authConfig map[string]types.AuthConfig) (string, *types.AuthConfig, error) {
+	if c == nil {
+		return lookupImageManifest("miss", image, authConfig)
+	}
+	key := lookupKey(image, authConfig)
+	if v, ok := c.cache.Get(key); ok {
+		r := v.(lookupResult)
+		manifestLookups.WithLabelValues("hit", lookupResultLabel(r.err)).Inc()
+		return r.image, r.auth, r.err
+	}
+	newImage, cfg, err := lookupImageManifest("miss", image, authConfig)
+	ttl := c.ttl
+	if err != nil {
+		ttl = c.negativeTTL
+	}
+	c.cache.Add(key, lookupResult{image: newImage, auth: cfg, err: err}, ttl)
+	return newImage, cfg, err
+}
+
+// purge drops all cached lookups, the auth they were made with may be stale.
+func (c *manifestCache) purge() {
+	if c == nil {
+		return
+	}
+	for _, key := range c.cache.Keys() {
+		c.cache.Remove(key)
+	}
+}
+
+func lookupImageManifest(cacheLabel, image string, authConfig map[string]types.AuthConfig) (string, *types.AuthConfig, error) {
+	newImage, _, cfg, err := crane.GetImageManifestFromAuth(image, authConfig)
+	manifestLookups.WithLabelValues(cacheLabel, lookupResultLabel(err)).Inc()
+	return newImage, cfg, err
+}
+
+func lookupResultLabel(err error) string {
+	if err != nil {
+		return "not_found"
+	}
+	return "found"
+}
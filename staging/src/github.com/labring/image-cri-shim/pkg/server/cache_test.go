@@ -0,0 +1,85 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestManifestCache(t *testing.T) {
+	var manifestRequests atomic.Int32
+	handler := registry.New()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/manifests/") && r.Method != http.MethodPut {
+			manifestRequests.Add(1)
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer s.Close()
+	host := strings.TrimPrefix(s.URL, "http://")
+
+	img, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := name.ParseReference(host+"/library/nginx:1.25", name.Insecure)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = remote.Write(ref, img); err != nil {
+		t.Fatal(err)
+	}
+
+	authConfig := map[string]types.AuthConfig{host: {ServerAddress: s.URL}}
+	c := newManifestCache(10, time.Minute, time.Minute)
+	var requests int32
+	for i := 0; i < 3; i++ {
+		newImage, _, err := c.getImageManifest("nginx:1.25", authConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := host + "/library/nginx:1.25"; newImage != want {
+			t.Fatalf("got %s, want %s", newImage, want)
+		}
+		if _, _, err = c.getImageManifest("nginx:missing", authConfig); err == nil {
+			t.Fatal("expected nginx:missing not found")
+		}
+		if i == 0 {
+			requests = manifestRequests.Load()
+		}
+	}
+	if got := manifestRequests.Load(); got != requests {
+		t.Errorf("cached lookups sent %d manifest requests to the registry", got-requests)
+	}
+
+	c.purge()
+	if _, _, err = c.getImageManifest("nginx:1.25", authConfig); err != nil {
+		t.Fatal(err)
+	}
+	if manifestRequests.Load() == requests {
+		t.Error("lookup after purge is still cached")
+	}
+}
@@ -18,20 +18,26 @@ package server
 
 import (
 	"context"
+	"sync/atomic"
+	"time"
 
 	"github.com/docker/docker/api/types"
 
 	"github.com/google/go-containerregistry/pkg/name"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	api "k8s.io/cri-api/pkg/apis/runtime/v1"
 
+	shimtypes "github.com/labring/image-cri-shim/pkg/types"
+
 	"github.com/labring/sealos/pkg/utils/logger"
 )
 
 type v1ImageService struct {
-	imageClient       api.ImageServiceClient
-	CRIConfigs        map[string]types.AuthConfig
-	OfflineCRIConfigs map[string]types.AuthConfig
+	imageClient api.ImageServiceClient
+	auth        atomic.Pointer[shimtypes.ShimAuthConfig]
+	cache       *manifestCache
 }
 
 func ToV1AuthConfig(c *types.AuthConfig) *api.AuthConfig {
@@ -60,16 +66,20 @@ func (s *v1ImageService) ListImages(ctx context.Context,
 func (s *v1ImageService) ImageStatus(ctx context.Context,
 	req *api.ImageStatusRequest) (*api.ImageStatusResponse, error) {
 	logger.Debug("ImageStatus: %+v", req)
+	auth := s.auth.Load()
 	if req.Image != nil {
 		if id, _ := s.GetImageRefByID(ctx, req.Image.Image); id != "" {
 			req.Image.Image = id
+		} else if id = s.getRewrittenImageRef(ctx, req.Image.Image, auth.Rules); id != "" {
+			req.Image.Image = id
 		} else {
-			req.Image.Image, _, _ = replaceImage(req.Image.Image, "ImageStatus", s.OfflineCRIConfigs)
+			req.Image.Image, _, _ = replaceImage(s.cache, auth.Rules.Rewrite(req.Image.Image), "ImageStatus", auth.OfflineCRIConfigs)
 		}
 	}
 	rsp, err := s.imageClient.ImageStatus(ctx, req)
 
 	if err != nil {
+		requestErrors.WithLabelValues("ImageStatus").Inc()
 		return nil, err
 	}
 
@@ -79,25 +89,54 @@ func (s *v1ImageService) ImageStatus(ctx context.Context,
 func (s *v1ImageService) PullImage(ctx context.Context,
 	req *api.PullImageRequest) (*api.PullImageResponse, error) {
 	logger.Debug("PullImage begin: %+v", req)
+	shimAuth := s.auth.Load()
 	if req.Image != nil {
-		imageName, ok, auth := replaceImage(req.Image.Image, "PullImage", s.OfflineCRIConfigs)
+		if rule, denied := shimAuth.Rules.Denied(req.Image.Image); denied {
+			imageRewrites.WithLabelValues("deny").Inc()
+			requestErrors.WithLabelValues("PullImage").Inc()
+			return nil, status.Errorf(codes.PermissionDenied, "image %s is denied by %s", req.Image.Image, rule)
+		}
+		image := shimAuth.Rules.Rewrite(req.Image.Image)
+		if image != req.Image.Image {
+			if rule, denied := shimAuth.Rules.Denied(image); denied {
+				imageRewrites.WithLabelValues("deny").Inc()
+				requestErrors.WithLabelValues("PullImage").Inc()
+				return nil, status.Errorf(codes.PermissionDenied, "image %s rewritten to %s is denied by %s", req.Image.Image, image, rule)
+			}
+			logger.Info("image: %s, rewritten: %s", req.Image.Image, image)
+			imageRewrites.WithLabelValues("rewrite").Inc()
+			// the auth kubelet sent is for the original registry
+			req.Auth = nil
+		}
+		imageName, ok, auth := replaceImage(s.cache, image, "PullImage", shimAuth.OfflineCRIConfigs)
 		if ok {
-			req.Auth = ToV1AuthConfig(auth)
+			imageRewrites.WithLabelValues("offline").Inc()
 		} else {
-			if req.Auth == nil {
-				ref, _ := name.ParseReference(imageName)
-				if v, ok := s.CRIConfigs[ref.Context().RegistryStr()]; ok {
-					req.Auth = ToV1AuthConfig(&v)
-				}
+			imageName, auth = mirrorImage(s.cache, shimAuth.Rules.Candidates(image), shimAuth.CRIConfigs)
+			if imageName != image {
+				imageRewrites.WithLabelValues("mirror").Inc()
+			}
+		}
+		if auth != nil {
+			req.Auth = ToV1AuthConfig(auth)
+		} else if req.Auth == nil {
+			ref, _ := name.ParseReference(imageName)
+			if v, ok := shimAuth.CRIConfigs[ref.Context().RegistryStr()]; ok {
+				req.Auth = ToV1AuthConfig(&v)
 			}
 		}
 		req.Image.Image = imageName
 	}
 	logger.Debug("PullImage after: %+v", req)
+	start := time.Now()
 	rsp, err := s.imageClient.PullImage(ctx, req)
 	if err != nil {
+		requestErrors.WithLabelValues("PullImage").Inc()
 		return nil, err
 	}
+	if req.Image != nil {
+		pullDuration.WithLabelValues(imageRegistry(req.Image.Image)).Observe(time.Since(start).Seconds())
+	}
 
 	return rsp, err
 }
@@ -105,16 +144,20 @@ func (s *v1ImageService) PullImage(ctx context.Context,
 func (s *v1ImageService) RemoveImage(ctx context.Context,
 	req *api.RemoveImageRequest) (*api.RemoveImageResponse, error) {
 	logger.Debug("RemoveImage: %+v", req)
+	auth := s.auth.Load()
 	if req.Image != nil {
 		if id, _ := s.GetImageRefByID(ctx, req.Image.Image); id != "" {
 			req.Image.Image = id
+		} else if id = s.getRewrittenImageRef(ctx, req.Image.Image, auth.Rules); id != "" {
+			req.Image.Image = id
 		} else {
-			req.Image.Image, _, _ = replaceImage(req.Image.Image, "RemoveImage", s.OfflineCRIConfigs)
+			req.Image.Image, _, _ = replaceImage(s.cache, auth.Rules.Rewrite(req.Image.Image), "RemoveImage", auth.OfflineCRIConfigs)
 		}
 	}
 	rsp, err := s.imageClient.RemoveImage(ctx, req)
 
 	if err != nil {
+		requestErrors.WithLabelValues("RemoveImage").Inc()
 		return nil, err
 	}
 
@@ -133,6 +176,19 @@ func (s *v1ImageService) ImageFsInfo(ctx context.Context,
 	return rsp, err
 }
 
+// getRewrittenImageRef returns the id of the first rewritten or mirrored name of image the runtime has.
+func (s *v1ImageService) getRewrittenImageRef(ctx context.Context, image string, rules *shimtypes.ImageRules) string {
+	for _, c := range rules.Candidates(rules.Rewrite(image)) {
+		if c.Image == image {
+			continue
+		}
+		if id, _ := s.GetImageRefByID(ctx, c.Image); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
 func (s *v1ImageService) GetImageRefByID(ctx context.Context, image string) (string, error) {
 	resp, err := s.imageClient.ImageStatus(ctx, &api.ImageStatusRequest{
 		Image: &api.ImageSpec{
@@ -0,0 +1,70 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	httputils "github.com/labring/sealos/pkg/utils/http"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+const metricsNamespace = "image_cri_shim"
+
+var (
+	manifestLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "manifest_lookups_total",
+		Help:      "Image manifest lookups by cache hit or miss and whether the image was found.",
+	}, []string{"cache", "result"})
+
+	imageRewrites = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "image_rewrites_total",
+		Help:      "Images pulled by another name or denied, by the kind of rule.",
+	}, []string{"type"})
+
+	pullDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "pull_duration_seconds",
+		Help:      "Duration of image pulls by the registry pulled from.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 14),
+	}, []string{"registry"})
+
+	requestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "request_errors_total",
+		Help:      "Image service requests failed, by CRI method.",
+	}, []string{"method"})
+)
+
+var metricsRegistry = httputils.NewMetricsRegistry(
+	manifestLookups,
+	imageRewrites,
+	pullDuration,
+	requestErrors,
+)
+
+// serveMetrics serves the prometheus metrics at /metrics on address.
+func serveMetrics(address string) (*http.Server, error) {
+	srv, err := httputils.ServeMetrics(address, httputils.NewMetricsMux(metricsRegistry))
+	if err != nil {
+		return nil, serverError("failed to listen metrics on %s: %v", address, err)
+	}
+	logger.Info("serving metrics on %s/metrics", address)
+	return srv, nil
+}
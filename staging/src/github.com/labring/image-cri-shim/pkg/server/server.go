@@ -18,16 +18,18 @@ package server
 import (
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"os/user"
 	"path/filepath"
 	"strconv"
 	"time"
 
-	dockertype "github.com/docker/docker/api/types"
 	"google.golang.org/grpc"
 	k8sv1api "k8s.io/cri-api/pkg/apis/runtime/v1"
 
+	"github.com/labring/image-cri-shim/pkg/types"
+
 	"github.com/labring/sealos/pkg/utils/logger"
 	netutil "github.com/labring/sealos/pkg/utils/net"
 )
@@ -42,14 +44,19 @@ type Options struct {
 	Group int
 	// Mode is the permission mode bits for our gRPC socket.
 	Mode os.FileMode
-	//CRIConfigs is cri config for auth
-	CRIConfigs        map[string]dockertype.AuthConfig
-	OfflineCRIConfigs map[string]dockertype.AuthConfig
+	// Auth is the cri config for auth and the image rules, it can be swapped by UpdateAuth.
+	Auth *types.ShimAuthConfig
+	// Cache configures the cache of image manifest lookups.
+	Cache types.Cache
+	// Metrics is the address serving prometheus metrics, empty disables it.
+	Metrics string
 }
 
 type Server interface {
 	RegisterImageService(conn *grpc.ClientConn) error
 
+	UpdateAuth(auth *types.ShimAuthConfig)
+
 	Chown(uid, gid int) error
 
 	Chmod(mode os.FileMode) error
@@ -62,8 +69,10 @@ type Server interface {
 type server struct {
 	server        *grpc.Server
 	imageV1Client k8sv1api.ImageServiceClient
+	imageService  *v1ImageService
 	options       Options
 	listener      net.Listener // socket our gRPC server listens on
+	metrics       *http.Server
 }
 
 // RegisterImageService registers an image service with the server.
@@ -78,16 +87,35 @@ func (s *server) RegisterImageService(conn *grpc.ClientConn) error {
 		return err
 	}
 
-	k8sv1api.RegisterImageServiceServer(s.server, &v1ImageService{
-		imageClient:       s.imageV1Client,
-		CRIConfigs:        s.options.CRIConfigs,
-		OfflineCRIConfigs: s.options.OfflineCRIConfigs,
-	})
+	s.imageService = &v1ImageService{
+		imageClient: s.imageV1Client,
+		cache:       newManifestCache(s.options.Cache.Size, s.options.Cache.TTL.Duration, s.options.Cache.NegativeTTL.Duration),
+	}
+	s.imageService.auth.Store(s.options.Auth)
+	k8sv1api.RegisterImageServiceServer(s.server, s.imageService)
 
 	return nil
 }
 
+// UpdateAuth atomically swaps the auth and image rules used by the image service,
+// requests in flight keep using the ones they started with.
+func (s *server) UpdateAuth(auth *types.ShimAuthConfig) {
+	s.options.Auth = auth
+	if s.imageService != nil {
+		s.imageService.auth.Store(auth)
+		s.imageService.cache.purge()
+	}
+}
+
 func (s *server) Start() error {
+	if s.options.Metrics != "" {
+		metrics, err := serveMetrics(s.options.Metrics)
+		if err != nil {
+			return err
+		}
+		s.metrics = metrics
+	}
+
 	go func() {
 		_ = s.server.Serve(s.listener)
 	}()
@@ -190,12 +218,18 @@ func (s *server) Chown(uid, gid int) error {
 func (s *server) Stop() {
 	logger.Info("stopping server on socket %s...", s.options.Socket)
 	s.server.Stop()
+	if s.metrics != nil {
+		_ = s.metrics.Close()
+	}
 }
 
 func NewServer(options Options) (Server, error) {
 	if !filepath.IsAbs(options.Socket) {
 		return nil, fmt.Errorf("invalid socked")
 	}
+	if options.Auth == nil {
+		options.Auth = &types.ShimAuthConfig{}
+	}
 
 	s := &server{
 		options: options,
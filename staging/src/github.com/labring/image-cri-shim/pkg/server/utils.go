@@ -20,6 +20,9 @@ import (
 	"github.com/labring/sreg/pkg/registry/crane"
 
 	"github.com/docker/docker/api/types"
+	"github.com/google/go-containerregistry/pkg/name"
+
+	shimtypes "github.com/labring/image-cri-shim/pkg/types"
 
 	"github.com/labring/sealos/pkg/utils/logger"
 )
@@ -72,7 +75,7 @@ import (
 //	}
 
 // replaceImage replaces the image name to a new valid image name with the private registry.
-func replaceImage(image, action string, authConfig map[string]types.AuthConfig) (newImage string, isReplace bool, cfg *types.AuthConfig) {
+func replaceImage(c *manifestCache, image, action string, authConfig map[string]types.AuthConfig) (newImage string, isReplace bool, cfg *types.AuthConfig) {
 	// TODO we can change the image name of req, and make the cri pull the image we need.
 	// for example:
 	// req.Image.Image = "sealos.hub:5000/library/nginx:1.1.1"
@@ -81,7 +84,7 @@ func replaceImage(image, action string, authConfig map[string]types.AuthConfig)
 	// but kubelet sometimes will invoke imageService.RemoveImage() or something else. The req.Image.Image will the original name.
 	// so we'd better tag "sealos.hub:5000/library/nginx:1.1.1" with original name "req.Image.Image" After "rsp, err := (*s.imageService).PullImage(ctx, req)".
 	//for image id] this is mistake, we should replace the image name, not the image id.
-	newImage, _, cfg, err := crane.GetImageManifestFromAuth(image, authConfig)
+	newImage, cfg, err := c.getImageManifest(image, authConfig)
 	if err != nil {
 		logger.Warn("get image %s manifest error %s", newImage, err.Error())
 		logger.Debug("image %s not found in registry, skipping", image)
@@ -90,3 +93,38 @@ func replaceImage(image, action string, authConfig map[string]types.AuthConfig)
 	logger.Info("image: %s, newImage: %s, action: %s", image, newImage, action)
 	return newImage, true, cfg
 }
+
+// mirrorImage returns the first mirror candidate whose manifest can be fetched and the auth for it,
+// falling back to the image itself with a nil auth.
+func mirrorImage(mc *manifestCache, candidates []shimtypes.Candidate, authConfig map[string]types.AuthConfig) (string, *types.AuthConfig) {
+	for _, c := range candidates {
+		if c.Endpoint == "" {
+			continue
+		}
+		domain := crane.NormalizeRegistry(crane.GetRegistryDomain(c.Endpoint))
+		auth, ok := authConfig[domain]
+		if !ok {
+			auth = types.AuthConfig{ServerAddress: c.Endpoint}
+		}
+		newImage, cfg, err := mc.getImageManifest(c.Image, map[string]types.AuthConfig{domain: auth})
+		if err != nil {
+			logger.Debug("image %s not found in mirror %s: %v", c.Image, c.Endpoint, err)
+			continue
+		}
+		logger.Info("image: %s, mirror: %s", c.Image, c.Endpoint)
+		if !ok {
+			return newImage, nil
+		}
+		return newImage, cfg
+	}
+	return candidates[len(candidates)-1].Image, nil
+}
+
+// imageRegistry returns the registry of image, or unknown if image can not be parsed.
+func imageRegistry(image string) string {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return "unknown"
+	}
+	return ref.Context().RegistryStr()
+}
@@ -42,6 +42,8 @@ type Shim interface {
 	Start() error
 	// Stop stops the shim.
 	Stop()
+	// Reload applies a new config to the running shim.
+	Reload(cfg *types.Config) error
 }
 
 // shim is the implementation of Shim.
@@ -69,13 +71,12 @@ func NewShim(cfg *types.Config, auth *types.ShimAuthConfig) (Shim, error) {
 	r.client = clt
 
 	srvopts := server.Options{
-		Timeout:           cfg.Timeout.Duration,
-		Socket:            cfg.ImageShimSocket,
-		User:              -1,
-		Group:             -1,
-		Mode:              0660,
-		CRIConfigs:        auth.CRIConfigs,
-		OfflineCRIConfigs: auth.OfflineCRIConfigs,
+		Timeout: cfg.Timeout.Duration,
+		Socket:  cfg.ImageShimSocket,
+		User:    -1,
+		Group:   -1,
+		Mode:    0660,
+		Auth:    auth,
 	}
 	srv, err := server.NewServer(srvopts)
 	if err != nil {
@@ -116,6 +117,45 @@ func (r *shim) Stop() {
 	r.server.Stop()
 }
 
+// Reload preprocesses cfg and swaps the auth and image rules used by the running shim.
+// Changing the shim or cri socket or the timeout requires a restart, the current ones are kept.
+func (r *shim) Reload(cfg *types.Config) error {
+	r.Lock()
+	defer r.Unlock()
+
+	auth, err := cfg.PreProcess()
+	if err != nil {
+		return shimError("failed to preprocess config: %v", err)
+	}
+	diffs := r.cfg.Diff(cfg)
+	if len(diffs) == 0 {
+		logger.Info("image shim config is not changed")
+		return nil
+	}
+	for _, diff := range diffs {
+		logger.Info("image shim config changed, %s", diff)
+	}
+	if cfg.ImageShimSocket != r.cfg.ImageShimSocket || cfg.RuntimeSocket != r.cfg.RuntimeSocket {
+		logger.Warn("changing the shim or cri socket requires a restart, keep using %s and %s",
+			r.cfg.ImageShimSocket, r.cfg.RuntimeSocket)
+		cfg.ImageShimSocket = r.cfg.ImageShimSocket
+		cfg.RuntimeSocket = r.cfg.RuntimeSocket
+	}
+	if cfg.Timeout.Duration != r.cfg.Timeout.Duration {
+		logger.Warn("changing the timeout requires a restart, keep using %s", r.cfg.Timeout.Duration)
+		cfg.Timeout = r.cfg.Timeout
+	}
+	if cfg.Cache != r.cfg.Cache || cfg.Metrics != r.cfg.Metrics {
+		logger.Warn("changing the cache or metrics requires a restart, keep using %+v and %q",
+			r.cfg.Cache, r.cfg.Metrics)
+		cfg.Cache = r.cfg.Cache
+		cfg.Metrics = r.cfg.Metrics
+	}
+	r.server.UpdateAuth(auth)
+	r.cfg = cfg
+	return nil
+}
+
 func (r *shim) dialNotify(socket string, uid int, gid int, mode os.FileMode, err error) {
 	if err != nil {
 		logger.Error("failed to determine permissions/ownership of client socket %q: %v",
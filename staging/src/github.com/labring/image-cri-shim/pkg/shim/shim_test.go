@@ -0,0 +1,59 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shim
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/labring/image-cri-shim/pkg/types"
+)
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	newConfig := func(address string, timeout time.Duration) *types.Config {
+		return &types.Config{
+			ImageShimSocket: filepath.Join(dir, "image-cri-shim.sock"),
+			RuntimeSocket:   filepath.Join(dir, "containerd.sock"),
+			Address:         address,
+			Force:           true,
+			Timeout:         metav1.Duration{Duration: timeout},
+		}
+	}
+	cfg := newConfig("http://sealos.hub:5000", 15*time.Minute)
+	auth, err := cfg.PreProcess()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewShim(cfg, auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.Reload(newConfig("http://192.168.64.1:5000", time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	running := s.(*shim).cfg
+	if running.Address != "http://192.168.64.1:5000" {
+		t.Errorf("address %s is not reloaded", running.Address)
+	}
+	// the timeout requires a restart
+	if running.Timeout.Duration != 15*time.Minute {
+		t.Errorf("timeout changed to %s without restart", running.Timeout.Duration)
+	}
+}
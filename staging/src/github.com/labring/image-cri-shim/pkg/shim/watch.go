@@ -0,0 +1,81 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shim
+
+import (
+	"crypto/sha256"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/labring/image-cri-shim/pkg/types"
+
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+// DefaultReloadInterval is how often the config file is checked for changes.
+const DefaultReloadInterval = 5 * time.Second
+
+// Watch reloads s whenever the content of the config file changes or SIGHUP is received,
+// until stopCh is closed. A config that fails to load is logged and the running one is kept.
+func Watch(s Shim, path string, interval time.Duration, stopCh <-chan struct{}) {
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	defer signal.Stop(hupCh)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	sum, _ := checksum(path)
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-hupCh:
+			logger.Info("received SIGHUP, reloading image shim config %s", path)
+		case <-ticker.C:
+			newSum, err := checksum(path)
+			if err != nil {
+				logger.Debug("failed to read image shim config %s: %v", path, err)
+				continue
+			}
+			if newSum == sum {
+				continue
+			}
+			logger.Info("image shim config %s is modified, reloading", path)
+		}
+		sum, _ = checksum(path)
+		if err := reload(s, path); err != nil {
+			logger.Error("failed to reload image shim config, keep the running one: %v", err)
+		}
+	}
+}
+
+func reload(s Shim, path string) error {
+	cfg, err := types.Unmarshal(path)
+	if err != nil {
+		return err
+	}
+	return s.Reload(cfg)
+}
+
+func checksum(path string) ([sha256.Size]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(data), nil
+}
@@ -0,0 +1,71 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shim
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/labring/image-cri-shim/pkg/types"
+)
+
+type fakeShim struct {
+	reloaded chan *types.Config
+}
+
+func (f *fakeShim) Setup() error { return nil }
+func (f *fakeShim) Start() error { return nil }
+func (f *fakeShim) Stop()        {}
+func (f *fakeShim) Reload(cfg *types.Config) error {
+	f.reloaded <- cfg
+	return nil
+}
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "image-cri-shim.yaml")
+	if err := os.WriteFile(path, []byte("address: http://sealos.hub:5000\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeShim{reloaded: make(chan *types.Config, 1)}
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go Watch(s, path, 10*time.Millisecond, stopCh)
+
+	expect := func(address string) {
+		t.Helper()
+		select {
+		case cfg := <-s.reloaded:
+			if cfg.Address != address {
+				t.Errorf("reloaded address %s, want %s", cfg.Address, address)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("config with address %s is not reloaded", address)
+		}
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(path, []byte("address: http://192.168.64.1:5000\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	expect("http://192.168.64.1:5000")
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	expect("http://192.168.64.1:5000")
+}
@@ -18,7 +18,10 @@ package types
 
 import (
 	"errors"
+	"fmt"
 	"net/url"
+	"reflect"
+	"sort"
 	"strings"
 	"time"
 
@@ -38,6 +41,10 @@ const (
 	// SealosShimSock is the CRI socket the shim listens on.
 	SealosShimSock            = "/var/run/image-cri-shim.sock"
 	DefaultImageCRIShimConfig = "/etc/image-cri-shim.yaml"
+
+	defaultCacheSize        = 1024
+	defaultCacheTTL         = 10 * time.Minute
+	defaultNegativeCacheTTL = time.Minute
 )
 
 type Registry struct {
@@ -45,6 +52,16 @@ type Registry struct {
 	Auth    string `json:"auth"`
 }
 
+// Cache configures the cache of image manifest lookups.
+type Cache struct {
+	// Size is the max number of cached lookups, a negative value disables the cache.
+	Size int `json:"size,omitempty"`
+	// TTL is how long a found image is cached.
+	TTL metav1.Duration `json:"ttl,omitempty"`
+	// NegativeTTL is how long an image not found in the registry is cached.
+	NegativeTTL metav1.Duration `json:"negativeTTL,omitempty"`
+}
+
 type Config struct {
 	ImageShimSocket string          `json:"shim"`
 	RuntimeSocket   string          `json:"cri"`
@@ -54,11 +71,22 @@ type Config struct {
 	Timeout         metav1.Duration `json:"timeout"`
 	Auth            string          `json:"auth"`
 	Registries      []Registry      `json:"registries"`
+	// Rewrites redirect matching images to another name, the first match wins.
+	Rewrites []Rewrite `json:"rewrites,omitempty"`
+	// Mirrors are tried in order before pulling from the registry itself.
+	Mirrors []Mirror `json:"mirrors,omitempty"`
+	// Deny rejects pulling matching images.
+	Deny []ImageRule `json:"deny,omitempty"`
+	// Cache configures the cache of image manifest lookups.
+	Cache Cache `json:"cache,omitempty"`
+	// Metrics is the address serving prometheus metrics at /metrics, empty disables it.
+	Metrics string `json:"metrics,omitempty"`
 }
 
 type ShimAuthConfig struct {
 	CRIConfigs        map[string]types2.AuthConfig `json:"-"`
 	OfflineCRIConfigs map[string]types2.AuthConfig `json:"-"`
+	Rules             *ImageRules                  `json:"-"`
 }
 
 func (c *Config) PreProcess() (*ShimAuthConfig, error) {
@@ -79,11 +107,23 @@ func (c *Config) PreProcess() (*ShimAuthConfig, error) {
 		c.Timeout.Duration, _ = time.ParseDuration("15m")
 	}
 
+	if c.Cache.Size == 0 {
+		c.Cache.Size = defaultCacheSize
+	}
+	if c.Cache.TTL.Duration == 0 {
+		c.Cache.TTL = metav1.Duration{Duration: defaultCacheTTL}
+	}
+	if c.Cache.NegativeTTL.Duration == 0 {
+		c.Cache.NegativeTTL = metav1.Duration{Duration: defaultNegativeCacheTTL}
+	}
+
 	logger.Info("RegistryDomain: %v", domain)
 	logger.Info("Force: %v", c.Force)
 	logger.Info("Debug: %v", c.Debug)
 	logger.CfgConsoleLogger(c.Debug, false)
 	logger.Info("Timeout: %v", c.Timeout)
+	logger.Info("Cache: %+v", c.Cache)
+	logger.Info("Metrics: %v", c.Metrics)
 	shimAuth := new(ShimAuthConfig)
 
 	splitNameAndPasswd := func(auth string) (string, string) {
@@ -133,6 +173,10 @@ func (c *Config) PreProcess() (*ShimAuthConfig, error) {
 	if c.Address == "" {
 		return nil, errors.New("registry addr is empty")
 	}
+	if shimAuth.Rules, err = c.ImageRules(); err != nil {
+		return nil, err
+	}
+	logger.Info("rewrites: %d, mirrors: %d, deny: %d", len(c.Rewrites), len(c.Mirrors), len(c.Deny))
 	if c.RuntimeSocket == "" {
 		socket, err := cri.DetectCRISocket()
 		if err != nil {
@@ -159,3 +203,52 @@ func Unmarshal(path string) (*Config, error) {
 	}
 	return cfg, err
 }
+
+// Diff describes what changed from c to o, secrets are not included.
+func (c *Config) Diff(o *Config) []string {
+	var diffs []string
+	changed := func(field string, old, new interface{}) {
+		if !reflect.DeepEqual(old, new) {
+			diffs = append(diffs, fmt.Sprintf("%s: %+v -> %+v", field, old, new))
+		}
+	}
+	changed("shim", c.ImageShimSocket, o.ImageShimSocket)
+	changed("cri", c.RuntimeSocket, o.RuntimeSocket)
+	changed("address", c.Address, o.Address)
+	changed("force", c.Force, o.Force)
+	changed("debug", c.Debug, o.Debug)
+	changed("timeout", c.Timeout.Duration, o.Timeout.Duration)
+	if c.Auth != o.Auth {
+		diffs = append(diffs, "auth changed")
+	}
+
+	registries := make(map[string]string, len(c.Registries))
+	for _, r := range c.Registries {
+		registries[r.Address] = r.Auth
+	}
+	for _, r := range o.Registries {
+		auth, ok := registries[r.Address]
+		switch {
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("registry %s added", r.Address))
+		case auth != r.Auth:
+			diffs = append(diffs, fmt.Sprintf("registry %s auth changed", r.Address))
+		}
+		delete(registries, r.Address)
+	}
+	removed := make([]string, 0, len(registries))
+	for address := range registries {
+		removed = append(removed, address)
+	}
+	sort.Strings(removed)
+	for _, address := range removed {
+		diffs = append(diffs, fmt.Sprintf("registry %s removed", address))
+	}
+
+	changed("rewrites", c.Rewrites, o.Rewrites)
+	changed("mirrors", c.Mirrors, o.Mirrors)
+	changed("deny", c.Deny, o.Deny)
+	changed("cache", c.Cache, o.Cache)
+	changed("metrics", c.Metrics, o.Metrics)
+	return diffs
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package types
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -31,3 +32,30 @@ func TestUnmarshal(t *testing.T) {
 		return
 	}
 }
+
+func TestConfigDiff(t *testing.T) {
+	old := &Config{
+		Address:    "http://sealos.hub:5000",
+		Auth:       "admin:passw0rd",
+		Registries: []Registry{{Address: "http://192.168.64.1:5000", Auth: "admin:passw0rd"}, {Address: "https://ghcr.io"}},
+	}
+	cfg := &Config{
+		Address:    "http://sealos.hub:5000",
+		Auth:       "admin:newpassw0rd",
+		Registries: []Registry{{Address: "http://192.168.64.1:5000", Auth: "admin:newpassw0rd"}, {Address: "https://quay.io"}},
+		Deny:       []ImageRule{{Prefix: "docker.io/library/busybox"}},
+	}
+	want := []string{
+		"auth changed",
+		"registry http://192.168.64.1:5000 auth changed",
+		"registry https://quay.io added",
+		"registry https://ghcr.io removed",
+		"deny: [] -> [{Prefix:docker.io/library/busybox Regex:}]",
+	}
+	if got := old.Diff(cfg); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %q, want %q", got, want)
+	}
+	if got := cfg.Diff(cfg); len(got) != 0 {
+		t.Errorf("Diff() of the same config = %q", got)
+	}
+}
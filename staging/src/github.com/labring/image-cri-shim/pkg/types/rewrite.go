@@ -0,0 +1,211 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	registry2 "github.com/labring/sreg/pkg/registry/crane"
+)
+
+const dockerHub = "docker.io"
+
+// ImageRule matches an image by its normalized reference, e.g. docker.io/library/nginx:1.25.
+// Exactly one of Prefix and Regex must be set.
+type ImageRule struct {
+	Prefix string `json:"prefix,omitempty"`
+	Regex  string `json:"regex,omitempty"`
+}
+
+// Rewrite redirects images matching the rule to Target. A prefix rule replaces the
+// matched prefix with Target, a regex rule expands Target with the submatches.
+type Rewrite struct {
+	ImageRule
+	Target string `json:"target"`
+}
+
+// Mirror lists the endpoints tried in order before pulling from Registry itself.
+type Mirror struct {
+	Registry  string   `json:"registry"`
+	Endpoints []string `json:"endpoints"`
+}
+
+// Candidate is an image name to try and the registry endpoint serving it.
+// Endpoint is empty for the image itself.
+type Candidate struct {
+	Image    string
+	Endpoint string
+}
+
+type imageMatcher struct {
+	rule   string
+	prefix string
+	regex  *regexp.Regexp
+}
+
+func (m imageMatcher) match(image string) bool {
+	if m.regex != nil {
+		return m.regex.MatchString(image)
+	}
+	return strings.HasPrefix(image, m.prefix)
+}
+
+type rewriteRule struct {
+	imageMatcher
+	target string
+}
+
+func (r rewriteRule) apply(image string) string {
+	if r.regex != nil {
+		return r.regex.ReplaceAllString(image, r.target)
+	}
+	return r.target + strings.TrimPrefix(image, r.prefix)
+}
+
+// ImageRules are the compiled rewrite, mirror and deny rules of the config.
+// A nil *ImageRules leaves every image untouched.
+type ImageRules struct {
+	rewrites []rewriteRule
+	deny     []imageMatcher
+	mirrors  map[string][]string
+}
+
+func newImageMatcher(r ImageRule) (imageMatcher, error) {
+	switch {
+	case r.Prefix != "" && r.Regex != "":
+		return imageMatcher{}, fmt.Errorf("only one of prefix and regex can be set, got prefix %q and regex %q", r.Prefix, r.Regex)
+	case r.Prefix != "":
+		return imageMatcher{rule: "prefix " + r.Prefix, prefix: r.Prefix}, nil
+	case r.Regex != "":
+		re, err := regexp.Compile(r.Regex)
+		if err != nil {
+			return imageMatcher{}, fmt.Errorf("invalid regex %q: %w", r.Regex, err)
+		}
+		return imageMatcher{rule: "regex " + r.Regex, regex: re}, nil
+	}
+	return imageMatcher{}, fmt.Errorf("one of prefix and regex must be set")
+}
+
+// ImageRules validates and compiles the rewrites, mirrors and deny list.
+func (c *Config) ImageRules() (*ImageRules, error) {
+	rules := &ImageRules{mirrors: make(map[string][]string)}
+	for i, r := range c.Rewrites {
+		m, err := newImageMatcher(r.ImageRule)
+		if err != nil {
+			return nil, fmt.Errorf("rewrites[%d]: %w", i, err)
+		}
+		if r.Target == "" {
+			return nil, fmt.Errorf("rewrites[%d]: target is empty", i)
+		}
+		rules.rewrites = append(rules.rewrites, rewriteRule{imageMatcher: m, target: r.Target})
+	}
+	for i, r := range c.Deny {
+		m, err := newImageMatcher(r)
+		if err != nil {
+			return nil, fmt.Errorf("deny[%d]: %w", i, err)
+		}
+		rules.deny = append(rules.deny, m)
+	}
+	for i, m := range c.Mirrors {
+		if m.Registry == "" {
+			return nil, fmt.Errorf("mirrors[%d]: registry is empty", i)
+		}
+		if len(m.Endpoints) == 0 {
+			return nil, fmt.Errorf("mirrors[%d]: endpoints of %s is empty", i, m.Registry)
+		}
+		registry := normalizeRegistry(registry2.GetRegistryDomain(m.Registry))
+		rules.mirrors[registry] = append(rules.mirrors[registry], m.Endpoints...)
+	}
+	return rules, nil
+}
+
+func normalizeRegistry(registry string) string {
+	if registry2.NormalizeRegistry(registry) == name.DefaultRegistry {
+		return dockerHub
+	}
+	return registry
+}
+
+// normalizeImage returns the fully qualified form of image, which is what rules match against.
+func normalizeImage(image string) (registry, repo string, err error) {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return "", "", err
+	}
+	sep := ":"
+	if _, ok := ref.(name.Digest); ok {
+		sep = "@"
+	}
+	return normalizeRegistry(ref.Context().RegistryStr()), ref.Context().RepositoryStr() + sep + ref.Identifier(), nil
+}
+
+// Denied reports whether image is blocked, and by which rule.
+func (r *ImageRules) Denied(image string) (string, bool) {
+	if r == nil || len(r.deny) == 0 {
+		return "", false
+	}
+	registry, repo, err := normalizeImage(image)
+	if err != nil {
+		return "", false
+	}
+	full := registry + "/" + repo
+	for _, m := range r.deny {
+		if m.match(full) {
+			return m.rule, true
+		}
+	}
+	return "", false
+}
+
+// Rewrite returns image redirected by the first matching rewrite rule,
+// or image unchanged if no rule matches.
+func (r *ImageRules) Rewrite(image string) string {
+	if r == nil || len(r.rewrites) == 0 {
+		return image
+	}
+	registry, repo, err := normalizeImage(image)
+	if err != nil {
+		return image
+	}
+	full := registry + "/" + repo
+	for _, rule := range r.rewrites {
+		if rule.match(full) {
+			return rule.apply(full)
+		}
+	}
+	return image
+}
+
+// Candidates returns the mirrors of image in order, followed by image itself.
+func (r *ImageRules) Candidates(image string) []Candidate {
+	self := []Candidate{{Image: image}}
+	if r == nil || len(r.mirrors) == 0 {
+		return self
+	}
+	registry, repo, err := normalizeImage(image)
+	if err != nil {
+		return self
+	}
+	endpoints := r.mirrors[registry]
+	candidates := make([]Candidate, 0, len(endpoints)+1)
+	for _, endpoint := range endpoints {
+		host := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(endpoint, "https://"), "http://"), "/")
+		candidates = append(candidates, Candidate{Image: host + "/" + repo, Endpoint: endpoint})
+	}
+	return append(candidates, self...)
+}
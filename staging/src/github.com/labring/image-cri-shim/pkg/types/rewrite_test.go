@@ -0,0 +1,85 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestImageRules(t *testing.T) {
+	cfg := &Config{
+		Rewrites: []Rewrite{
+			{ImageRule: ImageRule{Prefix: "ghcr.io/"}, Target: "sealos.hub:5000/ghcr/"},
+			{ImageRule: ImageRule{Regex: `^docker\.io/bitnami/(.*)$`}, Target: "sealos.hub:5000/bitnami/$1"},
+		},
+		Mirrors: []Mirror{
+			{Registry: "docker.io", Endpoints: []string{"https://mirror.example.com", "http://192.168.64.1:5000"}},
+		},
+		Deny: []ImageRule{{Prefix: "docker.io/library/busybox"}, {Regex: `:latest$`}},
+	}
+	rules, err := cfg.ImageRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rewrites := map[string]string{
+		"ghcr.io/labring/sealos:v4.3.0": "sealos.hub:5000/ghcr/labring/sealos:v4.3.0",
+		"bitnami/redis:7.0":             "sealos.hub:5000/bitnami/redis:7.0",
+		"nginx:1.25":                    "nginx:1.25",
+	}
+	for image, want := range rewrites {
+		if got := rules.Rewrite(image); got != want {
+			t.Errorf("Rewrite(%s) = %s, want %s", image, got, want)
+		}
+	}
+
+	for image, want := range map[string]bool{
+		"busybox:1.36":               true,
+		"index.docker.io/nginx":      true,
+		"nginx:1.25":                 false,
+		"quay.io/library/busybox:v1": false,
+	} {
+		if _, got := rules.Denied(image); got != want {
+			t.Errorf("Denied(%s) = %v, want %v", image, got, want)
+		}
+	}
+
+	want := []Candidate{
+		{Image: "mirror.example.com/library/nginx:1.25", Endpoint: "https://mirror.example.com"},
+		{Image: "192.168.64.1:5000/library/nginx:1.25", Endpoint: "http://192.168.64.1:5000"},
+		{Image: "nginx:1.25"},
+	}
+	if got := rules.Candidates("nginx:1.25"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Candidates() = %+v, want %+v", got, want)
+	}
+	if got := rules.Candidates("quay.io/coreos/etcd:v3.5"); len(got) != 1 {
+		t.Errorf("Candidates() of an unmirrored registry = %+v", got)
+	}
+}
+
+func TestImageRulesInvalid(t *testing.T) {
+	for _, cfg := range []*Config{
+		{Rewrites: []Rewrite{{ImageRule: ImageRule{Prefix: "ghcr.io/"}}}},
+		{Rewrites: []Rewrite{{ImageRule: ImageRule{Prefix: "ghcr.io/", Regex: "ghcr"}, Target: "x"}}},
+		{Deny: []ImageRule{{Regex: "("}}},
+		{Deny: []ImageRule{{}}},
+		{Mirrors: []Mirror{{Registry: "docker.io"}}},
+	} {
+		if _, err := cfg.ImageRules(); err == nil {
+			t.Errorf("expected error for %+v", cfg)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package care
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Config describes the virtual servers taken care of, it is read from the --config file.
+//
+//	virtualServers:
+//	- address: 10.103.97.12:6443
+//	  realServers:
+//	  - address: 192.168.0.2:6443
+//	  - address: 192.168.0.3:6443
+//	- address: 10.103.97.12:5000
+//	  scheduler: wrr
+//	  realServers:
+//	  - address: 192.168.0.2:5000
+//	    weight: 2
+//	  health:
+//	    type: tcp
+//	    fall: 3
+type Config struct {
+	VirtualServers []VirtualServerConfig `json:"virtualServers"`
+}
+
+type VirtualServerConfig struct {
+	Address string `json:"address"`
+	// Scheduler defaults to the --scheduler flag.
+	Scheduler   string             `json:"scheduler,omitempty"`
+	RealServers []RealServerConfig `json:"realServers"`
+	Health      HealthConfig       `json:"health,omitempty"`
+}
+
+type RealServerConfig struct {
+	Address string `json:"address"`
+	// Weight defaults to 1.
+	Weight int `json:"weight,omitempty"`
+}
+
+// HealthConfig overrides the health check flags for a virtual server,
+// fields left empty default to the flags.
+type HealthConfig struct {
+	// Type is one of http, tcp, tls, grpc and exec.
+	Type      string          `json:"type,omitempty"`
+	Timeout   metav1.Duration `json:"timeout,omitempty"`
+	Rise      int             `json:"rise,omitempty"`
+	Fall      int             `json:"fall,omitempty"`
+	SlowStart metav1.Duration `json:"slowStart,omitempty"`
+
+	// http
+	Path        string            `json:"path,omitempty"`
+	Scheme      string            `json:"scheme,omitempty"`
+	Method      string            `json:"method,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        string            `json:"body,omitempty"`
+	StatusCodes []int             `json:"statusCodes,omitempty"`
+
+	// tls, also used by http and grpc
+	InsecureSkipVerify *bool  `json:"insecureSkipVerify,omitempty"`
+	CA                 string `json:"ca,omitempty"`
+	Cert               string `json:"cert,omitempty"`
+	Key                string `json:"key,omitempty"`
+	ServerName         string `json:"serverName,omitempty"`
+
+	// grpc
+	Service string `json:"service,omitempty"`
+	TLS     *bool  `json:"tls,omitempty"`
+
+	// exec
+	Command string `json:"command,omitempty"`
+}
+
+func loadConfig(path string) (*Config, [sha256.Size]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, [sha256.Size]byte{}, err
+	}
+	cfg := &Config{}
+	if err = yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, [sha256.Size]byte{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return cfg, sha256.Sum256(data), nil
+}
+
+// services validates the config and builds the services by virtual server address,
+// with the flags as defaults.
+func (o *options) services(cfg *Config, defaults *probers) (map[string]Service, error) {
+	services := make(map[string]Service, len(cfg.VirtualServers))
+	for i, vs := range cfg.VirtualServers {
+		if _, err := parseEndpoint(vs.Address); err != nil {
+			return nil, fmt.Errorf("virtualServers[%d]: invalid address %q: %w", i, vs.Address, err)
+		}
+		if _, ok := services[vs.Address]; ok {
+			return nil, fmt.Errorf("virtualServers[%d]: duplicated address %s", i, vs.Address)
+		}
+		svc, err := o.service(vs, defaults)
+		if err != nil {
+			return nil, fmt.Errorf("virtual server %s: %w", vs.Address, err)
+		}
+		services[vs.Address] = svc
+	}
+	return services, nil
+}
+
+func (o *options) service(vs VirtualServerConfig, defaults *probers) (Service, error) {
+	svc := Service{Scheduler: vs.Scheduler}
+	if svc.Scheduler == "" {
+		svc.Scheduler = o.scheduler
+	}
+	if err := validateScheduler(svc.Scheduler); err != nil {
+		return svc, err
+	}
+	if len(vs.RealServers) == 0 {
+		return svc, fmt.Errorf("no real servers")
+	}
+	hc := o.healthCheck()
+	hc.Weights = make(map[string]int)
+	for _, rs := range vs.RealServers {
+		if _, err := parseEndpoint(rs.Address); err != nil {
+			return svc, fmt.Errorf("invalid real server address %q: %w", rs.Address, err)
+		}
+		if rs.Weight < 0 {
+			return svc, fmt.Errorf("invalid weight %d of real server %s", rs.Weight, rs.Address)
+		}
+		if rs.Weight > 0 {
+			hc.Weights[rs.Address] = rs.Weight
+		}
+		svc.RealServers = append(svc.RealServers, rs.Address)
+	}
+	if vs.Health.Rise > 0 {
+		hc.Rise = vs.Health.Rise
+	}
+	if vs.Health.Fall > 0 {
+		hc.Fall = vs.Health.Fall
+	}
+	if vs.Health.SlowStart.Duration > 0 {
+		hc.SlowStart = vs.Health.SlowStart.Duration
+	}
+	svc.HealthCheck = &hc
+	prober, err := defaults.withHealthConfig(vs.Health)
+	if err != nil {
+		return svc, err
+	}
+	svc.Prober = prober
+	return svc, nil
+}
+
+// withHealthConfig returns a prober of the health config type, configured as the flags
+// with the fields set in the health config overridden.
+func (p *probers) withHealthConfig(h HealthConfig) (Prober, error) {
+	typ := h.Type
+	if typ == "" {
+		typ = p.Type
+	}
+	timeout := func(d *time.Duration) {
+		if h.Timeout.Duration > 0 {
+			*d = h.Timeout.Duration
+		}
+	}
+	override := func(dst *string, src string) {
+		if src != "" {
+			*dst = src
+		}
+	}
+	tlsP := *p.probers[tlsProbe].(*tlsProber)
+	tlsP.config = nil
+	override(&tlsP.CAFile, h.CA)
+	override(&tlsP.CertFile, h.Cert)
+	override(&tlsP.KeyFile, h.Key)
+	override(&tlsP.ServerName, h.ServerName)
+	if h.InsecureSkipVerify != nil {
+		tlsP.InsecureSkipVerify = *h.InsecureSkipVerify
+	}
+
+	var prober Prober
+	switch typ {
+	case httpProbe:
+		httpP := *p.probers[httpProbe].(*httpProber)
+		httpP.client, httpP.validStatus = nil, nil
+		override(&httpP.HealthPath, h.Path)
+		override(&httpP.HealthScheme, h.Scheme)
+		override(&httpP.Method, h.Method)
+		override(&httpP.Body, h.Body)
+		if h.Headers != nil {
+			httpP.Headers = h.Headers
+		}
+		if h.StatusCodes != nil {
+			httpP.ValidStatusCodes = h.StatusCodes
+		}
+		if h.InsecureSkipVerify != nil {
+			httpP.InsecureSkipVerify = *h.InsecureSkipVerify
+		}
+		timeout(&httpP.timeout)
+		prober = &httpP
+	case tcpProbe:
+		tcpP := *p.probers[tcpProbe].(*tcpProber)
+		timeout(&tcpP.timeout)
+		prober = &tcpP
+	case tlsProbe:
+		timeout(&tlsP.timeout)
+		prober = &tlsP
+	case grpcProbe:
+		grpcP := *p.probers[grpcProbe].(*grpcProber)
+		grpcP.tls = &tlsP
+		override(&grpcP.Service, h.Service)
+		if h.TLS != nil {
+			grpcP.TLS = *h.TLS
+		}
+		timeout(&grpcP.timeout)
+		prober = &grpcP
+	case execProbe:
+		execP := *p.probers[execProbe].(*execProber)
+		override(&execP.Command, h.Command)
+		timeout(&execP.timeout)
+		prober = &execP
+	default:
+		return nil, fmt.Errorf("unsupported health type %s", typ)
+	}
+	if validator, ok := prober.(flagValidator); ok {
+		if err := validator.ValidateAndSetDefaults(); err != nil {
+			return nil, err
+		}
+	}
+	return prober, nil
+}
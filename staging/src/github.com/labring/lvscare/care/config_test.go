@@ -0,0 +1,157 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package care
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ipvstest "k8s.io/kubernetes/pkg/util/ipvs/testing"
+)
+
+const testConfig = `
+virtualServers:
+- address: 10.103.97.12:6443
+  realServers:
+  - address: 192.168.0.2:6443
+  - address: 192.168.0.3:6443
+- address: 10.103.97.12:5000
+  scheduler: wrr
+  realServers:
+  - address: 192.168.0.2:5000
+    weight: 2
+  health:
+    type: tcp
+    timeout: 3s
+    fall: 3
+`
+
+func TestConfigServices(t *testing.T) {
+	o := &options{}
+	defaults := newProbers()
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	o.RegisterFlags(fs)
+	defaults.RegisterFlags(fs)
+	if err := fs.Parse([]string{"--config", "lvscare.yaml"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.ValidateAndSetDefaults(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "lvscare.yaml")
+	if err := os.WriteFile(path, []byte(testConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, _, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	services, err := o.services(cfg, defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 2 {
+		t.Fatalf("got %d services, want 2", len(services))
+	}
+
+	api := services["10.103.97.12:6443"]
+	if api.Scheduler != o.scheduler {
+		t.Errorf("scheduler = %s, want default %s", api.Scheduler, o.scheduler)
+	}
+	if _, ok := api.Prober.(*httpProber); !ok {
+		t.Errorf("prober = %T, want default *httpProber", api.Prober)
+	}
+	if api.HealthCheck.Fall != o.Fall || len(api.HealthCheck.Weights) != 0 {
+		t.Errorf("health check = %+v, want defaults", api.HealthCheck)
+	}
+
+	registry := services["10.103.97.12:5000"]
+	if registry.Scheduler != "wrr" {
+		t.Errorf("scheduler = %s, want wrr", registry.Scheduler)
+	}
+	if p, ok := registry.Prober.(*tcpProber); !ok || p.timeout != 3*time.Second {
+		t.Errorf("prober = %#v, want tcp prober with 3s timeout", registry.Prober)
+	}
+	if registry.HealthCheck.Fall != 3 || registry.HealthCheck.Weights["192.168.0.2:5000"] != 2 {
+		t.Errorf("health check = %+v, want fall 3 and weight 2", registry.HealthCheck)
+	}
+
+	for name, config := range map[string]string{
+		"unknown field":     "virtualServers:\n- address: 10.103.97.12:6443\n  rs: []\n",
+		"no real servers":   "virtualServers:\n- address: 10.103.97.12:6443\n",
+		"invalid scheduler": "virtualServers:\n- address: 10.103.97.12:6443\n  scheduler: foo\n  realServers:\n  - address: 192.168.0.2:6443\n",
+		"duplicated":        "virtualServers:\n- address: 10.103.97.12:6443\n  realServers:\n  - address: 192.168.0.2:6443\n- address: 10.103.97.12:6443\n  realServers:\n  - address: 192.168.0.2:6443\n",
+		"invalid type":      "virtualServers:\n- address: 10.103.97.12:6443\n  realServers:\n  - address: 192.168.0.2:6443\n  health:\n    type: udp\n",
+	} {
+		if err = os.WriteFile(path, []byte(config), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if cfg, _, err = loadConfig(path); err == nil {
+			_, err = o.services(cfg, defaults)
+		}
+		if err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestEnsureService(t *testing.T) {
+	const vs = "10.103.97.12:6443"
+	handle := ipvstest.NewFake()
+	p := &realProxier{
+		scheduler:  "rr",
+		ipvsHandle: handle,
+		serviceMap: make(map[endpoint]*virtualService),
+		prober:     &fakeProber{},
+		states:     make(map[string]*healthState),
+		now:        time.Now,
+	}
+	realServers := func() sets.Set[string] {
+		ep, _ := parseEndpoint(vs)
+		servers, err := handle.GetRealServers(p.buildVirtualServer(&ep))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := sets.New[string]()
+		for _, rs := range servers {
+			got.Insert(rs.String())
+		}
+		return got
+	}
+
+	if err := p.EnsureService(vs, Service{Scheduler: "wrr", RealServers: []string{"192.168.0.2:6443", "192.168.0.3:6443"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := realServers(); !got.Equal(sets.New("192.168.0.2:6443", "192.168.0.3:6443")) {
+		t.Errorf("real servers = %v", sets.List(got))
+	}
+	if err := p.EnsureService(vs, Service{Scheduler: "wrr", RealServers: []string{"192.168.0.3:6443", "192.168.0.4:6443"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := realServers(); !got.Equal(sets.New("192.168.0.3:6443", "192.168.0.4:6443")) {
+		t.Errorf("real servers = %v", sets.List(got))
+	}
+	if err := p.DeleteVirtualServer(vs); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.serviceMap) != 0 {
+		t.Errorf("service map not cleaned: %v", p.serviceMap)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package care
+
+import "time"
+
+// HealthCheck configures how probe results move a real server between up and down,
+// and the weight it gets while up.
+type HealthCheck struct {
+	// Rise is the number of consecutive successful probes to bring a down real server up.
+	Rise int
+	// Fall is the number of consecutive failed probes to bring an up real server down.
+	Fall int
+	// SlowStart ramps the weight of a real server coming up from 1 to its full weight.
+	SlowStart time.Duration
+	// Weights are the weights of real servers by address, defaults to 1.
+	Weights map[string]int
+}
+
+// healthState is the state of one real server, it starts up.
+type healthState struct {
+	up        bool
+	successes int
+	failures  int
+	// upSince is zero if the real server has been up from the start, so it is not slow started.
+	upSince time.Time
+	// lastProbe is the time of the last probe, and lastError its error if failed.
+	lastProbe time.Time
+	lastError string
+}
+
+// observe records a probe result and reports whether the real server changed between up and down.
+func (hc *HealthCheck) observe(s *healthState, ok bool, now time.Time) bool {
+	if ok {
+		s.failures = 0
+		s.successes++
+		if !s.up && s.successes >= hc.Rise {
+			s.up = true
+			s.upSince = now
+			return true
+		}
+		return false
+	}
+	s.successes = 0
+	s.failures++
+	if s.up && s.failures >= hc.Fall {
+		s.up = false
+		return true
+	}
+	return false
+}
+
+// targetWeight returns the full weight of the real server.
+func (hc *HealthCheck) targetWeight(rs string) int {
+	if w := hc.Weights[rs]; w > 0 {
+		return w
+	}
+	return 1
+}
+
+// weight returns the weight of an up real server, linearly ramped from 1 during slow start.
+func (hc *HealthCheck) weight(s *healthState, rs string, now time.Time) int {
+	target := hc.targetWeight(rs)
+	if hc.SlowStart <= 0 || s.upSince.IsZero() {
+		return target
+	}
+	elapsed := now.Sub(s.upSince)
+	if elapsed >= hc.SlowStart {
+		return target
+	}
+	if w := int(int64(target) * int64(elapsed) / int64(hc.SlowStart)); w > 1 {
+		return w
+	}
+	return 1
+}
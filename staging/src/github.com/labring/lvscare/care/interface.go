@@ -36,3 +36,14 @@ type Ruler interface {
 	Setup() error
 	Cleanup() error
 }
+
+// virtualServersUpdater is implemented by rulers following the virtual servers
+// changing without a cleanup, it sets up the new ones and cleans up the removed ones.
+type virtualServersUpdater interface {
+	UpdateVirtualServers(vs ...string) error
+}
+
+// statusReporter reports the virtual servers in IPVS and the health of their real servers.
+type statusReporter interface {
+	Status() ([]VirtualServerStatus, error)
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"time"
 
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	utilsysctl "k8s.io/component-helpers/node/util/sysctl"
 	proxyipvs "k8s.io/kubernetes/pkg/proxy/ipvs"
@@ -26,23 +27,35 @@ import (
 	utiliptables "k8s.io/kubernetes/pkg/util/iptables"
 	"k8s.io/utils/exec"
 
+	"github.com/labring/sealos/pkg/utils/iputils"
 	"github.com/labring/sealos/pkg/utils/logger"
 )
 
 type iptablesImpl struct {
-	ipset    utilipset.Interface
-	iptables utiliptables.Interface
-	nl       proxyipvs.NetLinkHandle
-	sysctl   utilsysctl.Interface
+	execer exec.Interface
+	ipset  utilipset.Interface
+	nl     proxyipvs.NetLinkHandle
+	sysctl utilsysctl.Interface
 
 	bindAddresses  []string
-	virtualEntries []string
+	families       map[utiliptables.Protocol]*ipFamily
 	ifaceName      string
 	masqueradeMark string
 }
 
+// ipFamily is the iptables handle and the ipset of the virtual servers of one IP family,
+// IPv4 rules go to iptables and IPv6 rules go to ip6tables.
+type ipFamily struct {
+	iptables       utiliptables.Interface
+	set            utilipset.IPSet
+	virtualEntries []string
+	// monitored is true once the rules are set up and monitored
+	monitored bool
+}
+
 const (
 	virtualIPSet        = "VIRTUAL-IP"
+	virtualIP6Set       = "VIRTUAL-IP6"
 	virtualIPSetComment = "virtual service ip + port for masquerade purpose"
 )
 
@@ -76,21 +89,59 @@ var iptablesChains = []struct {
 	{utiliptables.TableNAT, virtualMarkMasqChain},
 }
 
-var ipsetInfo = []struct {
-	name    string
-	setType utilipset.Type
-	comment string
-}{
-	{virtualIPSet, utilipset.HashIPPort, virtualIPSetComment},
+// ipsetInfo is the ipset of the virtual servers of each IP family.
+var ipsetInfo = map[utiliptables.Protocol]utilipset.IPSet{
+	utiliptables.ProtocolIPv4: {
+		Name:       virtualIPSet,
+		SetType:    utilipset.HashIPPort,
+		HashFamily: utilipset.ProtocolFamilyIPV4,
+		Comment:    virtualIPSetComment,
+	},
+	utiliptables.ProtocolIPv6: {
+		Name:       virtualIP6Set,
+		SetType:    utilipset.HashIPPort,
+		HashFamily: utilipset.ProtocolFamilyIPV6,
+		Comment:    virtualIPSetComment,
+	},
 }
 
 func newIptablesImpl(iface string, masqueradeBit int, virtualIPs ...string) (Ruler, error) {
+	bindAddresses, virtualEntries, err := parseVirtualEntries(virtualIPs...)
+	if err != nil {
+		return nil, err
+	}
+
+	masqueradeValue := 1 << uint(masqueradeBit)
+
+	execer := exec.New()
+	impl := &iptablesImpl{
+		execer:         execer,
+		ipset:          utilipset.New(execer),
+		nl:             proxyipvs.NewNetLinkHandle(false),
+		sysctl:         utilsysctl.New(),
+		bindAddresses:  bindAddresses,
+		families:       make(map[utiliptables.Protocol]*ipFamily),
+		ifaceName:      iface,
+		masqueradeMark: fmt.Sprintf("%#08x", masqueradeValue),
+	}
+	for protocol, entries := range virtualEntries {
+		impl.family(protocol).virtualEntries = entries
+	}
+	return impl, nil
+}
+
+// parseVirtualEntries returns the addresses to bind and the ipset entries by IP family of the virtual servers.
+func parseVirtualEntries(virtualIPs ...string) ([]string, map[utiliptables.Protocol][]string, error) {
 	bindAddresses := make([]string, 0)
-	virtualEntries := make([]string, 0)
+	virtualEntries := make(map[utiliptables.Protocol][]string)
 	for i := range virtualIPs {
 		host, port, err := splitHostPort(virtualIPs[i])
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		protocol := utiliptables.ProtocolIPv4
+		if !iputils.IsIpv4(host) {
+			protocol = utiliptables.ProtocolIPv6
 		}
 		bindAddresses = append(bindAddresses, host)
 		entry := &utilipset.Entry{
@@ -99,22 +150,27 @@ func newIptablesImpl(iface string, masqueradeBit int, virtualIPs ...string) (Rul
 			Protocol: "tcp",
 			SetType:  utilipset.HashIPPort,
 		}
-		virtualEntries = append(virtualEntries, entry.String())
+		virtualEntries[protocol] = append(virtualEntries[protocol], entry.String())
 	}
+	return bindAddresses, virtualEntries, nil
+}
 
-	masqueradeValue := 1 << uint(masqueradeBit)
+// family returns the ipFamily of protocol, the iptables handle is created at the first call.
+func (impl *iptablesImpl) family(protocol utiliptables.Protocol) *ipFamily {
+	f, ok := impl.families[protocol]
+	if !ok {
+		f = &ipFamily{
+			iptables: utiliptables.New(impl.execer, protocol),
+			set:      ipsetInfo[protocol],
+		}
+		impl.families[protocol] = f
+	}
+	return f
+}
 
-	execer := exec.New()
-	return &iptablesImpl{
-		ipset:          utilipset.New(execer),
-		iptables:       utiliptables.New(execer, utiliptables.ProtocolIPv4),
-		nl:             proxyipvs.NewNetLinkHandle(false),
-		sysctl:         utilsysctl.New(),
-		bindAddresses:  bindAddresses,
-		virtualEntries: virtualEntries,
-		ifaceName:      iface,
-		masqueradeMark: fmt.Sprintf("%#08x", masqueradeValue),
-	}, nil
+// protocols returns the IP families in a stable order.
+func (impl *iptablesImpl) protocols() []utiliptables.Protocol {
+	return sets.List(sets.KeySet(impl.families))
 }
 
 func (impl *iptablesImpl) Setup() error {
@@ -126,17 +182,29 @@ func (impl *iptablesImpl) Setup() error {
 		logger.Error("Failed to ensure dummy device: %v", err)
 		return err
 	}
-	if err := impl.ensureIpset(); err != nil {
+	for _, protocol := range impl.protocols() {
+		if err := impl.setupFamily(impl.families[protocol]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setupFamily ensures the ipset and the iptables rules of an IP family, and rebuilds the rules
+// whenever they are flushed.
+func (impl *iptablesImpl) setupFamily(f *ipFamily) error {
+	if err := ensureIPSetWithEntries(impl.ipset, f.set, f.virtualEntries...); err != nil {
 		logger.Error("Failed to ensure ipset: %v", err)
 		return err
 	}
-	err := impl.ensureIptablesChains()
-	if err == nil {
-		go impl.iptables.Monitor(utiliptables.Chain("VIRTUAL-CANARY"),
+	err := impl.ensureIptablesChains(f)
+	if err == nil && !f.monitored {
+		f.monitored = true
+		go f.iptables.Monitor(utiliptables.Chain("VIRTUAL-CANARY"),
 			[]utiliptables.Table{utiliptables.TableFilter, utiliptables.TableNAT},
 			func() {
 				logger.Info("looks like canary rules has been flushed, rebuild it")
-				if err := impl.ensureIptablesChains(); err != nil {
+				if err := impl.ensureIptablesChains(f); err != nil {
 					logger.Error("Failed to ensure iptables chains: %v", err)
 				}
 			},
@@ -145,6 +213,45 @@ func (impl *iptablesImpl) Setup() error {
 	return err
 }
 
+func (impl *iptablesImpl) UpdateVirtualServers(virtualServers ...string) error {
+	bindAddresses, virtualEntries, err := parseVirtualEntries(virtualServers...)
+	if err != nil {
+		return err
+	}
+	if err = ensureDummyDeviceAndAddresses(impl.nl, impl.ifaceName, bindAddresses...); err != nil {
+		logger.Error("Failed to ensure dummy device: %v", err)
+		return err
+	}
+	for protocol := range virtualEntries {
+		impl.family(protocol)
+	}
+	for _, protocol := range impl.protocols() {
+		f := impl.families[protocol]
+		staleEntries := sets.New[string](f.virtualEntries...).Delete(virtualEntries[protocol]...)
+		f.virtualEntries = virtualEntries[protocol]
+		if err = impl.setupFamily(f); err != nil {
+			return err
+		}
+		for _, entry := range sets.List(staleEntries) {
+			logger.Info("Deleting ipset entry %s", entry)
+			if err = impl.ipset.DelEntry(entry, f.set.Name); err != nil {
+				return err
+			}
+		}
+	}
+	keepAddresses := sets.New[string](bindAddresses...)
+	for _, address := range impl.bindAddresses {
+		if !keepAddresses.Has(address) {
+			logger.Info("Unbinding address %s from %s", address, impl.ifaceName)
+			if err = impl.nl.UnbindAddress(address, impl.ifaceName); err != nil {
+				return err
+			}
+		}
+	}
+	impl.bindAddresses = bindAddresses
+	return nil
+}
+
 func (impl *iptablesImpl) Cleanup() error {
 	if encounteredError := impl.cleanupLeftovers(); encounteredError {
 		return errors.New("encountered an error while tearing down rules")
@@ -158,14 +265,15 @@ func (impl *iptablesImpl) cleanupLeftovers() (encounteredError bool) {
 		logger.Error("Error deleting dummy device: %v", err)
 		encounteredError = true
 	}
-	logger.Info("Cleanup IPTables rules")
-	encounteredError = impl.cleanupIptablesLeftovers() || encounteredError
-	for _, set := range ipsetInfo {
-		logger.Info("Destroying ipset %s", set.name)
-		err := impl.ipset.DestroySet(set.name)
+	for _, protocol := range impl.protocols() {
+		f := impl.families[protocol]
+		logger.Info("Cleanup %s IPTables rules", protocol)
+		encounteredError = impl.cleanupIptablesLeftovers(f) || encounteredError
+		logger.Info("Destroying ipset %s", f.set.Name)
+		err := impl.ipset.DestroySet(f.set.Name)
 		if err != nil {
 			if !utilipset.IsNotFoundError(err) {
-				logger.Error("Error removing ipset %s: %v", set.name, err)
+				logger.Error("Error removing ipset %s: %v", f.set.Name, err)
 				encounteredError = true
 			}
 		}
@@ -196,19 +304,6 @@ func ensureDummyDeviceAndAddresses(nl proxyipvs.NetLinkHandle, ifaceName string,
 	return nil
 }
 
-func (impl *iptablesImpl) ensureIpset() error {
-	for _, set := range ipsetInfo {
-		entries := make([]string, 0)
-		if set.name == virtualIPSet {
-			entries = append(entries, impl.virtualEntries...)
-		}
-		if err := ensureIPSetWithEntries(impl.ipset, set.name, set.comment, set.setType, entries...); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
 type iptablesRule struct {
 	position utiliptables.RulePosition
 	table    utiliptables.Table
@@ -234,10 +329,10 @@ func (impl *iptablesImpl) extraChainRules() []iptablesRule {
 	return rules
 }
 
-func (impl *iptablesImpl) ensureIptablesChains() error {
+func (impl *iptablesImpl) ensureIptablesChains(f *ipFamily) error {
 	// service chain
 	for _, ch := range iptablesChains {
-		if _, err := impl.iptables.EnsureChain(ch.table, ch.chain); err != nil {
+		if _, err := f.iptables.EnsureChain(ch.table, ch.chain); err != nil {
 			logger.Error("Failed to ensure chain, table: %s, chain: %s, %v", ch.table, ch.chain, err)
 			return err
 		}
@@ -245,7 +340,7 @@ func (impl *iptablesImpl) ensureIptablesChains() error {
 	// jump chain
 	for _, jc := range iptablesJumpChain {
 		args := []string{"-m", "comment", "--comment", jc.comment, "-j", string(jc.to)}
-		if _, err := impl.iptables.EnsureRule(utiliptables.Append, jc.table, jc.from, args...); err != nil {
+		if _, err := f.iptables.EnsureRule(utiliptables.Append, jc.table, jc.from, args...); err != nil {
 			logger.Error("Failed to ensure chain jumps, table: %s, src: %s, dst: %s, %v", jc.table, jc.from, jc.to, err)
 		}
 	}
@@ -255,7 +350,7 @@ func (impl *iptablesImpl) ensureIptablesChains() error {
 		{
 			utiliptables.Append, utiliptables.TableNAT, virtualServicesChain, []string{
 				"-m", "comment", "--comment", virtualIPSetComment,
-				"-m", "set", "--match-set", virtualIPSet,
+				"-m", "set", "--match-set", f.set.Name,
 				"dst,dst", "-j", string(virtualMarkMasqChain),
 			},
 		},
@@ -277,25 +372,19 @@ func (impl *iptablesImpl) ensureIptablesChains() error {
 		"-m", "comment", "--comment", `virtual service traffic requiring SNAT`,
 		"-j", "MASQUERADE",
 	}
-	if impl.iptables.HasRandomFully() {
+	if f.iptables.HasRandomFully() {
 		masqArgs = append(masqArgs, "--random-fully")
 	}
 	rules = append(rules, iptablesRule{utiliptables.Append, utiliptables.TableNAT, virtualPostroutingChain, masqArgs})
 	for i := range rules {
-		if _, err := impl.iptables.EnsureRule(rules[i].position, rules[i].table, rules[i].chain, rules[i].args...); err != nil {
+		if _, err := f.iptables.EnsureRule(rules[i].position, rules[i].table, rules[i].chain, rules[i].args...); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func ensureIPSetWithEntries(handle utilipset.Interface, name, comment string, setType utilipset.Type, entries ...string) error {
-	set := utilipset.IPSet{
-		Name:       name,
-		SetType:    setType,
-		HashFamily: utilipset.ProtocolFamilyIPV4,
-		Comment:    comment,
-	}
+func ensureIPSetWithEntries(handle utilipset.Interface, set utilipset.IPSet, entries ...string) error {
 	if err := handle.CreateSet(&set, true); err != nil {
 		return err
 	}
@@ -307,14 +396,14 @@ func ensureIPSetWithEntries(handle utilipset.Interface, name, comment string, se
 	return nil
 }
 
-func (impl *iptablesImpl) cleanupIptablesLeftovers() (encounteredError bool) {
+func (impl *iptablesImpl) cleanupIptablesLeftovers(f *ipFamily) (encounteredError bool) {
 	// Unlink the iptables chains created by ipvs Proxier
 	for _, jc := range iptablesJumpChain {
 		args := []string{
 			"-m", "comment", "--comment", jc.comment,
 			"-j", string(jc.to),
 		}
-		if err := impl.iptables.DeleteRule(jc.table, jc.from, args...); err != nil {
+		if err := f.iptables.DeleteRule(jc.table, jc.from, args...); err != nil {
 			if !utiliptables.IsNotFoundError(err) {
 				logger.Error("Error removing iptables rules: %v", err)
 				encounteredError = true
@@ -322,7 +411,7 @@ func (impl *iptablesImpl) cleanupIptablesLeftovers() (encounteredError bool) {
 		}
 	}
 	for _, rule := range impl.extraChainRules() {
-		if err := impl.iptables.DeleteRule(rule.table, rule.chain, rule.args...); err != nil {
+		if err := f.iptables.DeleteRule(rule.table, rule.chain, rule.args...); err != nil {
 			if !utiliptables.IsNotFoundError(err) {
 				logger.Error("Error removing iptables rules: %v", err)
 				encounteredError = true
@@ -332,7 +421,7 @@ func (impl *iptablesImpl) cleanupIptablesLeftovers() (encounteredError bool) {
 
 	// Flush and remove all of our chains. Flushing all chains before removing them also removes all links between chains first.
 	for _, ch := range iptablesChains {
-		if err := impl.iptables.FlushChain(ch.table, ch.chain); err != nil {
+		if err := f.iptables.FlushChain(ch.table, ch.chain); err != nil {
 			if !utiliptables.IsNotFoundError(err) {
 				logger.Error("Error removing iptables rules: %v", err)
 				encounteredError = true
@@ -342,7 +431,7 @@ func (impl *iptablesImpl) cleanupIptablesLeftovers() (encounteredError bool) {
 
 	// Remove all of our chains.
 	for _, ch := range iptablesChains {
-		if err := impl.iptables.DeleteChain(ch.table, ch.chain); err != nil {
+		if err := f.iptables.DeleteChain(ch.table, ch.chain); err != nil {
 			if !utiliptables.IsNotFoundError(err) {
 				logger.Error("Error removing iptables rules: %v", err)
 				encounteredError = true
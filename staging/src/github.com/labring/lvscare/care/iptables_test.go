@@ -0,0 +1,42 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package care
+
+import (
+	"reflect"
+	"testing"
+
+	utiliptables "k8s.io/kubernetes/pkg/util/iptables"
+)
+
+func TestParseVirtualEntries(t *testing.T) {
+	bindAddresses, virtualEntries, err := parseVirtualEntries("10.103.97.2:6443", "[fd00::2]:6443", "[fd00::2]:5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"10.103.97.2", "fd00::2", "fd00::2"}; !reflect.DeepEqual(bindAddresses, want) {
+		t.Errorf("bind addresses = %v, want %v", bindAddresses, want)
+	}
+	want := map[utiliptables.Protocol][]string{
+		utiliptables.ProtocolIPv4: {"10.103.97.2,tcp:6443"},
+		utiliptables.ProtocolIPv6: {"fd00::2,tcp:6443", "fd00::2,tcp:5000"},
+	}
+	if !reflect.DeepEqual(virtualEntries, want) {
+		t.Errorf("virtual entries = %v, want %v", virtualEntries, want)
+	}
+	if _, _, err = parseVirtualEntries("fd00::2:6443"); err == nil {
+		t.Error("expected error for IPv6 address without brackets")
+	}
+}
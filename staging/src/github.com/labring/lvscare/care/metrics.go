@@ -0,0 +1,171 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package care
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	httputils "github.com/labring/sealos/pkg/utils/http"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+const metricsNamespace = "lvscare"
+
+// operations of IPVS counted by syncErrors
+const (
+	opAddVirtualServer    = "add_virtual_server"
+	opUpdateVirtualServer = "update_virtual_server"
+	opDeleteVirtualServer = "delete_virtual_server"
+	opGetRealServers      = "get_real_servers"
+	opAddRealServer       = "add_real_server"
+	opUpdateRealServer    = "update_real_server"
+	opDeleteRealServer    = "delete_real_server"
+)
+
+var (
+	realServerUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "real_server_up",
+		Help:      "Whether the real server is considered healthy by the health check, 1 for up and 0 for down.",
+	}, []string{"virtual_server", "real_server"})
+
+	realServerWeight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "real_server_weight",
+		Help:      "Weight of the real server in IPVS, 0 if it is drained or removed.",
+	}, []string{"virtual_server", "real_server"})
+
+	weightChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "weight_changes_total",
+		Help:      "Changes of the weight of the real server in IPVS, including adding and removing it.",
+	}, []string{"virtual_server", "real_server"})
+
+	probeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "probe_duration_seconds",
+		Help:      "Duration of health check probes by result.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 15),
+	}, []string{"virtual_server", "real_server", "result"})
+
+	syncErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "ipvs_sync_errors_total",
+		Help:      "IPVS operations failed, by operation.",
+	}, []string{"operation"})
+)
+
+var metricsRegistry = httputils.NewMetricsRegistry(
+	realServerUp,
+	realServerWeight,
+	weightChanges,
+	probeDuration,
+	syncErrors,
+)
+
+func observeProbe(vs, rs string, d time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	probeDuration.WithLabelValues(vs, rs, result).Observe(d.Seconds())
+}
+
+func recordWeight(vs, rs string, weight int) {
+	realServerWeight.WithLabelValues(vs, rs).Set(float64(weight))
+	weightChanges.WithLabelValues(vs, rs).Inc()
+}
+
+func recordUp(vs, rs string, up bool) {
+	v := 0.0
+	if up {
+		v = 1
+	}
+	realServerUp.WithLabelValues(vs, rs).Set(v)
+}
+
+// forgetRealServer deletes the series of a real server, or of all the real servers of vs if rs is empty.
+func forgetRealServer(vs, rs string) {
+	labels := prometheus.Labels{"virtual_server": vs}
+	if rs != "" {
+		labels["real_server"] = rs
+	}
+	realServerUp.DeletePartialMatch(labels)
+	realServerWeight.DeletePartialMatch(labels)
+	weightChanges.DeletePartialMatch(labels)
+	probeDuration.DeletePartialMatch(labels)
+}
+
+// VirtualServerStatus is a virtual server in IPVS and the health of its real servers.
+type VirtualServerStatus struct {
+	Address   string `json:"address"`
+	Scheduler string `json:"scheduler"`
+	// InIPVS is false if the virtual server is missing in IPVS.
+	InIPVS      bool               `json:"inIPVS"`
+	RealServers []RealServerStatus `json:"realServers"`
+}
+
+type RealServerStatus struct {
+	Address string `json:"address"`
+	Up      bool   `json:"up"`
+	// InIPVS is false if the real server is removed from IPVS, Weight and the connections are 0 then.
+	InIPVS               bool       `json:"inIPVS"`
+	Weight               int        `json:"weight"`
+	ActiveConnections    int        `json:"activeConnections"`
+	InactiveConnections  int        `json:"inactiveConnections"`
+	ConsecutiveSuccesses int        `json:"consecutiveSuccesses"`
+	ConsecutiveFailures  int        `json:"consecutiveFailures"`
+	LastProbe            *time.Time `json:"lastProbe,omitempty"`
+	LastError            string     `json:"lastError,omitempty"`
+}
+
+// newMetricsHandler serves the prometheus metrics at /metrics, and the status of
+// reporter as JSON at /status.
+func newMetricsHandler(reporter statusReporter) http.Handler {
+	mux := httputils.NewMetricsMux(metricsRegistry)
+	mux.HandleFunc("/status", func(w http.ResponseWriter, req *http.Request) {
+		if reporter == nil {
+			http.Error(w, "status is not supported", http.StatusNotImplemented)
+			return
+		}
+		status, err := reporter.Status()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err = enc.Encode(status); err != nil {
+			logger.Warn("failed to write status: %v", err)
+		}
+	})
+	return mux
+}
+
+// serveMetrics serves newMetricsHandler on address.
+func serveMetrics(address string, reporter statusReporter) (*http.Server, error) {
+	srv, err := httputils.ServeMetrics(address, newMetricsHandler(reporter))
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen metrics on %s: %w", address, err)
+	}
+	logger.Info("serving metrics on %s/metrics and status on %s/status", address, address)
+	return srv, nil
+}
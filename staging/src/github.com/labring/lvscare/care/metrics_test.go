@@ -0,0 +1,110 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package care
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	ipvstest "k8s.io/kubernetes/pkg/util/ipvs/testing"
+)
+
+func TestStatusAndMetrics(t *testing.T) {
+	const vs, up, down = "10.103.97.13:6443", "192.168.0.2:6443", "192.168.0.3:6443"
+	p := &realProxier{
+		scheduler:  "rr",
+		ipvsHandle: ipvstest.NewFake(),
+		serviceMap: make(map[endpoint]*virtualService),
+		prober:     &hostProber{down: "192.168.0.3", err: errors.New("connection refused")},
+		states:     make(map[string]*healthState),
+		now:        time.Now,
+	}
+	if err := p.EnsureService(vs, Service{RealServers: []string{up, down}}); err != nil {
+		t.Fatal(err)
+	}
+	// check one by one, the fake IPVS is not safe for concurrent use
+	vsEp, _ := parseEndpoint(vs)
+	vSvc := p.serviceMap[vsEp]
+	wg := &sync.WaitGroup{}
+	for _, rs := range vSvc.realServers {
+		wg.Add(1)
+		p.checkRealServer(wg, vsEp, vSvc, p.buildVirtualServer(&vsEp), rs)
+	}
+
+	if got := testutil.ToFloat64(realServerUp.WithLabelValues(vs, up)); got != 1 {
+		t.Errorf("%s up = %v, want 1", up, got)
+	}
+	if got := testutil.ToFloat64(realServerUp.WithLabelValues(vs, down)); got != 0 {
+		t.Errorf("%s up = %v, want 0", down, got)
+	}
+	if got := testutil.ToFloat64(realServerWeight.WithLabelValues(vs, down)); got != 0 {
+		t.Errorf("%s weight = %v, want 0", down, got)
+	}
+	// added with weight 1, then drained
+	if got := testutil.ToFloat64(weightChanges.WithLabelValues(vs, down)); got != 2 {
+		t.Errorf("%s weight changes = %v, want 2", down, got)
+	}
+
+	rec := httptest.NewRecorder()
+	newMetricsHandler(p).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status code %d: %s", rec.Code, rec.Body.String())
+	}
+	var status []VirtualServerStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != 1 || status[0].Address != vs || !status[0].InIPVS || len(status[0].RealServers) != 2 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	if rs := status[0].RealServers[0]; rs.Address != up || !rs.Up || !rs.InIPVS || rs.Weight != 1 || rs.ConsecutiveSuccesses != 1 {
+		t.Errorf("unexpected status of %s: %+v", up, rs)
+	}
+	if rs := status[0].RealServers[1]; rs.Address != down || rs.Up || !rs.InIPVS || rs.Weight != 0 || rs.LastError != "connection refused" || rs.LastProbe == nil {
+		t.Errorf("unexpected status of %s: %+v", down, rs)
+	}
+
+	rec = httptest.NewRecorder()
+	newMetricsHandler(p).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("metrics code %d", rec.Code)
+	}
+
+	if err := p.DeleteVirtualServer(vs); err != nil {
+		t.Fatal(err)
+	}
+	if n := testutil.CollectAndCount(realServerUp, "lvscare_real_server_up"); n != 0 {
+		t.Errorf("%d real_server_up series left after the virtual server is deleted", n)
+	}
+}
+
+// hostProber fails the probes of the down host.
+type hostProber struct {
+	down string
+	err  error
+}
+
+func (p *hostProber) Probe(host, _ string) error {
+	if host == p.down {
+		return p.err
+	}
+	return nil
+}
@@ -24,10 +24,12 @@ import (
 	"time"
 
 	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 
 	"github.com/labring/sealos/pkg/constants"
 	"github.com/labring/sealos/pkg/utils/hosts"
+	"github.com/labring/sealos/pkg/utils/logger"
 )
 
 const (
@@ -46,12 +48,19 @@ type options struct {
 	RunOnce       bool
 	CleanAndExit  bool
 	Interval      durationOrSecondValue
-	TargetIP      net.IP
+	TargetIPs     []net.IP
 	MasqueradeBit int
+	Rise          int
+	Fall          int
+	SlowStart     time.Duration
+	Weights       map[string]int
+	ConfigFile    string
+	MetricsAddr   string
 }
 
 func (o *options) RegisterFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.VirtualServer, "vs", "", "virtual server address, for example 169.254.0.1:6443")
+	fs.StringVar(&o.ConfigFile, "config", "", "config file of virtual servers to use instead of --vs and --rs, reloaded when changed")
 	fs.StringSliceVar(&o.RealServer, "rs", []string{}, "real server address like 192.168.0.2:6443")
 	fs.StringVar(&o.scheduler, "scheduler", "rr", "proxier scheduler")
 	fs.StringVarP(&o.IfaceName, "iface", "i", appName, "name of dummy interface to created, same behavior as kube-proxy")
@@ -60,8 +69,13 @@ func (o *options) RegisterFlags(fs *pflag.FlagSet) {
 	fs.BoolVar(&o.RunOnce, "run-once", false, "create proxy rules and exit")
 	fs.BoolVarP(&o.CleanAndExit, "clean", "C", false, "clean existing rules and then exit")
 	fs.Var(&o.Interval, "interval", "health check interval")
-	fs.IPVar(&o.TargetIP, "ip", nil, "target ip as route gateway, use with route mode")
+	fs.IPSliceVar(&o.TargetIPs, "ip", nil, "target ip as route gateway, use with route mode, set one IPv4 and one IPv6 for dual-stack virtual servers")
 	fs.IntVar(&o.MasqueradeBit, "masqueradebit", 0, "IPTables masquerade bit")
+	fs.IntVar(&o.Rise, "rise", 1, "consecutive successful health checks before a real server receives traffic again")
+	fs.IntVar(&o.Fall, "fall", 1, "consecutive failed health checks before a real server stops receiving traffic")
+	fs.DurationVar(&o.SlowStart, "slow-start", 0, "ramp the weight of a recovered real server up to its weight over this duration, 0 to disable")
+	fs.StringVar(&o.MetricsAddr, "metrics-address", "", "address to serve prometheus metrics at /metrics and the IPVS status at /status, for example 127.0.0.1:9090, empty to disable")
+	fs.StringToIntVar(&o.Weights, "weight", map[string]int{}, "real server weight like 192.168.0.2:6443=3, defaults to 1, requires a weighted scheduler")
 
 	// set klog flag
 	if v := os.Getenv("ENABLE_KLOG_FLAGS"); len(v) > 0 {
@@ -72,31 +86,72 @@ func (o *options) RegisterFlags(fs *pflag.FlagSet) {
 	}
 }
 
-func (o *options) RequiredFlags() []string {
-	return []string{"vs"}
-}
-
 func (o *options) ValidateAndSetDefaults() error {
-	if len(o.RealServer) == 0 && !o.CleanAndExit {
-		return errors.New(`required flag(s) "rs" not set`)
+	if o.ConfigFile != "" {
+		if o.VirtualServer != "" || len(o.RealServer) > 0 {
+			return errors.New(`flag "config" can't be used with "vs" and "rs"`)
+		}
+	} else {
+		if o.VirtualServer == "" {
+			return errors.New(`required flag(s) "vs" not set`)
+		}
+		if len(o.RealServer) == 0 && !o.CleanAndExit {
+			return errors.New(`required flag(s) "rs" not set`)
+		}
 	}
-	switch o.scheduler {
-	case "rr", "lc", "dh", "sh", "wrr", "wlc":
-	default:
-		return fmt.Errorf(`invalid flag "scheduler=%s"`, o.scheduler)
+	if err := validateScheduler(o.scheduler); err != nil {
+		return fmt.Errorf(`invalid flag "scheduler": %w`, err)
 	}
-	if o.TargetIP == nil && o.Mode == routeMode {
+	if len(o.TargetIPs) == 0 && o.Mode == routeMode {
 		hf := &hosts.HostFile{Path: constants.DefaultHostsPath}
-		if ip, ok := hf.HasDomain(constants.DefaultLvscareDomain); ok {
-			o.TargetIP = net.ParseIP(ip)
+		if ip, ok := hf.HasDomain(constants.DefaultLvscareDomain); ok && net.ParseIP(ip) != nil {
+			o.TargetIPs = []net.IP{net.ParseIP(ip)}
 		}
 	}
 	if o.Interval == 0 {
 		o.Interval = durationOrSecondValue(5 * time.Second)
 	}
+	if o.Rise < 1 {
+		return fmt.Errorf(`invalid flag "rise=%d", must be at least 1`, o.Rise)
+	}
+	if o.Fall < 1 {
+		return fmt.Errorf(`invalid flag "fall=%d", must be at least 1`, o.Fall)
+	}
+	if o.SlowStart < 0 {
+		return fmt.Errorf(`invalid flag "slow-start=%s"`, o.SlowStart)
+	}
+	realServers := sets.New[string](o.RealServer...)
+	for rs, w := range o.Weights {
+		if !realServers.Has(rs) {
+			return fmt.Errorf(`invalid flag "weight", %s is not a real server`, rs)
+		}
+		if w < 1 {
+			return fmt.Errorf(`invalid flag "weight=%s=%d", must be at least 1`, rs, w)
+		}
+	}
+	if (len(o.Weights) > 0 || o.SlowStart > 0) && o.scheduler != "wrr" && o.scheduler != "wlc" {
+		logger.Warn("scheduler %s ignores the weights of real servers, use wrr or wlc", o.scheduler)
+	}
 	return nil
 }
 
+func validateScheduler(scheduler string) error {
+	switch scheduler {
+	case "rr", "lc", "dh", "sh", "wrr", "wlc":
+		return nil
+	}
+	return fmt.Errorf("unsupported scheduler %s", scheduler)
+}
+
+func (o *options) healthCheck() HealthCheck {
+	return HealthCheck{
+		Rise:      o.Rise,
+		Fall:      o.Fall,
+		SlowStart: o.SlowStart,
+		Weights:   o.Weights,
+	}
+}
+
 type durationOrSecondValue time.Duration
 
 func (d *durationOrSecondValue) Set(s string) error {
@@ -34,6 +34,62 @@ type Prober interface {
 	Probe(string, string) error
 }
 
+const (
+	httpProbe = "http"
+	tcpProbe  = "tcp"
+	tlsProbe  = "tls"
+	grpcProbe = "grpc"
+	execProbe = "exec"
+)
+
+// probers probes with one of the probe types selected by the health-type flag.
+type probers struct {
+	Type string
+
+	probers  map[string]Prober
+	selected Prober
+}
+
+func newProbers() *probers {
+	tlsP := &tlsProber{}
+	return &probers{
+		probers: map[string]Prober{
+			httpProbe: &httpProber{},
+			tcpProbe:  &tcpProber{},
+			tlsProbe:  tlsP,
+			grpcProbe: &grpcProber{tls: tlsP},
+			execProbe: &execProber{},
+		},
+	}
+}
+
+func (p *probers) RegisterFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&p.Type, "health-type", httpProbe, fmt.Sprintf("health check type: %s", strings.Join(sets.List(sets.KeySet(p.probers)), "/")))
+	for _, prober := range p.probers {
+		if registerer, ok := prober.(flagRegisterer); ok {
+			registerer.RegisterFlags(fs)
+		}
+	}
+}
+
+func (p *probers) ValidateAndSetDefaults() error {
+	prober, ok := p.probers[p.Type]
+	if !ok {
+		return fmt.Errorf(`invalid flag "health-type=%s"`, p.Type)
+	}
+	if validator, ok := prober.(flagValidator); ok {
+		if err := validator.ValidateAndSetDefaults(); err != nil {
+			return err
+		}
+	}
+	p.selected = prober
+	return nil
+}
+
+func (p *probers) Probe(host, port string) error {
+	return p.selected.Probe(host, port)
+}
+
 type httpProber struct {
 	HealthPath         string
 	HealthScheme       string
@@ -0,0 +1,72 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package care
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// execProber runs a shell command to check real servers, exit code 0 is healthy.
+// The real server is passed to the command in the RS_HOST, RS_PORT and RS_ADDRESS environment variables.
+type execProber struct {
+	Command string
+	timeout time.Duration
+}
+
+func (p *execProber) RegisterFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&p.Command, "health-exec-command", "", `shell command to check real servers, for example "etcdctl --endpoints https://$RS_ADDRESS endpoint health"`)
+	fs.DurationVar(&p.timeout, "health-exec-timeout", 10*time.Second, "exec probe timeout")
+}
+
+func (p *execProber) ValidateAndSetDefaults() error {
+	if p.Command == "" {
+		return errors.New(`required flag "health-exec-command" not set`)
+	}
+	return nil
+}
+
+func (p *execProber) Probe(host, port string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	// nosemgrep
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", p.Command)
+	cmd.Env = append(os.Environ(),
+		"RS_HOST="+host,
+		"RS_PORT="+port,
+		"RS_ADDRESS="+net.JoinHostPort(host, port),
+	)
+	// the children forked by the shell may hold the output pipe after the shell is killed,
+	// so the command runs in its own process group and the whole group is killed on timeout.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("command failed: %v, output: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
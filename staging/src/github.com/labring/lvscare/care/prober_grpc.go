@@ -0,0 +1,72 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package care
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/spf13/pflag"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// grpcProber checks real servers with the gRPC health checking protocol v1.
+type grpcProber struct {
+	Service string
+	TLS     bool
+	timeout time.Duration
+
+	tls *tlsProber
+}
+
+func (p *grpcProber) RegisterFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&p.Service, "health-grpc-service", "", "service name to check, empty checks the whole server")
+	fs.BoolVar(&p.TLS, "health-grpc-tls", false, "connect with tls configured by the health-tls-* flags")
+	fs.DurationVar(&p.timeout, "health-grpc-timeout", 5*time.Second, "grpc probe timeout")
+}
+
+func (p *grpcProber) ValidateAndSetDefaults() error {
+	if p.TLS {
+		return p.tls.ValidateAndSetDefaults()
+	}
+	return nil
+}
+
+func (p *grpcProber) Probe(host, port string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	creds := insecure.NewCredentials()
+	if p.TLS {
+		creds = credentials.NewTLS(p.tls.clientConfig(host))
+	}
+	conn, err := grpc.DialContext(ctx, net.JoinHostPort(host, port), grpc.WithTransportCredentials(creds), grpc.WithBlock())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: p.Service})
+	if err != nil {
+		return err
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("unexpected serving status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package care
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+type tcpProber struct {
+	timeout time.Duration
+}
+
+func (p *tcpProber) RegisterFlags(fs *pflag.FlagSet) {
+	fs.DurationVar(&p.timeout, "health-tcp-timeout", 5*time.Second, "tcp probe timeout")
+}
+
+func (p *tcpProber) Probe(host, port string) error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, port), p.timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+type tlsProber struct {
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	ServerName         string
+	InsecureSkipVerify bool
+	timeout            time.Duration
+
+	config *tls.Config
+}
+
+func (p *tlsProber) RegisterFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&p.CAFile, "health-tls-ca", "", "CA file to verify the certificate of real servers, defaults to the system roots")
+	fs.StringVar(&p.CertFile, "health-tls-cert", "", "client certificate file for real servers requiring client auth")
+	fs.StringVar(&p.KeyFile, "health-tls-key", "", "client key file for real servers requiring client auth")
+	fs.StringVar(&p.ServerName, "health-tls-server-name", "", "server name to verify the certificate against, defaults to the real server ip")
+	fs.BoolVar(&p.InsecureSkipVerify, "health-tls-insecure-skip-verify", false, "skip verify the certificate of real servers")
+	fs.DurationVar(&p.timeout, "health-tls-timeout", 5*time.Second, "tls handshake probe timeout")
+}
+
+func (p *tlsProber) ValidateAndSetDefaults() error {
+	if p.config != nil {
+		return nil
+	}
+	// nosemgrep
+	config := &tls.Config{
+		ServerName:         p.ServerName,
+		InsecureSkipVerify: p.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if p.CAFile != "" {
+		data, err := os.ReadFile(p.CAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read tls ca: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("no certificate found in %s", p.CAFile)
+		}
+		config.RootCAs = pool
+	}
+	if (p.CertFile == "") != (p.KeyFile == "") {
+		return errors.New(`flags "health-tls-cert" and "health-tls-key" must be set together`)
+	}
+	if p.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(p.CertFile, p.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load tls client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	p.config = config
+	return nil
+}
+
+// clientConfig returns the tls config to connect to host.
+func (p *tlsProber) clientConfig(host string) *tls.Config {
+	config := p.config.Clone()
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+	return config
+}
+
+func (p *tlsProber) Probe(host, port string) error {
+	dialer := &net.Dialer{Timeout: p.timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(host, port), p.clientConfig(host))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
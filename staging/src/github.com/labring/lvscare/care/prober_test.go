@@ -0,0 +1,144 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package care
+
+import (
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func newTestProber(t *testing.T, typ string, set func(p Prober)) *probers {
+	t.Helper()
+	p := newProbers()
+	p.Type = typ
+	for _, prober := range p.probers {
+		switch v := prober.(type) {
+		case *tcpProber:
+			v.timeout = time.Second
+		case *tlsProber:
+			v.timeout = time.Second
+		case *grpcProber:
+			v.timeout = time.Second
+		case *execProber:
+			v.timeout = time.Second
+		}
+	}
+	set(p.probers[typ])
+	if err := p.ValidateAndSetDefaults(); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestTCPProber(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	p := newTestProber(t, tcpProbe, func(Prober) {})
+	if err = p.Probe(host, port); err != nil {
+		t.Errorf("probe listening port: %v", err)
+	}
+	l.Close()
+	if err = p.Probe(host, port); err == nil {
+		t.Error("probe closed port succeeded")
+	}
+}
+
+func TestTLSProber(t *testing.T) {
+	s := httptest.NewTLSServer(http.NotFoundHandler())
+	defer s.Close()
+	host, port, _ := net.SplitHostPort(s.Listener.Addr().String())
+
+	if err := newTestProber(t, tlsProbe, func(Prober) {}).Probe(host, port); err == nil {
+		t.Error("probe with an unknown CA succeeded")
+	}
+
+	ca := filepath.Join(t.TempDir(), "ca.crt")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw})
+	if err := os.WriteFile(ca, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	p := newTestProber(t, tlsProbe, func(p Prober) {
+		p.(*tlsProber).CAFile = ca
+	})
+	if err := p.Probe(host, port); err != nil {
+		t.Errorf("probe with the CA: %v", err)
+	}
+}
+
+func TestGRPCProber(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	healthServer := health.NewServer()
+	s := grpc.NewServer()
+	healthpb.RegisterHealthServer(s, healthServer)
+	go func() {
+		_ = s.Serve(l)
+	}()
+	defer s.Stop()
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+
+	p := newTestProber(t, grpcProbe, func(p Prober) {
+		p.(*grpcProber).Service = "etcd"
+	})
+	healthServer.SetServingStatus("etcd", healthpb.HealthCheckResponse_SERVING)
+	if err = p.Probe(host, port); err != nil {
+		t.Errorf("probe serving service: %v", err)
+	}
+	healthServer.SetServingStatus("etcd", healthpb.HealthCheckResponse_NOT_SERVING)
+	if err = p.Probe(host, port); err == nil {
+		t.Error("probe not serving service succeeded")
+	}
+}
+
+func TestExecProber(t *testing.T) {
+	p := newTestProber(t, execProbe, func(p Prober) {
+		p.(*execProber).Command = `test "$RS_ADDRESS" = "192.168.0.2:6443"`
+	})
+	if err := p.Probe("192.168.0.2", "6443"); err != nil {
+		t.Errorf("probe: %v", err)
+	}
+	if err := p.Probe("192.168.0.3", "6443"); err == nil {
+		t.Error("probe with failing command succeeded")
+	}
+}
+
+func TestExecProberTimeout(t *testing.T) {
+	p := newTestProber(t, execProbe, func(p Prober) {
+		// the background child keeps the output pipe open after the shell is killed
+		p.(*execProber).Command = "sleep 60 & wait"
+	})
+	start := time.Now()
+	if err := p.Probe("192.168.0.2", "6443"); err == nil {
+		t.Error("probe with timed out command succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("probe returned after %v, want it to stop at the timeout", elapsed)
+	}
+}
@@ -18,10 +18,12 @@ import (
 	"context"
 	"errors"
 	"net"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
 
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/kubernetes/pkg/util/ipvs"
 
 	"github.com/labring/sealos/pkg/utils/logger"
@@ -32,6 +34,7 @@ type Proxier interface {
 	DeleteVirtualServer(vs string) error
 	EnsureRealServer(vs, rs string) error
 	DeleteRealServer(vs, rs string) error
+	EnsureService(vs string, svc Service) error
 	RunLoop(context.Context) error
 	TryRun() error
 }
@@ -45,16 +48,36 @@ func (ep *endpoint) String() string {
 	return net.JoinHostPort(ep.IP, strconv.Itoa(int(ep.Port)))
 }
 
-func NewProxier(scheduler string, interval time.Duration, prober Prober, syncFn func() error) Proxier {
+// Service is a virtual server with its own scheduler, prober and health check,
+// the zero values fall back to the ones of the proxier.
+type Service struct {
+	Scheduler   string
+	Prober      Prober
+	HealthCheck *HealthCheck
+	RealServers []string
+}
+
+// virtualService is a virtual server in the serviceMap and how its real servers are checked.
+type virtualService struct {
+	scheduler   string
+	prober      Prober
+	healthCheck *HealthCheck
+	realServers map[string]endpoint
+}
+
+func NewProxier(scheduler string, interval time.Duration, prober Prober, healthCheck HealthCheck, syncFn func() error) Proxier {
 	return &realProxier{
-		scheduler:  scheduler,
-		ipvsHandle: ipvs.New(),
-		syncFn:     syncFn,
-		serviceMap: make(map[endpoint]map[string]endpoint),
-		prober:     prober,
-		ticker:     time.NewTicker(interval),
-		tryCh:      make(chan struct{}, 1),
-		errCh:      make(chan error, 1),
+		scheduler:   scheduler,
+		ipvsHandle:  ipvs.New(),
+		syncFn:      syncFn,
+		serviceMap:  make(map[endpoint]*virtualService),
+		prober:      prober,
+		healthCheck: healthCheck,
+		states:      make(map[string]*healthState),
+		now:         time.Now,
+		ticker:      time.NewTicker(interval),
+		tryCh:       make(chan struct{}, 1),
+		errCh:       make(chan error, 1),
 	}
 }
 
@@ -63,12 +86,16 @@ type realProxier struct {
 	ipvsHandle ipvs.Interface
 	syncFn     func() error
 
-	// for prober
-	serviceMap map[endpoint]map[string]endpoint
-	prober     Prober
-	ticker     *time.Ticker
-	tryCh      chan struct{}
-	errCh      chan error
+	// for prober, mu guards serviceMap and states
+	mu          sync.Mutex
+	serviceMap  map[endpoint]*virtualService
+	prober      Prober
+	healthCheck HealthCheck
+	states      map[string]*healthState
+	now         func() time.Time
+	ticker      *time.Ticker
+	tryCh       chan struct{}
+	errCh       chan error
 }
 
 func (p *realProxier) ensureVirtualServer(vs *ipvs.VirtualServer) (*ipvs.VirtualServer, error) {
@@ -76,12 +103,14 @@ func (p *realProxier) ensureVirtualServer(vs *ipvs.VirtualServer) (*ipvs.Virtual
 	if applied == nil {
 		logger.Debug("Add new IPVS service", vs.String())
 		if err := p.ipvsHandle.AddVirtualServer(vs); err != nil {
+			syncErrors.WithLabelValues(opAddVirtualServer).Inc()
 			logger.Error("Failed to add IPVS service: %v", err)
 			return nil, err
 		}
 	} else if !applied.Equal(vs) {
 		logger.Debug("IPVS service is changed %s", applied.String())
 		if err := p.ipvsHandle.UpdateVirtualServer(vs); err != nil {
+			syncErrors.WithLabelValues(opUpdateVirtualServer).Inc()
 			logger.Error("Failed to update IPVS service: %v", err)
 			return nil, err
 		}
@@ -94,12 +123,69 @@ func (p *realProxier) EnsureVirtualServer(vs string) error {
 	if err != nil {
 		return err
 	}
+	p.mu.Lock()
+	if _, ok := p.serviceMap[ep]; !ok {
+		p.serviceMap[ep] = p.newVirtualService(Service{})
+	}
+	p.mu.Unlock()
 	_, err = p.ensureVirtualServer(p.buildVirtualServer(&ep))
+	return err
+}
+
+func (p *realProxier) newVirtualService(svc Service) *virtualService {
+	vSvc := &virtualService{
+		scheduler:   svc.Scheduler,
+		prober:      svc.Prober,
+		healthCheck: svc.HealthCheck,
+		realServers: make(map[string]endpoint),
+	}
+	if vSvc.scheduler == "" {
+		vSvc.scheduler = p.scheduler
+	}
+	if vSvc.prober == nil {
+		vSvc.prober = p.prober
+	}
+	if vSvc.healthCheck == nil {
+		vSvc.healthCheck = &p.healthCheck
+	}
+	return vSvc
+}
+
+// EnsureService ensures the virtual server vs with the settings of svc, and makes its real servers
+// exactly svc.RealServers. Real servers kept keep their health state.
+func (p *realProxier) EnsureService(vs string, svc Service) error {
+	ep, err := parseEndpoint(vs)
 	if err != nil {
 		return err
 	}
-	if _, ok := p.serviceMap[ep]; !ok {
-		p.serviceMap[ep] = make(map[string]endpoint)
+	p.mu.Lock()
+	vSvc := p.newVirtualService(svc)
+	if applied, ok := p.serviceMap[ep]; ok {
+		vSvc.realServers = applied.realServers
+	}
+	p.serviceMap[ep] = vSvc
+	desired := sets.New[string](svc.RealServers...)
+	stale := make([]string, 0)
+	for rs := range vSvc.realServers {
+		if !desired.Has(rs) {
+			stale = append(stale, rs)
+		}
+	}
+	p.mu.Unlock()
+
+	if _, err = p.ensureVirtualServer(p.buildVirtualServer(&ep)); err != nil {
+		return err
+	}
+	for _, rs := range svc.RealServers {
+		if err = p.EnsureRealServer(vs, rs); err != nil {
+			return err
+		}
+	}
+	for _, rs := range stale {
+		logger.Info("delete real server %s of %s", rs, vs)
+		if err = p.DeleteRealServer(vs, rs); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -113,10 +199,19 @@ func (p *realProxier) DeleteVirtualServer(vs string) error {
 	applied, _ := p.ipvsHandle.GetVirtualServer(vSrv)
 	if applied != nil {
 		if err := p.ipvsHandle.DeleteVirtualServer(vSrv); err != nil {
+			syncErrors.WithLabelValues(opDeleteVirtualServer).Inc()
 			logger.Error("Failed to delete IPVS service: %v", err)
 			return err
 		}
 	}
+	forgetRealServer(ep.String(), "")
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if vSvc, ok := p.serviceMap[ep]; ok {
+		for rs := range vSvc.realServers {
+			delete(p.states, stateKey(ep, rs))
+		}
+	}
 	delete(p.serviceMap, ep)
 	return nil
 }
@@ -124,6 +219,7 @@ func (p *realProxier) DeleteVirtualServer(vs string) error {
 func (p *realProxier) getRealServer(vs *ipvs.VirtualServer, rs *ipvs.RealServer) (*ipvs.RealServer, error) {
 	applied, err := p.ipvsHandle.GetRealServers(vs)
 	if err != nil {
+		syncErrors.WithLabelValues(opGetRealServers).Inc()
 		return nil, err
 	}
 	for i := range applied {
@@ -159,19 +255,31 @@ func (p *realProxier) EnsureRealServer(vs, rs string) error {
 	if err != nil {
 		return err
 	}
+	p.mu.Lock()
+	vSvc, ok := p.serviceMap[vsEp]
+	if !ok {
+		vSvc = p.newVirtualService(Service{})
+		p.serviceMap[vsEp] = vSvc
+	}
+	p.mu.Unlock()
 	defer func() {
 		if err == nil {
-			p.serviceMap[vsEp][rsEp.String()] = rsEp
+			p.mu.Lock()
+			vSvc.realServers[rsEp.String()] = rsEp
+			p.mu.Unlock()
 		}
 	}()
 	if rSrv != nil {
 		return nil
 	}
 	rSrv = p.buildRealServer(&rsEp)
+	rSrv.Weight = vSvc.healthCheck.targetWeight(rsEp.String())
 	if err = p.ipvsHandle.AddRealServer(vSrv, rSrv); err != nil {
+		syncErrors.WithLabelValues(opAddRealServer).Inc()
 		logger.Error("Failed to add real server: %v", err)
 		return err
 	}
+	recordWeight(vsEp.String(), rsEp.String(), rSrv.Weight)
 	return nil
 }
 
@@ -184,6 +292,13 @@ func (p *realProxier) DeleteRealServer(vs, rs string) error {
 	if err != nil {
 		return err
 	}
+	p.mu.Lock()
+	if vSvc, ok := p.serviceMap[vsEp]; ok {
+		delete(vSvc.realServers, rsEp.String())
+	}
+	delete(p.states, stateKey(vsEp, rsEp.String()))
+	p.mu.Unlock()
+	forgetRealServer(vsEp.String(), rsEp.String())
 	vSrv, rSrv, err := p.getServersByEndpoint(vsEp, rsEp)
 	if err != nil {
 		return err
@@ -192,6 +307,7 @@ func (p *realProxier) DeleteRealServer(vs, rs string) error {
 		return nil
 	}
 	if err = p.ipvsHandle.DeleteRealServer(vSrv, rSrv); err != nil {
+		syncErrors.WithLabelValues(opDeleteRealServer).Inc()
 		logger.Error("Failed to delete real server: %v", err)
 		return err
 	}
@@ -240,9 +356,41 @@ func (p *realProxier) Stop() {
 	close(p.errCh)
 }
 
-func (p *realProxier) checkRealServer(wg *sync.WaitGroup, vSrv *ipvs.VirtualServer, rs endpoint) {
+func stateKey(vs endpoint, rs string) string {
+	return vs.String() + "/" + rs
+}
+
+// observe records a probe result of rs, and returns whether rs is up and the weight it should have.
+func (p *realProxier) observe(vs endpoint, hc *HealthCheck, rs endpoint, probeErr error) (bool, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := stateKey(vs, rs.String())
+	state, exists := p.states[key]
+	if !exists {
+		state = &healthState{up: true}
+		p.states[key] = state
+	}
+	now := p.now()
+	state.lastProbe, state.lastError = now, ""
+	if probeErr != nil {
+		state.lastError = probeErr.Error()
+	}
+	if hc.observe(state, probeErr == nil, now) {
+		if state.up {
+			logger.Info("real server %s is up after %d successful probes", rs.String(), state.successes)
+		} else {
+			logger.Warn("real server %s is down after %d failed probes", rs.String(), state.failures)
+		}
+	}
+	recordUp(vs.String(), rs.String(), state.up)
+	return state.up, hc.weight(state, rs.String(), now)
+}
+
+func (p *realProxier) checkRealServer(wg *sync.WaitGroup, vs endpoint, vSvc *virtualService, vSrv *ipvs.VirtualServer, rs endpoint) {
 	defer wg.Done()
-	probeErr := p.prober.Probe(rs.IP, strconv.Itoa(int(rs.Port)))
+	start := time.Now()
+	probeErr := vSvc.prober.Probe(rs.IP, strconv.Itoa(int(rs.Port)))
+	observeProbe(vs.String(), rs.String(), time.Since(start), probeErr)
 	rSrv, err := p.getRealServer(vSrv, p.buildRealServer(&rs))
 	if err != nil {
 		logger.Warn("Failed to get real server: %v", err)
@@ -250,60 +398,162 @@ func (p *realProxier) checkRealServer(wg *sync.WaitGroup, vSrv *ipvs.VirtualServ
 	}
 	if probeErr != nil {
 		logger.Debug("probe error: %v", probeErr)
+	}
+	up, weight := p.observe(vs, vSvc.healthCheck, rs, probeErr)
+	if !up {
 		if rSrv != nil {
 			if rSrv.Weight != 0 {
 				logger.Debug("Trying to update wight to 0 for graceful termination")
 				rSrv.Weight = 0
 				if err = p.ipvsHandle.UpdateRealServer(vSrv, rSrv); err != nil {
+					syncErrors.WithLabelValues(opUpdateRealServer).Inc()
 					logger.Warn("Failed to update real server wight: %v", err)
+					return
 				}
+				recordWeight(vs.String(), rs.String(), 0)
 				return
 			}
 			logger.Debug("Trying to delete real server")
 			if err = p.ipvsHandle.DeleteRealServer(vSrv, rSrv); err != nil {
+				syncErrors.WithLabelValues(opDeleteRealServer).Inc()
 				logger.Warn("Failed to delete real server: %v", err)
 			}
 		}
 		return
 	}
 	if rSrv != nil {
-		if rSrv.Weight == 0 {
-			logger.Debug("Trying to update wight to 1 to receive traffic")
-			rSrv.Weight = 1
+		if rSrv.Weight != weight {
+			logger.Debug("Trying to update wight to %d to receive traffic", weight)
+			rSrv.Weight = weight
 			if err = p.ipvsHandle.UpdateRealServer(vSrv, rSrv); err != nil {
+				syncErrors.WithLabelValues(opUpdateRealServer).Inc()
 				logger.Warn("Failed to update real server wight: %v", err)
+				return
 			}
+			recordWeight(vs.String(), rs.String(), weight)
 		}
 		return
 	}
 	logger.Debug("Trying to add real server back")
-	if err = p.ipvsHandle.AddRealServer(vSrv, p.buildRealServer(&rs)); err != nil {
+	rSrv = p.buildRealServer(&rs)
+	rSrv.Weight = weight
+	if err = p.ipvsHandle.AddRealServer(vSrv, rSrv); err != nil {
+		syncErrors.WithLabelValues(opAddRealServer).Inc()
 		logger.Warn("Failed to add real server back: %v", err)
+		return
 	}
+	recordWeight(vs.String(), rs.String(), weight)
 }
 
 func (p *realProxier) runCheck() {
+	type check struct {
+		vs          endpoint
+		vSvc        *virtualService
+		realServers []endpoint
+	}
+	p.mu.Lock()
+	checks := make([]check, 0, len(p.serviceMap))
+	for vs, vSvc := range p.serviceMap {
+		c := check{vs: vs, vSvc: vSvc}
+		for _, rs := range vSvc.realServers {
+			c.realServers = append(c.realServers, rs)
+		}
+		checks = append(checks, c)
+	}
+	p.mu.Unlock()
+
 	wg := &sync.WaitGroup{}
-	for vs, rsMap := range p.serviceMap {
-		vSrv, err := p.ensureVirtualServer(p.buildVirtualServer(&vs))
+	for _, c := range checks {
+		vSrv, err := p.ensureVirtualServer(p.buildVirtualServer(&c.vs))
 		if err != nil {
 			logger.Error("Failed to get or create IPVS service: %v", err)
 			continue
 		}
-		for _, rs := range rsMap {
+		for _, rs := range c.realServers {
 			wg.Add(1)
-			go p.checkRealServer(wg, vSrv, rs)
+			go p.checkRealServer(wg, c.vs, c.vSvc, vSrv, rs)
 		}
 	}
 	wg.Wait()
 }
 
+// Status returns the virtual servers taken care of as they are in IPVS, with the health of their real servers.
+func (p *realProxier) Status() ([]VirtualServerStatus, error) {
+	type service struct {
+		vs          endpoint
+		scheduler   string
+		realServers []endpoint
+		states      map[string]healthState
+	}
+	p.mu.Lock()
+	services := make([]service, 0, len(p.serviceMap))
+	for vs, vSvc := range p.serviceMap {
+		svc := service{vs: vs, scheduler: vSvc.scheduler, states: make(map[string]healthState)}
+		for key, rs := range vSvc.realServers {
+			svc.realServers = append(svc.realServers, rs)
+			if state, ok := p.states[stateKey(vs, key)]; ok {
+				svc.states[key] = *state
+			}
+		}
+		services = append(services, svc)
+	}
+	p.mu.Unlock()
+	sort.Slice(services, func(i, j int) bool { return services[i].vs.String() < services[j].vs.String() })
+
+	status := make([]VirtualServerStatus, 0, len(services))
+	for _, svc := range services {
+		vsStatus := VirtualServerStatus{Address: svc.vs.String(), Scheduler: svc.scheduler, RealServers: []RealServerStatus{}}
+		vSrv := p.buildVirtualServer(&svc.vs)
+		applied := make(map[string]*ipvs.RealServer)
+		if v, _ := p.ipvsHandle.GetVirtualServer(vSrv); v != nil {
+			vsStatus.InIPVS = true
+			vsStatus.Scheduler = v.Scheduler
+			realServers, err := p.ipvsHandle.GetRealServers(vSrv)
+			if err != nil {
+				syncErrors.WithLabelValues(opGetRealServers).Inc()
+				return nil, err
+			}
+			for _, rSrv := range realServers {
+				applied[rSrv.String()] = rSrv
+			}
+		}
+		sort.Slice(svc.realServers, func(i, j int) bool { return svc.realServers[i].String() < svc.realServers[j].String() })
+		for _, rs := range svc.realServers {
+			rsStatus := RealServerStatus{Address: rs.String(), Up: true}
+			if state, ok := svc.states[rs.String()]; ok {
+				rsStatus.Up = state.up
+				rsStatus.ConsecutiveSuccesses = state.successes
+				rsStatus.ConsecutiveFailures = state.failures
+				if !state.lastProbe.IsZero() {
+					rsStatus.LastProbe = &state.lastProbe
+				}
+				rsStatus.LastError = state.lastError
+			}
+			if rSrv, ok := applied[rs.String()]; ok {
+				rsStatus.InIPVS = true
+				rsStatus.Weight = rSrv.Weight
+				rsStatus.ActiveConnections = rSrv.ActiveConn
+				rsStatus.InactiveConnections = rSrv.InactiveConn
+			}
+			vsStatus.RealServers = append(vsStatus.RealServers, rsStatus)
+		}
+		status = append(status, vsStatus)
+	}
+	return status, nil
+}
+
 func (p *realProxier) buildVirtualServer(ep *endpoint) *ipvs.VirtualServer {
+	scheduler := p.scheduler
+	p.mu.Lock()
+	if vSvc, ok := p.serviceMap[*ep]; ok {
+		scheduler = vSvc.scheduler
+	}
+	p.mu.Unlock()
 	return &ipvs.VirtualServer{
 		Address:   net.ParseIP(ep.IP),
 		Protocol:  "TCP",
 		Port:      ep.Port,
-		Scheduler: p.scheduler,
+		Scheduler: scheduler,
 		Flags:     0,
 		Timeout:   0,
 	}
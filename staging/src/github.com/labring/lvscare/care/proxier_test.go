@@ -0,0 +1,99 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package care
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	ipvstest "k8s.io/kubernetes/pkg/util/ipvs/testing"
+)
+
+type fakeProber struct {
+	err error
+}
+
+func (p *fakeProber) Probe(string, string) error {
+	return p.err
+}
+
+func TestHealthCheckHysteresis(t *testing.T) {
+	const vs, rs = "10.103.97.12:6443", "192.168.0.2:6443"
+	now := time.Unix(0, 0)
+	prober := &fakeProber{}
+	handle := ipvstest.NewFake()
+	p := &realProxier{
+		scheduler:  "wrr",
+		ipvsHandle: handle,
+		serviceMap: make(map[endpoint]*virtualService),
+		prober:     prober,
+		healthCheck: HealthCheck{
+			Rise:      2,
+			Fall:      2,
+			SlowStart: 10 * time.Second,
+			Weights:   map[string]int{rs: 4},
+		},
+		states: make(map[string]*healthState),
+		now:    func() time.Time { return now },
+	}
+	if err := p.EnsureVirtualServer(vs); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.EnsureRealServer(vs, rs); err != nil {
+		t.Fatal(err)
+	}
+
+	// weight returns the weight of the real server in IPVS, or -1 if it is removed
+	weight := func() int {
+		vsEp, _ := parseEndpoint(vs)
+		servers, err := handle.GetRealServers(p.buildVirtualServer(&vsEp))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(servers) == 0 {
+			return -1
+		}
+		return servers[0].Weight
+	}
+
+	steps := []struct {
+		name    string
+		probe   error
+		advance time.Duration
+		want    int
+	}{
+		{name: "initial weight", want: 4},
+		{name: "first failure is tolerated", probe: errors.New("timeout"), want: 4},
+		{name: "fall reached, drain", probe: errors.New("timeout"), want: 0},
+		{name: "still down, delete", probe: errors.New("timeout"), want: -1},
+		{name: "first success is not enough", want: -1},
+		{name: "rise reached, slow start", want: 1},
+		{name: "half of slow start", advance: 5 * time.Second, want: 2},
+		{name: "slow start done", advance: 5 * time.Second, want: 4},
+		{name: "single failure while up", probe: errors.New("gc pause"), want: 4},
+		{name: "recovered without flapping", want: 4},
+	}
+	for i, step := range steps {
+		if i > 0 {
+			now = now.Add(step.advance)
+			prober.err = step.probe
+			p.runCheck()
+		}
+		if got := weight(); got != step.want {
+			t.Fatalf("%s: weight %d, want %d", step.name, got, step.want)
+		}
+	}
+}
@@ -15,8 +15,13 @@
 package care
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/labring/lvscare/pkg/route"
 
+	"github.com/labring/sealos/pkg/utils/iputils"
 	"github.com/labring/sealos/pkg/utils/logger"
 )
 
@@ -39,3 +44,88 @@ func (impl *routeImpl) Cleanup() error {
 	logger.Info("Trying to delete route")
 	return impl.DelRoute()
 }
+
+// routesImpl routes the ips of all the virtual servers via the gateway of the same IP family.
+type routesImpl struct {
+	gws    []string
+	routes map[string]Ruler
+}
+
+func newRoutesImpl(gws []string, virtualServers ...string) (Ruler, error) {
+	impl := &routesImpl{gws: gws}
+	routes, err := impl.buildRoutes(virtualServers...)
+	if err != nil {
+		return nil, err
+	}
+	impl.routes = routes
+	return impl, nil
+}
+
+func (impl *routesImpl) buildRoutes(virtualServers ...string) (map[string]Ruler, error) {
+	routes := make(map[string]Ruler)
+	for _, vs := range virtualServers {
+		host, _, err := splitHostPort(vs)
+		if err != nil {
+			return nil, err
+		}
+		if ruler, ok := impl.routes[host]; ok {
+			routes[host] = ruler
+			continue
+		}
+		gw, err := impl.gateway(host)
+		if err != nil {
+			return nil, err
+		}
+		if routes[host], err = newRouteImpl(host, gw); err != nil {
+			return nil, err
+		}
+	}
+	return routes, nil
+}
+
+func (impl *routesImpl) gateway(host string) (string, error) {
+	for _, gw := range impl.gws {
+		if iputils.IsIpv4(gw) == iputils.IsIpv4(host) {
+			return gw, nil
+		}
+	}
+	return "", fmt.Errorf("no target ip of the same IP family as virtual server %s", host)
+}
+
+func (impl *routesImpl) Setup() error {
+	for _, ruler := range impl.routes {
+		if err := ruler.Setup(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (impl *routesImpl) Cleanup() error {
+	var errs []string
+	for _, ruler := range impl.routes {
+		if err := ruler.Cleanup(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func (impl *routesImpl) UpdateVirtualServers(virtualServers ...string) error {
+	routes, err := impl.buildRoutes(virtualServers...)
+	if err != nil {
+		return err
+	}
+	for host, ruler := range impl.routes {
+		if _, ok := routes[host]; !ok {
+			if err = ruler.Cleanup(); err != nil {
+				return err
+			}
+		}
+	}
+	impl.routes = routes
+	return impl.Setup()
+}
@@ -0,0 +1,36 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package care
+
+import "testing"
+
+func TestRoutesGateway(t *testing.T) {
+	impl := &routesImpl{gws: []string{"192.168.0.2", "fd00:192::2"}}
+	routes, err := impl.buildRoutes("10.103.97.2:6443", "[fd00::2]:6443")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for host, gw := range map[string]string{"10.103.97.2": "192.168.0.2", "fd00::2": "fd00:192::2"} {
+		r, ok := routes[host].(*routeImpl)
+		if !ok || r.Gateway != gw {
+			t.Errorf("route of %s = %+v, want via %s", host, routes[host], gw)
+		}
+	}
+
+	impl = &routesImpl{gws: []string{"192.168.0.2"}}
+	if _, err = impl.buildRoutes("[fd00::2]:6443"); err == nil {
+		t.Error("expected error for IPv6 virtual server without IPv6 target ip")
+	}
+}
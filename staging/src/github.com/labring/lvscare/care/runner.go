@@ -15,12 +15,15 @@
 package care
 
 import (
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
 
 	"github.com/labring/sealos/pkg/utils/logger"
@@ -28,7 +31,7 @@ import (
 
 var LVS = &runner{
 	options: &options{},
-	prober:  &httpProber{},
+	prober:  newProbers(),
 }
 
 type runner struct {
@@ -38,6 +41,10 @@ type runner struct {
 	proxier      Proxier
 	ruler        Ruler
 	cleanupFuncs []func() error
+
+	// services loaded from the config file and its checksum
+	services  map[string]Service
+	configSum [sha256.Size]byte
 }
 
 func (r *runner) Run() (err error) {
@@ -59,12 +66,18 @@ func (r *runner) Run() (err error) {
 	}
 
 	cleanVirtualServer := func() error {
-		logger.Info("delete IPVS service %s", r.options.VirtualServer)
-		err := r.proxier.DeleteVirtualServer(r.options.VirtualServer)
-		if err != nil {
-			logger.Warn("failed to delete IPVS service: %v", err)
+		var errs []string
+		for _, vs := range r.virtualServers() {
+			logger.Info("delete IPVS service %s", vs)
+			if err := r.proxier.DeleteVirtualServer(vs); err != nil {
+				logger.Warn("failed to delete IPVS service: %v", err)
+				errs = append(errs, err.Error())
+			}
 		}
-		return err
+		if len(errs) > 0 {
+			return errors.New(strings.Join(errs, ", "))
+		}
+		return nil
 	}
 	if r.options.CleanAndExit {
 		r.cleanupFuncs = append(r.cleanupFuncs, cleanVirtualServer)
@@ -73,6 +86,14 @@ func (r *runner) Run() (err error) {
 		}
 		return
 	}
+	if r.options.MetricsAddr != "" && !r.options.RunOnce {
+		reporter, _ := r.proxier.(statusReporter)
+		srv, err := serveMetrics(r.options.MetricsAddr, reporter)
+		if err != nil {
+			return err
+		}
+		r.cleanupFuncs = append(r.cleanupFuncs, srv.Close)
+	}
 	errCh := make(chan error, 1)
 	ctx := signals.SetupSignalHandler()
 	go func() {
@@ -94,6 +115,14 @@ func (r *runner) Run() (err error) {
 
 // run once at startup
 func (r *runner) ensureIPVSRules() error {
+	if r.options.ConfigFile != "" {
+		for _, vs := range r.virtualServers() {
+			if err := r.proxier.EnsureService(vs, r.services[vs]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 	if err := r.proxier.EnsureVirtualServer(r.options.VirtualServer); err != nil {
 		return err
 	}
@@ -108,6 +137,70 @@ func (r *runner) ensureIPVSRules() error {
 func (r *runner) periodicRun() error {
 	// ensure ipset/iptables ruler?
 	// or only run once at startup?
+	if r.options.ConfigFile != "" {
+		if err := r.reloadConfig(); err != nil {
+			logger.Error("failed to reload config %s, keep the running one: %v", r.options.ConfigFile, err)
+		}
+	}
+	return nil
+}
+
+// virtualServers returns the addresses of the virtual servers taken care of.
+func (r *runner) virtualServers() []string {
+	if r.options.ConfigFile == "" {
+		return []string{r.options.VirtualServer}
+	}
+	return sets.List(sets.KeySet(r.services))
+}
+
+func (r *runner) loadServices() (map[string]Service, [sha256.Size]byte, error) {
+	cfg, sum, err := loadConfig(r.options.ConfigFile)
+	if err != nil {
+		return nil, sum, err
+	}
+	defaults, ok := r.prober.(*probers)
+	if !ok {
+		return nil, sum, errors.New("health check flags are not available for the config file")
+	}
+	services, err := r.options.services(cfg, defaults)
+	return services, sum, err
+}
+
+// reloadConfig applies the config file if it is changed, the virtual servers not changed are kept as is.
+func (r *runner) reloadConfig() error {
+	data, err := os.ReadFile(r.options.ConfigFile)
+	if err != nil {
+		return err
+	}
+	if sha256.Sum256(data) == r.configSum {
+		return nil
+	}
+	services, sum, err := r.loadServices()
+	if err != nil {
+		return err
+	}
+	logger.Info("config %s is changed, reloading", r.options.ConfigFile)
+	for vs := range r.services {
+		if _, ok := services[vs]; !ok {
+			logger.Info("delete IPVS service %s", vs)
+			if err = r.proxier.DeleteVirtualServer(vs); err != nil {
+				return err
+			}
+		}
+	}
+	old := r.services
+	r.services, r.configSum = services, sum
+	for _, vs := range r.virtualServers() {
+		if _, ok := old[vs]; !ok {
+			logger.Info("add IPVS service %s", vs)
+		}
+		if err = r.proxier.EnsureService(vs, services[vs]); err != nil {
+			return err
+		}
+	}
+	if updater, ok := r.ruler.(virtualServersUpdater); ok {
+		return updater.UpdateVirtualServers(r.virtualServers()...)
+	}
 	return nil
 }
 
@@ -145,22 +238,35 @@ func (r *runner) ValidateAndSetDefaults() error {
 			}
 		}
 	}
-	r.proxier = NewProxier(r.options.scheduler, time.Duration(r.options.Interval), r.prober, r.periodicRun)
-	virtualIP, _, err := splitHostPort(r.options.VirtualServer)
-	if err != nil {
-		return err
+	r.proxier = NewProxier(r.options.scheduler, time.Duration(r.options.Interval), r.prober, r.options.healthCheck(), r.periodicRun)
+	if r.options.ConfigFile != "" {
+		services, sum, err := r.loadServices()
+		if err != nil {
+			return err
+		}
+		r.services, r.configSum = services, sum
+	}
+	for _, vs := range r.virtualServers() {
+		if _, _, err := splitHostPort(vs); err != nil {
+			return err
+		}
 	}
 
 	var ruler Ruler
+	var err error
 	switch r.Mode {
 	case routeMode:
-		if r.options.TargetIP == nil {
+		if len(r.options.TargetIPs) == 0 {
 			logger.Warn("running routeMode and Target IP is not valid IP, skipping")
 			break
 		}
-		ruler, err = newRouteImpl(virtualIP, r.options.TargetIP.String())
+		gws := make([]string, 0, len(r.options.TargetIPs))
+		for _, ip := range r.options.TargetIPs {
+			gws = append(gws, ip.String())
+		}
+		ruler, err = newRoutesImpl(gws, r.virtualServers()...)
 	case linkMode:
-		ruler, err = newIptablesImpl(r.options.IfaceName, r.options.MasqueradeBit, r.options.VirtualServer)
+		ruler, err = newIptablesImpl(r.options.IfaceName, r.options.MasqueradeBit, r.virtualServers()...)
 	case "":
 		// do nothing, disable ruler
 	default:
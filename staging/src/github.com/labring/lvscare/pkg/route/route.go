@@ -27,7 +27,10 @@ import (
 	"github.com/vishvananda/netlink"
 )
 
-var ErrNotIPV4Fmt = "IP %s is not valid IPV4 address"
+var (
+	ErrNotIPFmt         = "IP %s is not valid IP address"
+	ErrIPFamilyMismatch = "IP %s and %s are not of the same IP family"
+)
 
 type Route struct {
 	Host    string
@@ -41,17 +44,21 @@ func New(host, gateway string) *Route {
 	}
 }
 
-func validateIPv4Type(addresses ...string) error {
-	for i := range addresses {
-		if !iputils.IsIpv4(addresses[i]) {
-			return fmt.Errorf(ErrNotIPV4Fmt, addresses[i])
+// validateIPFamily checks host and gateway are both IPv4 or both IPv6.
+func validateIPFamily(host, gateway string) error {
+	for _, address := range []string{host, gateway} {
+		if net.ParseIP(address) == nil {
+			return fmt.Errorf(ErrNotIPFmt, address)
 		}
 	}
+	if iputils.IsIpv4(host) != iputils.IsIpv4(gateway) {
+		return fmt.Errorf(ErrIPFamilyMismatch, host, gateway)
+	}
 	return nil
 }
 
 func (r *Route) SetRoute() error {
-	if err := validateIPv4Type(r.Gateway, r.Host); err != nil {
+	if err := validateIPFamily(r.Host, r.Gateway); err != nil {
 		return err
 	}
 
@@ -64,7 +71,7 @@ func (r *Route) SetRoute() error {
 }
 
 func (r *Route) DelRoute() error {
-	if err := validateIPv4Type(r.Gateway, r.Host); err != nil {
+	if err := validateIPFamily(r.Host, r.Gateway); err != nil {
 		return err
 	}
 
@@ -76,12 +83,17 @@ func (r *Route) DelRoute() error {
 	return nil
 }
 
+// hostNet returns the host route of host, /32 for IPv4 and /128 for IPv6.
+func hostNet(host string) *net.IPNet {
+	if iputils.IsIpv4(host) {
+		return &net.IPNet{IP: net.ParseIP(host).To4(), Mask: net.CIDRMask(32, 32)}
+	}
+	return &net.IPNet{IP: net.ParseIP(host), Mask: net.CIDRMask(128, 128)}
+}
+
 // addRouteGatewayViaHost host: 10.103.97.2  gateway 192.168.253.129
 func addRouteGatewayViaHost(host, gateway string, priority int) error {
-	Dst := &net.IPNet{
-		IP:   net.ParseIP(host),
-		Mask: net.CIDRMask(32, 32),
-	}
+	Dst := hostNet(host)
 	r := netlink.Route{
 		Dst:      Dst,
 		Gw:       net.ParseIP(gateway),
@@ -92,10 +104,7 @@ func addRouteGatewayViaHost(host, gateway string, priority int) error {
 
 // addRouteGatewayViaHost host: 10.103.97.2  gateway 192.168.253.129
 func delRouteGatewayViaHost(host, gateway string) error {
-	Dst := &net.IPNet{
-		IP:   net.ParseIP(host),
-		Mask: net.CIDRMask(32, 32),
-	}
+	Dst := hostNet(host)
 	r := netlink.Route{
 		Dst: Dst,
 		Gw:  net.ParseIP(gateway),